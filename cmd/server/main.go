@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -10,6 +13,7 @@ import (
 	"time"
 
 	"github.com/cleibson/goexpert-rate-limiter/internal/config"
+	"github.com/cleibson/goexpert-rate-limiter/internal/metrics"
 	"github.com/cleibson/goexpert-rate-limiter/internal/middleware"
 	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
 	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
@@ -22,16 +26,102 @@ func main() {
 		log.Fatalf("Falha ao carregar configuração: %v", err)
 	}
 
-	// Inicializa armazenamento Redis
-	redisStorage := storage.NewRedisStorage(cfg.Redis.Addr, cfg.Redis.Password, cfg.Redis.DB)
-	defer redisStorage.Close()
+	// Inicializa o armazenamento escolhido em STORAGE_BACKEND
+	// O armazenamento é fechado apenas em gracefulShutdown, depois que o servidor drena as
+	// requisições em andamento
+	store, err := storage.New(storage.Config{
+		Backend: cfg.StorageBackend,
+		Redis: storage.RedisOptions{
+			Addr:      cfg.Redis.Addr,
+			Password:  cfg.Redis.Password,
+			DB:        cfg.Redis.DB,
+			KeyPrefix: cfg.Redis.KeyPrefix,
+			KeyNames:  cfg.Redis.StorageKeyNames(),
 
-	// Testa conexão Redis
+			ReadAddr:          cfg.Redis.ReadAddr,
+			StrongConsistency: cfg.Redis.StrongConsistency,
+
+			ReadTimeout:  cfg.Redis.ReadTimeout,
+			WriteTimeout: cfg.Redis.WriteTimeout,
+
+			SlideWindowOnEachRequest: cfg.SlideWindowOnEachRequest,
+		},
+		Memory: storage.MemoryOptions{
+			KeyNames: cfg.Redis.StorageKeyNames(),
+
+			SlideWindowOnEachRequest: cfg.SlideWindowOnEachRequest,
+		},
+	})
+	if err != nil {
+		log.Fatalf("Falha ao criar armazenamento: %v", err)
+	}
+
+	// Funcionalidades que dependem do Redis ficam indisponíveis com o armazenamento em memória
+	redisStorage, isRedis := store.(*storage.RedisStorage)
+	if !isRedis {
+		log.Printf("Armazenamento em memória: os limites não são compartilhados entre instâncias")
+		if cfg.Redis.ServerTime || cfg.DynamicTokens {
+			log.Printf("REDIS_SERVER_TIME e RATE_LIMIT_DYNAMIC_TOKENS são ignorados sem o Redis")
+		}
+	}
+
+	// Testa conexão com o armazenamento e falha rapidamente se estiver inacessível; com a
+	// limitação desligada ele não é necessário
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	if cfg.Enabled {
+		if err := store.Healthy(ctx); err != nil {
+			log.Fatalf("Armazenamento %s inacessível: %v", cfg.StorageBackend, err)
+		}
+	} else {
+		log.Println("Limitação de taxa desligada (RATE_LIMIT_ENABLED=false)")
+	}
+
+	// Registra uma amostra dos bloqueios no log para auditoria
+	limiterStorage := store
+	if cfg.AuditSampleRate > 0 {
+		limiterStorage = storage.NewAuditStorage(store, storage.AuditSinkFunc(logBlock), storage.AuditOptions{
+			SampleRate:   cfg.AuditSampleRate,
+			KeySeparator: cfg.Redis.KeyNames.Separator,
+		})
+	}
+
 	// Inicializa rate limiter
-	rateLimiter := ratelimiter.NewRateLimiter(redisStorage, cfg.IP)
+	rateLimiter := ratelimiter.NewRateLimiter(limiterStorage, cfg.IP,
+		ratelimiter.WithKeyNames(cfg.Redis.KeyNames))
+
+	// Usa o horário do Redis para que instâncias com relógios dessincronizados concordem
+	if cfg.Redis.ServerTime && isRedis {
+		rateLimiter.SetClock(redisStorage.ServerClock(storage.ServerClockOptions{}))
+	}
+
+	// Rejeita as chaves bloqueadas sem consultar o armazenamento enquanto o bloqueio está em cache
+	rateLimiter.SetBlockCache(cfg.BlockCacheTTL)
+
+	// Define o tratamento de tokens sem configuração
+	rateLimiter.SetUnknownTokenPolicy(cfg.UnknownTokenPolicy)
+	if cfg.DefaultToken != nil {
+		rateLimiter.SetDefaultTokenConfig(*cfg.DefaultToken)
+	}
+
+	// Consulta configurações de tokens compartilhadas no Redis para tokens fora do mapa local
+	if cfg.DynamicTokens && isRedis {
+		rateLimiter.SetTokenConfigSource(ratelimiter.NewDynamicConfigStore(redisStorage, ratelimiter.DynamicConfigOptions{
+			TTL:       cfg.DynamicTokensTTL,
+			Separator: rateLimiter.KeyNames().Separator,
+		}))
+	}
+
+	// Limite compartilhado por todas as requisições, aplicado antes dos limites de cada cliente
+	if cfg.Global != nil {
+		rateLimiter.SetGlobalConfig(*cfg.Global)
+	}
+
+	// Limite das requisições sem token, separado do limite de cada IP
+	if cfg.Anonymous != nil {
+		rateLimiter.SetAnonymousConfig(*cfg.Anonymous, cfg.AnonymousScope)
+	}
 
 	// Adiciona configurações de tokens
 	for token, tokenConfig := range cfg.Tokens {
@@ -40,28 +130,8 @@ func main() {
 			token, tokenConfig.Requests, tokenConfig.Window, tokenConfig.BlockTime)
 	}
 
-	// Inicializa middleware
-	rateLimiterMiddleware := middleware.NewRateLimiterMiddleware(rateLimiter)
-
-	// Configura rotas
-	mux := http.NewServeMux()
-
-	// Endpoint de verificação de saúde
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status": "ok"}`))
-	})
-
-	// Endpoint de teste
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"message": "Request successful!", "timestamp": "` + time.Now().Format(time.RFC3339) + `"}`))
-	})
-
-	// Encapsula com middleware de rate limiter
-	handler := rateLimiterMiddleware.Handler(mux)
+	// Configura rotas e o middleware de rate limiter
+	handler := newHandler(cfg, rateLimiter, store, metrics.New())
 
 	// Configura servidor
 	server := &http.Server{
@@ -90,9 +160,112 @@ func main() {
 	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := server.Shutdown(ctx); err != nil {
+	if err := gracefulShutdown(ctx, server, store); err != nil {
 		log.Fatalf("Servidor forçado a encerrar: %v", err)
 	}
 
 	log.Println("Servidor encerrado")
 }
+
+// newHandler registra as rotas do servidor e as encapsula com o middleware de rate limiter,
+// que alimenta as métricas expostas em /metrics
+func newHandler(cfg *config.Config, rateLimiter *ratelimiter.RateLimiter, store storage.Storage, rateLimiterMetrics *metrics.Metrics) http.Handler {
+	// Health checks e a coleta de métricas não consomem o limite dos clientes nem são
+	// bloqueados por ele
+	rateLimiterMiddleware := middleware.NewRateLimiterMiddleware(rateLimiter,
+		middleware.WithEnabled(cfg.Enabled),
+		middleware.WithShadowMode(cfg.ShadowMode),
+		middleware.WithMetrics(rateLimiterMetrics),
+		middleware.WithAPIKeyHeader(cfg.APIKeyHeader),
+		middleware.WithStripAPIKeyHeader(cfg.StripAPIKeyHeader),
+		middleware.WithBypassSecret(cfg.BypassHeader, cfg.BypassSecret),
+		middleware.WithRejectStatusCode(cfg.RejectStatusCode),
+		middleware.WithCheckOrder(cfg.CheckOrder),
+		middleware.WithClientIPStrategy(cfg.ClientIPStrategy),
+		middleware.WithGlobalLimit(cfg.Global != nil),
+		middleware.WithAnonymousMode(cfg.AnonymousMode),
+		middleware.WithPerHost(cfg.PerHost),
+		middleware.WithMaxWait(cfg.MaxWait),
+		middleware.WithQuotaHeaders(cfg.QuotaHeaders),
+		middleware.WithProblemJSON(cfg.ProblemJSON),
+		middleware.WithProblemType(cfg.ProblemType),
+		middleware.WithSkip(middleware.SkipPaths("/health", "/ready", "/metrics")),
+	)
+
+	mux := http.NewServeMux()
+
+	// Endpoint de verificação de saúde (liveness) e de prontidão (readiness)
+	mux.HandleFunc("/health", healthHandler)
+	mux.Handle("/ready", readyHandler(store))
+
+	// Métricas no formato de texto do Prometheus
+	mux.Handle("/metrics", rateLimiterMetrics.Handler())
+
+	// Endpoint de teste
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"message": "Request successful!", "timestamp": "` + time.Now().Format(time.RFC3339) + `"}`))
+	})
+
+	return rateLimiterMiddleware.Handler(mux)
+}
+
+// readyCheckTimeout limita a verificação do armazenamento feita pelo endpoint /ready
+const readyCheckTimeout = 2 * time.Second
+
+// healthHandler responde à sonda de liveness sem consultar dependências
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "ok"}`))
+}
+
+// readyHandler responde à sonda de readiness verificando o armazenamento, com 503 enquanto
+// ele estiver inacessível
+func readyHandler(store storage.Storage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readyCheckTimeout)
+		defer cancel()
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := store.Healthy(ctx); err != nil {
+			log.Printf("Armazenamento indisponível: %v", err)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status": "unavailable"}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "ready"}`))
+	})
+}
+
+// logBlock registra no log um bloqueio amostrado pelo AuditStorage
+func logBlock(event storage.AuditEvent) {
+	log.Printf("Auditoria de bloqueio: tipo=%s chave=%s duração=%s instante=%s",
+		event.KeyType, event.Key, event.Duration, event.Time.Format(time.RFC3339))
+}
+
+// shutdowner é implementado por *http.Server
+type shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// gracefulShutdown encerra o servidor HTTP aguardando as requisições em andamento e só então
+// fecha o armazenamento, para que as verificações de limite ainda em execução não falhem. O
+// armazenamento é fechado mesmo se o servidor não drenar a tempo.
+func gracefulShutdown(ctx context.Context, server shutdowner, store io.Closer) error {
+	shutdownErr := server.Shutdown(ctx)
+	if shutdownErr != nil {
+		shutdownErr = fmt.Errorf("falha ao encerrar servidor: %w", shutdownErr)
+	}
+
+	closeErr := store.Close()
+	if closeErr != nil {
+		closeErr = fmt.Errorf("falha ao fechar armazenamento: %w", closeErr)
+	}
+
+	return errors.Join(shutdownErr, closeErr)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/config"
+	"github.com/cleibson/goexpert-rate-limiter/internal/metrics"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter/ratelimitertest"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder registra a ordem em que o servidor e o armazenamento são encerrados
+type recorder struct {
+	calls []string
+}
+
+type fakeServer struct {
+	recorder *recorder
+	err      error
+}
+
+func (s *fakeServer) Shutdown(ctx context.Context) error {
+	s.recorder.calls = append(s.recorder.calls, "server")
+	return s.err
+}
+
+type fakeStorage struct {
+	recorder *recorder
+	err      error
+}
+
+func (s *fakeStorage) Close() error {
+	s.recorder.calls = append(s.recorder.calls, "storage")
+	return s.err
+}
+
+func TestGracefulShutdown_DrainsServerBeforeClosingStorage(t *testing.T) {
+	rec := &recorder{}
+
+	err := gracefulShutdown(context.Background(), &fakeServer{recorder: rec}, &fakeStorage{recorder: rec})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"server", "storage"}, rec.calls)
+}
+
+func TestGracefulShutdown_ClosesStorageWhenServerFails(t *testing.T) {
+	rec := &recorder{}
+
+	err := gracefulShutdown(context.Background(),
+		&fakeServer{recorder: rec, err: context.DeadlineExceeded},
+		&fakeStorage{recorder: rec, err: errors.New("conexão fechada")})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "falha ao fechar armazenamento: conexão fechada")
+	assert.Equal(t, []string{"server", "storage"}, rec.calls)
+}
+
+// healthStorage reporta o resultado configurado em Healthy; os demais métodos não são usados
+type healthStorage struct {
+	storage.Storage
+	err error
+}
+
+func (s *healthStorage) Healthy(ctx context.Context) error {
+	return s.err
+}
+
+func TestHealthHandler_IgnoresStorage(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	healthHandler(recorder, httptest.NewRequest("GET", "/health", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"status": "ok"}`, recorder.Body.String())
+}
+
+func TestReadyHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+		body string
+	}{
+		{name: "armazenamento acessível", code: http.StatusOK, body: `{"status": "ready"}`},
+		{name: "armazenamento indisponível", err: errors.New("connection refused"), code: http.StatusServiceUnavailable, body: `{"status": "unavailable"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			readyHandler(&healthStorage{err: tt.err}).ServeHTTP(recorder, httptest.NewRequest("GET", "/ready", nil))
+
+			assert.Equal(t, tt.code, recorder.Code)
+			assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+			assert.JSONEq(t, tt.body, recorder.Body.String())
+		})
+	}
+}
+
+func TestNewHandler_ExposesMetrics(t *testing.T) {
+	store := ratelimitertest.NewStorage(nil)
+	rateLimiter := ratelimiter.NewRateLimiter(store, ratelimiter.Config{Requests: 2, Window: time.Minute, BlockTime: time.Minute})
+	handler := newHandler(&config.Config{Enabled: true}, rateLimiter, store, metrics.New())
+
+	send := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	for i := 0; i < 3; i++ {
+		send("/")
+	}
+
+	// O IP já está bloqueado, mas a coleta de métricas não é limitada
+	recorder := send("/metrics")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), `ratelimiter_requests_allowed_total{scope="ip"} 2`+"\n")
+	assert.Contains(t, recorder.Body.String(), `ratelimiter_requests_rejected_total{scope="ip"} 1`+"\n")
+	assert.Contains(t, recorder.Body.String(), "# TYPE ratelimiter_shadow_blocks_total counter\n")
+}
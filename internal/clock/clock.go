@@ -0,0 +1,63 @@
+// Package clock fornece uma abstração de tempo para que janelas e bloqueios possam ser
+// testados de forma determinística
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock fornece o instante atual
+type Clock interface {
+	// Now retorna o instante atual
+	Now() time.Time
+}
+
+// realClock implementa Clock usando o relógio do sistema
+type realClock struct{}
+
+// New retorna um Clock baseado no relógio do sistema
+func New() Clock {
+	return realClock{}
+}
+
+// Now retorna o instante atual do sistema
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// FakeClock é um Clock controlado manualmente, destinado a testes.
+// É seguro para uso concorrente.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock cria um FakeClock parado no instante informado
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now retorna o instante atual do relógio
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Advance avança o relógio pela duração especificada
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// Set posiciona o relógio no instante especificado
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+}
@@ -0,0 +1,33 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClock_New(t *testing.T) {
+	before := time.Now()
+	now := New().Now()
+	after := time.Now()
+
+	assert.False(t, now.Before(before))
+	assert.False(t, now.After(after))
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+
+	// O relógio fica parado até ser avançado
+	assert.Equal(t, start, clock.Now())
+	assert.Equal(t, start, clock.Now())
+
+	clock.Advance(1500 * time.Millisecond)
+	assert.Equal(t, start.Add(1500*time.Millisecond), clock.Now())
+
+	later := start.Add(time.Hour)
+	clock.Set(later)
+	assert.Equal(t, later, clock.Now())
+}
@@ -1,21 +1,109 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/cleibson/goexpert-rate-limiter/internal/middleware"
 	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
 	"github.com/joho/godotenv"
 )
 
 // Config armazena toda a configuração da aplicação
 type Config struct {
+	// Enabled liga a limitação de taxa; falso mantém o middleware apenas repassando requisições
+	Enabled bool
+
+	// ShadowMode avalia os limites sem aplicá-los, apenas registrando as requisições que
+	// seriam rejeitadas
+	ShadowMode bool
+
+	// APIKeyHeader é o header de onde o token é lido; vazio usa o header API_KEY
+	APIKeyHeader string
+
+	// StripAPIKeyHeader remove o header do token antes de repassar a requisição aos handlers
+	StripAPIKeyHeader bool
+
+	// BypassHeader e BypassSecret isentam da limitação as requisições que trazem o segredo no
+	// header (ex: chamadas internas entre serviços); vazios desligam a isenção
+	BypassHeader string
+	BypassSecret string
+
+	// RejectStatusCode é o status das respostas para requisições acima do limite (padrão 429)
+	RejectStatusCode int
+
+	// StorageBackend é a implementação de armazenamento usada (redis ou memory)
+	StorageBackend storage.Backend
+
+	// SlideWindowOnEachRequest renova a expiração das janelas a cada requisição em vez de
+	// defini-la apenas no início da janela
+	SlideWindowOnEachRequest bool
+
 	Redis  RedisConfig
 	IP     ratelimiter.Config
 	Tokens map[string]ratelimiter.Config
+
+	// UnknownTokenPolicy define o tratamento de tokens sem configuração
+	UnknownTokenPolicy ratelimiter.UnknownTokenPolicy
+
+	// CheckOrder define como o token e o IP das requisições são combinados
+	CheckOrder middleware.CheckOrder
+
+	// ClientIPStrategy define a precedência dos headers de proxy na obtenção do IP do cliente
+	ClientIPStrategy middleware.ClientIPStrategy
+
+	// QuotaHeaders define quais headers de cota são enviados nas respostas
+	QuotaHeaders middleware.QuotaHeaders
+
+	// PerHost separa os orçamentos dos clientes por host de destino (header Host), para
+	// proxies reversos que atendem muitos domínios
+	PerHost bool
+
+	// ProblemJSON responde às requisições acima do limite no formato problem+json (RFC 7807),
+	// com ProblemType no membro type; vazio usa "about:blank"
+	ProblemJSON bool
+	ProblemType string
+
+	// MaxWait é o tempo máximo em que uma requisição acima do limite aguarda a próxima vaga
+	// antes de ser rejeitada; zero rejeita de imediato
+	MaxWait time.Duration
+
+	// DefaultToken é a configuração aplicada a tokens desconhecidos com a política
+	// AllowWithDefault; nil usa a configuração de IP
+	DefaultToken *ratelimiter.Config
+
+	// Global é o limite compartilhado por todas as requisições, aplicado antes dos limites de
+	// cada cliente; nil desliga o limite global
+	Global *ratelimiter.Config
+
+	// Anonymous é o limite das requisições sem token, compartilhado ou por IP conforme
+	// AnonymousScope e combinado com o limite de IP conforme AnonymousMode; nil desliga o
+	// limite anônimo
+	Anonymous      *ratelimiter.Config
+	AnonymousScope ratelimiter.AnonymousScope
+	AnonymousMode  middleware.AnonymousMode
+
+	// DynamicTokens habilita a leitura de configurações de tokens de hashes no Redis,
+	// compartilhadas entre instâncias, relidas a cada DynamicTokensTTL
+	DynamicTokens    bool
+	DynamicTokensTTL time.Duration
+
+	// BlockCacheTTL é a duração máxima em que um bloqueio visto é mantido em cache local,
+	// rejeitando a chave sem consultar o armazenamento; zero desliga o cache
+	BlockCacheTTL time.Duration
+
+	// AuditSampleRate é a fração dos bloqueios registrados no log de auditoria, entre 0 e 1;
+	// zero desliga a auditoria
+	AuditSampleRate float64
+
+	// TokenMetadata armazena metadados livres por token (ex: dono, plano), quando fornecidos
+	TokenMetadata map[string]map[string]string
 }
 
 // RedisConfig armazena a configuração de conexão Redis
@@ -23,6 +111,39 @@ type RedisConfig struct {
 	Addr     string
 	Password string
 	DB       int
+
+	// KeyPrefix é o namespace aplicado a todas as chaves no Redis
+	KeyPrefix string
+
+	// KeyNames define o separador e os namespaces das chaves de IP e de token; campos vazios
+	// usam ratelimiter.DefaultKeyNames
+	KeyNames ratelimiter.KeyNames
+
+	// BlockedKeyName é o namespace das chaves de bloqueio; vazio usa "blocked"
+	BlockedKeyName string
+
+	// ReadAddr é o endereço de uma réplica para as leituras; vazio lê do primário
+	ReadAddr string
+
+	// StrongConsistency ignora ReadAddr e faz todas as leituras no primário
+	StrongConsistency bool
+
+	// ServerTime faz o rate limiter usar o horário do Redis em vez do relógio local
+	ServerTime bool
+
+	// ReadTimeout e WriteTimeout limitam cada leitura e cada escrita no Redis; zero não
+	// aplica limite
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// StorageKeyNames retorna os nomes das chaves auxiliares do armazenamento, com o mesmo
+// separador das chaves do rate limiter
+func (c RedisConfig) StorageKeyNames() storage.KeyNames {
+	return storage.KeyNames{
+		Separator: c.KeyNames.Separator,
+		Blocked:   c.BlockedKeyName,
+	}
 }
 
 // Load carrega configuração a partir de variáveis de ambiente
@@ -31,13 +152,56 @@ func Load() (*Config, error) {
 	_ = godotenv.Load()
 
 	config := &Config{
-		Tokens: make(map[string]ratelimiter.Config),
+		Tokens:        make(map[string]ratelimiter.Config),
+		TokenMetadata: make(map[string]map[string]string),
+	}
+
+	config.Enabled = getEnvAsBool("RATE_LIMIT_ENABLED", true)
+	config.ShadowMode = getEnvAsBool("RATE_LIMIT_SHADOW_MODE", false)
+	config.APIKeyHeader = getEnv("RATE_LIMIT_API_KEY_HEADER", "")
+	config.StripAPIKeyHeader = getEnvAsBool("RATE_LIMIT_STRIP_API_KEY_HEADER", false)
+
+	config.BypassHeader = getEnv("RATE_LIMIT_BYPASS_HEADER", "")
+	config.BypassSecret = getEnv("RATE_LIMIT_BYPASS_SECRET", "")
+	if (config.BypassHeader == "") != (config.BypassSecret == "") {
+		return nil, errors.New("RATE_LIMIT_BYPASS_HEADER e RATE_LIMIT_BYPASS_SECRET devem ser definidos juntos")
+	}
+
+	config.RejectStatusCode = getEnvAsInt("RATE_LIMIT_REJECT_STATUS_CODE", http.StatusTooManyRequests)
+	if err := validateRejectStatusCode(config.RejectStatusCode); err != nil {
+		return nil, err
+	}
+
+	storageBackend, err := storage.ParseBackend(getEnv("STORAGE_BACKEND", ""))
+	if err != nil {
+		return nil, err
 	}
+	config.StorageBackend = storageBackend
+	config.SlideWindowOnEachRequest = getEnvAsBool("RATE_LIMIT_SLIDE_WINDOW", false)
 
 	// Carrega configuração Redis
 	config.Redis.Addr = getEnv("REDIS_ADDR", "localhost:6379")
 	config.Redis.Password = getEnv("REDIS_PASSWORD", "")
 	config.Redis.DB = getEnvAsInt("REDIS_DB", 0)
+	config.Redis.KeyPrefix = getEnv("REDIS_KEY_PREFIX", "")
+	config.Redis.KeyNames = ratelimiter.KeyNames{
+		Separator: getEnv("REDIS_KEY_SEPARATOR", ""),
+		IP:        getEnv("REDIS_KEY_NAME_IP", ""),
+		Token:     getEnv("REDIS_KEY_NAME_TOKEN", ""),
+	}
+	config.Redis.BlockedKeyName = getEnv("REDIS_KEY_NAME_BLOCKED", "")
+	config.Redis.ReadAddr = getEnv("REDIS_READ_ADDR", "")
+	config.Redis.StrongConsistency = getEnvAsBool("REDIS_STRONG_CONSISTENCY", false)
+	config.Redis.ServerTime = getEnvAsBool("REDIS_SERVER_TIME", false)
+
+	config.Redis.ReadTimeout, err = time.ParseDuration(getEnv("REDIS_READ_TIMEOUT", "0s"))
+	if err != nil {
+		return nil, fmt.Errorf("duração inválida do tempo máximo de leitura do Redis: %w", err)
+	}
+	config.Redis.WriteTimeout, err = time.ParseDuration(getEnv("REDIS_WRITE_TIMEOUT", "0s"))
+	if err != nil {
+		return nil, fmt.Errorf("duração inválida do tempo máximo de escrita do Redis: %w", err)
+	}
 
 	// Carrega configuração de limitação de IP
 	ipRequests := getEnvAsInt64("RATE_LIMIT_IP_REQUESTS", 10)
@@ -50,10 +214,151 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("duração inválida do tempo de bloqueio de IP: %w", err)
 	}
 
+	ipBurst := getEnvAsInt64("RATE_LIMIT_IP_BURST", 0)
+	ipBurstWindow, err := time.ParseDuration(getEnv("RATE_LIMIT_IP_BURST_WINDOW", "0s"))
+	if err != nil {
+		return nil, fmt.Errorf("duração inválida do período de burst de IP: %w", err)
+	}
+
+	ipBlockJitter, err := time.ParseDuration(getEnv("RATE_LIMIT_IP_BLOCK_JITTER", "0s"))
+	if err != nil {
+		return nil, fmt.Errorf("duração inválida da variação do bloqueio de IP: %w", err)
+	}
+
+	ipAlgorithm, err := parseAlgorithm(getEnv("RATE_LIMIT_IP_ALGORITHM", ""))
+	if err != nil {
+		return nil, fmt.Errorf("algoritmo inválido para IP: %w", err)
+	}
+
+	ipWindowAlignment, err := parseWindowAlignment(getEnv("RATE_LIMIT_IP_WINDOW_ALIGNMENT", ""))
+	if err != nil {
+		return nil, fmt.Errorf("alinhamento de janela inválido para IP: %w", err)
+	}
+
 	config.IP = ratelimiter.Config{
-		Requests:  ipRequests,
-		Window:    ipWindow,
-		BlockTime: ipBlockTime,
+		Requests:        ipRequests,
+		Window:          ipWindow,
+		WindowAlignment: ipWindowAlignment,
+		Burst:           ipBurst,
+		BurstWindow:     ipBurstWindow,
+		BlockTime:       ipBlockTime,
+		BlockJitter:     ipBlockJitter,
+		Algorithm:       ipAlgorithm,
+		MaxConcurrent:   getEnvAsInt64("RATE_LIMIT_IP_MAX_CONCURRENT", 0),
+		SoftLimit:       getEnvAsInt64("RATE_LIMIT_IP_SOFT_LIMIT", 0),
+	}
+	if err := validateSoftLimit(config.IP); err != nil {
+		return nil, fmt.Errorf("configuração inválida de IP: %w", err)
+	}
+	if err := loadBlockEscalation("RATE_LIMIT_IP", &config.IP); err != nil {
+		return nil, fmt.Errorf("escalada de bloqueio inválida para IP: %w", err)
+	}
+
+	// Carrega a política para tokens desconhecidos
+	config.UnknownTokenPolicy, err = parseUnknownTokenPolicy(getEnv("RATE_LIMIT_UNKNOWN_TOKEN_POLICY", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	config.CheckOrder, err = parseCheckOrder(getEnv("RATE_LIMIT_CHECK_ORDER", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	config.ClientIPStrategy, err = parseClientIPStrategy(getEnv("RATE_LIMIT_CLIENT_IP_STRATEGY", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	config.QuotaHeaders, err = parseQuotaHeaders(getEnv("RATE_LIMIT_HEADERS", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	config.PerHost = getEnvAsBool("RATE_LIMIT_PER_HOST", false)
+
+	config.ProblemJSON = getEnvAsBool("RATE_LIMIT_PROBLEM_JSON", false)
+	config.ProblemType = getEnv("RATE_LIMIT_PROBLEM_TYPE", "")
+
+	if requests := getEnvAsInt64("RATE_LIMIT_DEFAULT_TOKEN_REQUESTS", 0); requests > 0 {
+		window, err := time.ParseDuration(getEnv("RATE_LIMIT_DEFAULT_TOKEN_WINDOW", "1s"))
+		if err != nil {
+			return nil, fmt.Errorf("duração inválida da janela do token padrão: %w", err)
+		}
+		blockTime, err := time.ParseDuration(getEnv("RATE_LIMIT_DEFAULT_TOKEN_BLOCK_TIME", "5m"))
+		if err != nil {
+			return nil, fmt.Errorf("duração inválida do tempo de bloqueio do token padrão: %w", err)
+		}
+
+		config.DefaultToken = &ratelimiter.Config{
+			Requests:  requests,
+			Window:    window,
+			BlockTime: blockTime,
+		}
+	}
+
+	if requests := getEnvAsInt64("RATE_LIMIT_GLOBAL_REQUESTS", 0); requests > 0 {
+		window, err := time.ParseDuration(getEnv("RATE_LIMIT_GLOBAL_WINDOW", "1s"))
+		if err != nil {
+			return nil, fmt.Errorf("duração inválida da janela do limite global: %w", err)
+		}
+		blockTime, err := time.ParseDuration(getEnv("RATE_LIMIT_GLOBAL_BLOCK_TIME", "0s"))
+		if err != nil {
+			return nil, fmt.Errorf("duração inválida do tempo de bloqueio do limite global: %w", err)
+		}
+
+		config.Global = &ratelimiter.Config{
+			Requests:  requests,
+			Window:    window,
+			BlockTime: blockTime,
+		}
+	}
+
+	if requests := getEnvAsInt64("RATE_LIMIT_ANONYMOUS_REQUESTS", 0); requests > 0 {
+		window, err := time.ParseDuration(getEnv("RATE_LIMIT_ANONYMOUS_WINDOW", "1s"))
+		if err != nil {
+			return nil, fmt.Errorf("duração inválida da janela do limite anônimo: %w", err)
+		}
+		blockTime, err := time.ParseDuration(getEnv("RATE_LIMIT_ANONYMOUS_BLOCK_TIME", "0s"))
+		if err != nil {
+			return nil, fmt.Errorf("duração inválida do tempo de bloqueio do limite anônimo: %w", err)
+		}
+
+		config.Anonymous = &ratelimiter.Config{
+			Requests:  requests,
+			Window:    window,
+			BlockTime: blockTime,
+		}
+
+		config.AnonymousScope, err = parseAnonymousScope(getEnv("RATE_LIMIT_ANONYMOUS_SCOPE", ""))
+		if err != nil {
+			return nil, err
+		}
+		config.AnonymousMode, err = parseAnonymousMode(getEnv("RATE_LIMIT_ANONYMOUS_MODE", ""))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	config.DynamicTokens = getEnvAsBool("RATE_LIMIT_DYNAMIC_TOKENS", false)
+	config.DynamicTokensTTL, err = time.ParseDuration(getEnv("RATE_LIMIT_DYNAMIC_TOKENS_TTL", "5s"))
+	if err != nil {
+		return nil, fmt.Errorf("duração inválida do cache de configurações dinâmicas: %w", err)
+	}
+
+	config.MaxWait, err = time.ParseDuration(getEnv("RATE_LIMIT_MAX_WAIT", "0s"))
+	if err != nil {
+		return nil, fmt.Errorf("duração inválida da espera máxima: %w", err)
+	}
+
+	config.BlockCacheTTL, err = time.ParseDuration(getEnv("RATE_LIMIT_BLOCK_CACHE_TTL", "0s"))
+	if err != nil {
+		return nil, fmt.Errorf("duração inválida do cache de bloqueios: %w", err)
+	}
+
+	config.AuditSampleRate, err = strconv.ParseFloat(getEnv("RATE_LIMIT_AUDIT_SAMPLE_RATE", "0"), 64)
+	if err != nil || config.AuditSampleRate < 0 || config.AuditSampleRate > 1 {
+		return nil, fmt.Errorf("taxa de amostragem da auditoria inválida %q: deve estar entre 0 e 1", getEnv("RATE_LIMIT_AUDIT_SAMPLE_RATE", "0"))
 	}
 
 	// Carrega configurações de tokens
@@ -105,16 +410,194 @@ func (c *Config) loadTokenConfigs() error {
 			return fmt.Errorf("duração inválida do tempo de bloqueio para token %s: %w", tokenPart, err)
 		}
 
-		c.Tokens[tokenPart] = ratelimiter.Config{
-			Requests:  requests,
-			Window:    window,
-			BlockTime: blockTime,
+		blockJitterStr := getEnv(fmt.Sprintf("RATE_LIMIT_TOKEN_%s_BLOCK_JITTER", tokenPart), "0s")
+		blockJitter, err := time.ParseDuration(blockJitterStr)
+		if err != nil {
+			return fmt.Errorf("duração inválida da variação do bloqueio para token %s: %w", tokenPart, err)
+		}
+
+		algorithm, err := parseAlgorithm(getEnv(fmt.Sprintf("RATE_LIMIT_TOKEN_%s_ALGORITHM", tokenPart), ""))
+		if err != nil {
+			return fmt.Errorf("algoritmo inválido para token %s: %w", tokenPart, err)
+		}
+
+		windowAlignment, err := parseWindowAlignment(getEnv(fmt.Sprintf("RATE_LIMIT_TOKEN_%s_WINDOW_ALIGNMENT", tokenPart), ""))
+		if err != nil {
+			return fmt.Errorf("alinhamento de janela inválido para token %s: %w", tokenPart, err)
+		}
+
+		tokenConfig := ratelimiter.Config{
+			Requests:        requests,
+			Window:          window,
+			WindowAlignment: windowAlignment,
+			BlockTime:       blockTime,
+			BlockJitter:     blockJitter,
+			Algorithm:       algorithm,
+			MaxConcurrent:   getEnvAsInt64(fmt.Sprintf("RATE_LIMIT_TOKEN_%s_MAX_CONCURRENT", tokenPart), 0),
+			SoftLimit:       getEnvAsInt64(fmt.Sprintf("RATE_LIMIT_TOKEN_%s_SOFT_LIMIT", tokenPart), 0),
+		}
+		if err := validateSoftLimit(tokenConfig); err != nil {
+			return fmt.Errorf("configuração inválida para token %s: %w", tokenPart, err)
 		}
+		if err := loadBlockEscalation("RATE_LIMIT_TOKEN_"+tokenPart, &tokenConfig); err != nil {
+			return fmt.Errorf("escalada de bloqueio inválida para token %s: %w", tokenPart, err)
+		}
+		if getEnvAsBool(fmt.Sprintf("RATE_LIMIT_TOKEN_%s_REJECT_ONLY", tokenPart), false) {
+			tokenConfig = tokenConfig.WithoutBlock()
+		}
+		c.Tokens[tokenPart] = tokenConfig
+	}
+
+	return nil
+}
+
+// loadBlockEscalation carrega a escalada de bloqueios de <prefix>_BLOCK_ESCALATION_FACTOR,
+// <prefix>_MAX_BLOCK_TIME e <prefix>_BLOCK_ESCALATION_RESET
+func loadBlockEscalation(prefix string, config *ratelimiter.Config) error {
+	config.BlockEscalationFactor = getEnvAsFloat64(prefix+"_BLOCK_ESCALATION_FACTOR", 0)
+
+	maxBlockTime, err := time.ParseDuration(getEnv(prefix+"_MAX_BLOCK_TIME", "0s"))
+	if err != nil {
+		return fmt.Errorf("duração inválida do tempo máximo de bloqueio: %w", err)
 	}
+	config.MaxBlockTime = maxBlockTime
+
+	reset, err := time.ParseDuration(getEnv(prefix+"_BLOCK_ESCALATION_RESET", "0s"))
+	if err != nil {
+		return fmt.Errorf("duração inválida do período de reinício da escalada: %w", err)
+	}
+	config.BlockEscalationReset = reset
 
 	return nil
 }
 
+// parseAlgorithm valida o nome do algoritmo de limitação; vazio usa o algoritmo padrão
+func parseAlgorithm(value string) (ratelimiter.Algorithm, error) {
+	algorithm := ratelimiter.Algorithm(value)
+
+	switch algorithm {
+	case "", ratelimiter.AlgorithmFixedWindow, ratelimiter.AlgorithmLeakyBucket:
+		return algorithm, nil
+	default:
+		return "", fmt.Errorf("algoritmo desconhecido %q", value)
+	}
+}
+
+// parseWindowAlignment valida o alinhamento das janelas; vazio usa Rolling
+func parseWindowAlignment(value string) (ratelimiter.WindowAlignment, error) {
+	alignment := ratelimiter.WindowAlignment(value)
+
+	switch alignment {
+	case "", ratelimiter.Rolling, ratelimiter.Calendar:
+		return alignment, nil
+	default:
+		return "", fmt.Errorf("alinhamento de janela desconhecido %q", value)
+	}
+}
+
+// parseUnknownTokenPolicy converte o nome da política para tokens desconhecidos; vazio usa
+// FallbackToIP
+func parseUnknownTokenPolicy(value string) (ratelimiter.UnknownTokenPolicy, error) {
+	switch value {
+	case "", "fallback_to_ip":
+		return ratelimiter.FallbackToIP, nil
+	case "reject":
+		return ratelimiter.Reject, nil
+	case "allow_with_default":
+		return ratelimiter.AllowWithDefault, nil
+	default:
+		return 0, fmt.Errorf("política desconhecida para tokens desconhecidos %q", value)
+	}
+}
+
+// parseCheckOrder converte o nome da ordem de verificação de token e IP; vazio usa TokenThenIP
+func parseCheckOrder(value string) (middleware.CheckOrder, error) {
+	switch value {
+	case "", "token_then_ip":
+		return middleware.TokenThenIP, nil
+	case "ip_only":
+		return middleware.IPOnly, nil
+	case "token_raises_ip":
+		return middleware.TokenRaisesIP, nil
+	case "both":
+		return middleware.Both, nil
+	default:
+		return 0, fmt.Errorf("ordem de verificação desconhecida %q", value)
+	}
+}
+
+// parseClientIPStrategy converte o nome da estratégia de obtenção do IP do cliente; vazio usa
+// XForwardedForFirst
+func parseClientIPStrategy(value string) (middleware.ClientIPStrategy, error) {
+	switch value {
+	case "", "xff_first":
+		return middleware.XForwardedForFirst, nil
+	case "real_ip_first":
+		return middleware.RealIPFirst, nil
+	case "remote_addr_only":
+		return middleware.RemoteAddrOnly, nil
+	default:
+		return 0, fmt.Errorf("estratégia de IP do cliente desconhecida %q", value)
+	}
+}
+
+// parseAnonymousScope converte o nome da divisão do orçamento anônimo; vazio usa
+// AnonymousShared
+func parseAnonymousScope(value string) (ratelimiter.AnonymousScope, error) {
+	switch value {
+	case "", "shared":
+		return ratelimiter.AnonymousShared, nil
+	case "per_ip":
+		return ratelimiter.AnonymousPerIP, nil
+	default:
+		return 0, fmt.Errorf("escopo do limite anônimo desconhecido %q", value)
+	}
+}
+
+// parseAnonymousMode converte o nome do modo do limite anônimo; vazio usa AnonymousInsteadOfIP
+func parseAnonymousMode(value string) (middleware.AnonymousMode, error) {
+	switch value {
+	case "", "instead_of_ip":
+		return middleware.AnonymousInsteadOfIP, nil
+	case "with_ip":
+		return middleware.AnonymousWithIP, nil
+	default:
+		return 0, fmt.Errorf("modo do limite anônimo desconhecido %q", value)
+	}
+}
+
+// parseQuotaHeaders converte o nome do conjunto de headers de cota; vazio usa NoQuotaHeaders
+func parseQuotaHeaders(value string) (middleware.QuotaHeaders, error) {
+	switch value {
+	case "", "none":
+		return middleware.NoQuotaHeaders, nil
+	case "legacy":
+		return middleware.LegacyQuotaHeaders, nil
+	case "standard":
+		return middleware.StandardQuotaHeaders, nil
+	case "all":
+		return middleware.AllQuotaHeaders, nil
+	default:
+		return 0, fmt.Errorf("headers de cota desconhecidos %q", value)
+	}
+}
+
+// validateSoftLimit garante que o limite de aviso, quando definido, seja menor que o limite
+func validateSoftLimit(config ratelimiter.Config) error {
+	if config.SoftLimit < 0 || (config.SoftLimit > 0 && config.SoftLimit >= config.Requests) {
+		return fmt.Errorf("soft_limit deve estar entre 0 e requests (%d), obtido %d", config.Requests, config.SoftLimit)
+	}
+	return nil
+}
+
+// validateRejectStatusCode garante que o status de rejeição seja um código 4xx ou 5xx
+func validateRejectStatusCode(code int) error {
+	if !middleware.ValidRejectStatusCode(code) {
+		return fmt.Errorf("status de rejeição inválido %d: deve ser 4xx ou 5xx", code)
+	}
+	return nil
+}
+
 // getEnv obtém uma variável de ambiente com um valor padrão
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -138,6 +621,36 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return value
 }
 
+// getEnvAsBool obtém uma variável de ambiente como um booleano com um valor padrão
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// getEnvAsFloat64 obtém uma variável de ambiente como um float64 com um valor padrão
+func getEnvAsFloat64(key string, defaultValue float64) float64 {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
 // getEnvAsInt64 obtém uma variável de ambiente como um int64 com um valor padrão
 func getEnvAsInt64(key string, defaultValue int64) int64 {
 	valueStr := getEnv(key, "")
@@ -0,0 +1,686 @@
+package config
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/middleware"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFromJSON_Valid(t *testing.T) {
+	file, err := os.Open("testdata/valid.json")
+	require.NoError(t, err)
+	defer file.Close()
+
+	config, err := LoadFromJSON(file)
+	require.NoError(t, err)
+
+	assert.Equal(t, RedisConfig{Addr: "redis:6379", Password: "secret", DB: 1, ReadAddr: "redis-replica:6379"}, config.Redis)
+	assert.Equal(t, ratelimiter.Config{
+		Requests:    5,
+		Window:      time.Second,
+		Burst:       3,
+		BurstWindow: time.Minute,
+		BlockTime:   5 * time.Minute,
+		BlockJitter: 30 * time.Second,
+
+		BlockEscalationFactor: 2,
+		MaxBlockTime:          time.Hour,
+	}, config.IP)
+
+	assert.Equal(t, ratelimiter.Config{
+		Requests:      100,
+		Window:        time.Second,
+		BlockTime:     time.Minute,
+		MaxConcurrent: 5,
+		Tiers: []ratelimiter.Config{
+			{Requests: 1000, Window: time.Hour, BlockTime: time.Hour},
+		},
+	}, config.Tokens["abc123"])
+	assert.Equal(t, map[string]string{"owner": "team-payments", "plan": "premium"}, config.TokenMetadata["abc123"])
+
+	// block_time explícito "0s" desabilita o bloqueio
+	assert.Equal(t, ratelimiter.Config{
+		Requests:  50,
+		Window:    10 * time.Second,
+		Algorithm: ratelimiter.AlgorithmLeakyBucket,
+	}, config.Tokens["xyz789"])
+	assert.NotContains(t, config.TokenMetadata, "xyz789")
+}
+
+func TestLoadFromJSON_Defaults(t *testing.T) {
+	config, err := LoadFromJSON(strings.NewReader(`{}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, storage.BackendRedis, config.StorageBackend)
+	assert.Equal(t, "localhost:6379", config.Redis.Addr)
+	assert.Equal(t, ratelimiter.Config{Requests: 10, Window: time.Second, BlockTime: 5 * time.Minute}, config.IP)
+	assert.Empty(t, config.Tokens)
+}
+
+func TestLoadFromJSON_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		errMsg  string
+	}{
+		{name: "JSON malformado", fixture: "testdata/malformed.json", errMsg: "falha ao decodificar configuração JSON"},
+		{name: "campo desconhecido", fixture: "testdata/unknown_field.json", errMsg: "windw"},
+		{name: "duração inválida no token", fixture: "testdata/invalid_token_duration.json", errMsg: "token abc123: duração inválida em window"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := os.Open(tt.fixture)
+			require.NoError(t, err)
+			defer file.Close()
+
+			config, err := LoadFromJSON(file)
+			assert.Nil(t, config)
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestLoadFromJSON_InvalidTokenRequests(t *testing.T) {
+	_, err := LoadFromJSON(strings.NewReader(`{"tokens": {"abc123": {"requests": 0}}}`))
+	assert.ErrorContains(t, err, "token abc123: requests deve ser positivo")
+}
+
+func TestLoadFromJSON_InvalidTierDuration(t *testing.T) {
+	_, err := LoadFromJSON(strings.NewReader(`{"tokens": {"abc123": {"requests": 1, "tiers": [{"requests": 10, "block_time": "-1m"}]}}}`))
+	assert.ErrorContains(t, err, "token abc123: limite adicional 0: duração negativa em block_time")
+}
+
+func TestLoadFromJSON_UnknownTokenPolicy(t *testing.T) {
+	config, err := LoadFromJSON(strings.NewReader(`{
+		"unknown_token_policy": "allow_with_default",
+		"default_token": {"requests": 3, "window": "1m"}
+	}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, ratelimiter.AllowWithDefault, config.UnknownTokenPolicy)
+	assert.Equal(t, &ratelimiter.Config{Requests: 3, Window: time.Minute, BlockTime: 5 * time.Minute}, config.DefaultToken)
+
+	_, err = LoadFromJSON(strings.NewReader(`{"unknown_token_policy": "ignore"}`))
+	assert.ErrorContains(t, err, `"ignore"`)
+}
+
+func TestLoad_UnknownTokenPolicy(t *testing.T) {
+	t.Setenv("RATE_LIMIT_UNKNOWN_TOKEN_POLICY", "reject")
+
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, ratelimiter.Reject, config.UnknownTokenPolicy)
+	assert.Nil(t, config.DefaultToken)
+
+	t.Setenv("RATE_LIMIT_UNKNOWN_TOKEN_POLICY", "allow_with_default")
+	t.Setenv("RATE_LIMIT_DEFAULT_TOKEN_REQUESTS", "20")
+	t.Setenv("RATE_LIMIT_DEFAULT_TOKEN_WINDOW", "10s")
+
+	config, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, ratelimiter.AllowWithDefault, config.UnknownTokenPolicy)
+	assert.Equal(t, &ratelimiter.Config{Requests: 20, Window: 10 * time.Second, BlockTime: 5 * time.Minute}, config.DefaultToken)
+}
+
+func TestLoad_Enabled(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.True(t, config.Enabled)
+
+	t.Setenv("RATE_LIMIT_ENABLED", "false")
+
+	config, err = Load()
+	require.NoError(t, err)
+	assert.False(t, config.Enabled)
+
+	config, err = LoadFromJSON(strings.NewReader(`{"enabled": false}`))
+	require.NoError(t, err)
+	assert.False(t, config.Enabled)
+}
+
+func TestLoad_ShadowMode(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.False(t, config.ShadowMode)
+
+	t.Setenv("RATE_LIMIT_SHADOW_MODE", "true")
+
+	config, err = Load()
+	require.NoError(t, err)
+	assert.True(t, config.ShadowMode)
+}
+
+func TestLoad_StripAPIKeyHeader(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.False(t, config.StripAPIKeyHeader)
+
+	t.Setenv("RATE_LIMIT_STRIP_API_KEY_HEADER", "true")
+
+	config, err = Load()
+	require.NoError(t, err)
+	assert.True(t, config.StripAPIKeyHeader)
+
+	config, err = LoadFromJSON(strings.NewReader(`{"strip_api_key_header": true}`))
+	require.NoError(t, err)
+	assert.True(t, config.StripAPIKeyHeader)
+}
+
+func TestLoad_MaxConcurrent(t *testing.T) {
+	t.Setenv("RATE_LIMIT_IP_MAX_CONCURRENT", "2")
+	t.Setenv("RATE_LIMIT_TOKEN_ABC123_REQUESTS", "100")
+	t.Setenv("RATE_LIMIT_TOKEN_ABC123_MAX_CONCURRENT", "5")
+
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), config.IP.MaxConcurrent)
+	assert.Equal(t, int64(5), config.Tokens["ABC123"].MaxConcurrent)
+}
+
+func TestLoad_SoftLimit(t *testing.T) {
+	t.Setenv("RATE_LIMIT_IP_SOFT_LIMIT", "8")
+	t.Setenv("RATE_LIMIT_TOKEN_ABC123_REQUESTS", "100")
+	t.Setenv("RATE_LIMIT_TOKEN_ABC123_SOFT_LIMIT", "80")
+
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), config.IP.SoftLimit)
+	assert.Equal(t, int64(80), config.Tokens["ABC123"].SoftLimit)
+
+	// O limite de aviso deve ser menor que o limite efetivo
+	t.Setenv("RATE_LIMIT_IP_SOFT_LIMIT", "10")
+	_, err = Load()
+	assert.ErrorContains(t, err, "soft_limit deve estar entre 0 e requests (10), obtido 10")
+}
+
+func TestLoadFromJSON_SoftLimit(t *testing.T) {
+	config, err := LoadFromJSON(strings.NewReader(`{"tokens": {"abc123": {"requests": 100, "soft_limit": 80}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, int64(80), config.Tokens["abc123"].SoftLimit)
+
+	_, err = LoadFromJSON(strings.NewReader(`{"tokens": {"abc123": {"requests": 100, "soft_limit": 150}}}`))
+	assert.ErrorContains(t, err, "token abc123: soft_limit deve estar entre 0 e requests (100), obtido 150")
+}
+
+func TestLoad_TokenRejectOnly(t *testing.T) {
+	t.Setenv("RATE_LIMIT_TOKEN_PAID_REQUESTS", "100")
+	t.Setenv("RATE_LIMIT_TOKEN_PAID_BLOCK_TIME", "2m")
+	t.Setenv("RATE_LIMIT_TOKEN_PAID_REJECT_ONLY", "true")
+	t.Setenv("RATE_LIMIT_TOKEN_FREE_REQUESTS", "10")
+
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Negative(t, config.Tokens["PAID"].BlockTime)
+	assert.Equal(t, int64(100), config.Tokens["PAID"].Requests)
+	assert.Equal(t, 5*time.Minute, config.Tokens["FREE"].BlockTime)
+
+	// O IP mantém o bloqueio configurado
+	assert.Equal(t, 5*time.Minute, config.IP.BlockTime)
+}
+
+func TestLoadFromJSON_RejectOnly(t *testing.T) {
+	config, err := LoadFromJSON(strings.NewReader(`{
+		"tokens": {
+			"paid": {"requests": 100, "block_time": "2m", "reject_only": true},
+			"free": {"requests": 10}
+		}
+	}`))
+	require.NoError(t, err)
+	assert.Negative(t, config.Tokens["paid"].BlockTime)
+	assert.Equal(t, 5*time.Minute, config.Tokens["free"].BlockTime)
+	assert.Equal(t, 5*time.Minute, config.IP.BlockTime)
+}
+
+func TestLoad_WindowAlignment(t *testing.T) {
+	t.Setenv("RATE_LIMIT_IP_WINDOW_ALIGNMENT", "calendar")
+	t.Setenv("RATE_LIMIT_TOKEN_ABC123_REQUESTS", "100")
+
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, ratelimiter.Calendar, config.IP.WindowAlignment)
+	assert.Empty(t, config.Tokens["ABC123"].WindowAlignment)
+
+	t.Setenv("RATE_LIMIT_TOKEN_ABC123_WINDOW_ALIGNMENT", "hourly")
+	_, err = Load()
+	assert.ErrorContains(t, err, `alinhamento de janela inválido para token ABC123: alinhamento de janela desconhecido "hourly"`)
+}
+
+func TestLoadFromJSON_WindowAlignment(t *testing.T) {
+	config, err := LoadFromJSON(strings.NewReader(`{"tokens": {"abc123": {"requests": 100, "window": "1m", "window_alignment": "calendar"}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, ratelimiter.Calendar, config.Tokens["abc123"].WindowAlignment)
+
+	_, err = LoadFromJSON(strings.NewReader(`{"ip": {"window_alignment": "Calendar"}}`))
+	assert.ErrorContains(t, err, "alinhamento de janela inválido")
+}
+
+func TestLoad_BlockEscalation(t *testing.T) {
+	t.Setenv("RATE_LIMIT_IP_BLOCK_ESCALATION_FACTOR", "2")
+	t.Setenv("RATE_LIMIT_IP_MAX_BLOCK_TIME", "1h")
+	t.Setenv("RATE_LIMIT_TOKEN_ABC123_REQUESTS", "100")
+	t.Setenv("RATE_LIMIT_TOKEN_ABC123_BLOCK_ESCALATION_FACTOR", "1.5")
+	t.Setenv("RATE_LIMIT_TOKEN_ABC123_BLOCK_ESCALATION_RESET", "6h")
+
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, config.IP.BlockEscalationFactor)
+	assert.Equal(t, time.Hour, config.IP.MaxBlockTime)
+	assert.Zero(t, config.IP.BlockEscalationReset)
+	assert.Equal(t, 1.5, config.Tokens["ABC123"].BlockEscalationFactor)
+	assert.Equal(t, 6*time.Hour, config.Tokens["ABC123"].BlockEscalationReset)
+
+	t.Setenv("RATE_LIMIT_IP_MAX_BLOCK_TIME", "uma hora")
+	_, err = Load()
+	assert.ErrorContains(t, err, "escalada de bloqueio inválida para IP")
+}
+
+func TestLoad_RedisReadReplica(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Empty(t, config.Redis.ReadAddr)
+	assert.False(t, config.Redis.StrongConsistency)
+
+	t.Setenv("REDIS_READ_ADDR", "redis-replica:6379")
+	t.Setenv("REDIS_STRONG_CONSISTENCY", "true")
+
+	config, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "redis-replica:6379", config.Redis.ReadAddr)
+	assert.True(t, config.Redis.StrongConsistency)
+}
+
+func TestLoad_RedisTimeouts(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Zero(t, config.Redis.ReadTimeout)
+	assert.Zero(t, config.Redis.WriteTimeout)
+
+	t.Setenv("REDIS_READ_TIMEOUT", "50ms")
+	t.Setenv("REDIS_WRITE_TIMEOUT", "500ms")
+
+	config, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, 50*time.Millisecond, config.Redis.ReadTimeout)
+	assert.Equal(t, 500*time.Millisecond, config.Redis.WriteTimeout)
+
+	t.Setenv("REDIS_WRITE_TIMEOUT", "meio segundo")
+	_, err = Load()
+	assert.ErrorContains(t, err, "tempo máximo de escrita do Redis")
+
+	config, err = LoadFromJSON(strings.NewReader(`{"redis": {"read_timeout": "20ms", "write_timeout": "1s"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, 20*time.Millisecond, config.Redis.ReadTimeout)
+	assert.Equal(t, time.Second, config.Redis.WriteTimeout)
+
+	_, err = LoadFromJSON(strings.NewReader(`{"redis": {"read_timeout": "-1s"}}`))
+	assert.ErrorContains(t, err, "redis.read_timeout")
+}
+
+func TestLoad_RedisServerTime(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.False(t, config.Redis.ServerTime)
+
+	t.Setenv("REDIS_SERVER_TIME", "true")
+	config, err = Load()
+	require.NoError(t, err)
+	assert.True(t, config.Redis.ServerTime)
+}
+
+func TestLoad_RedisKeyNames(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, ratelimiter.KeyNames{}, config.Redis.KeyNames)
+	assert.Equal(t, storage.KeyNames{}, config.Redis.StorageKeyNames())
+
+	t.Setenv("REDIS_KEY_SEPARATOR", ".")
+	t.Setenv("REDIS_KEY_NAME_IP", "addr")
+	t.Setenv("REDIS_KEY_NAME_TOKEN", "key")
+	t.Setenv("REDIS_KEY_NAME_BLOCKED", "ban")
+
+	config, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, ratelimiter.KeyNames{Separator: ".", IP: "addr", Token: "key"}, config.Redis.KeyNames)
+	assert.Equal(t, storage.KeyNames{Separator: ".", Blocked: "ban"}, config.Redis.StorageKeyNames())
+}
+
+func TestLoadFromJSON_RedisKeyNames(t *testing.T) {
+	config, err := LoadFromJSON(strings.NewReader(`{"redis": {
+		"key_separator": "|", "ip_key_name": "addr", "token_key_name": "key", "blocked_key_name": "ban"
+	}}`))
+	require.NoError(t, err)
+	assert.Equal(t, ratelimiter.KeyNames{Separator: "|", IP: "addr", Token: "key"}, config.Redis.KeyNames)
+	assert.Equal(t, storage.KeyNames{Separator: "|", Blocked: "ban"}, config.Redis.StorageKeyNames())
+}
+
+func TestLoad_CheckOrder(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, middleware.TokenThenIP, config.CheckOrder)
+
+	for value, expected := range map[string]middleware.CheckOrder{
+		"token_then_ip":   middleware.TokenThenIP,
+		"ip_only":         middleware.IPOnly,
+		"token_raises_ip": middleware.TokenRaisesIP,
+		"both":            middleware.Both,
+	} {
+		t.Setenv("RATE_LIMIT_CHECK_ORDER", value)
+		config, err = Load()
+		require.NoError(t, err)
+		assert.Equal(t, expected, config.CheckOrder, value)
+	}
+
+	t.Setenv("RATE_LIMIT_CHECK_ORDER", "ip_then_token")
+	_, err = Load()
+	assert.ErrorContains(t, err, `ordem de verificação desconhecida "ip_then_token"`)
+}
+
+func TestLoadFromJSON_CheckOrder(t *testing.T) {
+	config, err := LoadFromJSON(strings.NewReader(`{"check_order": "token_raises_ip"}`))
+	require.NoError(t, err)
+	assert.Equal(t, middleware.TokenRaisesIP, config.CheckOrder)
+
+	_, err = LoadFromJSON(strings.NewReader(`{"check_order": "random"}`))
+	assert.ErrorContains(t, err, `"random"`)
+}
+
+func TestLoad_ClientIPStrategy(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, middleware.XForwardedForFirst, config.ClientIPStrategy)
+
+	for value, expected := range map[string]middleware.ClientIPStrategy{
+		"xff_first":        middleware.XForwardedForFirst,
+		"real_ip_first":    middleware.RealIPFirst,
+		"remote_addr_only": middleware.RemoteAddrOnly,
+	} {
+		t.Setenv("RATE_LIMIT_CLIENT_IP_STRATEGY", value)
+		config, err = Load()
+		require.NoError(t, err)
+		assert.Equal(t, expected, config.ClientIPStrategy, value)
+	}
+
+	t.Setenv("RATE_LIMIT_CLIENT_IP_STRATEGY", "x_real_ip")
+	_, err = Load()
+	assert.ErrorContains(t, err, `estratégia de IP do cliente desconhecida "x_real_ip"`)
+}
+
+func TestLoadFromJSON_ClientIPStrategy(t *testing.T) {
+	config, err := LoadFromJSON(strings.NewReader(`{"client_ip_strategy": "real_ip_first"}`))
+	require.NoError(t, err)
+	assert.Equal(t, middleware.RealIPFirst, config.ClientIPStrategy)
+
+	_, err = LoadFromJSON(strings.NewReader(`{"client_ip_strategy": "random"}`))
+	assert.ErrorContains(t, err, `"random"`)
+}
+
+func TestLoad_QuotaHeaders(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, middleware.NoQuotaHeaders, config.QuotaHeaders)
+
+	for value, expected := range map[string]middleware.QuotaHeaders{
+		"none":     middleware.NoQuotaHeaders,
+		"legacy":   middleware.LegacyQuotaHeaders,
+		"standard": middleware.StandardQuotaHeaders,
+		"all":      middleware.AllQuotaHeaders,
+	} {
+		t.Setenv("RATE_LIMIT_HEADERS", value)
+		config, err = Load()
+		require.NoError(t, err)
+		assert.Equal(t, expected, config.QuotaHeaders, value)
+	}
+
+	t.Setenv("RATE_LIMIT_HEADERS", "ietf")
+	_, err = Load()
+	assert.ErrorContains(t, err, `headers de cota desconhecidos "ietf"`)
+}
+
+func TestLoadFromJSON_QuotaHeaders(t *testing.T) {
+	config, err := LoadFromJSON(strings.NewReader(`{"quota_headers": "standard"}`))
+	require.NoError(t, err)
+	assert.Equal(t, middleware.StandardQuotaHeaders, config.QuotaHeaders)
+
+	_, err = LoadFromJSON(strings.NewReader(`{"quota_headers": "x"}`))
+	assert.ErrorContains(t, err, `"x"`)
+}
+
+func TestLoad_StorageBackend(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, storage.BackendRedis, config.StorageBackend)
+
+	t.Setenv("STORAGE_BACKEND", "memory")
+	config, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, storage.BackendMemory, config.StorageBackend)
+
+	t.Setenv("STORAGE_BACKEND", "postgres")
+	_, err = Load()
+	assert.ErrorContains(t, err, `backend de armazenamento desconhecido "postgres"`)
+}
+
+func TestLoadFromJSON_StorageBackend(t *testing.T) {
+	config, err := LoadFromJSON(strings.NewReader(`{"storage_backend": "memory"}`))
+	require.NoError(t, err)
+	assert.Equal(t, storage.BackendMemory, config.StorageBackend)
+
+	_, err = LoadFromJSON(strings.NewReader(`{"storage_backend": "postgres"}`))
+	assert.ErrorContains(t, err, `"postgres"`)
+}
+
+func TestLoad_Anonymous(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Nil(t, config.Anonymous)
+	assert.Equal(t, middleware.AnonymousDisabled, config.AnonymousMode)
+
+	t.Setenv("RATE_LIMIT_ANONYMOUS_REQUESTS", "100")
+
+	config, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, &ratelimiter.Config{Requests: 100, Window: time.Second}, config.Anonymous)
+	assert.Equal(t, ratelimiter.AnonymousShared, config.AnonymousScope)
+	assert.Equal(t, middleware.AnonymousInsteadOfIP, config.AnonymousMode)
+
+	t.Setenv("RATE_LIMIT_ANONYMOUS_WINDOW", "1m")
+	t.Setenv("RATE_LIMIT_ANONYMOUS_BLOCK_TIME", "10s")
+	t.Setenv("RATE_LIMIT_ANONYMOUS_SCOPE", "per_ip")
+	t.Setenv("RATE_LIMIT_ANONYMOUS_MODE", "with_ip")
+
+	config, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, &ratelimiter.Config{Requests: 100, Window: time.Minute, BlockTime: 10 * time.Second}, config.Anonymous)
+	assert.Equal(t, ratelimiter.AnonymousPerIP, config.AnonymousScope)
+	assert.Equal(t, middleware.AnonymousWithIP, config.AnonymousMode)
+
+	t.Setenv("RATE_LIMIT_ANONYMOUS_SCOPE", "tenant")
+	_, err = Load()
+	assert.ErrorContains(t, err, `escopo do limite anônimo desconhecido "tenant"`)
+
+	t.Setenv("RATE_LIMIT_ANONYMOUS_SCOPE", "shared")
+	t.Setenv("RATE_LIMIT_ANONYMOUS_MODE", "both")
+	_, err = Load()
+	assert.ErrorContains(t, err, `modo do limite anônimo desconhecido "both"`)
+}
+
+func TestLoad_PerHost(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.False(t, config.PerHost)
+
+	t.Setenv("RATE_LIMIT_PER_HOST", "true")
+
+	config, err = Load()
+	require.NoError(t, err)
+	assert.True(t, config.PerHost)
+}
+
+func TestLoad_ProblemJSON(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.False(t, config.ProblemJSON)
+	assert.Empty(t, config.ProblemType)
+
+	t.Setenv("RATE_LIMIT_PROBLEM_JSON", "true")
+	t.Setenv("RATE_LIMIT_PROBLEM_TYPE", "https://example.com/problems/rate-limit")
+
+	config, err = Load()
+	require.NoError(t, err)
+	assert.True(t, config.ProblemJSON)
+	assert.Equal(t, "https://example.com/problems/rate-limit", config.ProblemType)
+}
+
+func TestLoad_BypassSecret(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Empty(t, config.BypassHeader)
+	assert.Empty(t, config.BypassSecret)
+
+	t.Setenv("RATE_LIMIT_BYPASS_HEADER", "X-Internal-Secret")
+	_, err = Load()
+	assert.ErrorContains(t, err, "devem ser definidos juntos")
+
+	t.Setenv("RATE_LIMIT_BYPASS_SECRET", "s3cr3t")
+	config, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, "X-Internal-Secret", config.BypassHeader)
+	assert.Equal(t, "s3cr3t", config.BypassSecret)
+}
+
+func TestLoad_SlideWindow(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.False(t, config.SlideWindowOnEachRequest)
+
+	t.Setenv("RATE_LIMIT_SLIDE_WINDOW", "true")
+	config, err = Load()
+	require.NoError(t, err)
+	assert.True(t, config.SlideWindowOnEachRequest)
+
+	config, err = LoadFromJSON(strings.NewReader(`{"slide_window": true}`))
+	require.NoError(t, err)
+	assert.True(t, config.SlideWindowOnEachRequest)
+}
+
+func TestLoad_RejectStatusCode(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, config.RejectStatusCode)
+
+	t.Setenv("RATE_LIMIT_REJECT_STATUS_CODE", "503")
+
+	config, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, config.RejectStatusCode)
+
+	t.Setenv("RATE_LIMIT_REJECT_STATUS_CODE", "200")
+
+	_, err = Load()
+	assert.ErrorContains(t, err, "status de rejeição inválido 200")
+
+	_, err = LoadFromJSON(strings.NewReader(`{"reject_status_code": 302}`))
+	assert.ErrorContains(t, err, "status de rejeição inválido 302")
+}
+
+func TestLoad_DynamicTokens(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.False(t, config.DynamicTokens)
+	assert.Equal(t, 5*time.Second, config.DynamicTokensTTL)
+
+	t.Setenv("RATE_LIMIT_DYNAMIC_TOKENS", "true")
+	t.Setenv("RATE_LIMIT_DYNAMIC_TOKENS_TTL", "30s")
+
+	config, err = Load()
+	require.NoError(t, err)
+	assert.True(t, config.DynamicTokens)
+	assert.Equal(t, 30*time.Second, config.DynamicTokensTTL)
+
+	t.Setenv("RATE_LIMIT_DYNAMIC_TOKENS_TTL", "30")
+
+	_, err = Load()
+	assert.ErrorContains(t, err, "duração inválida do cache de configurações dinâmicas")
+}
+
+func TestLoad_MaxWait(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Zero(t, config.MaxWait)
+
+	t.Setenv("RATE_LIMIT_MAX_WAIT", "500ms")
+	config, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, config.MaxWait)
+
+	t.Setenv("RATE_LIMIT_MAX_WAIT", "meio segundo")
+	_, err = Load()
+	assert.ErrorContains(t, err, "duração inválida da espera máxima")
+}
+
+func TestLoad_BlockCacheTTL(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Zero(t, config.BlockCacheTTL)
+
+	t.Setenv("RATE_LIMIT_BLOCK_CACHE_TTL", "2s")
+
+	config, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, config.BlockCacheTTL)
+
+	t.Setenv("RATE_LIMIT_BLOCK_CACHE_TTL", "2")
+
+	_, err = Load()
+	assert.ErrorContains(t, err, "duração inválida do cache de bloqueios")
+}
+
+func TestLoad_Global(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Nil(t, config.Global)
+
+	t.Setenv("RATE_LIMIT_GLOBAL_REQUESTS", "1000")
+
+	config, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, &ratelimiter.Config{Requests: 1000, Window: time.Second}, config.Global)
+
+	t.Setenv("RATE_LIMIT_GLOBAL_WINDOW", "1m")
+	t.Setenv("RATE_LIMIT_GLOBAL_BLOCK_TIME", "10s")
+
+	config, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, &ratelimiter.Config{Requests: 1000, Window: time.Minute, BlockTime: 10 * time.Second}, config.Global)
+
+	t.Setenv("RATE_LIMIT_GLOBAL_WINDOW", "60")
+
+	_, err = Load()
+	assert.ErrorContains(t, err, "duração inválida da janela do limite global")
+}
+
+func TestLoad_AuditSampleRate(t *testing.T) {
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Zero(t, config.AuditSampleRate)
+
+	t.Setenv("RATE_LIMIT_AUDIT_SAMPLE_RATE", "0.1")
+
+	config, err = Load()
+	require.NoError(t, err)
+	assert.Equal(t, 0.1, config.AuditSampleRate)
+
+	t.Setenv("RATE_LIMIT_AUDIT_SAMPLE_RATE", "1.5")
+
+	_, err = Load()
+	assert.ErrorContains(t, err, "taxa de amostragem da auditoria inválida")
+}
@@ -0,0 +1,317 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+)
+
+// jsonConfig é o formato do arquivo de configuração JSON
+type jsonConfig struct {
+	Enabled           *bool                `json:"enabled"`
+	APIKeyHeader      string               `json:"api_key_header"`
+	StripAPIKeyHeader bool                 `json:"strip_api_key_header"`
+	RejectStatusCode  int                  `json:"reject_status_code"`
+	StorageBackend    string               `json:"storage_backend"`
+	SlideWindow       bool                 `json:"slide_window"`
+	Redis             jsonRedis            `json:"redis"`
+	IP                jsonLimit            `json:"ip"`
+	Tokens            map[string]jsonToken `json:"tokens"`
+
+	UnknownTokenPolicy string     `json:"unknown_token_policy"`
+	CheckOrder         string     `json:"check_order"`
+	ClientIPStrategy   string     `json:"client_ip_strategy"`
+	QuotaHeaders       string     `json:"quota_headers"`
+	DefaultToken       *jsonLimit `json:"default_token"`
+}
+
+// jsonRedis é a configuração de conexão Redis no formato JSON
+type jsonRedis struct {
+	Addr     string `json:"addr"`
+	Password string `json:"password"`
+	DB       int    `json:"db"`
+
+	KeyPrefix      string `json:"key_prefix"`
+	KeySeparator   string `json:"key_separator"`
+	IPKeyName      string `json:"ip_key_name"`
+	TokenKeyName   string `json:"token_key_name"`
+	BlockedKeyName string `json:"blocked_key_name"`
+
+	ReadAddr          string `json:"read_addr"`
+	StrongConsistency bool   `json:"strong_consistency"`
+	ServerTime        bool   `json:"server_time"`
+
+	ReadTimeout  string `json:"read_timeout"`
+	WriteTimeout string `json:"write_timeout"`
+}
+
+// jsonLimit é uma configuração de limite no formato JSON, com durações em texto (ex: "1s", "5m")
+type jsonLimit struct {
+	Requests              int64       `json:"requests"`
+	Window                string      `json:"window"`
+	WindowAlignment       string      `json:"window_alignment"`
+	Burst                 int64       `json:"burst"`
+	BurstWindow           string      `json:"burst_window"`
+	BlockTime             *string     `json:"block_time"`
+	BlockJitter           string      `json:"block_jitter"`
+	BlockEscalationFactor float64     `json:"block_escalation_factor"`
+	MaxBlockTime          string      `json:"max_block_time"`
+	BlockEscalationReset  string      `json:"block_escalation_reset"`
+	Algorithm             string      `json:"algorithm"`
+	GracePeriod           string      `json:"grace_period"`
+	MaxConcurrent         int64       `json:"max_concurrent"`
+	SoftLimit             int64       `json:"soft_limit"`
+	RejectOnly            bool        `json:"reject_only"`
+	Tiers                 []jsonLimit `json:"tiers"`
+}
+
+// jsonToken é a configuração de um token no formato JSON, com metadados livres (ex: dono, plano)
+type jsonToken struct {
+	jsonLimit
+	Metadata map[string]string `json:"metadata"`
+}
+
+// LoadFromJSON carrega a configuração a partir de um documento JSON com o bloco de Redis, o
+// limite de IP e o mapa de tokens. Campos ausentes usam os mesmos padrões das variáveis de
+// ambiente.
+func LoadFromJSON(reader io.Reader) (*Config, error) {
+	var file jsonConfig
+
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&file)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao decodificar configuração JSON: %w", err)
+	}
+
+	config := &Config{
+		Enabled:           file.Enabled == nil || *file.Enabled,
+		APIKeyHeader:      file.APIKeyHeader,
+		StripAPIKeyHeader: file.StripAPIKeyHeader,
+		Tokens:            make(map[string]ratelimiter.Config),
+		TokenMetadata:     make(map[string]map[string]string),
+	}
+
+	config.RejectStatusCode = file.RejectStatusCode
+	if config.RejectStatusCode == 0 {
+		config.RejectStatusCode = http.StatusTooManyRequests
+	}
+	if err := validateRejectStatusCode(config.RejectStatusCode); err != nil {
+		return nil, err
+	}
+
+	config.StorageBackend, err = storage.ParseBackend(file.StorageBackend)
+	if err != nil {
+		return nil, err
+	}
+	config.SlideWindowOnEachRequest = file.SlideWindow
+
+	// Carrega configuração Redis
+	config.Redis.Addr = file.Redis.Addr
+	if config.Redis.Addr == "" {
+		config.Redis.Addr = "localhost:6379"
+	}
+	config.Redis.Password = file.Redis.Password
+	config.Redis.DB = file.Redis.DB
+	config.Redis.KeyPrefix = file.Redis.KeyPrefix
+	config.Redis.KeyNames = ratelimiter.KeyNames{
+		Separator: file.Redis.KeySeparator,
+		IP:        file.Redis.IPKeyName,
+		Token:     file.Redis.TokenKeyName,
+	}
+	config.Redis.BlockedKeyName = file.Redis.BlockedKeyName
+	config.Redis.ReadAddr = file.Redis.ReadAddr
+	config.Redis.StrongConsistency = file.Redis.StrongConsistency
+	config.Redis.ServerTime = file.Redis.ServerTime
+
+	config.Redis.ReadTimeout, err = parseJSONDuration("redis.read_timeout", file.Redis.ReadTimeout, 0)
+	if err != nil {
+		return nil, err
+	}
+	config.Redis.WriteTimeout, err = parseJSONDuration("redis.write_timeout", file.Redis.WriteTimeout, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Carrega configuração de limitação de IP
+	if file.IP.Requests == 0 {
+		file.IP.Requests = 10
+	}
+	config.IP, err = file.IP.toConfig()
+	if err != nil {
+		return nil, fmt.Errorf("configuração inválida de IP: %w", err)
+	}
+
+	// Carrega a política para tokens desconhecidos
+	config.UnknownTokenPolicy, err = parseUnknownTokenPolicy(file.UnknownTokenPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	config.CheckOrder, err = parseCheckOrder(file.CheckOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	config.ClientIPStrategy, err = parseClientIPStrategy(file.ClientIPStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	config.QuotaHeaders, err = parseQuotaHeaders(file.QuotaHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	if file.DefaultToken != nil {
+		defaultToken, err := file.DefaultToken.toConfig()
+		if err != nil {
+			return nil, fmt.Errorf("configuração inválida do token padrão: %w", err)
+		}
+		config.DefaultToken = &defaultToken
+	}
+
+	// Carrega configurações de tokens
+	for token, tokenConfig := range file.Tokens {
+		if token == "" {
+			return nil, fmt.Errorf("token sem nome na configuração JSON")
+		}
+
+		config.Tokens[token], err = tokenConfig.toConfig()
+		if err != nil {
+			return nil, fmt.Errorf("configuração inválida para token %s: %w", token, err)
+		}
+
+		if len(tokenConfig.Metadata) > 0 {
+			config.TokenMetadata[token] = tokenConfig.Metadata
+		}
+	}
+
+	return config, nil
+}
+
+// toConfig converte o limite JSON na configuração do rate limiter, validando as durações
+func (l jsonLimit) toConfig() (ratelimiter.Config, error) {
+	if l.Requests <= 0 {
+		return ratelimiter.Config{}, fmt.Errorf("requests deve ser positivo, obtido %d", l.Requests)
+	}
+
+	window, err := parseJSONDuration("window", l.Window, time.Second)
+	if err != nil {
+		return ratelimiter.Config{}, err
+	}
+
+	// block_time ausente usa o padrão; "0s" explícito desabilita o bloqueio
+	blockTime := 5 * time.Minute
+	if l.BlockTime != nil {
+		blockTime, err = parseJSONDuration("block_time", *l.BlockTime, 0)
+		if err != nil {
+			return ratelimiter.Config{}, err
+		}
+	}
+
+	if l.Burst < 0 {
+		return ratelimiter.Config{}, fmt.Errorf("burst não pode ser negativo, obtido %d", l.Burst)
+	}
+
+	if l.MaxConcurrent < 0 {
+		return ratelimiter.Config{}, fmt.Errorf("max_concurrent não pode ser negativo, obtido %d", l.MaxConcurrent)
+	}
+
+	burstWindow, err := parseJSONDuration("burst_window", l.BurstWindow, 0)
+	if err != nil {
+		return ratelimiter.Config{}, err
+	}
+
+	blockJitter, err := parseJSONDuration("block_jitter", l.BlockJitter, 0)
+	if err != nil {
+		return ratelimiter.Config{}, err
+	}
+
+	gracePeriod, err := parseJSONDuration("grace_period", l.GracePeriod, 0)
+	if err != nil {
+		return ratelimiter.Config{}, err
+	}
+
+	if l.BlockEscalationFactor < 0 {
+		return ratelimiter.Config{}, fmt.Errorf("block_escalation_factor não pode ser negativo, obtido %g", l.BlockEscalationFactor)
+	}
+
+	maxBlockTime, err := parseJSONDuration("max_block_time", l.MaxBlockTime, 0)
+	if err != nil {
+		return ratelimiter.Config{}, err
+	}
+
+	blockEscalationReset, err := parseJSONDuration("block_escalation_reset", l.BlockEscalationReset, 0)
+	if err != nil {
+		return ratelimiter.Config{}, err
+	}
+
+	algorithm, err := parseAlgorithm(l.Algorithm)
+	if err != nil {
+		return ratelimiter.Config{}, fmt.Errorf("algoritmo inválido: %w", err)
+	}
+
+	windowAlignment, err := parseWindowAlignment(l.WindowAlignment)
+	if err != nil {
+		return ratelimiter.Config{}, fmt.Errorf("alinhamento de janela inválido: %w", err)
+	}
+
+	config := ratelimiter.Config{
+		Requests:              l.Requests,
+		Window:                window,
+		WindowAlignment:       windowAlignment,
+		Burst:                 l.Burst,
+		BurstWindow:           burstWindow,
+		BlockTime:             blockTime,
+		BlockJitter:           blockJitter,
+		Algorithm:             algorithm,
+		GracePeriod:           gracePeriod,
+		MaxConcurrent:         l.MaxConcurrent,
+		SoftLimit:             l.SoftLimit,
+		BlockEscalationFactor: l.BlockEscalationFactor,
+		MaxBlockTime:          maxBlockTime,
+		BlockEscalationReset:  blockEscalationReset,
+	}
+
+	if err := validateSoftLimit(config); err != nil {
+		return ratelimiter.Config{}, err
+	}
+
+	for i, tier := range l.Tiers {
+		tierConfig, err := tier.toConfig()
+		if err != nil {
+			return ratelimiter.Config{}, fmt.Errorf("limite adicional %d: %w", i, err)
+		}
+		config.Tiers = append(config.Tiers, tierConfig)
+	}
+
+	// reject_only apenas rejeita as requisições excedentes, ignorando o bloqueio configurado
+	if l.RejectOnly {
+		config = config.WithoutBlock()
+	}
+
+	return config, nil
+}
+
+// parseJSONDuration converte uma duração em texto; vazio usa o valor padrão
+func parseJSONDuration(field, value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("duração inválida em %s: %w", field, err)
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("duração negativa em %s: %s", field, value)
+	}
+
+	return duration, nil
+}
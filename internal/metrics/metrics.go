@@ -0,0 +1,209 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Counter é um contador monotônico seguro para uso concorrente
+type Counter struct {
+	value atomic.Uint64
+}
+
+// Inc incrementa o contador em um
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Value retorna o valor atual do contador
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+// Labels identifica uma série de um CounterVec
+type Labels struct {
+	// Scope é o escopo do limite avaliado (ex: "ip", "token")
+	Scope string
+
+	// Route é a rota da requisição, definida apenas quando o middleware recebe uma função de
+	// rótulo de rota; vazia, o rótulo não é exposto
+	Route string
+}
+
+// CounterVec reúne contadores monotônicos separados por Labels. O valor zero está pronto para
+// uso e é seguro para uso concorrente.
+type CounterVec struct {
+	mu       sync.RWMutex
+	counters map[Labels]*Counter
+}
+
+// With retorna o contador da série identificada por labels, criando-o se necessário
+func (v *CounterVec) With(labels Labels) *Counter {
+	v.mu.RLock()
+	counter, exists := v.counters[labels]
+	v.mu.RUnlock()
+	if exists {
+		return counter
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if counter, exists := v.counters[labels]; exists {
+		return counter
+	}
+	if v.counters == nil {
+		v.counters = make(map[Labels]*Counter)
+	}
+	counter = &Counter{}
+	v.counters[labels] = counter
+	return counter
+}
+
+// Value retorna o valor da série identificada por labels, zero se ela não existe
+func (v *CounterVec) Value(labels Labels) uint64 {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if counter, exists := v.counters[labels]; exists {
+		return counter.Value()
+	}
+	return 0
+}
+
+// Total retorna a soma de todas as séries
+func (v *CounterVec) Total() uint64 {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	var total uint64
+	for _, counter := range v.counters {
+		total += counter.Value()
+	}
+	return total
+}
+
+// series retorna as séries ordenadas pelos rótulos, para uma exposição estável
+func (v *CounterVec) series() []series {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	all := make([]series, 0, len(v.counters))
+	for labels, counter := range v.counters {
+		all = append(all, series{labels: labels, value: counter.Value()})
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].labels.Scope != all[j].labels.Scope {
+			return all[i].labels.Scope < all[j].labels.Scope
+		}
+		return all[i].labels.Route < all[j].labels.Route
+	})
+	return all
+}
+
+// series é o valor de uma série no momento da exposição
+type series struct {
+	labels Labels
+	value  uint64
+}
+
+// Metrics reúne os contadores do rate limiter, expostos no formato de texto do Prometheus com
+// os rótulos scope e, quando definido, route
+type Metrics struct {
+	// Allowed conta as requisições permitidas pelo middleware
+	Allowed CounterVec
+
+	// Rejected conta as requisições rejeitadas por exceder o limite
+	Rejected CounterVec
+
+	// ShadowBlocks conta as requisições que seriam rejeitadas, mas foram permitidas pelo modo
+	// shadow do middleware
+	ShadowBlocks CounterVec
+
+	// Degraded conta as requisições liberadas sem limitação por falha do armazenamento, com o
+	// middleware em FailOpen
+	Degraded CounterVec
+}
+
+// New cria um conjunto de métricas zerado
+func New() *Metrics {
+	return &Metrics{}
+}
+
+// metric descreve um contador exposto
+type metric struct {
+	name    string
+	help    string
+	counter *CounterVec
+}
+
+// metrics lista os contadores na ordem de exposição
+func (m *Metrics) metrics() []metric {
+	return []metric{
+		{
+			name:    "ratelimiter_requests_allowed_total",
+			help:    "Requisições permitidas pelo rate limiter.",
+			counter: &m.Allowed,
+		},
+		{
+			name:    "ratelimiter_requests_rejected_total",
+			help:    "Requisições rejeitadas por exceder o limite.",
+			counter: &m.Rejected,
+		},
+		{
+			name:    "ratelimiter_shadow_blocks_total",
+			help:    "Requisições que excederiam o limite, permitidas pelo modo shadow.",
+			counter: &m.ShadowBlocks,
+		},
+		{
+			name:    "ratelimiter_degraded_requests_total",
+			help:    "Requisições liberadas sem limitação por falha do armazenamento.",
+			counter: &m.Degraded,
+		},
+	}
+}
+
+// WriteTo escreve os contadores no formato de exposição de texto do Prometheus. Contadores sem
+// séries trazem apenas HELP e TYPE.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	var out strings.Builder
+	for _, metric := range m.metrics() {
+		fmt.Fprintf(&out, "# HELP %s %s\n# TYPE %s counter\n", metric.name, metric.help, metric.name)
+		for _, series := range metric.counter.series() {
+			fmt.Fprintf(&out, "%s{%s} %d\n", metric.name, formatLabels(series.labels), series.value)
+		}
+	}
+
+	n, err := io.WriteString(w, out.String())
+	return int64(n), err
+}
+
+// formatLabels formata os rótulos de uma série, omitindo route quando vazio
+func formatLabels(labels Labels) string {
+	formatted := `scope="` + escapeLabelValue(labels.Scope) + `"`
+	if labels.Route != "" {
+		formatted += `,route="` + escapeLabelValue(labels.Route) + `"`
+	}
+	return formatted
+}
+
+// labelValueEscaper escapa os caracteres especiais dos valores de rótulo do formato de texto
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabelValue escapa um valor de rótulo para o formato de texto do Prometheus
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+// Handler retorna um handler HTTP que expõe as métricas para coleta pelo Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.WriteTo(w)
+	})
+}
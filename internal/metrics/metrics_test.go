@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounter_ConcurrentInc(t *testing.T) {
+	var counter Counter
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			counter.Inc()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, uint64(100), counter.Value())
+}
+
+func TestCounterVec(t *testing.T) {
+	var vec CounterVec
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			vec.With(Labels{Scope: []string{"ip", "token"}[i%2]}).Inc()
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, uint64(50), vec.Value(Labels{Scope: "ip"}))
+	assert.Equal(t, uint64(50), vec.Value(Labels{Scope: "token"}))
+	assert.Zero(t, vec.Value(Labels{Scope: "ip", Route: "/upload"}))
+	assert.Equal(t, uint64(100), vec.Total())
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	m := New()
+	m.ShadowBlocks.With(Labels{Scope: "ip"}).Inc()
+	m.ShadowBlocks.With(Labels{Scope: "ip"}).Inc()
+	m.Rejected.With(Labels{Scope: "token", Route: "/upload"}).Inc()
+	m.Rejected.With(Labels{Scope: "ip", Route: `/a"b\c`}).Inc()
+	m.Degraded.With(Labels{Scope: "token"}).Inc()
+
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+	body := recorder.Body.String()
+	assert.Contains(t, body, "# TYPE ratelimiter_shadow_blocks_total counter\nratelimiter_shadow_blocks_total{scope=\"ip\"} 2\n")
+
+	// As séries são ordenadas e os valores dos rótulos escapados
+	assert.Contains(t, body, "# TYPE ratelimiter_requests_rejected_total counter\n"+
+		`ratelimiter_requests_rejected_total{scope="ip",route="/a\"b\\c"} 1`+"\n"+
+		`ratelimiter_requests_rejected_total{scope="token",route="/upload"} 1`+"\n")
+
+	assert.Contains(t, body, "# TYPE ratelimiter_degraded_requests_total counter\nratelimiter_degraded_requests_total{scope=\"token\"} 1\n")
+
+	// Contadores sem séries trazem apenas HELP e TYPE
+	assert.Contains(t, body, "# TYPE ratelimiter_requests_allowed_total counter\n# HELP ratelimiter_requests_rejected_total")
+}
@@ -0,0 +1,24 @@
+package middleware
+
+// AnonymousMode define como o limite anônimo (ver ratelimiter.RateLimiter.SetAnonymousConfig)
+// se combina com o limite de IP nas requisições sem token
+type AnonymousMode int
+
+const (
+	// AnonymousDisabled limita as requisições sem token apenas pelo IP (padrão)
+	AnonymousDisabled AnonymousMode = iota
+
+	// AnonymousInsteadOfIP aplica às requisições sem token o limite anônimo no lugar do limite
+	// de IP
+	AnonymousInsteadOfIP
+
+	// AnonymousWithIP exige que as requisições sem token estejam dentro do limite de IP e do
+	// limite anônimo
+	AnonymousWithIP
+)
+
+// anonymous indica se o limite anônimo se aplica à requisição, ou seja, se ela não traz token
+// e a limitação anônima está habilitada
+func (m *RateLimiterMiddleware) anonymous(apiKey string) bool {
+	return apiKey == "" && m.AnonymousMode != AnonymousDisabled
+}
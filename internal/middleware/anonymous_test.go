@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter/ratelimitertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAnonymousHandler cria um handler com limite de IP de 3 requisições e o limite anônimo
+// informado, aplicado no modo indicado
+func newAnonymousHandler(mode AnonymousMode, anonymousConfig ratelimiter.Config, scope ratelimiter.AnonymousScope) (*ratelimiter.RateLimiter, func(remoteAddr, token string) *httptest.ResponseRecorder) {
+	ipConfig := ratelimiter.Config{Requests: 3, Window: time.Second, BlockTime: time.Minute}
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ipConfig)
+	rateLimiter.SetAnonymousConfig(anonymousConfig, scope)
+	rateLimiter.AddTokenConfig("abc123", ratelimiter.Config{Requests: 10, Window: time.Second, BlockTime: time.Minute})
+
+	handler := NewRateLimiterMiddleware(rateLimiter, WithAnonymousMode(mode)).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	return rateLimiter, func(remoteAddr, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+		if token != "" {
+			req.Header.Set(DefaultAPIKeyHeader, token)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+}
+
+func TestRateLimiterMiddleware_AnonymousInsteadOfIP(t *testing.T) {
+	rateLimiter, send := newAnonymousHandler(AnonymousInsteadOfIP,
+		ratelimiter.Config{Requests: 2, Window: time.Second}, ratelimiter.AnonymousShared)
+
+	// O tráfego anônimo de todos os IPs divide o orçamento anônimo, menor que o de cada IP
+	assert.Equal(t, http.StatusOK, send("192.168.1.1:12345", "").Code)
+	assert.Equal(t, http.StatusOK, send("192.168.1.2:12345", "").Code)
+
+	recorder := send("192.168.1.3:12345", "")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, ScopeAnonymous, recorder.Header().Get(ScopeHeader))
+
+	// O orçamento de IP não é consumido
+	result, err := rateLimiter.Peek(context.Background(), "ip:192.168.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), result.Count)
+
+	// Requisições com token não usam o orçamento anônimo
+	assert.Equal(t, http.StatusOK, send("192.168.1.3:12345", "abc123").Code)
+}
+
+func TestRateLimiterMiddleware_AnonymousWithIP(t *testing.T) {
+	_, send := newAnonymousHandler(AnonymousWithIP,
+		ratelimiter.Config{Requests: 4, Window: time.Second}, ratelimiter.AnonymousShared)
+
+	// O limite de IP continua valendo para cada cliente
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, send("192.168.1.1:12345", "").Code)
+	}
+	recorder := send("192.168.1.1:12345", "")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, ScopeIP, recorder.Header().Get(ScopeHeader))
+
+	// E o orçamento anônimo limita o total, mesmo com os demais IPs dentro do seu limite
+	assert.Equal(t, http.StatusOK, send("192.168.1.2:12345", "").Code)
+	recorder = send("192.168.1.3:12345", "")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, ScopeAnonymous, recorder.Header().Get(ScopeHeader))
+}
+
+func TestRateLimiterMiddleware_AnonymousPerIP(t *testing.T) {
+	_, send := newAnonymousHandler(AnonymousInsteadOfIP,
+		ratelimiter.Config{Requests: 1, Window: time.Second, BlockTime: time.Minute}, ratelimiter.AnonymousPerIP)
+
+	// Cada IP tem o seu orçamento anônimo
+	for i := 1; i <= 3; i++ {
+		assert.Equal(t, http.StatusOK, send(fmt.Sprintf("192.168.1.%d:12345", i), "").Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, send("192.168.1.1:12345", "").Code)
+}
+
+func TestRateLimiterMiddleware_AnonymousDisabled(t *testing.T) {
+	_, send := newAnonymousHandler(AnonymousDisabled,
+		ratelimiter.Config{Requests: 1, Window: time.Second}, ratelimiter.AnonymousShared)
+
+	// Sem o modo anônimo, vale apenas o limite de IP
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, send("192.168.1.1:12345", "").Code)
+	}
+	recorder := send("192.168.1.1:12345", "")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, ScopeIP, recorder.Header().Get(ScopeHeader))
+}
+
+func TestResetForRequest_Anonymous(t *testing.T) {
+	for _, scope := range []ratelimiter.AnonymousScope{ratelimiter.AnonymousShared, ratelimiter.AnonymousPerIP} {
+		rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{Requests: 5, Window: time.Minute})
+		rateLimiter.SetAnonymousConfig(ratelimiter.Config{Requests: 5, Window: time.Minute}, scope)
+
+		handler := Wrap(rateLimiter, loginHandler(t), WithAnonymousMode(AnonymousWithIP))
+
+		login := func(password string) {
+			req := httptest.NewRequest("POST", "/login", nil)
+			req.RemoteAddr = "192.168.1.1:12345"
+			req.Header.Set("X-Password", password)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		login("wrong")
+		login("wrong")
+		login("secret")
+
+		ctx := context.Background()
+		ipResult, err := rateLimiter.Peek(ctx, "ip:192.168.1.1")
+		require.NoError(t, err)
+		assert.Zero(t, ipResult.Count, scope)
+
+		// O orçamento anônimo por IP é do cliente e é zerado; o compartilhado é mantido
+		if scope == ratelimiter.AnonymousPerIP {
+			result, err := rateLimiter.Peek(ctx, "anonymous:192.168.1.1")
+			require.NoError(t, err)
+			assert.Zero(t, result.Count)
+		} else {
+			result, err := rateLimiter.Peek(ctx, ratelimiter.AnonymousKey)
+			require.NoError(t, err)
+			assert.Equal(t, int64(3), result.Count)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// DefaultAPIKeyHeader é o header de onde o token é lido quando APIKeyHeader não é definido
+const DefaultAPIKeyHeader = "API_KEY"
+
+// bearerPrefix é o esquema de autenticação removido do header Authorization
+const bearerPrefix = "Bearer "
+
+// apiKey extrai o token da requisição a partir do header configurado. No header
+// Authorization o prefixo "Bearer " é removido (sem diferenciar maiúsculas); sem o prefixo,
+// o valor bruto do header é usado.
+func (m *RateLimiterMiddleware) apiKey(r *http.Request) string {
+	header := m.apiKeyHeader()
+
+	value := r.Header.Get(header)
+	if http.CanonicalHeaderKey(header) != "Authorization" {
+		return value
+	}
+
+	if len(value) > len(bearerPrefix) && strings.EqualFold(value[:len(bearerPrefix)], bearerPrefix) {
+		return strings.TrimSpace(value[len(bearerPrefix):])
+	}
+
+	return value
+}
+
+// stripAPIKey retorna uma cópia da requisição sem o header do token quando StripAPIKeyHeader
+// está ativo. A requisição original não é alterada, pois middlewares anteriores podem
+// continuar usando-a.
+func (m *RateLimiterMiddleware) stripAPIKey(r *http.Request) *http.Request {
+	header := m.apiKeyHeader()
+	if !m.StripAPIKeyHeader || r.Header.Get(header) == "" {
+		return r
+	}
+
+	stripped := r.Clone(r.Context())
+	stripped.Header.Del(header)
+	return stripped
+}
+
+// apiKeyHeader retorna o header de onde o token é lido
+func (m *RateLimiterMiddleware) apiKeyHeader() string {
+	if m.APIKeyHeader == "" {
+		return DefaultAPIKeyHeader
+	}
+	return m.APIKeyHeader
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter/ratelimitertest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterMiddleware_APIKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		sentHeader  string
+		value       string
+		expectedKey string
+	}{
+		{
+			name:        "header padrão",
+			sentHeader:  "API_KEY",
+			value:       "abc123",
+			expectedKey: "abc123",
+		},
+		{
+			name:        "Authorization com prefixo Bearer",
+			header:      "Authorization",
+			sentHeader:  "Authorization",
+			value:       "Bearer abc123",
+			expectedKey: "abc123",
+		},
+		{
+			name:        "prefixo Bearer sem diferenciar maiúsculas",
+			header:      "authorization",
+			sentHeader:  "Authorization",
+			value:       "bEaReR abc123",
+			expectedKey: "abc123",
+		},
+		{
+			name:        "Authorization sem prefixo usa o valor bruto",
+			header:      "Authorization",
+			sentHeader:  "Authorization",
+			value:       "abc123",
+			expectedKey: "abc123",
+		},
+		{
+			name:        "outro esquema usa o valor bruto",
+			header:      "Authorization",
+			sentHeader:  "Authorization",
+			value:       "Basic YWJjOjEyMw==",
+			expectedKey: "Basic YWJjOjEyMw==",
+		},
+		{
+			name:        "prefixo Bearer mantido em outros headers",
+			header:      "X-API-Key",
+			sentHeader:  "X-API-Key",
+			value:       "Bearer abc123",
+			expectedKey: "Bearer abc123",
+		},
+		{
+			name:        "header ausente",
+			header:      "Authorization",
+			expectedKey: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middleware := &RateLimiterMiddleware{APIKeyHeader: tt.header}
+
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.sentHeader != "" {
+				req.Header.Set(tt.sentHeader, tt.value)
+			}
+
+			assert.Equal(t, tt.expectedKey, middleware.apiKey(req))
+		})
+	}
+}
+
+func TestRateLimiterMiddleware_StripAPIKeyHeader(t *testing.T) {
+	newRateLimiter := func() *ratelimiter.RateLimiter {
+		rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{Requests: 10, Window: time.Second})
+		rateLimiter.AddTokenConfig("abc123", ratelimiter.Config{Requests: 1, Window: time.Second, BlockTime: time.Minute})
+		return rateLimiter
+	}
+
+	tests := []struct {
+		name     string
+		strip    bool
+		skip     bool
+		expected string
+	}{
+		{name: "header repassado por padrão", expected: "abc123"},
+		{name: "header removido", strip: true, expected: ""},
+		{name: "header removido em requisições isentas", strip: true, skip: true, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middleware := NewRateLimiterMiddleware(newRateLimiter(),
+				WithAPIKeyHeader("X-API-Key"),
+				WithStripAPIKeyHeader(tt.strip),
+				WithSkip(func(r *http.Request) bool { return tt.skip }),
+			)
+
+			var downstream string
+			handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				downstream = r.Header.Get("X-API-Key")
+			}))
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-API-Key", "abc123")
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tt.expected, downstream)
+
+			// A requisição do chamador não é alterada
+			assert.Equal(t, "abc123", req.Header.Get("X-API-Key"))
+		})
+	}
+
+	// O token continua sendo usado na limitação mesmo com o header removido
+	middleware := NewRateLimiterMiddleware(newRateLimiter(), WithAPIKeyHeader("X-API-Key"), WithStripAPIKeyHeader(true))
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	codes := make([]int, 2)
+	for i := range codes {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-API-Key", "abc123")
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		codes[i] = recorder.Code
+	}
+	assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests}, codes)
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxStreamedBytes é o limite padrão de leitura dos corpos sem Content-Length no modo
+// Bandwidth
+const DefaultMaxStreamedBytes int64 = 1 << 20
+
+// errBodyTooLarge indica que um corpo sem Content-Length excedeu MaxStreamedBytes ou
+// MaxBodyBytes
+var errBodyTooLarge = errors.New("corpo da requisição excede o limite de leitura")
+
+// requestSize retorna o tamanho do corpo da requisição em bytes. Sem Content-Length (ex:
+// chunked), o corpo é lido até MaxStreamedBytes (ou MaxBodyBytes, se menor) para ser contado e
+// então devolvido à requisição, para que o handler o leia normalmente.
+func (m *RateLimiterMiddleware) requestSize(r *http.Request) (int64, error) {
+	if r.ContentLength >= 0 {
+		return r.ContentLength, nil
+	}
+
+	maxBytes := m.MaxStreamedBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxStreamedBytes
+	}
+	if m.MaxBodyBytes > 0 {
+		maxBytes = min(maxBytes, m.MaxBodyBytes)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return 0, errBodyTooLarge
+	}
+	if err != nil {
+		return 0, err
+	}
+	if int64(len(body)) > maxBytes {
+		return 0, errBodyTooLarge
+	}
+
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{bytes.NewReader(body), r.Body}
+
+	return int64(len(body)), nil
+}
+
+// writeBodyTooLarge responde 413 para corpos maiores que MaxBodyBytes ou, sem Content-Length,
+// que MaxStreamedBytes
+func writeBodyTooLarge(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	w.Write([]byte(`{"error": "request body too large"}`))
+}
+
+// writeBadRequest responde 400 quando o corpo da requisição não pode ser lido
+func writeBadRequest(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write([]byte(`{"error": "failed to read request body"}`))
+}
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter/ratelimitertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiterMiddleware_Bandwidth(t *testing.T) {
+	// Orçamento de 1000 bytes por janela para cada IP
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+		Requests: 1000,
+		Window:   time.Minute,
+	})
+
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithBandwidth(0))
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	steps := []struct {
+		size     int
+		expected int
+	}{
+		{size: 400, expected: http.StatusOK},
+		{size: 500, expected: http.StatusOK},
+		{size: 0, expected: http.StatusOK},
+		{size: 100, expected: http.StatusOK},
+		{size: 1, expected: http.StatusTooManyRequests},
+	}
+
+	for i, step := range steps {
+		req := httptest.NewRequest("POST", "/upload", strings.NewReader(strings.Repeat("a", step.size)))
+		req.RemoteAddr = "192.168.1.1:12345"
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, step.expected, recorder.Code, "requisição %d (%d bytes)", i+1, step.size)
+	}
+
+	// Outro IP tem o seu próprio orçamento de bytes
+	req := httptest.NewRequest("POST", "/upload", strings.NewReader(strings.Repeat("a", 1000)))
+	req.RemoteAddr = "192.168.1.2:12345"
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestRateLimiterMiddleware_BandwidthWithoutContentLength(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+		Requests: 100,
+		Window:   time.Minute,
+	})
+
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithBandwidth(80))
+
+	var received string
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		received = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(body io.Reader) int {
+		req := httptest.NewRequest("POST", "/upload", body)
+		req.ContentLength = -1
+		req.RemoteAddr = "192.168.1.1:12345"
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// O corpo lido para a contagem continua disponível para o handler
+	assert.Equal(t, http.StatusOK, send(io.NopCloser(strings.NewReader(strings.Repeat("a", 60)))))
+	assert.Equal(t, strings.Repeat("a", 60), received)
+
+	// Corpos acima do limite de leitura são rejeitados sem consumir o orçamento
+	assert.Equal(t, http.StatusRequestEntityTooLarge, send(io.NopCloser(strings.NewReader(strings.Repeat("a", 81)))))
+
+	// Falhas de leitura do corpo são rejeitadas
+	assert.Equal(t, http.StatusBadRequest, send(io.NopCloser(iotest.ErrReader(io.ErrUnexpectedEOF))))
+
+	// Os 60 bytes anteriores mais 40 esgotam o orçamento de 100
+	assert.Equal(t, http.StatusOK, send(io.NopCloser(strings.NewReader(strings.Repeat("a", 40)))))
+	assert.Equal(t, http.StatusTooManyRequests, send(io.NopCloser(strings.NewReader(strings.Repeat("a", 1)))))
+}
+
+func TestRateLimiterMiddleware_MaxBodyBytes(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+		Requests:  1,
+		Window:    time.Minute,
+		BlockTime: time.Minute,
+	})
+
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithMaxBodyBytes(100))
+
+	var readErr error
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(body io.Reader, contentLength int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/upload", body)
+		req.ContentLength = contentLength
+		req.RemoteAddr = "192.168.1.1:12345"
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// Corpos com Content-Length acima do máximo são rejeitados sem consumir o limite
+	recorder := send(strings.NewReader(strings.Repeat("a", 101)), 101)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	assert.JSONEq(t, `{"error": "request body too large"}`, recorder.Body.String())
+
+	// Sem Content-Length, o handler não consegue ler além do máximo
+	recorder = send(io.NopCloser(strings.NewReader(strings.Repeat("a", 101))), -1)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var maxBytesErr *http.MaxBytesError
+	assert.ErrorAs(t, readErr, &maxBytesErr)
+
+	// O limite de 1 requisição foi consumido apenas pela requisição anterior
+	assert.Equal(t, http.StatusTooManyRequests, send(strings.NewReader("ok"), 2).Code)
+}
+
+func TestRateLimiterMiddleware_MaxBodyBytesBandwidth(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+		Requests: 100,
+		Window:   time.Minute,
+	})
+
+	// O máximo do corpo é menor que o limite de leitura do modo Bandwidth
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithBandwidth(1000), WithMaxBodyBytes(50))
+
+	var received string
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		received = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(body io.Reader, contentLength int64) int {
+		req := httptest.NewRequest("POST", "/upload", body)
+		req.ContentLength = contentLength
+		req.RemoteAddr = "192.168.1.1:12345"
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// Acima do máximo, com ou sem Content-Length, a requisição é rejeitada sem consumir bytes
+	assert.Equal(t, http.StatusRequestEntityTooLarge, send(strings.NewReader(strings.Repeat("a", 51)), 51))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, send(io.NopCloser(strings.NewReader(strings.Repeat("a", 51))), -1))
+
+	// Até o máximo, o corpo é contado e entregue ao handler
+	assert.Equal(t, http.StatusOK, send(io.NopCloser(strings.NewReader(strings.Repeat("a", 50))), -1))
+	assert.Equal(t, strings.Repeat("a", 50), received)
+	assert.Equal(t, http.StatusOK, send(strings.NewReader(strings.Repeat("a", 50)), 50))
+
+	// Os 100 bytes do orçamento foram consumidos apenas pelas requisições aceitas
+	assert.Equal(t, http.StatusTooManyRequests, send(strings.NewReader("a"), 1))
+}
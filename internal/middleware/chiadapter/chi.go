@@ -0,0 +1,14 @@
+// Package chiadapter adapta o middleware de rate limiter para o roteador chi
+package chiadapter
+
+import (
+	"net/http"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/middleware"
+)
+
+// ChiMiddleware retorna um middleware no formato aceito por chi.Router.Use.
+// Como o chi usa a assinatura padrão do net/http, o handler do middleware é reaproveitado diretamente.
+func ChiMiddleware(m *middleware.RateLimiterMiddleware) func(http.Handler) http.Handler {
+	return m.Handler
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter/ratelimitertest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterMiddleware_CompositeKey(t *testing.T) {
+	ipConfig := ratelimiter.Config{Requests: 1, Window: time.Second, BlockTime: time.Minute}
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ipConfig)
+
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithCompositeKey(CompositeKey{
+		Headers:     []string{"X-Tenant-ID", "x-client-version"},
+		IncludePath: true,
+		Config:      ratelimiter.Config{Requests: 2, Window: time.Second, BlockTime: time.Minute},
+	}))
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(path, tenant, version, remoteAddr string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = remoteAddr
+		if tenant != "" {
+			req.Header.Set("X-Tenant-ID", tenant)
+		}
+		if version != "" {
+			req.Header.Set("X-Client-Version", version)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// A mesma combinação compartilha o orçamento mesmo vindo de IPs diferentes
+	assert.Equal(t, http.StatusOK, send("/orders", "acme", "1.0", "192.168.1.1:12345"))
+	assert.Equal(t, http.StatusOK, send("/orders", "acme", "1.0", "192.168.1.2:12345"))
+	assert.Equal(t, http.StatusTooManyRequests, send("/orders", "acme", "1.0", "192.168.1.3:12345"))
+
+	// Combinações distintas de headers ou de caminho têm orçamentos independentes
+	assert.Equal(t, http.StatusOK, send("/orders", "globex", "1.0", "192.168.1.1:12345"))
+	assert.Equal(t, http.StatusOK, send("/orders", "acme", "2.0", "192.168.1.1:12345"))
+	assert.Equal(t, http.StatusOK, send("/payments", "acme", "1.0", "192.168.1.1:12345"))
+
+	// Sem um dos headers, a limitação por IP é aplicada
+	assert.Equal(t, http.StatusOK, send("/orders", "acme", "", "192.168.1.9:12345"))
+	assert.Equal(t, http.StatusTooManyRequests, send("/orders", "acme", "", "192.168.1.9:12345"))
+}
+
+func TestCompositeKey_KeyFunc(t *testing.T) {
+	config := ratelimiter.Config{Requests: 5, Window: time.Minute}
+
+	request := func(headers map[string]string) *http.Request {
+		req := httptest.NewRequest("GET", "/orders", nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		return req
+	}
+
+	keyFunc := CompositeKey{Headers: []string{"X-A", "X-B"}, Config: config}.KeyFunc()
+
+	key, cfg, ok := keyFunc(request(map[string]string{"X-A": "tenant", "X-B": "v1"}))
+	assert.True(t, ok)
+	assert.Equal(t, config, cfg)
+	assert.True(t, strings.HasPrefix(key, compositeKeyNamespace+":"))
+
+	// O resumo tem tamanho fixo e não expõe os valores
+	assert.Len(t, key, len(compositeKeyNamespace+":")+32)
+	assert.NotContains(t, key, "tenant")
+
+	// Separadores nos valores não fazem combinações diferentes colidirem
+	first, _, _ := keyFunc(request(map[string]string{"X-A": "a:b", "X-B": "c"}))
+	second, _, _ := keyFunc(request(map[string]string{"X-A": "a", "X-B": "b:c"}))
+	assert.NotEqual(t, first, second)
+
+	// O caminho só diferencia as chaves com IncludePath
+	otherPath := request(map[string]string{"X-A": "tenant", "X-B": "v1"})
+	otherPath.URL.Path = "/payments"
+	samePath, _, _ := keyFunc(otherPath)
+	assert.Equal(t, key, samePath)
+
+	// Headers ausentes não geram identidade, a menos que AllowMissing esteja ativo
+	_, _, ok = keyFunc(request(map[string]string{"X-A": "tenant"}))
+	assert.False(t, ok)
+
+	allowMissing := CompositeKey{Headers: []string{"X-A", "X-B"}, AllowMissing: true, Config: config}.KeyFunc()
+	key, _, ok = allowMissing(request(map[string]string{"X-A": "tenant"}))
+	assert.True(t, ok)
+	assert.NotEmpty(t, key)
+
+	// Sem headers configurados a identidade composta não é usada
+	_, _, ok = CompositeKey{Config: config}.KeyFunc()(request(nil))
+	assert.False(t, ok)
+}
+
+func TestWithCompositeKey_KeySeparator(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{Requests: 1, Window: time.Second},
+		ratelimiter.WithKeyNames(ratelimiter.KeyNames{Separator: "."}))
+
+	// A identidade composta segue o separador das chaves do rate limiter
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithCompositeKey(CompositeKey{
+		Headers: []string{"X-Tenant-ID"},
+		Config:  ratelimiter.Config{Requests: 2, Window: time.Second},
+	}))
+
+	req := httptest.NewRequest("GET", "/orders", nil)
+	req.Header.Set("X-Tenant-ID", "acme")
+
+	key, _, ok := middleware.KeyFunc(req)
+	assert.True(t, ok)
+	assert.True(t, strings.HasPrefix(key, "composite."), key)
+	assert.NotContains(t, key, ":")
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+)
+
+// statusRecorder registra o status da resposta escrita pelo handler para CountStatuses
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader registra o primeiro status escrito
+func (s *statusRecorder) WriteHeader(statusCode int) {
+	if s.status == 0 {
+		s.status = statusCode
+	}
+	s.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write registra o status implícito 200 quando o handler escreve sem chamar WriteHeader
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// Unwrap expõe o ResponseWriter original a http.ResponseController (ex: Flush)
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// statusCode retorna o status da resposta; sem nada escrito, o servidor responde 200
+func (s *statusRecorder) statusCode() int {
+	if s.status == 0 {
+		return http.StatusOK
+	}
+	return s.status
+}
+
+// countsStatus indica se as respostas com o status são contadas em CountStatuses
+func (m *RateLimiterMiddleware) countsStatus(status int) bool {
+	return slices.Contains(m.CountStatuses, status)
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter/ratelimitertest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterMiddleware_CountStatuses(t *testing.T) {
+	config := ratelimiter.Config{Requests: 2, Window: time.Minute, BlockTime: time.Minute}
+	rateLimiter, _, fakeClock := ratelimitertest.New(t, config)
+
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithCountStatuses(http.StatusUnauthorized, http.StatusForbidden))
+
+	// Simula um endpoint de login que responde 401 para senhas incorretas
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Password") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	login := func(password string) int {
+		req := httptest.NewRequest("POST", "/login", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		req.Header.Set("X-Password", password)
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// Respostas 200 não são contadas
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, login("secret"))
+	}
+
+	// As falhas são contadas; a que excede o limite é atendida e bloqueia o IP
+	assert.Equal(t, http.StatusUnauthorized, login("wrong"))
+	assert.Equal(t, http.StatusUnauthorized, login("wrong"))
+	assert.Equal(t, http.StatusOK, login("secret"))
+	assert.Equal(t, http.StatusUnauthorized, login("wrong"))
+
+	// Bloqueado, nem a senha correta chega ao handler
+	assert.Equal(t, http.StatusTooManyRequests, login("secret"))
+
+	fakeClock.Advance(time.Minute)
+	assert.Equal(t, http.StatusOK, login("secret"))
+}
+
+func TestRateLimiterMiddleware_CountStatusesWithoutBlockTime(t *testing.T) {
+	config := ratelimiter.Config{Requests: 1, Window: time.Minute}
+	rateLimiter, _, fakeClock := ratelimitertest.New(t, config)
+
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithCountStatuses(http.StatusForbidden))
+
+	status := http.StatusForbidden
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+
+	send := func() int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, send())
+	assert.Equal(t, http.StatusForbidden, send())
+
+	// Sem tempo de bloqueio, as requisições são rejeitadas até a janela terminar
+	status = http.StatusOK
+	assert.Equal(t, http.StatusTooManyRequests, send())
+
+	fakeClock.Advance(time.Minute)
+	assert.Equal(t, http.StatusOK, send())
+}
+
+func TestStatusRecorder(t *testing.T) {
+	recorder := &statusRecorder{ResponseWriter: httptest.NewRecorder()}
+	assert.Equal(t, http.StatusOK, recorder.statusCode())
+
+	recorder.Write([]byte("ok"))
+	recorder.WriteHeader(http.StatusInternalServerError)
+	assert.Equal(t, http.StatusOK, recorder.statusCode())
+
+	recorder = &statusRecorder{ResponseWriter: httptest.NewRecorder()}
+	recorder.WriteHeader(http.StatusUnauthorized)
+	assert.Equal(t, http.StatusUnauthorized, recorder.statusCode())
+}
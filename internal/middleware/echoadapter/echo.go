@@ -0,0 +1,13 @@
+// Package echoadapter adapta o middleware de rate limiter para o framework Echo
+package echoadapter
+
+import (
+	"github.com/cleibson/goexpert-rate-limiter/internal/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+// EchoMiddleware retorna um echo.MiddlewareFunc que aplica o rate limiter às requisições.
+// A verificação, os headers e a resposta de limite excedido são os mesmos do middleware HTTP.
+func EchoMiddleware(m *middleware.RateLimiterMiddleware) echo.MiddlewareFunc {
+	return echo.WrapMiddleware(m.Handler)
+}
@@ -0,0 +1,43 @@
+// Package ginadapter adapta o middleware de rate limiter para o framework Gin
+package ginadapter
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// ginRequestKey é a chave de contexto que leva o estado da requisição do Gin até o handler
+// repassado ao middleware HTTP
+type ginRequestKey struct{}
+
+// ginRequest é o estado de uma requisição do Gin durante a verificação
+type ginRequest struct {
+	context *gin.Context
+	passed  bool
+}
+
+// GinMiddleware retorna um gin.HandlerFunc que aplica o rate limiter às requisições.
+// A verificação, os headers e a resposta de limite excedido são os mesmos do middleware HTTP.
+func GinMiddleware(m *middleware.RateLimiterMiddleware) gin.HandlerFunc {
+	// O handler do middleware é montado uma única vez; o *gin.Context de cada requisição chega
+	// a next pelo contexto da requisição
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := r.Context().Value(ginRequestKey{}).(*ginRequest)
+		state.passed = true
+		state.context.Request = r
+		state.context.Next()
+	}))
+
+	return func(c *gin.Context) {
+		state := &ginRequest{context: c}
+		handler.ServeHTTP(c.Writer, c.Request.WithContext(context.WithValue(c.Request.Context(), ginRequestKey{}, state)))
+
+		// Interrompe a cadeia quando o middleware respondeu diretamente (limite excedido ou erro)
+		if !state.passed {
+			c.Abort()
+		}
+	}
+}
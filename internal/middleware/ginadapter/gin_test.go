@@ -0,0 +1,200 @@
+package ginadapter
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/middleware"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingStorage é um armazenamento mínimo em memória para os testes do adaptador
+type countingStorage struct {
+	storage.AlwaysHealthy
+
+	counters map[string]int64
+	blocked  map[string]bool
+}
+
+func newCountingStorage() *countingStorage {
+	return &countingStorage{
+		counters: make(map[string]int64),
+		blocked:  make(map[string]bool),
+	}
+}
+
+func (s *countingStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, bool, error) {
+	s.counters[key]++
+	return s.counters[key], s.counters[key] == 1, nil
+}
+
+func (s *countingStorage) IncrementBy(ctx context.Context, key string, amount int64, window time.Duration) (int64, error) {
+	s.counters[key] += amount
+	return s.counters[key], nil
+}
+
+func (s *countingStorage) CheckAndIncrement(ctx context.Context, key string, limit storage.Limit) (storage.Decision, error) {
+	if s.blocked[key] {
+		return storage.Decision{Blocked: true, TTL: limit.BlockTime}, nil
+	}
+
+	s.counters[key]++
+	count := s.counters[key]
+	if count <= limit.Requests {
+		return storage.Decision{Allowed: true, Count: count, TTL: limit.Window}, nil
+	}
+
+	s.blocked[key] = true
+	delete(s.counters, key)
+	return storage.Decision{Count: count, Blocked: true, NewlyBlocked: true, TTL: limit.BlockTime}, nil
+}
+
+func (s *countingStorage) Get(ctx context.Context, key string) (int64, time.Duration, error) {
+	return s.counters[key], 0, nil
+}
+
+func (s *countingStorage) IsBlocked(ctx context.Context, key string) (bool, time.Duration, error) {
+	return s.blocked[key], 0, nil
+}
+
+func (s *countingStorage) GetMany(ctx context.Context, keys []string) ([]storage.KeyState, error) {
+	states := make([]storage.KeyState, len(keys))
+	for i, key := range keys {
+		states[i] = storage.KeyState{Count: s.counters[key], Blocked: s.blocked[key]}
+	}
+	return states, nil
+}
+
+func (s *countingStorage) Block(ctx context.Context, key string, duration time.Duration) error {
+	s.blocked[key] = true
+	delete(s.counters, key)
+	return nil
+}
+
+func (s *countingStorage) ListBlocked(ctx context.Context, pattern string) ([]string, error) {
+	return nil, nil
+}
+
+func (s *countingStorage) Reset(ctx context.Context, key string) error {
+	delete(s.blocked, key)
+	delete(s.counters, key)
+	return nil
+}
+
+func (s *countingStorage) LeakyBucket(ctx context.Context, key string, capacity int64, leakInterval time.Duration, now time.Time) (bool, time.Duration, error) {
+	return true, 0, nil
+}
+
+func (s *countingStorage) FirstSeen(ctx context.Context, key string, now time.Time, ttl time.Duration) (time.Time, error) {
+	return now, nil
+}
+
+func (s *countingStorage) GetDecision(ctx context.Context, key string) (bool, bool, error) {
+	return false, false, nil
+}
+
+func (s *countingStorage) SetDecision(ctx context.Context, key string, allowed bool, ttl time.Duration) error {
+	return nil
+}
+
+func (s *countingStorage) IncrementSliding(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return 1, nil
+}
+
+func (s *countingStorage) Acquire(ctx context.Context, key string, limit int64, ttl time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (s *countingStorage) Release(ctx context.Context, key string) error {
+	return nil
+}
+
+func (s *countingStorage) Close() error {
+	return nil
+}
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	config := ratelimiter.Config{
+		Requests:  2,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+	rateLimiter := ratelimiter.NewRateLimiter(newCountingStorage(), config)
+
+	handlerCalls := 0
+	router := gin.New()
+	router.Use(GinMiddleware(middleware.NewRateLimiterMiddleware(rateLimiter)))
+	router.GET("/", func(c *gin.Context) {
+		handlerCalls++
+		c.String(http.StatusOK, "OK")
+	})
+
+	// Requisições dentro do limite chegam ao handler
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "OK", recorder.Body.String())
+	}
+
+	// A requisição excedente recebe a mesma resposta do middleware HTTP e não chega ao handler
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "you have reached the maximum number of requests")
+	assert.Equal(t, 2, handlerCalls)
+}
+
+func TestGinMiddleware_BuildsHandlerOnce(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	rateLimiter := ratelimiter.NewRateLimiter(newCountingStorage(), ratelimiter.Config{Requests: 10, Window: time.Second})
+	m := middleware.NewRateLimiterMiddleware(rateLimiter)
+	m.RejectStatusCode = http.StatusOK
+
+	router := gin.New()
+	router.Use(GinMiddleware(m))
+	router.GET("/", func(c *gin.Context) {
+		// O resultado da verificação chega ao handler pela requisição repassada
+		_, ok := ratelimiter.ResultFromContext(c.Request.Context())
+		assert.True(t, ok)
+		c.String(http.StatusOK, "OK")
+	})
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+
+	// A configuração do middleware é resolvida na montagem, não a cada requisição
+	assert.Equal(t, 1, strings.Count(logs.String(), "Status de rejeição inválido"))
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter/ratelimitertest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterMiddleware_GlobalLimit(t *testing.T) {
+	ipConfig := ratelimiter.Config{Requests: 2, Window: time.Second, BlockTime: time.Minute}
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ipConfig)
+	rateLimiter.SetGlobalConfig(ratelimiter.Config{Requests: 3, Window: time.Second})
+
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithGlobalLimit(true))
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// Nenhum IP excede o seu limite de 2 requisições, mas o total de 3 é atingido
+	for i := 1; i <= 3; i++ {
+		assert.Equal(t, http.StatusOK, send(fmt.Sprintf("192.168.1.%d:12345", i)).Code)
+	}
+
+	recorder := send("192.168.1.4:12345")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, ScopeGlobal, recorder.Header().Get("X-RateLimit-Scope"))
+
+	// A requisição rejeitada pelo limite global não consome o limite do IP
+	result, err := rateLimiter.Peek(context.Background(), "ip:192.168.1.4")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), result.Remaining)
+}
+
+func TestRateLimiterMiddleware_GlobalLimitDisabled(t *testing.T) {
+	ipConfig := ratelimiter.Config{Requests: 2, Window: time.Second, BlockTime: time.Minute}
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ipConfig)
+	rateLimiter.SetGlobalConfig(ratelimiter.Config{Requests: 1, Window: time.Second})
+
+	handler := NewRateLimiterMiddleware(rateLimiter).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 1; i <= 3; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = fmt.Sprintf("192.168.1.%d:12345", i)
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+}
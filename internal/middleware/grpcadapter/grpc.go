@@ -0,0 +1,210 @@
+//go:build grpc
+
+// Package grpcadapter aplica o rate limiter a servidores gRPC, com interceptors unários e de
+// stream. A versão do gRPC está fixada no go.mod, mas o pacote só entra no build com a build
+// tag grpc:
+//
+//	go test -tags grpc ./internal/middleware/grpcadapter/
+package grpcadapter
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net"
+	"strconv"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/middleware"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultAPIKeyMetadata é a chave de metadata de onde o token é lido quando APIKeyMetadata não
+// é definido
+const DefaultAPIKeyMetadata = "api-key"
+
+// RetryAfterMetadata é a chave do header de resposta com os segundos até que uma nova chamada
+// seja aceita, enviada nas chamadas rejeitadas quando o tempo é conhecido
+const RetryAfterMetadata = "retry-after"
+
+// Interceptor aplica o rate limiter às chamadas gRPC. A identidade segue a mesma precedência
+// do middleware HTTP: KeyFunc, o token da metadata e, por fim, o IP do peer. Chamadas acima do
+// limite são rejeitadas com codes.ResourceExhausted.
+type Interceptor struct {
+	rateLimiter *ratelimiter.RateLimiter
+
+	// APIKeyMetadata é a chave de metadata de onde o token é lido. Vazio usa
+	// DefaultAPIKeyMetadata.
+	APIKeyMetadata string
+
+	// KeyFunc deriva uma identidade personalizada da chamada (ex: o usuário autenticado por
+	// um interceptor anterior) e a configuração aplicada a ela. Quando retorna ok, a chave e a
+	// configuração substituem a limitação por token e por IP.
+	KeyFunc func(ctx context.Context, fullMethod string) (key string, cfg ratelimiter.Config, ok bool)
+
+	// FailureMode define o tratamento das chamadas quando o armazenamento falha: FailClosed
+	// (padrão) as rejeita com codes.Unavailable e FailOpen as libera sem limitação
+	FailureMode middleware.FailureMode
+}
+
+// Option configura um Interceptor criado por New
+type Option func(*Interceptor)
+
+// WithAPIKeyMetadata define a chave de metadata de onde o token é lido
+func WithAPIKeyMetadata(key string) Option {
+	return func(i *Interceptor) {
+		i.APIKeyMetadata = key
+	}
+}
+
+// WithKeyFunc define a identidade personalizada das chamadas (ver Interceptor.KeyFunc)
+func WithKeyFunc(keyFunc func(ctx context.Context, fullMethod string) (string, ratelimiter.Config, bool)) Option {
+	return func(i *Interceptor) {
+		i.KeyFunc = keyFunc
+	}
+}
+
+// WithFailureMode define o tratamento das chamadas quando o armazenamento falha
+func WithFailureMode(mode middleware.FailureMode) Option {
+	return func(i *Interceptor) {
+		i.FailureMode = mode
+	}
+}
+
+// New cria um Interceptor para o rate limiter informado
+func New(rateLimiter *ratelimiter.RateLimiter, opts ...Option) *Interceptor {
+	i := &Interceptor{rateLimiter: rateLimiter}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// Unary retorna o interceptor de chamadas unárias. O resultado da verificação fica disponível
+// ao handler em ratelimiter.ResultFromContext.
+func (i *Interceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, release, err := i.admit(ctx, info.FullMethod, func(md metadata.MD) error {
+			return grpc.SetHeader(ctx, md)
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		return handler(ctx, req)
+	}
+}
+
+// Stream retorna o interceptor de streams. Cada stream conta como uma chamada, verificada na
+// abertura; as mensagens trocadas depois não são limitadas.
+func (i *Interceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, release, err := i.admit(ss.Context(), info.FullMethod, ss.SetHeader)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// serverStream substitui o contexto do stream pelo que carrega o resultado da verificação
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context retorna o contexto com o resultado da verificação
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// admit verifica o limite da chamada e retorna o contexto repassado ao handler e a liberação
+// da vaga de concorrência ocupada. Chamadas que não podem prosseguir retornam um erro de
+// status gRPC; setHeader envia o Retry-After das rejeitadas.
+func (i *Interceptor) admit(ctx context.Context, fullMethod string, setHeader func(metadata.MD) error) (context.Context, func(), error) {
+	result, err := i.check(ctx, fullMethod)
+
+	if errors.Is(err, ratelimiter.ErrUnknownToken) {
+		return nil, nil, status.Error(codes.Unauthenticated, "invalid API key")
+	}
+
+	// O cliente desistiu da chamada
+	if err != nil && ctx.Err() != nil {
+		return nil, nil, status.FromContextError(ctx.Err()).Err()
+	}
+
+	if err != nil {
+		log.Printf("Falha ao verificar limite de taxa: %v", err)
+		if i.FailureMode == middleware.FailOpen {
+			return ctx, func() {}, nil
+		}
+		return nil, nil, status.Error(codes.Unavailable, "rate limiter temporarily unavailable, please retry later")
+	}
+
+	if !result.Allowed {
+		result.Release()
+		if result.RetryAfter > 0 {
+			seconds := strconv.FormatInt(int64(math.Ceil(result.RetryAfter.Seconds())), 10)
+			if err := setHeader(metadata.Pairs(RetryAfterMetadata, seconds)); err != nil {
+				log.Printf("Falha ao enviar %s: %v", RetryAfterMetadata, err)
+			}
+		}
+		return nil, nil, status.Error(codes.ResourceExhausted, middleware.DefaultLimitMessage)
+	}
+
+	return ratelimiter.ContextWithResult(ctx, result), result.Release, nil
+}
+
+// check aplica à chamada o limite da sua identidade. Tokens desconhecidos voltam para a
+// limitação por IP, exceto com a política Reject.
+func (i *Interceptor) check(ctx context.Context, fullMethod string) (ratelimiter.Result, error) {
+	if i.KeyFunc != nil {
+		if key, config, ok := i.KeyFunc(ctx, fullMethod); ok && key != "" {
+			return i.rateLimiter.CheckKey(ctx, key, config)
+		}
+	}
+
+	if token := i.apiKey(ctx); token != "" {
+		result, err := i.rateLimiter.CheckToken(ctx, token)
+		if !errors.Is(err, ratelimiter.ErrUnknownToken) || i.rateLimiter.UnknownTokenPolicy() == ratelimiter.Reject {
+			return result, err
+		}
+	}
+
+	return i.rateLimiter.CheckIP(ctx, peerIP(ctx))
+}
+
+// apiKey lê o token da metadata recebida
+func (i *Interceptor) apiKey(ctx context.Context) string {
+	key := i.APIKeyMetadata
+	if key == "" {
+		key = DefaultAPIKeyMetadata
+	}
+
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// peerIP extrai o IP do cliente a partir do endereço do peer, sem a porta
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
@@ -0,0 +1,169 @@
+//go:build grpc
+
+package grpcadapter
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/middleware"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter/ratelimitertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// fakeServerStream simula o stream de um servidor gRPC, registrando os headers enviados
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	header metadata.MD
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeServerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+// incomingContext simula uma chamada recebida do endereço informado, com a metadata informada
+func incomingContext(addr string, pairs ...string) context.Context {
+	tcpAddr, _ := net.ResolveTCPAddr("tcp", addr)
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: tcpAddr})
+	return metadata.NewIncomingContext(ctx, metadata.Pairs(pairs...))
+}
+
+func TestInterceptor_UnaryByPeerIP(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+		Requests:  2,
+		Window:    time.Minute,
+		BlockTime: time.Minute,
+	})
+	interceptor := New(rateLimiter).Unary()
+	info := &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Get"}
+
+	var handlerResult ratelimiter.Result
+	handler := func(ctx context.Context, req any) (any, error) {
+		handlerResult, _ = ratelimiter.ResultFromContext(ctx)
+		return "ok", nil
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := interceptor(incomingContext("192.168.1.1:5000"), "req", info, handler)
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp)
+	}
+	assert.Equal(t, int64(2), handlerResult.Limit)
+
+	// A porta do peer não faz parte da identidade
+	_, err := interceptor(incomingContext("192.168.1.1:6000"), "req", info, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, middleware.DefaultLimitMessage, status.Convert(err).Message())
+
+	// Outro IP tem seu próprio limite
+	_, err = interceptor(incomingContext("192.168.1.2:5000"), "req", info, handler)
+	assert.NoError(t, err)
+}
+
+func TestInterceptor_UnaryByAPIKey(t *testing.T) {
+	ipConfig := ratelimiter.Config{Requests: 1, Window: time.Minute}
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ipConfig,
+		ratelimiter.WithTokenConfig("abc123", ratelimiter.Config{Requests: 3, Window: time.Minute}))
+	interceptor := New(rateLimiter).Unary()
+	info := &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Get"}
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	// O token da metadata tem precedência sobre o IP
+	for i := 0; i < 3; i++ {
+		_, err := interceptor(incomingContext("192.168.1.1:5000", "api-key", "abc123"), "req", info, handler)
+		require.NoError(t, err)
+	}
+	_, err := interceptor(incomingContext("192.168.1.1:5000", "api-key", "abc123"), "req", info, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// Token desconhecido volta para a limitação por IP
+	_, err = interceptor(incomingContext("192.168.1.1:5000", "api-key", "unknown"), "req", info, handler)
+	assert.NoError(t, err)
+	_, err = interceptor(incomingContext("192.168.1.1:5000", "api-key", "unknown"), "req", info, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// Com a política Reject, o token desconhecido é recusado
+	rateLimiter.SetUnknownTokenPolicy(ratelimiter.Reject)
+	_, err = interceptor(incomingContext("192.168.1.9:5000", "api-key", "unknown"), "req", info, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestInterceptor_KeyFunc(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{Requests: 100, Window: time.Minute})
+
+	// Cada método tem um orçamento próprio, compartilhado por todos os clientes
+	methodConfig := ratelimiter.Config{Requests: 1, Window: time.Minute}
+	interceptor := New(rateLimiter, WithKeyFunc(func(ctx context.Context, fullMethod string) (string, ratelimiter.Config, bool) {
+		return "method:" + fullMethod, methodConfig, true
+	})).Unary()
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	_, err := interceptor(incomingContext("192.168.1.1:5000"), "req", &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Get"}, handler)
+	require.NoError(t, err)
+	_, err = interceptor(incomingContext("192.168.1.2:5000"), "req", &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Get"}, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	_, err = interceptor(incomingContext("192.168.1.1:5000"), "req", &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/List"}, handler)
+	assert.NoError(t, err)
+}
+
+func TestInterceptor_Stream(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+		Requests:  1,
+		Window:    time.Minute,
+		BlockTime: 30 * time.Second,
+	})
+	interceptor := New(rateLimiter, WithAPIKeyMetadata("x-api-key")).Stream()
+	info := &grpc.StreamServerInfo{FullMethod: "/orders.Orders/Watch"}
+
+	var handlerResult ratelimiter.Result
+	var hasResult bool
+	handler := func(srv any, ss grpc.ServerStream) error {
+		handlerResult, hasResult = ratelimiter.ResultFromContext(ss.Context())
+		return nil
+	}
+
+	stream := &fakeServerStream{ctx: incomingContext("192.168.1.1:5000")}
+	require.NoError(t, interceptor(nil, stream, info, handler))
+	assert.True(t, hasResult)
+	assert.True(t, handlerResult.Allowed)
+
+	// A abertura que excede o limite bloqueia o IP e informa quando tentar novamente
+	stream = &fakeServerStream{ctx: incomingContext("192.168.1.1:5000")}
+	err := interceptor(nil, stream, info, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"30"}, stream.header.Get(RetryAfterMetadata))
+}
+
+func TestInterceptor_StorageFailure(t *testing.T) {
+	storage := ratelimitertest.NewStorage(nil)
+	storage.Fail(errors.New("redis indisponível"))
+	rateLimiter := ratelimiter.NewRateLimiter(storage, ratelimiter.Config{Requests: 1, Window: time.Minute})
+	info := &grpc.UnaryServerInfo{FullMethod: "/orders.Orders/Get"}
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	// Por padrão a falha rejeita a chamada
+	_, err := New(rateLimiter).Unary()(incomingContext("192.168.1.1:5000"), "req", info, handler)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	// Com FailOpen a chamada é liberada sem limitação
+	resp, err := New(rateLimiter, WithFailureMode(middleware.FailOpen)).Unary()(incomingContext("192.168.1.1:5000"), "req", info, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+)
+
+// Headers de cota enviados conforme QuotaHeaders
+const (
+	// LimitHeader, RemainingHeader e ResetHeader são os headers de cota no formato X-RateLimit-*
+	LimitHeader     = "X-RateLimit-Limit"
+	RemainingHeader = "X-RateLimit-Remaining"
+	ResetHeader     = "X-RateLimit-Reset"
+
+	// RateLimitHeader e RateLimitPolicyHeader são os headers do draft IETF "RateLimit header
+	// fields for HTTP"
+	RateLimitHeader       = "RateLimit"
+	RateLimitPolicyHeader = "RateLimit-Policy"
+)
+
+// QuotaHeaders define quais headers de cota são enviados nas respostas das requisições
+// verificadas
+type QuotaHeaders int
+
+const (
+	// NoQuotaHeaders não envia headers de cota (padrão)
+	NoQuotaHeaders QuotaHeaders = iota
+
+	// LegacyQuotaHeaders envia X-RateLimit-Limit, X-RateLimit-Remaining e X-RateLimit-Reset
+	LegacyQuotaHeaders
+
+	// StandardQuotaHeaders envia RateLimit e RateLimit-Policy, no formato do draft IETF (ex:
+	// "RateLimit: limit=10, remaining=3, reset=5" e "RateLimit-Policy: 10;w=1")
+	StandardQuotaHeaders
+
+	// AllQuotaHeaders envia os headers X-RateLimit-* e os do draft IETF
+	AllQuotaHeaders
+)
+
+// writeQuotaHeaders adiciona à resposta os headers de cota do resultado, conforme
+// QuotaHeaders. Resultados sem limite conhecido (ex: leaky bucket) não geram headers.
+func (m *RateLimiterMiddleware) writeQuotaHeaders(w http.ResponseWriter, result ratelimiter.Result) {
+	if m.QuotaHeaders == NoQuotaHeaders || result.Limit <= 0 {
+		return
+	}
+
+	limit := strconv.FormatInt(result.Limit, 10)
+	remaining := strconv.FormatInt(max(result.Remaining, 0), 10)
+	reset, hasReset := m.quotaReset(result)
+
+	if m.QuotaHeaders == LegacyQuotaHeaders || m.QuotaHeaders == AllQuotaHeaders {
+		w.Header().Set(LimitHeader, limit)
+		w.Header().Set(RemainingHeader, remaining)
+		if hasReset {
+			w.Header().Set(ResetHeader, strconv.FormatInt(reset, 10))
+		}
+	}
+
+	if m.QuotaHeaders == StandardQuotaHeaders || m.QuotaHeaders == AllQuotaHeaders {
+		value := fmt.Sprintf("limit=%s, remaining=%s", limit, remaining)
+		if hasReset {
+			value += fmt.Sprintf(", reset=%d", reset)
+		}
+		w.Header().Set(RateLimitHeader, value)
+
+		policy := limit
+		if result.Window > 0 {
+			policy += fmt.Sprintf(";w=%d", ceilSeconds(result.Window))
+		}
+		w.Header().Set(RateLimitPolicyHeader, policy)
+	}
+}
+
+// quotaReset retorna os segundos, arredondados para cima, até que a cota seja restabelecida:
+// o tempo até uma nova requisição ser aceita nas rejeições e o fim da janela nas demais
+func (m *RateLimiterMiddleware) quotaReset(result ratelimiter.Result) (int64, bool) {
+	if !result.Allowed && result.RetryAfter > 0 {
+		return ceilSeconds(result.RetryAfter), true
+	}
+
+	if result.ResetAt.IsZero() {
+		return 0, false
+	}
+
+	return ceilSeconds(max(result.ResetAt.Sub(m.rateLimiter.Clock().Now()), time.Duration(0))), true
+}
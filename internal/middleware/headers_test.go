@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter/ratelimitertest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterMiddleware_QuotaHeaders(t *testing.T) {
+	ipConfig := ratelimiter.Config{Requests: 10, Window: 10 * time.Second, BlockTime: time.Minute}
+
+	tests := []struct {
+		name     string
+		headers  QuotaHeaders
+		legacy   bool
+		standard bool
+	}{
+		{name: "nenhum", headers: NoQuotaHeaders},
+		{name: "X-RateLimit", headers: LegacyQuotaHeaders, legacy: true},
+		{name: "draft IETF", headers: StandardQuotaHeaders, standard: true},
+		{name: "todos", headers: AllQuotaHeaders, legacy: true, standard: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rateLimiter, _, fakeClock := ratelimitertest.New(t, ipConfig)
+			handler := NewRateLimiterMiddleware(rateLimiter, WithQuotaHeaders(tt.headers)).Handler(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				}))
+
+			send := func() *httptest.ResponseRecorder {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.RemoteAddr = "192.168.1.1:12345"
+
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, req)
+				return recorder
+			}
+
+			for i := 0; i < 7; i++ {
+				send()
+			}
+
+			// Cinco segundos depois, a oitava requisição deixa 2 disponíveis até o fim da janela
+			fakeClock.Advance(5 * time.Second)
+			recorder := send()
+			assert.Equal(t, http.StatusOK, recorder.Code)
+
+			if tt.legacy {
+				assert.Equal(t, "10", recorder.Header().Get(LimitHeader))
+				assert.Equal(t, "2", recorder.Header().Get(RemainingHeader))
+				assert.Equal(t, "5", recorder.Header().Get(ResetHeader))
+			} else {
+				assert.Empty(t, recorder.Header().Get(LimitHeader))
+			}
+
+			if tt.standard {
+				assert.Equal(t, "limit=10, remaining=2, reset=5", recorder.Header().Get(RateLimitHeader))
+				assert.Equal(t, "10;w=10", recorder.Header().Get(RateLimitPolicyHeader))
+			} else {
+				assert.Empty(t, recorder.Header().Get(RateLimitHeader))
+				assert.Empty(t, recorder.Header().Get(RateLimitPolicyHeader))
+			}
+		})
+	}
+}
+
+func TestRateLimiterMiddleware_QuotaHeadersOnRejection(t *testing.T) {
+	ipConfig := ratelimiter.Config{Requests: 1, Window: time.Second, BlockTime: time.Minute}
+	rateLimiter, _, fakeClock := ratelimitertest.New(t, ipConfig)
+
+	handler := NewRateLimiterMiddleware(rateLimiter, WithQuotaHeaders(AllQuotaHeaders)).Handler(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := send()
+	assert.Equal(t, "limit=1, remaining=0, reset=1", recorder.Header().Get(RateLimitHeader))
+
+	// Durante o bloqueio, reset acompanha o Retry-After
+	recorder = send()
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "limit=1, remaining=0, reset=60", recorder.Header().Get(RateLimitHeader))
+	assert.Equal(t, "1;w=1", recorder.Header().Get(RateLimitPolicyHeader))
+	assert.Equal(t, "60", recorder.Header().Get(ResetHeader))
+
+	fakeClock.Advance(15 * time.Second)
+	recorder = send()
+	assert.Equal(t, "limit=1, remaining=0, reset=45", recorder.Header().Get(RateLimitHeader))
+	assert.Equal(t, recorder.Header().Get("Retry-After"), recorder.Header().Get(ResetHeader))
+}
+
+func TestRateLimiterMiddleware_QuotaHeadersReportTier(t *testing.T) {
+	ipConfig := ratelimiter.Config{
+		Requests: 10,
+		Window:   time.Second,
+		Tiers:    []ratelimiter.Config{{Requests: 3, Window: time.Hour}},
+	}
+	rateLimiter, _, _ := ratelimitertest.New(t, ipConfig)
+
+	handler := NewRateLimiterMiddleware(rateLimiter, WithQuotaHeaders(StandardQuotaHeaders)).Handler(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	// O limite mais próximo de ser atingido é o informado, com a sua própria janela; sem o fim
+	// da janela conhecido, reset é omitido
+	assert.Equal(t, "limit=3, remaining=2", recorder.Header().Get(RateLimitHeader))
+	assert.Equal(t, "3;w=3600", recorder.Header().Get(RateLimitPolicyHeader))
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+)
+
+// hostKeyNamespace é o namespace das identidades limitadas por host de destino
+const hostKeyNamespace = "host"
+
+// HostLimit associa uma configuração de limite a um host de destino
+type HostLimit struct {
+	// Host é o nome do host (ex: "api.example.com"), comparado com o header Host sem a porta
+	// e sem diferenciar maiúsculas
+	Host string
+
+	// Config é o limite aplicado, por IP, às requisições sem token destinadas ao host
+	Config ratelimiter.Config
+}
+
+// hostLimiters são os limites por host, indexados pelo host normalizado
+type hostLimiters map[string]HostLimit
+
+// newHostLimiters indexa os limites pelo host normalizado, descartando hosts vazios e
+// repetidos (vale a primeira ocorrência)
+func newHostLimiters(limits []HostLimit) hostLimiters {
+	limiters := make(hostLimiters, len(limits))
+	for _, limit := range limits {
+		host := normalizeHost(limit.Host)
+		if _, ok := limiters[host]; ok || host == "" {
+			continue
+		}
+		limiters[host] = limit
+	}
+	return limiters
+}
+
+// normalizeHost remove a porta, os colchetes de endereços IPv6 e o ponto final do host e o
+// converte para minúsculas, para que variações do mesmo host compartilhem o orçamento
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	return strings.ToLower(host)
+}
+
+// hostIdentity resolve a identidade da requisição no orçamento do host: o token, com a sua
+// própria configuração, ou o IP, com a configuração do host em HostLimits ou, sem ela, a de
+// IP. Com CheckOrder IPOnly o token é ignorado; tokens desconhecidos retornam
+// ratelimiter.ErrUnknownToken com a política Reject e usam o IP nos demais casos.
+func (m *RateLimiterMiddleware) hostIdentity(ctx context.Context, limits hostLimiters, host, ip, apiKey string) (string, ratelimiter.Config, error) {
+	names := m.rateLimiter.KeyNames()
+	prefix := hostKeyNamespace + names.Separator + host + names.Separator
+
+	if apiKey != "" && m.CheckOrder != IPOnly {
+		config, exists, err := m.rateLimiter.TokenConfig(ctx, apiKey)
+		if err != nil {
+			return "", ratelimiter.Config{}, err
+		}
+		if exists {
+			return prefix + names.Token + names.Separator + apiKey, config, nil
+		}
+		if m.rateLimiter.UnknownTokenPolicy() == ratelimiter.Reject {
+			return "", ratelimiter.Config{}, ratelimiter.ErrUnknownToken
+		}
+	}
+
+	config := m.rateLimiter.IPConfig()
+	if limit, ok := limits[host]; ok {
+		config = limit.Config
+	}
+	return prefix + names.IP + names.Separator + ip, config, nil
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter/ratelimitertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiterMiddleware_PerHost(t *testing.T) {
+	rateLimiter, _, fakeClock := ratelimitertest.New(t, ratelimiter.Config{Requests: 2, Window: time.Second, BlockTime: time.Minute})
+
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithPerHost(true))
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(host string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = host
+		req.RemoteAddr = "192.168.1.1:12345"
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// O mesmo IP tem orçamentos independentes em cada host
+	assert.Equal(t, http.StatusOK, send("a.example.com").Code)
+	assert.Equal(t, http.StatusOK, send("a.example.com").Code)
+
+	recorder := send("a.example.com")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, ScopeHost, recorder.Header().Get(ScopeHeader))
+
+	assert.Equal(t, http.StatusOK, send("b.example.com").Code)
+	assert.Equal(t, http.StatusOK, send("b.example.com").Code)
+
+	// A porta e as maiúsculas não criam um host diferente
+	assert.Equal(t, http.StatusTooManyRequests, send("A.Example.com:8080").Code)
+	assert.Equal(t, http.StatusTooManyRequests, send("b.example.com").Code)
+
+	fakeClock.Advance(time.Minute)
+	assert.Equal(t, http.StatusOK, send("a.example.com").Code)
+}
+
+func TestRateLimiterMiddleware_HostLimits(t *testing.T) {
+	rateLimiter, _, _ := ratelimitertest.New(t, ratelimiter.Config{Requests: 1, Window: time.Second})
+	rateLimiter.AddTokenConfig("abc123", ratelimiter.Config{Requests: 2, Window: time.Second})
+
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithPerHost(true), WithHostLimits(
+		HostLimit{Host: "API.example.com", Config: ratelimiter.Config{Requests: 3, Window: time.Second}},
+	))
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(host, apiKey string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = host
+		req.RemoteAddr = "192.168.1.1:12345"
+		if apiKey != "" {
+			req.Header.Set("API_KEY", apiKey)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// O host com limite próprio aplica a sua configuração ao IP
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, send("api.example.com", ""), "requisição %d", i+1)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, send("api.example.com", ""))
+
+	// Hosts sem entrada seguem a configuração de IP
+	assert.Equal(t, http.StatusOK, send("www.example.com", ""))
+	assert.Equal(t, http.StatusTooManyRequests, send("www.example.com", ""))
+
+	// O token mantém a sua configuração, com um orçamento separado em cada host
+	for _, host := range []string{"api.example.com", "www.example.com"} {
+		assert.Equal(t, http.StatusOK, send(host, "abc123"), host)
+		assert.Equal(t, http.StatusOK, send(host, "abc123"), host)
+		assert.Equal(t, http.StatusTooManyRequests, send(host, "abc123"), host)
+	}
+
+	// Tokens desconhecidos usam o orçamento do IP no host
+	assert.Equal(t, http.StatusTooManyRequests, send("www.example.com", "unknown"))
+}
+
+func TestResetForRequest_PerHost(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{Requests: 5, Window: time.Minute})
+
+	handler := Wrap(rateLimiter, loginHandler(t), WithPerHost(true))
+
+	login := func(host, password string) {
+		req := httptest.NewRequest("POST", "/login", nil)
+		req.Host = host
+		req.RemoteAddr = "192.168.1.1:12345"
+		req.Header.Set("X-Password", password)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	login("a.example.com", "wrong")
+	login("b.example.com", "wrong")
+	login("a.example.com", "secret")
+
+	// Apenas o orçamento do host em que o login teve sucesso é zerado
+	ctx := context.Background()
+	result, err := rateLimiter.Peek(ctx, "custom:host:a.example.com:ip:192.168.1.1")
+	require.NoError(t, err)
+	assert.Zero(t, result.Count)
+
+	result, err = rateLimiter.Peek(ctx, "custom:host:b.example.com:ip:192.168.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Count)
+}
+
+func TestNormalizeHost(t *testing.T) {
+	assert.Equal(t, "api.example.com", normalizeHost("API.Example.com:8080"))
+	assert.Equal(t, "api.example.com", normalizeHost("api.example.com."))
+	assert.Equal(t, "::1", normalizeHost("[::1]:8080"))
+	assert.Equal(t, "::1", normalizeHost("[::1]"))
+	assert.Equal(t, "", normalizeHost(""))
+}
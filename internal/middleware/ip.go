@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DefaultIPv6PrefixLen é o tamanho de prefixo padrão usado para agrupar endereços IPv6.
+// Um /64 corresponde normalmente a uma única sub-rede de cliente.
+const DefaultIPv6PrefixLen = 64
+
+// DefaultMaxForwardedHops é o número padrão de entradas aceitas no header X-Forwarded-For
+const DefaultMaxForwardedHops = 10
+
+// ClientIPStrategy define de onde o IP do cliente é obtido quando há headers de proxy
+type ClientIPStrategy int
+
+const (
+	// XForwardedForFirst usa o X-Forwarded-For e, se ausente ou inválido, o X-Real-IP antes
+	// do endereço da conexão (padrão)
+	XForwardedForFirst ClientIPStrategy = iota
+
+	// RealIPFirst usa o X-Real-IP e, se ausente ou inválido, o X-Forwarded-For antes do
+	// endereço da conexão, para balanceadores confiáveis que definem o X-Real-IP
+	RealIPFirst
+
+	// RemoteAddrOnly ignora os headers de proxy e usa apenas o endereço da conexão, para
+	// servidores expostos diretamente aos clientes
+	RemoteAddrOnly
+)
+
+// forwardedFor extrai o IP do cliente (a primeira entrada) do header X-Forwarded-For. Cadeias
+// com mais entradas que MaxForwardedHops são descartadas sem serem percorridas, e uma primeira
+// entrada que não é um IP válido invalida o header.
+func (m *RateLimiterMiddleware) forwardedFor(header string) (string, bool) {
+	if header == "" {
+		return "", false
+	}
+
+	maxHops := m.MaxForwardedHops
+	if maxHops <= 0 {
+		maxHops = DefaultMaxForwardedHops
+	}
+
+	if strings.Count(header, ",") >= maxHops {
+		return "", false
+	}
+
+	first, _, _ := strings.Cut(header, ",")
+	return parseHostIP(first)
+}
+
+// parseHostIP valida um endereço IP informado em header, aceitando porta (ex:
+// "203.0.113.1:8080" ou "[2001:db8::1]:443") e zona IPv6
+func parseHostIP(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", false
+	}
+
+	host := value
+	if h, _, err := net.SplitHostPort(value); err == nil {
+		host = h
+	}
+
+	addr := host
+	if i := strings.IndexByte(addr, '%'); i >= 0 {
+		addr = addr[:i]
+	}
+	if net.ParseIP(addr) == nil {
+		return "", false
+	}
+
+	return host, true
+}
+
+// normalizeIP converte o IP do cliente na forma canônica usada na chave de limitação.
+// Endereços são agrupados pelo prefixo configurado para a família, de modo que clientes que
+// rotacionam endereços dentro da mesma rede compartilhem o mesmo limite.
+func (m *RateLimiterMiddleware) normalizeIP(rawIP string) string {
+	// Remove a zona de endereços link-local (ex: fe80::1%eth0)
+	host := rawIP
+	if i := strings.IndexByte(host, '%'); i >= 0 {
+		host = host[:i]
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return rawIP
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		prefixLen := m.IPv4PrefixLen
+		if prefixLen <= 0 || prefixLen > 32 {
+			prefixLen = 32
+		}
+
+		return maskIP(ip4, prefixLen)
+	}
+
+	prefixLen := m.IPv6PrefixLen
+	if prefixLen <= 0 || prefixLen > 128 {
+		prefixLen = DefaultIPv6PrefixLen
+	}
+
+	return maskIP(ip, prefixLen)
+}
+
+// maskIP aplica a máscara de prefixo ao IP e retorna a rede em notação CIDR.
+// Com o prefixo completo o próprio endereço é retornado.
+func maskIP(ip net.IP, prefixLen int) string {
+	bits := 8 * len(ip)
+	if prefixLen >= bits {
+		return ip.String()
+	}
+
+	network := ip.Mask(net.CIDRMask(prefixLen, bits))
+	return network.String() + "/" + strconv.Itoa(prefixLen)
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterMiddleware_NormalizeIP(t *testing.T) {
+	tests := []struct {
+		name          string
+		prefixLen     int
+		ipv4PrefixLen int
+		rawIP         string
+		expectedIP    string
+	}{
+		{
+			name:       "IPv4 inalterado",
+			rawIP:      "192.168.1.1",
+			expectedIP: "192.168.1.1",
+		},
+		{
+			name:       "IPv4 mapeado em IPv6",
+			rawIP:      "::ffff:192.168.1.1",
+			expectedIP: "192.168.1.1",
+		},
+		{
+			name:          "IPv4 agrupado pelo prefixo configurado",
+			ipv4PrefixLen: 24,
+			rawIP:         "192.168.1.77",
+			expectedIP:    "192.168.1.0/24",
+		},
+		{
+			name:          "IPv4 mapeado em IPv6 agrupado pelo prefixo configurado",
+			ipv4PrefixLen: 16,
+			rawIP:         "::ffff:192.168.1.77",
+			expectedIP:    "192.168.0.0/16",
+		},
+		{
+			name:          "IPv4 sem agrupamento",
+			ipv4PrefixLen: 32,
+			rawIP:         "192.168.1.77",
+			expectedIP:    "192.168.1.77",
+		},
+		{
+			name:          "IPv4 com prefixo inválido não é agrupado",
+			ipv4PrefixLen: 33,
+			rawIP:         "192.168.1.77",
+			expectedIP:    "192.168.1.77",
+		},
+		{
+			name:       "IPv6 agrupado pelo prefixo padrão",
+			rawIP:      "2001:db8:abcd:12:1:2:3:4",
+			expectedIP: "2001:db8:abcd:12::/64",
+		},
+		{
+			name:       "IPv6 em forma não canônica",
+			rawIP:      "2001:0DB8:ABCD:0012:0000:0000:0000:0001",
+			expectedIP: "2001:db8:abcd:12::/64",
+		},
+		{
+			name:       "IPv6 com prefixo configurado",
+			prefixLen:  48,
+			rawIP:      "2001:db8:abcd:12:1:2:3:4",
+			expectedIP: "2001:db8:abcd::/48",
+		},
+		{
+			name:       "IPv6 sem agrupamento",
+			prefixLen:  128,
+			rawIP:      "2001:0db8::0001",
+			expectedIP: "2001:db8::1",
+		},
+		{
+			name:       "IPv6 link-local com zona",
+			rawIP:      "fe80::1%eth0",
+			expectedIP: "fe80::/64",
+		},
+		{
+			name:       "Valor inválido preservado",
+			rawIP:      "not-an-ip",
+			expectedIP: "not-an-ip",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middleware := &RateLimiterMiddleware{IPv6PrefixLen: tt.prefixLen, IPv4PrefixLen: tt.ipv4PrefixLen}
+			assert.Equal(t, tt.expectedIP, middleware.normalizeIP(tt.rawIP))
+		})
+	}
+}
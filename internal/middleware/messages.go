@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLimitMessage é a mensagem do corpo de limite excedido quando nenhuma mensagem
+// localizada se aplica
+const DefaultLimitMessage = "you have reached the maximum number of requests or actions allowed within a certain time frame"
+
+// maxAcceptLanguageTags limita quantos idiomas do Accept-Language são considerados
+const maxAcceptLanguageTags = 20
+
+// limitMessage seleciona a mensagem de limite excedido para os idiomas aceitos pela requisição,
+// retornando também o idioma escolhido ("" quando a mensagem padrão é usada). Cada idioma é
+// procurado primeiro pela tag completa (ex: "pt-BR") e depois pelo idioma base ("pt").
+func (m *RateLimiterMiddleware) limitMessage(r *http.Request) (string, string) {
+	defaultMessage := DefaultLimitMessage
+	if message, ok := m.Messages[""]; ok {
+		defaultMessage = message
+	}
+
+	if len(m.Messages) == 0 {
+		return defaultMessage, ""
+	}
+
+	for _, tag := range acceptedLanguages(r.Header.Get("Accept-Language")) {
+		if lang, message, ok := m.lookupMessage(tag); ok {
+			return message, lang
+		}
+
+		if base, _, found := strings.Cut(tag, "-"); found {
+			if lang, message, ok := m.lookupMessage(base); ok {
+				return message, lang
+			}
+		}
+	}
+
+	return defaultMessage, ""
+}
+
+// lookupMessage procura a mensagem do idioma ignorando maiúsculas e minúsculas
+func (m *RateLimiterMiddleware) lookupMessage(tag string) (string, string, bool) {
+	if message, ok := m.Messages[tag]; ok {
+		return tag, message, true
+	}
+
+	for lang, message := range m.Messages {
+		if lang != "" && strings.EqualFold(lang, tag) {
+			return lang, message, true
+		}
+	}
+
+	return "", "", false
+}
+
+// acceptedLanguages extrai as tags do header Accept-Language em ordem de preferência (valor q
+// decrescente), descartando o curinga "*", tags com q=0 e entradas malformadas
+func acceptedLanguages(header string) []string {
+	type weightedTag struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weightedTag
+	for _, part := range strings.SplitN(header, ",", maxAcceptLanguageTags+1) {
+		if len(tags) == maxAcceptLanguageTags {
+			break
+		}
+
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if q <= 0 {
+			continue
+		}
+
+		tags = append(tags, weightedTag{tag: tag, q: q})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	result := make([]string, len(tags))
+	for i, t := range tags {
+		result[i] = t.tag
+	}
+
+	return result
+}
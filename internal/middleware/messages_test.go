@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter/ratelimitertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptedLanguages(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected []string
+	}{
+		{name: "vazio", header: "", expected: []string{}},
+		{name: "único idioma", header: "pt-BR", expected: []string{"pt-BR"}},
+		{name: "ordenado por q", header: "en;q=0.5, pt-BR, es;q=0.8", expected: []string{"pt-BR", "es", "en"}},
+		{name: "empate mantém a ordem", header: "fr, de", expected: []string{"fr", "de"}},
+		{name: "descarta curinga e q=0", header: "*, en;q=0, pt", expected: []string{"pt"}},
+		{name: "descarta entradas malformadas", header: "pt;q=abc, , en", expected: []string{"en"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, acceptedLanguages(tt.header))
+		})
+	}
+}
+
+func TestRateLimiterMiddleware_LimitMessage(t *testing.T) {
+	middleware := &RateLimiterMiddleware{
+		Messages: map[string]string{
+			"pt-BR": "você atingiu o limite de requisições",
+			"en":    "rate limit reached",
+			"es":    "límite alcanzado",
+		},
+	}
+
+	tests := []struct {
+		name            string
+		acceptLanguage  string
+		expectedMessage string
+		expectedLang    string
+	}{
+		{name: "tag completa", acceptLanguage: "pt-BR", expectedMessage: "você atingiu o limite de requisições", expectedLang: "pt-BR"},
+		{name: "ignora maiúsculas", acceptLanguage: "PT-br", expectedMessage: "você atingiu o limite de requisições", expectedLang: "pt-BR"},
+		{name: "idioma base", acceptLanguage: "en-US", expectedMessage: "rate limit reached", expectedLang: "en"},
+		{name: "preferência por q", acceptLanguage: "en;q=0.3, es", expectedMessage: "límite alcanzado", expectedLang: "es"},
+		{name: "idioma indisponível", acceptLanguage: "fr", expectedMessage: DefaultLimitMessage},
+		{name: "sem Accept-Language", expectedMessage: DefaultLimitMessage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+
+			message, lang := middleware.limitMessage(req)
+			assert.Equal(t, tt.expectedMessage, message)
+			assert.Equal(t, tt.expectedLang, lang)
+		})
+	}
+
+	// A chave "" substitui a mensagem padrão
+	middleware.Messages[""] = "limit reached"
+	message, lang := middleware.limitMessage(httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "limit reached", message)
+	assert.Empty(t, lang)
+}
+
+func TestRateLimiterMiddleware_LocalizedResponse(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+		Requests:  1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	})
+
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithMessages(map[string]string{
+		"pt-BR": "você atingiu o limite de requisições",
+		"en":    "rate limit reached",
+	}))
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		acceptLanguage  string
+		expectedMessage string
+	}{
+		{acceptLanguage: "pt-BR", expectedMessage: "você atingiu o limite de requisições"},
+		{acceptLanguage: "en", expectedMessage: "rate limit reached"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptLanguage, func(t *testing.T) {
+			var recorder *httptest.ResponseRecorder
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.RemoteAddr = "192.168.1.1:12345"
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+				recorder = httptest.NewRecorder()
+				handler.ServeHTTP(recorder, req)
+			}
+
+			assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			assert.Equal(t, tt.acceptLanguage, recorder.Header().Get("Content-Language"))
+			assert.Equal(t, "Accept-Language", recorder.Header().Get("Vary"))
+
+			// A estrutura do corpo é a mesma em todos os idiomas
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+			assert.Equal(t, tt.expectedMessage, body["error"])
+			assert.Equal(t, ScopeIP, body["scope"])
+			assert.Contains(t, body, "retry_after_seconds")
+		})
+	}
+}
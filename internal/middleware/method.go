@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+)
+
+// methodKeyNamespace é o namespace das identidades limitadas por método
+const methodKeyNamespace = "method"
+
+// MethodLimit associa uma configuração de limite a um grupo de métodos HTTP
+type MethodLimit struct {
+	// Methods são os métodos (ex: "POST", "PUT") que compartilham o orçamento, sem
+	// diferenciar maiúsculas
+	Methods []string
+
+	// Config é o limite aplicado, por IP, às requisições com esses métodos
+	Config ratelimiter.Config
+}
+
+// methodLimiter é um MethodLimit pré-processado para consulta por requisição
+type methodLimiter struct {
+	methods map[string]struct{}
+	group   string
+	config  ratelimiter.Config
+}
+
+// methodLimiters são os limites por método na ordem de configuração
+type methodLimiters []methodLimiter
+
+// newMethodLimiters normaliza os métodos de cada limite e calcula o nome do grupo usado na
+// chave, de modo que a ordem em que os métodos são informados não altere o orçamento
+func newMethodLimiters(limits []MethodLimit) methodLimiters {
+	limiters := make(methodLimiters, 0, len(limits))
+	for _, limit := range limits {
+		methods := make(map[string]struct{}, len(limit.Methods))
+		names := make([]string, 0, len(limit.Methods))
+		for _, method := range limit.Methods {
+			method = strings.ToUpper(strings.TrimSpace(method))
+			if _, ok := methods[method]; ok || method == "" {
+				continue
+			}
+			methods[method] = struct{}{}
+			names = append(names, method)
+		}
+		if len(names) == 0 {
+			continue
+		}
+		sort.Strings(names)
+
+		limiters = append(limiters, methodLimiter{
+			methods: methods,
+			group:   strings.Join(names, ","),
+			config:  limit.Config,
+		})
+	}
+	return limiters
+}
+
+// match retorna o primeiro limite configurado para o método
+func (l methodLimiters) match(method string) (methodLimiter, bool) {
+	for _, limiter := range l {
+		if _, ok := limiter.methods[strings.ToUpper(method)]; ok {
+			return limiter, true
+		}
+	}
+	return methodLimiter{}, false
+}
+
+// key retorna a identidade do IP no orçamento do grupo de métodos, com as partes unidas pelo
+// separador das chaves do rate limiter (ver ratelimiter.KeyNames)
+func (l methodLimiter) key(ip, separator string) string {
+	return methodKeyNamespace + separator + l.group + separator + ip
+}
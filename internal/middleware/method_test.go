@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter/ratelimitertest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterMiddleware_MethodLimits(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+		Requests:  5,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	})
+
+	middleware := NewRateLimiterMiddleware(rateLimiter)
+	middleware.MethodLimits = []MethodLimit{
+		{
+			Methods: []string{"post", "PUT", "DELETE"},
+			Config:  ratelimiter.Config{Requests: 2, Window: time.Second, BlockTime: time.Minute},
+		},
+	}
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(method, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/orders", nil)
+		req.RemoteAddr = remoteAddr
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// POST e PUT compartilham o orçamento restrito
+	assert.Equal(t, http.StatusOK, send("POST", "192.168.1.1:12345").Code)
+	assert.Equal(t, http.StatusOK, send("PUT", "192.168.1.1:12345").Code)
+
+	recorder := send("POST", "192.168.1.1:12345")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, ScopeMethod, recorder.Header().Get(ScopeHeader))
+
+	// GET no mesmo caminho mantém o orçamento padrão, intacto
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, send("GET", "192.168.1.1:12345").Code)
+	}
+	recorder = send("GET", "192.168.1.1:12345")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, ScopeIP, recorder.Header().Get(ScopeHeader))
+
+	// O orçamento por método é individual para cada IP
+	assert.Equal(t, http.StatusOK, send("DELETE", "192.168.1.2:12345").Code)
+}
+
+func TestRateLimiterMiddleware_MethodLimitsKeyFuncPrecedence(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+		Requests:  5,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	})
+
+	middleware := NewRateLimiterMiddleware(rateLimiter)
+	middleware.MethodLimits = []MethodLimit{
+		{Methods: []string{"POST"}, Config: ratelimiter.Config{Requests: 1, Window: time.Second, BlockTime: time.Minute}},
+	}
+
+	// Limite por rota definido no KeyFunc
+	middleware.KeyFunc = func(r *http.Request) (string, ratelimiter.Config, bool) {
+		if r.URL.Path != "/upload" {
+			return "", ratelimiter.Config{}, false
+		}
+		return "upload", ratelimiter.Config{Requests: 3, Window: time.Second, BlockTime: time.Minute}, true
+	}
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, send("/upload").Code)
+	}
+	assert.Equal(t, ScopeCustom, send("/upload").Header().Get(ScopeHeader))
+
+	assert.Equal(t, http.StatusOK, send("/orders").Code)
+	assert.Equal(t, ScopeMethod, send("/orders").Header().Get(ScopeHeader))
+}
+
+func TestNewMethodLimiters(t *testing.T) {
+	config := ratelimiter.Config{Requests: 1, Window: time.Second}
+	limiters := newMethodLimiters([]MethodLimit{
+		{Methods: []string{"put", " POST ", "PUT"}, Config: config},
+		{Methods: []string{""}, Config: config},
+		{Methods: []string{"POST", "PATCH"}, Config: config},
+	})
+
+	// Entradas sem métodos válidos são descartadas
+	assert.Len(t, limiters, 2)
+
+	limiter, ok := limiters.match("POST")
+	assert.True(t, ok)
+	assert.Equal(t, "method:POST,PUT:192.168.1.1", limiter.key("192.168.1.1", ":"))
+	assert.Equal(t, "method.POST,PUT.192.168.1.1", limiter.key("192.168.1.1", "."))
+
+	// A primeira entrada correspondente é usada
+	limiter, ok = limiters.match("patch")
+	assert.True(t, ok)
+	assert.Equal(t, "method:PATCH,POST:192.168.1.1", limiter.key("192.168.1.1", ":"))
+
+	_, ok = limiters.match("GET")
+	assert.False(t, ok)
+}
@@ -0,0 +1,307 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/metrics"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+)
+
+// Option configura o RateLimiterMiddleware em NewRateLimiterMiddleware. Cada opção equivale a
+// definir o campo exportado correspondente.
+type Option func(*RateLimiterMiddleware)
+
+// WithEnabled liga ou desliga a limitação (ver RateLimiterMiddleware.Enabled)
+func WithEnabled(enabled bool) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.Enabled = enabled
+	}
+}
+
+// WithShadowMode habilita o modo shadow (ver RateLimiterMiddleware.ShadowMode)
+func WithShadowMode(enabled bool) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.ShadowMode = enabled
+	}
+}
+
+// WithMetrics define os contadores do middleware (ver RateLimiterMiddleware.Metrics)
+func WithMetrics(metrics *metrics.Metrics) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.Metrics = metrics
+	}
+}
+
+// WithRouteLabel define o rótulo route das métricas (ver RateLimiterMiddleware.RouteLabel)
+func WithRouteLabel(routeLabel func(r *http.Request) string) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.RouteLabel = routeLabel
+	}
+}
+
+// WithSkip define o predicado de requisições isentas (ver RateLimiterMiddleware.Skip)
+func WithSkip(skip func(r *http.Request) bool) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.Skip = skip
+	}
+}
+
+// WithBypassSecret isenta as requisições que trazem secret no header informado (ver
+// RateLimiterMiddleware.BypassHeader)
+func WithBypassSecret(header, secret string) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.BypassHeader = header
+		m.BypassSecret = secret
+	}
+}
+
+// WithIdempotency habilita o replay de decisões por Idempotency-Key (ver
+// RateLimiterMiddleware.Idempotency)
+func WithIdempotency(enabled bool) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.Idempotency = enabled
+	}
+}
+
+// WithAPIKeyHeader define o header de onde o token é lido (ver
+// RateLimiterMiddleware.APIKeyHeader)
+func WithAPIKeyHeader(header string) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.APIKeyHeader = header
+	}
+}
+
+// WithCountStatuses conta apenas as requisições respondidas com os status informados (ver
+// RateLimiterMiddleware.CountStatuses)
+func WithCountStatuses(statuses ...int) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.CountStatuses = statuses
+	}
+}
+
+// WithStripAPIKeyHeader remove o header do token antes de repassar a requisição (ver
+// RateLimiterMiddleware.StripAPIKeyHeader)
+func WithStripAPIKeyHeader(strip bool) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.StripAPIKeyHeader = strip
+	}
+}
+
+// WithIPv6PrefixLen define o prefixo de agrupamento de endereços IPv6 (ver
+// RateLimiterMiddleware.IPv6PrefixLen)
+func WithIPv6PrefixLen(prefixLen int) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.IPv6PrefixLen = prefixLen
+	}
+}
+
+// WithIPv4PrefixLen define o prefixo de agrupamento de endereços IPv4 (ver
+// RateLimiterMiddleware.IPv4PrefixLen)
+func WithIPv4PrefixLen(prefixLen int) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.IPv4PrefixLen = prefixLen
+	}
+}
+
+// WithMaxForwardedHops define o número máximo de entradas aceitas no X-Forwarded-For (ver
+// RateLimiterMiddleware.MaxForwardedHops)
+func WithMaxForwardedHops(maxHops int) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.MaxForwardedHops = maxHops
+	}
+}
+
+// WithClientIPStrategy define de onde o IP do cliente é obtido (ver
+// RateLimiterMiddleware.ClientIPStrategy)
+func WithClientIPStrategy(strategy ClientIPStrategy) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.ClientIPStrategy = strategy
+	}
+}
+
+// WithKeyFunc define a identidade personalizada (ver RateLimiterMiddleware.KeyFunc)
+func WithKeyFunc(keyFunc func(r *http.Request) (string, ratelimiter.Config, bool)) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.KeyFunc = keyFunc
+	}
+}
+
+// WithRouteLimits define os limites por rota (ver RateLimiterMiddleware.RouteLimits)
+func WithRouteLimits(limits ...RouteLimit) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.RouteLimits = limits
+	}
+}
+
+// WithMethodLimits define os limites por método (ver RateLimiterMiddleware.MethodLimits)
+func WithMethodLimits(limits ...MethodLimit) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.MethodLimits = limits
+	}
+}
+
+// WithPerHost habilita os orçamentos por host de destino (ver RateLimiterMiddleware.PerHost)
+func WithPerHost(enabled bool) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.PerHost = enabled
+	}
+}
+
+// WithHostLimits define os limites por host (ver RateLimiterMiddleware.HostLimits)
+func WithHostLimits(limits ...HostLimit) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.HostLimits = limits
+	}
+}
+
+// WithIPTokenLimit habilita o limite por par de IP e token (ver
+// RateLimiterMiddleware.IPTokenLimit)
+func WithIPTokenLimit(enabled bool) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.IPTokenLimit = enabled
+	}
+}
+
+// WithGlobalLimit habilita o limite global de todas as requisições (ver
+// RateLimiterMiddleware.GlobalLimit)
+func WithGlobalLimit(enabled bool) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.GlobalLimit = enabled
+	}
+}
+
+// WithCheckOrder define como o token e o IP são combinados (ver
+// RateLimiterMiddleware.CheckOrder)
+func WithCheckOrder(order CheckOrder) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.CheckOrder = order
+	}
+}
+
+// WithAnonymousMode define como o limite anônimo se aplica às requisições sem token (ver
+// RateLimiterMiddleware.AnonymousMode)
+func WithAnonymousMode(mode AnonymousMode) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.AnonymousMode = mode
+	}
+}
+
+// WithFailureMode define o comportamento quando o armazenamento falha (ver
+// RateLimiterMiddleware.FailureMode)
+func WithFailureMode(mode FailureMode) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.FailureMode = mode
+	}
+}
+
+// WithOnDegraded define o hook chamado para requisições liberadas por falha do armazenamento
+// (ver RateLimiterMiddleware.OnDegraded)
+func WithOnDegraded(hook func(r *http.Request, scope string, err error)) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.OnDegraded = hook
+	}
+}
+
+// WithMaxWait define por quanto tempo as requisições acima do limite aguardam a próxima vaga
+// (ver RateLimiterMiddleware.MaxWait)
+func WithMaxWait(maxWait time.Duration) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.MaxWait = maxWait
+	}
+}
+
+// WithQuotaHeaders define quais headers de cota são enviados nas respostas (ver
+// RateLimiterMiddleware.QuotaHeaders)
+func WithQuotaHeaders(headers QuotaHeaders) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.QuotaHeaders = headers
+	}
+}
+
+// WithRejectStatusCode define o status das respostas acima do limite (ver
+// RateLimiterMiddleware.RejectStatusCode)
+func WithRejectStatusCode(code int) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.RejectStatusCode = code
+	}
+}
+
+// WithMessages define as mensagens de limite excedido por idioma (ver
+// RateLimiterMiddleware.Messages)
+func WithMessages(messages map[string]string) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.Messages = messages
+	}
+}
+
+// WithProblemJSON habilita as respostas de limite excedido no formato problem+json (ver
+// RateLimiterMiddleware.ProblemJSON)
+func WithProblemJSON(enabled bool) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.ProblemJSON = enabled
+	}
+}
+
+// WithProblemType define o type das respostas problem+json (ver
+// RateLimiterMiddleware.ProblemType)
+func WithProblemType(problemType string) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.ProblemType = problemType
+	}
+}
+
+// WithOnLimitExceeded define a resposta das requisições acima do limite (ver
+// RateLimiterMiddleware.OnLimitExceeded)
+func WithOnLimitExceeded(handler func(w http.ResponseWriter, r *http.Request, scope string, result ratelimiter.Result)) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.OnLimitExceeded = handler
+	}
+}
+
+// WithStorageRetryAfter define o Retry-After das respostas 503 por falha do armazenamento (ver
+// RateLimiterMiddleware.StorageRetryAfter)
+func WithStorageRetryAfter(retryAfter time.Duration) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.StorageRetryAfter = retryAfter
+	}
+}
+
+// WithCompositeKey limita por uma combinação de headers, definindo KeyFunc a partir de key
+// (ver CompositeKey)
+func WithCompositeKey(key CompositeKey) Option {
+	return func(m *RateLimiterMiddleware) {
+		if key.Separator == "" {
+			key.Separator = m.rateLimiter.KeyNames().Separator
+		}
+		m.KeyFunc = key.KeyFunc()
+	}
+}
+
+// WithMaxBodyBytes define o tamanho máximo dos corpos aceitos (ver
+// RateLimiterMiddleware.MaxBodyBytes)
+func WithMaxBodyBytes(maxBytes int64) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.MaxBodyBytes = maxBytes
+	}
+}
+
+// WithSessionKey limita por sessão, definindo KeyFunc a partir de key (ver SessionKey)
+func WithSessionKey(key SessionKey) Option {
+	return func(m *RateLimiterMiddleware) {
+		if key.Separator == "" {
+			key.Separator = m.rateLimiter.KeyNames().Separator
+		}
+		m.KeyFunc = key.KeyFunc()
+	}
+}
+
+// WithBandwidth liga o modo Bandwidth, em que cada requisição consome o tamanho do corpo em
+// bytes, com maxStreamedBytes como limite de leitura dos corpos sem Content-Length (ver
+// RateLimiterMiddleware.Bandwidth e RateLimiterMiddleware.MaxStreamedBytes)
+func WithBandwidth(maxStreamedBytes int64) Option {
+	return func(m *RateLimiterMiddleware) {
+		m.Bandwidth = true
+		m.MaxStreamedBytes = maxStreamedBytes
+	}
+}
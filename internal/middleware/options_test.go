@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter/ratelimitertest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRateLimiterMiddleware_Options(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{Requests: 1, Window: time.Second})
+	skip := SkipPaths("/health")
+
+	middleware := NewRateLimiterMiddleware(rateLimiter,
+		WithAPIKeyHeader("Authorization"),
+		WithSkip(skip),
+		WithBypassSecret("X-Internal-Secret", "s3cr3t"),
+		WithAnonymousMode(AnonymousWithIP),
+		WithPerHost(true),
+		WithHostLimits(HostLimit{Host: "api.example.com", Config: ratelimiter.Config{Requests: 5, Window: time.Second}}),
+		WithProblemJSON(true),
+		WithProblemType("https://example.com/problems/rate-limit"),
+		WithFailureMode(FailOpen),
+		WithOnDegraded(func(r *http.Request, scope string, err error) {}),
+		WithRejectStatusCode(http.StatusServiceUnavailable),
+		WithStorageRetryAfter(time.Second),
+		WithMaxWait(500*time.Millisecond),
+		WithMaxBodyBytes(1024),
+		WithQuotaHeaders(StandardQuotaHeaders),
+		WithIPv4PrefixLen(24),
+		WithMaxForwardedHops(3),
+		WithClientIPStrategy(RealIPFirst),
+		WithMessages(map[string]string{"pt-BR": "limite atingido"}),
+	)
+
+	assert.True(t, middleware.Enabled)
+	assert.Equal(t, "Authorization", middleware.APIKeyHeader)
+	assert.NotNil(t, middleware.Skip)
+	assert.Equal(t, "X-Internal-Secret", middleware.BypassHeader)
+	assert.Equal(t, "s3cr3t", middleware.BypassSecret)
+	assert.Equal(t, AnonymousWithIP, middleware.AnonymousMode)
+	assert.True(t, middleware.PerHost)
+	assert.Len(t, middleware.HostLimits, 1)
+	assert.True(t, middleware.ProblemJSON)
+	assert.Equal(t, "https://example.com/problems/rate-limit", middleware.ProblemType)
+	assert.Equal(t, FailOpen, middleware.FailureMode)
+	assert.NotNil(t, middleware.OnDegraded)
+	assert.Equal(t, http.StatusServiceUnavailable, middleware.RejectStatusCode)
+	assert.Equal(t, time.Second, middleware.StorageRetryAfter)
+	assert.Equal(t, 500*time.Millisecond, middleware.MaxWait)
+	assert.Equal(t, int64(1024), middleware.MaxBodyBytes)
+	assert.Equal(t, StandardQuotaHeaders, middleware.QuotaHeaders)
+	assert.Equal(t, 24, middleware.IPv4PrefixLen)
+	assert.Equal(t, 3, middleware.MaxForwardedHops)
+	assert.Equal(t, RealIPFirst, middleware.ClientIPStrategy)
+	assert.Equal(t, "limite atingido", middleware.Messages["pt-BR"])
+
+	// A forma sem opções mantém os padrões
+	middleware = NewRateLimiterMiddleware(rateLimiter)
+	assert.True(t, middleware.Enabled)
+	assert.Empty(t, middleware.APIKeyHeader)
+	assert.Equal(t, FailClosed, middleware.FailureMode)
+}
+
+func TestNewRateLimiterMiddleware_WithOnLimitExceeded(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+		Requests:  1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	})
+
+	var gotScope string
+	var gotResult ratelimiter.Result
+	middleware := NewRateLimiterMiddleware(rateLimiter,
+		WithAPIKeyHeader("X-API-Key"),
+		WithOnLimitExceeded(func(w http.ResponseWriter, r *http.Request, scope string, result ratelimiter.Result) {
+			gotScope = scope
+			gotResult = result
+			http.Error(w, "slow down", http.StatusTeapot)
+		}),
+	)
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	var recorder *httptest.ResponseRecorder
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+	}
+
+	assert.Equal(t, http.StatusTeapot, recorder.Code)
+	assert.Equal(t, "slow down\n", recorder.Body.String())
+	assert.Empty(t, recorder.Header().Get(ScopeHeader))
+	assert.Equal(t, ScopeIP, gotScope)
+	assert.False(t, gotResult.Allowed)
+	assert.Equal(t, time.Minute, gotResult.RetryAfter)
+}
+
+func TestNewRateLimiterMiddleware_WithEnabled(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(unusedStorage{}, ratelimiter.Config{Requests: 1, Window: time.Second})
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithEnabled(false))
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+)
+
+// ProblemContentType é o Content-Type das respostas no formato "Problem Details for HTTP
+// APIs" (RFC 7807)
+const ProblemContentType = "application/problem+json"
+
+// DefaultProblemType é o type das respostas problem+json quando ProblemType não é definido.
+// "about:blank" indica que o problema é descrito apenas pelo status HTTP.
+const DefaultProblemType = "about:blank"
+
+// problemResponse é o corpo problem+json das respostas de limite excedido. Além dos membros da
+// RFC 7807, traz o escopo e o tempo de espera como membros de extensão, com os mesmos nomes
+// da resposta JSON padrão.
+type problemResponse struct {
+	Type              string `json:"type"`
+	Title             string `json:"title"`
+	Status            int    `json:"status"`
+	Detail            string `json:"detail"`
+	Scope             string `json:"scope"`
+	RetryAfterSeconds int64  `json:"retry_after_seconds,omitempty"`
+}
+
+// writeRateLimitedProblem responde como writeRateLimited, mas com o corpo no formato
+// problem+json. O detail acrescenta à mensagem o tempo até que uma nova requisição seja
+// aceita, quando conhecido.
+func writeRateLimitedProblem(w http.ResponseWriter, problemType string, statusCode int, scope, message string, result ratelimiter.Result) {
+	if problemType == "" {
+		problemType = DefaultProblemType
+	}
+
+	response := problemResponse{
+		Type:   problemType,
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+		Detail: message,
+		Scope:  scope,
+	}
+
+	if result.RetryAfter > 0 {
+		response.RetryAfterSeconds = ceilSeconds(result.RetryAfter)
+		response.Detail = fmt.Sprintf("%s; retry after %d seconds", message, response.RetryAfterSeconds)
+		w.Header().Set("Retry-After", retryAfterSeconds(result.RetryAfter))
+	}
+
+	w.Header().Set(ScopeHeader, scope)
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
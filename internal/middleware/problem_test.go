@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter/ratelimitertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiterMiddleware_ProblemJSON(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+		Requests:  1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	})
+
+	middleware := NewRateLimiterMiddleware(rateLimiter,
+		WithProblemJSON(true),
+		WithProblemType("https://example.com/problems/rate-limit"),
+		WithMessages(map[string]string{"pt-BR": "limite atingido"}),
+	)
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	var recorder *httptest.ResponseRecorder
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		req.Header.Set("Accept-Language", "pt-BR")
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+	}
+
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, ProblemContentType, recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "60", recorder.Header().Get("Retry-After"))
+	assert.Equal(t, ScopeIP, recorder.Header().Get(ScopeHeader))
+	assert.Equal(t, "pt-BR", recorder.Header().Get("Content-Language"))
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal(t, map[string]any{
+		"type":                "https://example.com/problems/rate-limit",
+		"title":               "Too Many Requests",
+		"status":              float64(http.StatusTooManyRequests),
+		"detail":              "limite atingido; retry after 60 seconds",
+		"scope":               ScopeIP,
+		"retry_after_seconds": float64(60),
+	}, body)
+}
+
+func TestWriteRateLimitedProblem(t *testing.T) {
+	// Sem type configurado e sem tempo de espera conhecido, o detail é apenas a mensagem
+	recorder := httptest.NewRecorder()
+	writeRateLimitedProblem(recorder, "", http.StatusServiceUnavailable, ScopeGlobal, DefaultLimitMessage, ratelimiter.Result{})
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, ProblemContentType, recorder.Header().Get("Content-Type"))
+	assert.Empty(t, recorder.Header().Get("Retry-After"))
+
+	var body problemResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal(t, problemResponse{
+		Type:   DefaultProblemType,
+		Title:  "Service Unavailable",
+		Status: http.StatusServiceUnavailable,
+		Detail: DefaultLimitMessage,
+		Scope:  ScopeGlobal,
+	}, body)
+}
+
+func TestRateLimiterMiddleware_PlainJSONByDefault(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+		Requests:  1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	})
+
+	handler := NewRateLimiterMiddleware(rateLimiter).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	var recorder *httptest.ResponseRecorder
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+	}
+
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.NotContains(t, recorder.Body.String(), `"title"`)
+}
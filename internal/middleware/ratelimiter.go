@@ -2,82 +2,773 @@ package middleware
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
 	"net"
 	"net/http"
-	"strings"
+	"strconv"
+	"time"
 
+	"github.com/cleibson/goexpert-rate-limiter/internal/metrics"
 	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
 )
 
+// IdempotencyKeyHeader é o header usado para identificar retentativas da mesma requisição
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// ScopeHeader é o header das respostas 429 que informa qual limite foi atingido
+const ScopeHeader = "X-RateLimit-Scope"
+
+// WarningHeader é o header adicionado às requisições permitidas cuja contagem ultrapassou o
+// SoftLimit da configuração, com o valor SoftLimitWarning
+const WarningHeader = "X-RateLimit-Warning"
+
+// SoftLimitWarning é o valor do WarningHeader
+const SoftLimitWarning = "approaching limit"
+
+// Escopos do limite atingido, informados no header ScopeHeader e no campo "scope" das
+// respostas 429
+const (
+	ScopeIP        = "ip"
+	ScopeToken     = "token"
+	ScopeIPToken   = "ip_token"
+	ScopeCustom    = "custom"
+	ScopeMethod    = "method"
+	ScopeRoute     = "route"
+	ScopeGlobal    = "global"
+	ScopeAnonymous = "anonymous"
+	ScopeHost      = "host"
+)
+
+// DefaultStorageRetryAfter é o Retry-After sugerido quando o armazenamento está indisponível
+const DefaultStorageRetryAfter = 5 * time.Second
+
+// FailureMode define o comportamento do middleware quando o armazenamento falha
+type FailureMode int
+
+const (
+	// FailClosed rejeita a requisição com 503 Service Unavailable quando o armazenamento falha
+	FailClosed FailureMode = iota
+
+	// FailOpen permite a requisição sem limitação quando o armazenamento falha
+	FailOpen
+)
+
+// CheckOrder define como o token e o IP de uma requisição são combinados na limitação
+type CheckOrder int
+
+const (
+	// TokenThenIP limita pelo token quando ele é informado e pelo IP caso contrário (padrão)
+	TokenThenIP CheckOrder = iota
+
+	// IPOnly ignora o token e limita todas as requisições pelo IP
+	IPOnly
+
+	// TokenRaisesIP limita sempre pelo IP, mas aplica a configuração do token informado como
+	// limite do IP (ex: clientes autenticados têm um teto maior), sem um orçamento próprio
+	// do token
+	TokenRaisesIP
+
+	// Both exige que a requisição esteja dentro do limite do IP e, quando informado, do limite
+	// do token
+	Both
+)
+
 // RateLimiterMiddleware encapsula a funcionalidade do rate limiter como um middleware HTTP
 type RateLimiterMiddleware struct {
 	rateLimiter *ratelimiter.RateLimiter
+
+	// Enabled liga a limitação (padrão em NewRateLimiterMiddleware). Quando falso o middleware
+	// apenas repassa as requisições, sem acessar o armazenamento, o que permite rodar
+	// localmente sem Redis.
+	Enabled bool
+
+	// ShadowMode avalia os limites normalmente, mas permite as requisições que seriam
+	// rejeitadas, apenas registrando-as em log e em Metrics.ShadowBlocks. Permite observar o
+	// efeito de um novo limite antes de aplicá-lo. Bloqueios continuam sendo registrados no
+	// armazenamento, e a política Reject para tokens desconhecidos continua sendo aplicada.
+	ShadowMode bool
+
+	// Metrics recebe os contadores do middleware, quando definido, rotulados com o escopo do
+	// limite avaliado
+	Metrics *metrics.Metrics
+
+	// RouteLabel define o rótulo route das métricas de cada requisição. Deve retornar um
+	// conjunto pequeno e fixo de valores (ex: o padrão da rota, não o caminho com IDs), já que
+	// cada valor distinto cria novas séries; vazio omite o rótulo. Sem a função, as métricas
+	// são rotuladas apenas pelo escopo.
+	RouteLabel func(r *http.Request) string
+
+	// Skip isenta da limitação as requisições para as quais retorna verdadeiro, sem acessar o
+	// armazenamento (ex: SkipPaths para health checks ou IsUpgradeRequest para conexões
+	// WebSocket). É avaliado antes de qualquer outra regra: uma requisição isenta não é contada
+	// nem rejeitada, independentemente de identidade personalizada, token ou IP.
+	Skip func(r *http.Request) bool
+
+	// BypassHeader e BypassSecret isentam da limitação as chamadas internas entre serviços: as
+	// requisições cujo header BypassHeader traz exatamente BypassSecret, comparado em tempo
+	// constante, são tratadas como as isentas por Skip. Com qualquer um dos dois vazio, a
+	// isenção fica desligada.
+	BypassHeader string
+	BypassSecret string
+
+	// Idempotency habilita o replay de decisões para requisições que repetem o header
+	// Idempotency-Key dentro da janela, evitando que retentativas sejam contadas duas vezes
+	Idempotency bool
+
+	// APIKeyHeader é o header de onde o token é lido. Vazio usa DefaultAPIKeyHeader; com
+	// "Authorization", o prefixo "Bearer " é removido do valor.
+	APIKeyHeader string
+
+	// StripAPIKeyHeader remove o header do token da requisição repassada aos handlers, para
+	// que a chave não chegue aos serviços seguintes. KeyFunc ainda recebe o header.
+	StripAPIKeyHeader bool
+
+	// IPv6PrefixLen define o tamanho do prefixo usado para agrupar endereços IPv6 em uma
+	// única chave. Zero usa DefaultIPv6PrefixLen; 128 limita cada endereço individualmente.
+	IPv6PrefixLen int
+
+	// ClientIPStrategy define a precedência entre X-Forwarded-For, X-Real-IP e o endereço da
+	// conexão na obtenção do IP do cliente (padrão XForwardedForFirst)
+	ClientIPStrategy ClientIPStrategy
+
+	// MaxForwardedHops limita o número de entradas aceitas no header X-Forwarded-For. Cadeias
+	// maiores, assim como entradas malformadas, são ignoradas e o IP é obtido de X-Real-IP ou
+	// da conexão. Zero usa DefaultMaxForwardedHops.
+	MaxForwardedHops int
+
+	// IPv4PrefixLen define o tamanho do prefixo usado para agrupar endereços IPv4 (ex: 24 para
+	// que todos os hosts de um /24 compartilhem o limite). Zero ou 32 limita cada endereço
+	// individualmente.
+	IPv4PrefixLen int
+
+	// KeyFunc deriva uma identidade personalizada (ex: ID do usuário extraído de um JWT) e a
+	// configuração aplicada a ela. Quando retorna ok, a chave e a configuração retornadas
+	// substituem a limitação por token e por IP. Valores adicionados ao contexto por
+	// middlewares anteriores (ex: o tenant autenticado) estão disponíveis em r.Context().
+	KeyFunc func(r *http.Request) (key string, cfg ratelimiter.Config, ok bool)
+
+	// RouteLimits aplica configurações completas, por IP, às requisições das rotas
+	// correspondentes (ex: "/login" com janela e bloqueio próprios), no lugar das limitações
+	// por método, token e IP. Cada rota tem contadores e bloqueios separados; quando várias
+	// correspondem, vale a de caminho mais longo. KeyFunc tem precedência.
+	RouteLimits []RouteLimit
+
+	// MethodLimits aplica configurações próprias, por IP, às requisições cujos métodos
+	// correspondem (ex: POST, PUT e DELETE mais restritos que GET), no lugar da limitação por
+	// token e por IP. Os métodos de um mesmo MethodLimit compartilham o orçamento; a primeira
+	// entrada correspondente é usada e os demais métodos seguem a limitação padrão. KeyFunc
+	// tem precedência, então limites por rota definidos nele não são afetados.
+	MethodLimits []MethodLimit
+
+	// PerHost separa os orçamentos por host de destino (header Host, sem a porta), para
+	// proxies reversos que atendem muitos domínios: o token, ou o IP nas requisições sem
+	// token, tem contadores e bloqueios independentes em cada host. O limite do IP em cada
+	// host vem de HostLimits ou, sem entrada para o host, da configuração de IP; os tokens
+	// mantêm as suas configurações. Substitui CheckOrder (exceto IPOnly), IPTokenLimit e
+	// AnonymousMode; KeyFunc, RouteLimits e MethodLimits têm precedência.
+	PerHost bool
+
+	// HostLimits define o limite por IP das requisições destinadas a cada host com PerHost
+	HostLimits []HostLimit
+
+	// IPTokenLimit aplica, às requisições com token, um limite adicional por par de IP e token
+	// (configurado com RateLimiter.SetIPTokenConfig), para que um token vazado não possa ser
+	// explorado a partir de muitos IPs consumindo o orçamento de um único cliente
+	IPTokenLimit bool
+
+	// GlobalLimit aplica, antes dos limites de cada cliente, o limite global de todas as
+	// requisições (configurado com RateLimiter.SetGlobalConfig), para proteger o backend mesmo
+	// quando nenhum cliente isolado excede o seu limite. Requisições rejeitadas pelo limite
+	// global não consomem o orçamento do cliente.
+	GlobalLimit bool
+
+	// CheckOrder define como o token e o IP são combinados (padrão TokenThenIP). IPTokenLimit
+	// se aplica apenas a TokenThenIP.
+	CheckOrder CheckOrder
+
+	// AnonymousMode aplica às requisições sem token o limite anônimo (configurado com
+	// RateLimiter.SetAnonymousConfig), no lugar do limite de IP ou em conjunto com ele. Não
+	// afeta as requisições com token, nem as que usam KeyFunc, RouteLimits ou MethodLimits.
+	AnonymousMode AnonymousMode
+
+	// Bandwidth faz cada requisição consumir do limite o tamanho do seu corpo em bytes, em vez
+	// de uma unidade, de modo que Requests das configurações passa a ser um orçamento de bytes
+	// por janela (ex: endpoints de upload). O tamanho vem do Content-Length ou, na sua
+	// ausência, da leitura do corpo até MaxStreamedBytes.
+	Bandwidth bool
+
+	// MaxStreamedBytes limita a leitura dos corpos sem Content-Length no modo Bandwidth;
+	// corpos maiores são rejeitados com 413 Request Entity Too Large. Zero usa
+	// DefaultMaxStreamedBytes.
+	MaxStreamedBytes int64
+
+	// MaxBodyBytes, quando positivo, rejeita com 413 Request Entity Too Large as requisições
+	// cujo corpo excede esse tamanho, antes de consumir o limite. Com Content-Length a rejeição
+	// é imediata; sem ele, o corpo lido no modo Bandwidth também é limitado a MaxBodyBytes, e
+	// nos demais casos o handler recebe um corpo que falha ao ultrapassá-lo
+	// (http.MaxBytesReader).
+	MaxBodyBytes int64
+
+	// CountStatuses, quando definido, conta apenas as requisições cujas respostas têm um dos
+	// status informados (ex: 401 e 403 para limitar tentativas de login com falha). A
+	// requisição é verificada sem ser contada antes de chegar ao handler, sendo rejeitada se a
+	// identidade estiver bloqueada ou já tiver excedido o limite, e contabilizada após a
+	// resposta. Como o status só é conhecido depois, a requisição que excede o limite é
+	// atendida e as seguintes são rejeitadas.
+	CountStatuses []int
+
+	// MaxWait, quando positivo, faz as requisições acima do limite aguardarem a próxima vaga
+	// por até esse tempo antes de serem rejeitadas (ver ratelimiter.RateLimiter.Wait), em vez
+	// da resposta imediata de limite excedido. A espera termina se o cliente desistir da
+	// requisição, e bloqueios mais longos que MaxWait são rejeitados sem espera. Ignorado no
+	// modo shadow.
+	MaxWait time.Duration
+
+	// QuotaHeaders define quais headers de cota (X-RateLimit-* e/ou RateLimit e
+	// RateLimit-Policy do draft IETF) são enviados nas respostas das requisições verificadas,
+	// permitidas ou rejeitadas. O padrão NoQuotaHeaders não envia nenhum.
+	QuotaHeaders QuotaHeaders
+
+	// FailureMode define se as requisições são rejeitadas (padrão) ou permitidas quando o
+	// armazenamento falha
+	FailureMode FailureMode
+
+	// OnDegraded, quando definido, é chamado a cada requisição liberada sem limitação por falha
+	// do armazenamento em FailOpen (ex: para alertar os operadores), com o escopo do limite que
+	// seria avaliado e o erro. É chamado de forma síncrona, então não deve bloquear.
+	OnDegraded func(r *http.Request, scope string, err error)
+
+	// RejectStatusCode é o status das respostas para requisições acima do limite (ex: 420 ou
+	// 503 para proxies que esperam outro código). Zero usa http.StatusTooManyRequests; valores
+	// fora das faixas 4xx e 5xx são ignorados.
+	RejectStatusCode int
+
+	// Messages define a mensagem do corpo de limite excedido por idioma (ex: "pt-BR", "en"),
+	// escolhida conforme o header Accept-Language. A chave "" substitui a mensagem padrão
+	// usada quando nenhum idioma aceito pelo cliente está disponível.
+	Messages map[string]string
+
+	// ProblemJSON responde às requisições acima do limite no formato problem+json da RFC 7807
+	// (ProblemContentType), com os membros type, title, status e detail, em vez do JSON
+	// padrão. O detail traz a mensagem de Messages e o tempo de espera, quando conhecido.
+	ProblemJSON bool
+
+	// ProblemType é o URI do membro type das respostas problem+json (ex: uma página que
+	// documenta os limites da API). Vazio usa DefaultProblemType.
+	ProblemType string
+
+	// OnLimitExceeded, quando definido, escreve a resposta das requisições acima do limite no
+	// lugar da resposta JSON padrão, recebendo o escopo do limite atingido e o resultado
+	OnLimitExceeded func(w http.ResponseWriter, r *http.Request, scope string, result ratelimiter.Result)
+
+	// StorageRetryAfter é o valor do header Retry-After nas respostas 503 geradas por falhas
+	// do armazenamento. Zero usa DefaultStorageRetryAfter.
+	StorageRetryAfter time.Duration
 }
 
-// NewRateLimiterMiddleware cria um novo middleware de rate limiter
-func NewRateLimiterMiddleware(rateLimiter *ratelimiter.RateLimiter) *RateLimiterMiddleware {
-	return &RateLimiterMiddleware{
+// NewRateLimiterMiddleware cria um novo middleware de rate limiter, aplicando as opções
+// informadas em ordem
+func NewRateLimiterMiddleware(rateLimiter *ratelimiter.RateLimiter, opts ...Option) *RateLimiterMiddleware {
+	m := &RateLimiterMiddleware{
 		rateLimiter: rateLimiter,
+		Enabled:     true,
 	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
 }
 
-// Handler retorna o handler do middleware HTTP
+// Wrap aplica o rate limiter a next com as opções informadas, equivalente a
+// NewRateLimiterMiddleware(rateLimiter, opts...).Handler(next). O resultado da verificação das
+// requisições permitidas fica disponível em ratelimiter.ResultFromContext(r.Context()).
+func Wrap(rateLimiter *ratelimiter.RateLimiter, next http.Handler, opts ...Option) http.Handler {
+	return NewRateLimiterMiddleware(rateLimiter, opts...).Handler(next)
+}
+
+// Handler retorna o handler do middleware HTTP. O resultado da verificação é adicionado ao
+// contexto da requisição repassada a next (ver ratelimiter.ResultFromContext).
 func (m *RateLimiterMiddleware) Handler(next http.Handler) http.Handler {
+	rejectStatusCode := m.rejectStatusCode()
+	routeLimits := newRouteLimiters(m.RouteLimits)
+	methodLimits := newMethodLimiters(m.MethodLimits)
+	hostLimits := newHostLimiters(m.HostLimits)
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
+		if !m.Enabled || m.skip(r) {
+			next.ServeHTTP(w, m.stripAPIKey(r))
+			return
+		}
 
-		// Extrai o endereço IP
-		ip := m.getClientIP(r)
+		// Usa o contexto da requisição, para que valores injetados por middlewares anteriores
+		// (ex: tenant ou usuário autenticado) cheguem ao armazenamento e o cancelamento pelo
+		// cliente interrompa a verificação
+		ctx := r.Context()
 
-		// Extrai a chave da API do header
-		apiKey := r.Header.Get("API_KEY")
+		// Associa a chave de idempotência para que retentativas não sejam contadas novamente
+		if m.Idempotency {
+			if idempotencyKey := r.Header.Get(IdempotencyKeyHeader); idempotencyKey != "" {
+				ctx = ratelimiter.WithIdempotencyKey(ctx, idempotencyKey)
+			}
+		}
 
-		var allowed bool
-		var err error
+		// Corpos acima de MaxBodyBytes são rejeitados antes de consumir o limite
+		if m.MaxBodyBytes > 0 {
+			if r.ContentLength > m.MaxBodyBytes {
+				writeBodyTooLarge(w)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, m.MaxBodyBytes)
+		}
 
-		// Verifica token primeiro (tem precedência sobre IP)
-		if apiKey != "" {
-			allowed, err = m.rateLimiter.CheckToken(ctx, apiKey)
+		// No modo Bandwidth a requisição consome o tamanho do corpo em bytes
+		if m.Bandwidth {
+			size, err := m.requestSize(r)
+			if errors.Is(err, errBodyTooLarge) {
+				writeBodyTooLarge(w)
+				return
+			}
 			if err != nil {
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				log.Printf("Falha ao ler corpo da requisição: %v", err)
+				writeBadRequest(w)
 				return
 			}
-		} else {
-			// Volta para limitação baseada em IP
-			allowed, err = m.rateLimiter.CheckIP(ctx, ip)
+			ctx = ratelimiter.WithWeight(ctx, size)
+		}
+
+		// Extrai o endereço IP e o normaliza para a chave de limitação
+		ip := m.normalizeIP(m.getClientIP(r))
+
+		// Extrai a chave da API do header
+		apiKey := m.apiKey(r)
+
+		// Extrai a identidade personalizada, se configurada
+		customKey, customConfig, hasCustomKey := m.customKey(r)
+
+		// Extrai o limite específico da rota, se configurado
+		routeLimit, hasRouteLimit := routeLimits.match(r.URL.Path)
+
+		// Extrai o limite específico do método, se configurado
+		methodLimit, hasMethodLimit := methodLimits.match(r.Method)
+
+		// Extrai o host de destino, usado apenas com PerHost
+		host := normalizeHost(r.Host)
+
+		// Lidos o token e as identidades, o header pode ser removido
+		r = m.stripAPIKey(r)
+
+		// checkClient aplica à requisição os limites da sua identidade. Verificações em duas
+		// etapas (ex: IP e token) retornam em release a liberação das vagas ocupadas pela
+		// primeira.
+		checkClient := func(ctx context.Context) (scope string, result ratelimiter.Result, release func(), err error) {
+			release = func() {}
+
+			switch {
+			case hasCustomKey:
+				// Identidade personalizada tem precedência sobre método, token e IP
+				scope = ScopeCustom
+				result, err = m.rateLimiter.CheckKey(ctx, customKey, customConfig)
+			case hasRouteLimit:
+				// Rotas com limite próprio usam um orçamento separado por IP
+				scope = ScopeRoute
+				result, err = m.rateLimiter.CheckKey(ctx, routeLimit.key(ip, m.rateLimiter.KeyNames().Separator), routeLimit.Config)
+			case hasMethodLimit:
+				// Métodos com limite próprio usam um orçamento separado por IP
+				scope = ScopeMethod
+				result, err = m.rateLimiter.CheckKey(ctx, methodLimit.key(ip, m.rateLimiter.KeyNames().Separator), methodLimit.config)
+			case m.PerHost:
+				// Cada host de destino tem orçamentos próprios para o token ou o IP
+				scope = ScopeHost
+				var key string
+				var config ratelimiter.Config
+				key, config, err = m.hostIdentity(ctx, hostLimits, host, ip, apiKey)
+				if err == nil {
+					result, err = m.rateLimiter.CheckKey(ctx, key, config)
+				}
+			case m.anonymous(apiKey) && m.AnonymousMode == AnonymousInsteadOfIP:
+				// Sem token, vale o orçamento anônimo no lugar do de IP
+				scope = ScopeAnonymous
+				result, err = m.rateLimiter.CheckAnonymous(ctx, ip)
+			case m.anonymous(apiKey):
+				// Verifica o IP e, se permitido, o orçamento anônimo
+				scope = ScopeIP
+				result, err = m.rateLimiter.CheckIP(ctx, ip)
+				if err == nil && result.Allowed {
+					release = result.Release
+
+					scope = ScopeAnonymous
+					result, err = m.rateLimiter.CheckAnonymous(ctx, ip)
+				}
+			case apiKey == "" || m.CheckOrder == IPOnly:
+				// Sem token, ou com o token ignorado, vale a limitação por IP
+				scope = ScopeIP
+				result, err = m.rateLimiter.CheckIP(ctx, ip)
+			case m.CheckOrder == TokenRaisesIP:
+				// O orçamento continua sendo do IP, com o teto definido pelo token
+				scope = ScopeIP
+				result, err = m.rateLimiter.CheckIPWithToken(ctx, ip, apiKey)
+			case m.CheckOrder == Both:
+				// Verifica o IP e, se permitido, o token; um token desconhecido não rejeitado pela
+				// política fica apenas com o limite do IP já verificado
+				scope = ScopeIP
+				result, err = m.rateLimiter.CheckIP(ctx, ip)
+				if err == nil && result.Allowed {
+					ipResult := result
+					release = ipResult.Release
+
+					scope = ScopeToken
+					result, err = m.rateLimiter.CheckToken(ctx, apiKey)
+					if errors.Is(err, ratelimiter.ErrUnknownToken) && m.rateLimiter.UnknownTokenPolicy() != ratelimiter.Reject {
+						scope, result, err = ScopeIP, ipResult, nil
+					}
+				}
+			case m.IPTokenLimit:
+				// Verifica o par de IP e token e, se permitido, o limite do próprio token
+				scope = ScopeIPToken
+				result, err = m.rateLimiter.CheckIPToken(ctx, ip, apiKey)
+				if err == nil && result.Allowed {
+					release = result.Release
+
+					scope = ScopeToken
+					result, err = m.rateLimiter.CheckToken(ctx, apiKey)
+				}
+			default:
+				// Verifica token primeiro (tem precedência sobre IP)
+				scope = ScopeToken
+				result, err = m.rateLimiter.CheckToken(ctx, apiKey)
+			}
+
+			// Token desconhecido: volta para a limitação por IP, exceto com a política Reject
+			if errors.Is(err, ratelimiter.ErrUnknownToken) && m.rateLimiter.UnknownTokenPolicy() != ratelimiter.Reject {
+				scope = ScopeIP
+				result, err = m.rateLimiter.CheckIP(ctx, ip)
+			}
+
+			return scope, result, release, err
+		}
+
+		// resetClient devolve o limite completo às identidades verificadas por checkClient,
+		// conforme o escopo final da verificação (ver ResetForRequest)
+		resetClient := func(ctx context.Context, scope string) error {
+			rl := m.rateLimiter
+			separator := rl.KeyNames().Separator
+
+			switch {
+			case hasCustomKey:
+				return rl.ResetKey(ctx, customKey, customConfig)
+			case hasRouteLimit:
+				return rl.ResetKey(ctx, routeLimit.key(ip, separator), routeLimit.Config)
+			case hasMethodLimit:
+				return rl.ResetKey(ctx, methodLimit.key(ip, separator), methodLimit.config)
+			case m.PerHost:
+				key, config, err := m.hostIdentity(ctx, hostLimits, host, ip, apiKey)
+				if err != nil {
+					return err
+				}
+				return rl.ResetKey(ctx, key, config)
+			case m.anonymous(apiKey):
+				// O orçamento anônimo compartilhado não pertence ao cliente e não é zerado
+				var err error
+				if rl.AnonymousScope() == ratelimiter.AnonymousPerIP {
+					err = rl.ResetAnonymous(ctx, ip)
+				}
+				if m.AnonymousMode == AnonymousWithIP {
+					err = errors.Join(rl.ResetIP(ctx, ip), err)
+				}
+				return err
+			case apiKey == "" || m.CheckOrder == IPOnly || m.CheckOrder == TokenRaisesIP || scope == ScopeIP:
+				return rl.ResetIP(ctx, ip)
+			case m.CheckOrder == Both:
+				return errors.Join(rl.ResetIP(ctx, ip), rl.ResetToken(ctx, apiKey))
+			case m.IPTokenLimit:
+				return errors.Join(rl.ResetIPToken(ctx, ip, apiKey), rl.ResetToken(ctx, apiKey))
+			default:
+				return rl.ResetToken(ctx, apiKey)
+			}
+		}
+
+		// check aplica o limite global, se habilitado, e em seguida os limites da identidade
+		check := func(ctx context.Context) (string, ratelimiter.Result, func(), error) {
+			if !m.GlobalLimit {
+				return checkClient(ctx)
+			}
+
+			result, err := m.rateLimiter.CheckGlobal(ctx)
+			if err != nil || !result.Allowed {
+				return ScopeGlobal, result, func() {}, err
+			}
+
+			globalRelease := result.Release
+			scope, result, release, err := checkClient(ctx)
+			return scope, result, func() {
+				release()
+				globalRelease()
+			}, err
+		}
+
+		// Com CountStatuses a requisição é apenas verificada agora e contada após a resposta
+		countAfterResponse := len(m.CountStatuses) > 0
+		checkCtx := ctx
+		if countAfterResponse {
+			checkCtx = ratelimiter.WithoutCounting(ctx)
+		}
+
+		// Com MaxWait as requisições rejeitadas aguardam a próxima vaga; a cada nova tentativa
+		// as vagas ocupadas pela anterior são liberadas
+		maxWait := m.MaxWait
+		if m.ShadowMode {
+			maxWait = 0
+		}
+
+		var scope string
+		release := func() {}
+		result, err := m.rateLimiter.Wait(checkCtx, maxWait, func(ctx context.Context) (ratelimiter.Result, error) {
+			release()
+
+			var result ratelimiter.Result
+			var err error
+			scope, result, release, err = check(ctx)
+			return result, err
+		})
+		defer func() { release() }()
+
+		if errors.Is(err, ratelimiter.ErrUnknownToken) {
+			writeUnauthorized(w)
+			return
+		}
+
+		// serve repassa a requisição a next e, com CountStatuses, contabiliza-a quando o status
+		// da resposta é um dos configurados, mesmo que o cliente já tenha desistido
+		serve := func(w http.ResponseWriter, r *http.Request) {
+			if !countAfterResponse {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			recorder := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(recorder, r)
+			if !m.countsStatus(recorder.statusCode()) {
+				return
+			}
+
+			_, counted, countedRelease, err := check(context.WithoutCancel(ctx))
+			countedRelease()
+			counted.Release()
 			if err != nil {
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				log.Printf("Falha ao contabilizar requisição após a resposta: %v", err)
+			}
+		}
+
+		// O cliente desistiu da requisição: não há a quem responder
+		if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+			return
+		}
+
+		if err != nil {
+			log.Printf("Falha ao verificar limite de taxa: %v", err)
+
+			if m.FailureMode == FailOpen {
+				if m.Metrics != nil {
+					m.Metrics.Degraded.With(m.metricLabels(r, scope)).Inc()
+				}
+				if m.OnDegraded != nil {
+					m.OnDegraded(r, scope, err)
+				}
+
+				next.ServeHTTP(w, r)
 				return
 			}
+
+			m.writeUnavailable(w)
+			return
 		}
 
-		if !allowed {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusTooManyRequests)
-			w.Write([]byte(`{"error": "you have reached the maximum number of requests or actions allowed within a certain time frame"}`))
+		// Libera a vaga de concorrência, se ocupada, quando o handler termina
+		defer result.Release()
+
+		m.writeQuotaHeaders(w, result)
+
+		// Disponibiliza o resultado aos handlers (ver ratelimiter.ResultFromContext) e a
+		// devolução do limite (ver ResetForRequest)
+		r = r.WithContext(contextWithReset(ratelimiter.ContextWithResult(r.Context(), result), func(ctx context.Context) error {
+			return resetClient(ctx, scope)
+		}))
+
+		if !result.Allowed && m.ShadowMode {
+			log.Printf("Modo shadow: requisição excederia o limite (escopo %s)", scope)
+			if m.Metrics != nil {
+				m.Metrics.ShadowBlocks.With(m.metricLabels(r, scope)).Inc()
+			}
+
+			serve(w, r)
+			return
+		}
+
+		if !result.Allowed {
+			if m.Metrics != nil {
+				m.Metrics.Rejected.With(m.metricLabels(r, scope)).Inc()
+			}
+
+			if m.OnLimitExceeded != nil {
+				m.OnLimitExceeded(w, r, scope, result)
+				return
+			}
+
+			message, lang := m.limitMessage(r)
+			if len(m.Messages) > 0 {
+				w.Header().Add("Vary", "Accept-Language")
+			}
+			if lang != "" {
+				w.Header().Set("Content-Language", lang)
+			}
+
+			if m.ProblemJSON {
+				writeRateLimitedProblem(w, m.ProblemType, rejectStatusCode, scope, message, result)
+				return
+			}
+
+			writeRateLimited(w, rejectStatusCode, scope, message, result)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		if m.Metrics != nil {
+			m.Metrics.Allowed.With(m.metricLabels(r, scope)).Inc()
+		}
+
+		// Avisa o cliente que ele se aproxima do limite, antes de ser bloqueado
+		if result.SoftLimitExceeded {
+			w.Header().Set(WarningHeader, SoftLimitWarning)
+		}
+
+		serve(w, r)
 	})
 }
 
-// getClientIP extrai o endereço IP do cliente a partir da requisição
+// metricLabels retorna os rótulos das métricas da requisição
+func (m *RateLimiterMiddleware) metricLabels(r *http.Request, scope string) metrics.Labels {
+	labels := metrics.Labels{Scope: scope}
+	if m.RouteLabel != nil {
+		labels.Route = m.RouteLabel(r)
+	}
+	return labels
+}
+
+// rejectStatusCode valida RejectStatusCode, voltando para 429 quando não definido ou inválido
+func (m *RateLimiterMiddleware) rejectStatusCode() int {
+	if m.RejectStatusCode == 0 {
+		return http.StatusTooManyRequests
+	}
+
+	if !ValidRejectStatusCode(m.RejectStatusCode) {
+		log.Printf("Status de rejeição inválido %d, usando %d", m.RejectStatusCode, http.StatusTooManyRequests)
+		return http.StatusTooManyRequests
+	}
+
+	return m.RejectStatusCode
+}
+
+// ValidRejectStatusCode indica se o status pode ser usado para rejeitar requisições (4xx ou 5xx)
+func ValidRejectStatusCode(code int) bool {
+	return code >= 400 && code <= 599
+}
+
+// rateLimitedResponse é o corpo JSON das respostas de limite excedido
+type rateLimitedResponse struct {
+	Error             string `json:"error"`
+	Scope             string `json:"scope"`
+	RetryAfterSeconds int64  `json:"retry_after_seconds,omitempty"`
+}
+
+// writeRateLimited responde com o status de rejeição informando qual limite foi atingido e,
+// quando conhecido, o tempo até que uma nova requisição seja aceita
+func writeRateLimited(w http.ResponseWriter, statusCode int, scope, message string, result ratelimiter.Result) {
+	response := rateLimitedResponse{
+		Error: message,
+		Scope: scope,
+	}
+
+	if result.RetryAfter > 0 {
+		response.RetryAfterSeconds = ceilSeconds(result.RetryAfter)
+		w.Header().Set("Retry-After", retryAfterSeconds(result.RetryAfter))
+	}
+
+	w.Header().Set(ScopeHeader, scope)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
+
+// writeUnavailable responde 503 indicando que a limitação está temporariamente indisponível,
+// para que o cliente trate a falha como transitória e tente novamente mais tarde
+func (m *RateLimiterMiddleware) writeUnavailable(w http.ResponseWriter) {
+	retryAfter := m.StorageRetryAfter
+	if retryAfter <= 0 {
+		retryAfter = DefaultStorageRetryAfter
+	}
+
+	w.Header().Set("Retry-After", retryAfterSeconds(retryAfter))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	w.Write([]byte(`{"error": "rate limiter temporarily unavailable, please retry later"}`))
+}
+
+// writeUnauthorized responde 401 para requisições com token desconhecido quando a política é
+// Reject
+func writeUnauthorized(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	w.Write([]byte(`{"error": "invalid API key"}`))
+}
+
+// customKey aplica o KeyFunc configurado à requisição
+func (m *RateLimiterMiddleware) customKey(r *http.Request) (string, ratelimiter.Config, bool) {
+	if m.KeyFunc == nil {
+		return "", ratelimiter.Config{}, false
+	}
+
+	key, config, ok := m.KeyFunc(r)
+	if !ok || key == "" {
+		return "", ratelimiter.Config{}, false
+	}
+
+	return key, config, true
+}
+
+// retryAfterSeconds formata a duração em segundos inteiros para o header Retry-After,
+// arredondando para cima para que o cliente não tente novamente antes do tempo
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.FormatInt(ceilSeconds(d), 10)
+}
+
+// ceilSeconds converte a duração em segundos inteiros, arredondando para cima
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
+
+// getClientIP extrai o endereço IP do cliente a partir da requisição, consultando os headers
+// de proxy na ordem definida por ClientIPStrategy
 func (m *RateLimiterMiddleware) getClientIP(r *http.Request) string {
-	// Verifica primeiro o header X-Forwarded-For
-	xForwardedFor := r.Header.Get("X-Forwarded-For")
-	if xForwardedFor != "" {
-		// Pega o primeiro IP se houver múltiplos
-		ips := strings.Split(xForwardedFor, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
-		}
+	forwardedFor := func() (string, bool) { return m.forwardedFor(r.Header.Get("X-Forwarded-For")) }
+	realIP := func() (string, bool) { return parseHostIP(r.Header.Get("X-Real-IP")) }
+
+	var sources []func() (string, bool)
+	switch m.ClientIPStrategy {
+	case RealIPFirst:
+		sources = []func() (string, bool){realIP, forwardedFor}
+	case RemoteAddrOnly:
+	default:
+		sources = []func() (string, bool){forwardedFor, realIP}
 	}
 
-	// Verifica o header X-Real-IP
-	xRealIP := r.Header.Get("X-Real-IP")
-	if xRealIP != "" {
-		return xRealIP
+	for _, source := range sources {
+		if ip, ok := source(); ok {
+			return ip
+		}
 	}
 
 	// Volta para RemoteAddr
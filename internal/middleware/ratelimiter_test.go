@@ -2,68 +2,35 @@ package middleware
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/metrics"
 	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter/ratelimitertest"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-// InMemoryStorage é um armazenamento simples em memória para testes
-type InMemoryStorage struct {
-	counters map[string]countData
-	blocked  map[string]time.Time
-}
-
-type countData struct {
-	count    int64
-	expireAt time.Time
-}
-
-func NewInMemoryStorage() *InMemoryStorage {
-	return &InMemoryStorage{
-		counters: make(map[string]countData),
-		blocked:  make(map[string]time.Time),
-	}
-}
-
-func (s *InMemoryStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
-	now := time.Now()
-
-	if data, exists := s.counters[key]; exists && now.Before(data.expireAt) {
-		data.count++
-		s.counters[key] = data
-		return data.count, nil
-	}
-
-	// Reset counter with new expiration
-	s.counters[key] = countData{
-		count:    1,
-		expireAt: now.Add(window),
-	}
-	return 1, nil
-}
-
-func (s *InMemoryStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
-	if blockedUntil, exists := s.blocked[key]; exists {
-		return time.Now().Before(blockedUntil), nil
-	}
-	return false, nil
-}
-
-func (s *InMemoryStorage) Block(ctx context.Context, key string, duration time.Duration) error {
-	s.blocked[key] = time.Now().Add(duration)
-	return nil
-}
+var errStorageDown = errors.New("connection refused")
 
-func (s *InMemoryStorage) Close() error {
-	return nil
+// newFailingStorage simula um armazenamento indisponível
+func newFailingStorage() *ratelimitertest.Storage {
+	s := ratelimitertest.NewStorage(nil)
+	s.Fail(errStorageDown)
+	return s
 }
 
 func TestRateLimiterMiddleware_IPLimiting(t *testing.T) {
-	storage := NewInMemoryStorage()
+	storage := ratelimitertest.NewStorage(nil)
 	config := ratelimiter.Config{
 		Requests:  3,
 		Window:    time.Second,
@@ -102,7 +69,7 @@ func TestRateLimiterMiddleware_IPLimiting(t *testing.T) {
 }
 
 func TestRateLimiterMiddleware_TokenLimiting(t *testing.T) {
-	storage := NewInMemoryStorage()
+	storage := ratelimitertest.NewStorage(nil)
 	ipConfig := ratelimiter.Config{
 		Requests:  2,
 		Window:    time.Second,
@@ -152,7 +119,7 @@ func TestRateLimiterMiddleware_TokenLimiting(t *testing.T) {
 }
 
 func TestRateLimiterMiddleware_TokenOverridesIP(t *testing.T) {
-	storage := NewInMemoryStorage()
+	storage := ratelimitertest.NewStorage(nil)
 	ipConfig := ratelimiter.Config{
 		Requests:  1, // Limite de IP muito baixo
 		Window:    time.Second,
@@ -190,7 +157,7 @@ func TestRateLimiterMiddleware_TokenOverridesIP(t *testing.T) {
 	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
 
 	// Reinicia armazenamento para novo teste
-	storage = NewInMemoryStorage()
+	storage = ratelimitertest.NewStorage(nil)
 	rateLimiter = ratelimiter.NewRateLimiter(storage, ipConfig)
 	rateLimiter.AddTokenConfig("abc123", tokenConfig)
 	middleware = NewRateLimiterMiddleware(rateLimiter)
@@ -211,44 +178,1488 @@ func TestRateLimiterMiddleware_TokenOverridesIP(t *testing.T) {
 	}
 }
 
-func TestRateLimiterMiddleware_GetClientIP(t *testing.T) {
-	middleware := &RateLimiterMiddleware{}
+func TestRateLimiterMiddleware_ConcurrentRequestsEnforceExactLimit(t *testing.T) {
+	storage := ratelimitertest.NewStorage(nil)
+	ipConfig := ratelimiter.Config{
+		Requests:  50,
+		Window:    time.Minute,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := ratelimiter.NewRateLimiter(storage, ipConfig)
+	middleware := NewRateLimiterMiddleware(rateLimiter)
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Requisições paralelas do mesmo IP não ultrapassam o limite nem bloqueiam mais de uma vez
+	const total = 200
+	codes := make(chan int, total)
+
+	var wg sync.WaitGroup
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = "192.168.1.1:12345"
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			codes <- recorder.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	allowed := 0
+	for code := range codes {
+		if code == http.StatusOK {
+			allowed++
+		} else {
+			assert.Equal(t, http.StatusTooManyRequests, code)
+		}
+	}
+	assert.Equal(t, 50, allowed)
+}
+
+func TestRateLimiterMiddleware_RejectionReportsScope(t *testing.T) {
+	ipConfig := ratelimiter.Config{
+		Requests:  1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+	tokenConfig := ratelimiter.Config{
+		Requests:  1,
+		Window:    time.Second,
+		BlockTime: 2 * time.Minute,
+	}
 
 	tests := []struct {
 		name         string
-		setupRequest func(*http.Request)
-		expectedIP   string
+		apiKey       string
+		ipTokenLimit bool
+		scope        string
+		retryAfter   int64
+	}{
+		{name: "limite de IP", scope: ScopeIP, retryAfter: 60},
+		{name: "limite de token", apiKey: "abc123", scope: ScopeToken, retryAfter: 120},
+		{name: "token desconhecido usa o limite de IP", apiKey: "unknown", scope: ScopeIP, retryAfter: 60},
+		{name: "limite do par IP e token", apiKey: "abc123", ipTokenLimit: true, scope: ScopeIPToken, retryAfter: 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ipConfig)
+			rateLimiter.AddTokenConfig("abc123", tokenConfig)
+			rateLimiter.SetIPTokenConfig(ratelimiter.Config{Requests: 1, Window: time.Second, BlockTime: 30 * time.Second})
+
+			middleware := NewRateLimiterMiddleware(rateLimiter)
+			middleware.IPTokenLimit = tt.ipTokenLimit
+
+			handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			var recorder *httptest.ResponseRecorder
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.RemoteAddr = "192.168.1.1:12345"
+				if tt.apiKey != "" {
+					req.Header.Set("API_KEY", tt.apiKey)
+				}
+
+				recorder = httptest.NewRecorder()
+				handler.ServeHTTP(recorder, req)
+			}
+
+			assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			assert.Equal(t, tt.scope, recorder.Header().Get(ScopeHeader))
+
+			var body struct {
+				Error             string `json:"error"`
+				Scope             string `json:"scope"`
+				RetryAfterSeconds int64  `json:"retry_after_seconds"`
+			}
+			assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+			assert.Contains(t, body.Error, "you have reached the maximum number of requests")
+			assert.Equal(t, tt.scope, body.Scope)
+			assert.Equal(t, tt.retryAfter, body.RetryAfterSeconds)
+		})
+	}
+}
+
+func TestRateLimiterMiddleware_UsableAfterBlockExpires(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	storage := ratelimitertest.NewStorage(fakeClock)
+	config := ratelimiter.Config{
+		Requests:  2,
+		Window:    time.Hour, // Janela maior que o bloqueio
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := ratelimiter.NewRateLimiter(storage, config)
+	rateLimiter.SetClock(fakeClock)
+	middleware := NewRateLimiterMiddleware(rateLimiter)
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func() int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// Esgota o limite e dispara o bloqueio
+	assert.Equal(t, http.StatusOK, send())
+	assert.Equal(t, http.StatusOK, send())
+	assert.Equal(t, http.StatusTooManyRequests, send())
+
+	// Ainda bloqueada um instante antes do bloqueio expirar
+	fakeClock.Advance(time.Minute - time.Second)
+	assert.Equal(t, http.StatusTooManyRequests, send())
+
+	// Após o bloqueio expirar; a janela do contador ainda estaria ativa
+	fakeClock.Advance(time.Second)
+
+	// A chave deve ter o limite completo novamente, sem rebloqueio imediato
+	assert.Equal(t, http.StatusOK, send())
+	assert.Equal(t, http.StatusOK, send())
+	assert.Equal(t, http.StatusTooManyRequests, send())
+}
+
+func TestRateLimiterMiddleware_BlockVersusRejectOnly(t *testing.T) {
+	tests := []struct {
+		name      string
+		blockTime time.Duration
+		// Status esperado logo após a janela que excedeu o limite terminar
+		expectedAfterWindow int
 	}{
 		{
-			name: "Header X-Forwarded-For",
-			setupRequest: func(r *http.Request) {
-				r.Header.Set("X-Forwarded-For", "203.0.113.1, 198.51.100.1")
+			name:                "Bloqueio punitivo",
+			blockTime:           time.Minute,
+			expectedAfterWindow: http.StatusTooManyRequests,
+		},
+		{
+			name:                "Apenas rejeição",
+			blockTime:           0,
+			expectedAfterWindow: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+			storage := ratelimitertest.NewStorage(fakeClock)
+			config := ratelimiter.Config{
+				Requests:  2,
+				Window:    time.Second,
+				BlockTime: tt.blockTime,
+			}
+
+			rateLimiter := ratelimiter.NewRateLimiter(storage, config)
+			rateLimiter.SetClock(fakeClock)
+			middleware := NewRateLimiterMiddleware(rateLimiter)
+
+			handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			send := func() int {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.RemoteAddr = "192.168.1.1:12345"
+
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, req)
+				return recorder.Code
+			}
+
+			assert.Equal(t, http.StatusOK, send())
+			assert.Equal(t, http.StatusOK, send())
+			assert.Equal(t, http.StatusTooManyRequests, send())
+
+			fakeClock.Advance(time.Second)
+			assert.Equal(t, tt.expectedAfterWindow, send())
+		})
+	}
+}
+
+func TestRateLimiterMiddleware_IPv6SamePrefixSharesLimit(t *testing.T) {
+	storage := ratelimitertest.NewStorage(nil)
+	config := ratelimiter.Config{
+		Requests:  3,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := ratelimiter.NewRateLimiter(storage, config)
+	middleware := NewRateLimiterMiddleware(rateLimiter)
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(remoteAddr string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// Endereços diferentes dentro do mesmo /64 compartilham o limite
+	assert.Equal(t, http.StatusOK, send("[2001:db8:1:2::1]:12345"))
+	assert.Equal(t, http.StatusOK, send("[2001:db8:1:2::2]:12345"))
+	assert.Equal(t, http.StatusOK, send("[2001:db8:1:2:aaaa:bbbb:cccc:dddd]:12345"))
+	assert.Equal(t, http.StatusTooManyRequests, send("[2001:db8:1:2::4]:12345"))
+
+	// Outro /64 possui seu próprio limite
+	assert.Equal(t, http.StatusOK, send("[2001:db8:1:3::1]:12345"))
+}
+
+func TestRateLimiterMiddleware_IPv4PrefixLen(t *testing.T) {
+	tests := []struct {
+		name          string
+		ipv4PrefixLen int
+		expected      []int
+	}{
+		{
+			name:          "hosts do mesmo /24 compartilham o limite",
+			ipv4PrefixLen: 24,
+			expected:      []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:     "sem agrupamento cada host tem seu próprio limite",
+			expected: []int{http.StatusOK, http.StatusOK, http.StatusOK},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+				Requests:  2,
+				Window:    time.Second,
+				BlockTime: time.Minute,
+			})
+
+			middleware := NewRateLimiterMiddleware(rateLimiter)
+			middleware.IPv4PrefixLen = tt.ipv4PrefixLen
+
+			handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			send := func(remoteAddr string) int {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.RemoteAddr = remoteAddr
+
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, req)
+				return recorder.Code
+			}
+
+			hosts := []string{"203.0.113.1:12345", "203.0.113.2:12345", "203.0.113.250:12345"}
+			for i, host := range hosts {
+				assert.Equal(t, tt.expected[i], send(host), host)
+			}
+
+			// Outra sub-rede possui seu próprio limite
+			assert.Equal(t, http.StatusOK, send("203.0.114.1:12345"))
+		})
+	}
+}
+
+func TestRateLimiterMiddleware_LeakyBucket(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	storage := ratelimitertest.NewStorage(fakeClock)
+	config := ratelimiter.Config{
+		Requests:  5,
+		Window:    5 * time.Second, // Escoa uma requisição por segundo
+		Algorithm: ratelimiter.AlgorithmLeakyBucket,
+	}
+
+	rateLimiter := ratelimiter.NewRateLimiter(storage, config)
+	rateLimiter.SetClock(fakeClock)
+	middleware := NewRateLimiterMiddleware(rateLimiter)
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// Um fluxo constante abaixo da taxa de escoamento sempre passa
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, http.StatusOK, send("192.168.1.1:12345").Code)
+		fakeClock.Advance(1500 * time.Millisecond)
+	}
+
+	// Uma rajada enche o bucket e o excedente é rejeitado
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, send("192.168.1.2:12345").Code)
+	}
+	recorder := send("192.168.1.2:12345")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "1", recorder.Header().Get("Retry-After"))
+
+	// O tempo de espera diminui conforme o bucket escoa
+	fakeClock.Advance(400 * time.Millisecond)
+	recorder = send("192.168.1.2:12345")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+
+	// Após o escoamento de uma requisição há espaço para exatamente mais uma
+	fakeClock.Advance(600 * time.Millisecond)
+	assert.Equal(t, http.StatusOK, send("192.168.1.2:12345").Code)
+	assert.Equal(t, http.StatusTooManyRequests, send("192.168.1.2:12345").Code)
+}
+
+func TestRateLimiterMiddleware_SoftLimitWarning(t *testing.T) {
+	config := ratelimiter.Config{
+		Requests:  4,
+		SoftLimit: 2,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), config)
+	handler := NewRateLimiterMiddleware(rateLimiter).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// O aviso aparece apenas entre o limite de aviso e o limite efetivo
+	for i, warning := range []string{"", "", SoftLimitWarning, SoftLimitWarning} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code, "requisição %d", i+1)
+		assert.Equal(t, warning, recorder.Header().Get(WarningHeader), "requisição %d", i+1)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Empty(t, recorder.Header().Get(WarningHeader))
+}
+
+func TestRateLimiterMiddleware_CheckOrder(t *testing.T) {
+	type step struct {
+		ip    string
+		token string
+		code  int
+		scope string
+	}
+
+	ok := func(ip, token string) step { return step{ip: ip, token: token, code: http.StatusOK} }
+	limited := func(ip, token, scope string) step {
+		return step{ip: ip, token: token, code: http.StatusTooManyRequests, scope: scope}
+	}
+
+	tests := []struct {
+		name  string
+		order CheckOrder
+		steps []step
+	}{
+		{
+			// O token tem orçamento próprio e não consome o do IP
+			name:  "TokenThenIP",
+			order: TokenThenIP,
+			steps: []step{
+				ok("1", "premium"), ok("1", "premium"), ok("1", "premium"), ok("1", "premium"),
+				limited("1", "premium", ScopeToken),
+				ok("1", ""),
 			},
-			expectedIP: "203.0.113.1",
 		},
 		{
-			name: "Header X-Real-IP",
-			setupRequest: func(r *http.Request) {
-				r.Header.Set("X-Real-IP", "203.0.113.2")
+			// O token é ignorado
+			name:  "IPOnly",
+			order: IPOnly,
+			steps: []step{
+				ok("1", "premium"), ok("1", "premium"),
+				limited("1", "premium", ScopeIP),
 			},
-			expectedIP: "203.0.113.2",
 		},
 		{
-			name: "Fallback RemoteAddr",
-			setupRequest: func(r *http.Request) {
-				r.RemoteAddr = "192.168.1.1:12345"
+			// O token eleva o teto do IP, mas o orçamento continua sendo do IP
+			name:  "TokenRaisesIP",
+			order: TokenRaisesIP,
+			steps: []step{
+				ok("1", "premium"), ok("1", "premium"), ok("1", "premium"), ok("1", "premium"),
+				limited("1", "premium", ScopeIP),
+				limited("1", "", ScopeIP),
+				ok("2", ""), ok("2", ""),
+				limited("2", "", ScopeIP),
+			},
+		},
+		{
+			// Os dois limites precisam ser respeitados; tokens desconhecidos contam só no IP
+			name:  "Both",
+			order: Both,
+			steps: []step{
+				ok("1", "basic"),
+				limited("2", "basic", ScopeToken),
+				ok("1", "premium"),
+				limited("1", "premium", ScopeIP),
+				ok("3", "unknown"), ok("3", "unknown"),
+				limited("3", "unknown", ScopeIP),
 			},
-			expectedIP: "192.168.1.1",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest("GET", "/", nil)
-			tt.setupRequest(req)
+			rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+				Requests:  2,
+				Window:    time.Minute,
+				BlockTime: time.Minute,
+			})
+			rateLimiter.AddTokenConfig("premium", ratelimiter.Config{Requests: 4, Window: time.Minute, BlockTime: time.Minute})
+			rateLimiter.AddTokenConfig("basic", ratelimiter.Config{Requests: 1, Window: time.Minute, BlockTime: time.Minute})
 
-			ip := middleware.getClientIP(req)
-			assert.Equal(t, tt.expectedIP, ip)
+			handler := NewRateLimiterMiddleware(rateLimiter, WithCheckOrder(tt.order)).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			for i, step := range tt.steps {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.RemoteAddr = "192.168.1." + step.ip + ":12345"
+				if step.token != "" {
+					req.Header.Set("API_KEY", step.token)
+				}
+
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, req)
+
+				assert.Equal(t, step.code, recorder.Code, "passo %d", i+1)
+				assert.Equal(t, step.scope, recorder.Header().Get(ScopeHeader), "passo %d", i+1)
+			}
+		})
+	}
+}
+
+func TestRateLimiterMiddleware_CheckOrderBothRejectsUnknownToken(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{Requests: 2, Window: time.Minute})
+	rateLimiter.SetUnknownTokenPolicy(ratelimiter.Reject)
+
+	handler := NewRateLimiterMiddleware(rateLimiter, WithCheckOrder(Both)).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+	req.Header.Set("API_KEY", "unknown")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestRateLimiterMiddleware_KeyFunc(t *testing.T) {
+	storage := ratelimitertest.NewStorage(nil)
+	ipConfig := ratelimiter.Config{
+		Requests:  1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := ratelimiter.NewRateLimiter(storage, ipConfig)
+	middleware := NewRateLimiterMiddleware(rateLimiter)
+
+	// Limita por usuário a partir de um header personalizado
+	userConfig := ratelimiter.Config{
+		Requests:  3,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+	middleware.KeyFunc = func(r *http.Request) (string, ratelimiter.Config, bool) {
+		userID := r.Header.Get("X-User-ID")
+		return "user:" + userID, userConfig, userID != ""
+	}
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(userID, remoteAddr string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+		if userID != "" {
+			req.Header.Set("X-User-ID", userID)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// O mesmo usuário compartilha o limite mesmo vindo de IPs diferentes
+	assert.Equal(t, http.StatusOK, send("42", "192.168.1.1:12345"))
+	assert.Equal(t, http.StatusOK, send("42", "192.168.1.2:12345"))
+	assert.Equal(t, http.StatusOK, send("42", "192.168.1.3:12345"))
+	assert.Equal(t, http.StatusTooManyRequests, send("42", "192.168.1.4:12345"))
+
+	// Outro usuário possui seu próprio limite
+	assert.Equal(t, http.StatusOK, send("7", "192.168.1.1:12345"))
+
+	// Sem a identidade personalizada, a limitação por IP é aplicada
+	assert.Equal(t, http.StatusOK, send("", "192.168.1.5:12345"))
+	assert.Equal(t, http.StatusTooManyRequests, send("", "192.168.1.5:12345"))
+}
+
+func TestRateLimiterMiddleware_UnknownTokenPolicy(t *testing.T) {
+	ipConfig := ratelimiter.Config{
+		Requests:  1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	tests := []struct {
+		name   string
+		policy ratelimiter.UnknownTokenPolicy
+		codes  []int
+	}{
+		// O limite de IP é aplicado em vez de ignorado
+		{name: "FallbackToIP", policy: ratelimiter.FallbackToIP, codes: []int{http.StatusOK, http.StatusTooManyRequests}},
+		// Tokens desconhecidos não são aceitos
+		{name: "Reject", policy: ratelimiter.Reject, codes: []int{http.StatusUnauthorized, http.StatusUnauthorized}},
+		// O token desconhecido recebe a configuração padrão de tokens
+		{name: "AllowWithDefault", policy: ratelimiter.AllowWithDefault, codes: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ipConfig)
+			rateLimiter.SetUnknownTokenPolicy(tt.policy)
+			rateLimiter.SetDefaultTokenConfig(ratelimiter.Config{
+				Requests:  2,
+				Window:    time.Second,
+				BlockTime: time.Minute,
+			})
+
+			middleware := NewRateLimiterMiddleware(rateLimiter)
+			handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			for i, code := range tt.codes {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.RemoteAddr = "192.168.1.1:12345"
+				req.Header.Set("API_KEY", "unknown")
+
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, req)
+				assert.Equal(t, code, recorder.Code, "requisição %d", i+1)
+			}
+		})
+	}
+}
+
+func TestRateLimiterMiddleware_IPTokenLimit(t *testing.T) {
+	storage := ratelimitertest.NewStorage(nil)
+	ipConfig := ratelimiter.Config{
+		Requests:  10,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := ratelimiter.NewRateLimiter(storage, ipConfig)
+	rateLimiter.AddTokenConfig("abc123", ratelimiter.Config{
+		Requests:  10,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	})
+	rateLimiter.SetIPTokenConfig(ratelimiter.Config{
+		Requests:  2,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	})
+
+	middleware := NewRateLimiterMiddleware(rateLimiter)
+	middleware.IPTokenLimit = true
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(remoteAddr string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("API_KEY", "abc123")
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// Dois IPs compartilhando o token possuem cada um o seu próprio limite
+	assert.Equal(t, http.StatusOK, send("192.168.1.1:12345"))
+	assert.Equal(t, http.StatusOK, send("192.168.1.1:12345"))
+	assert.Equal(t, http.StatusTooManyRequests, send("192.168.1.1:12345"))
+
+	assert.Equal(t, http.StatusOK, send("192.168.1.2:12345"))
+	assert.Equal(t, http.StatusOK, send("192.168.1.2:12345"))
+	assert.Equal(t, http.StatusTooManyRequests, send("192.168.1.2:12345"))
+
+	// Apenas as requisições aceitas pelo par consomem o limite do token
+	count, _, err := storage.Get(context.Background(), "token:abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+}
+
+// unusedStorage falha o teste com panic se qualquer método do armazenamento for chamado
+type unusedStorage struct {
+	storage.Storage
+}
+
+func TestRateLimiterMiddleware_Disabled(t *testing.T) {
+	ipConfig := ratelimiter.Config{
+		Requests:  1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := ratelimiter.NewRateLimiter(unusedStorage{}, ipConfig)
+	middleware := NewRateLimiterMiddleware(rateLimiter)
+	assert.True(t, middleware.Enabled)
+	middleware.Enabled = false
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Nenhuma requisição é limitada e o armazenamento nunca é consultado
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		req.Header.Set("API_KEY", "abc123")
+
+		recorder := httptest.NewRecorder()
+		assert.NotPanics(t, func() { handler.ServeHTTP(recorder, req) })
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+}
+
+// tenantKey é a chave de contexto usada pelo middleware de autenticação dos testes
+type tenantKey struct{}
+
+func TestRateLimiterMiddleware_KeyFuncReadsRequestContext(t *testing.T) {
+	ipConfig := ratelimiter.Config{Requests: 10, Window: time.Second, BlockTime: time.Minute}
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ipConfig)
+
+	// Limita por tenant, definido no contexto pelo middleware de autenticação
+	tenantConfig := ratelimiter.Config{Requests: 2, Window: time.Second, BlockTime: time.Minute}
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithKeyFunc(func(r *http.Request) (string, ratelimiter.Config, bool) {
+		tenant, ok := r.Context().Value(tenantKey{}).(string)
+		return "tenant:" + tenant, tenantConfig, ok
+	}))
+
+	limited := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Middleware de autenticação externo ao rate limiter
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tenant := r.Header.Get("Authorization"); tenant != "" {
+			r = r.WithContext(context.WithValue(r.Context(), tenantKey{}, tenant))
+		}
+		limited.ServeHTTP(w, r)
+	})
+
+	send := func(tenant, remoteAddr string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+		if tenant != "" {
+			req.Header.Set("Authorization", tenant)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// O tenant compartilha o limite entre IPs
+	assert.Equal(t, http.StatusOK, send("acme", "192.168.1.1:12345"))
+	assert.Equal(t, http.StatusOK, send("acme", "192.168.1.2:12345"))
+	assert.Equal(t, http.StatusTooManyRequests, send("acme", "192.168.1.3:12345"))
+
+	// Outro tenant e requisições anônimas não são afetados
+	assert.Equal(t, http.StatusOK, send("globex", "192.168.1.1:12345"))
+	assert.Equal(t, http.StatusOK, send("", "192.168.1.1:12345"))
+}
+
+func TestRateLimiterMiddleware_CanceledRequest(t *testing.T) {
+	config := ratelimiter.Config{Requests: 3, Window: time.Second, BlockTime: time.Minute}
+	rateLimiter := ratelimiter.NewRateLimiter(newFailingStorage(), config)
+	middleware := NewRateLimiterMiddleware(rateLimiter)
+
+	handlerCalled := false
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerCalled = true
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+	req.RemoteAddr = "192.168.1.1:12345"
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	// O cliente desistiu: nada é escrito e o handler não é chamado
+	assert.Empty(t, recorder.Body.String())
+	assert.Empty(t, recorder.Header().Get("Retry-After"))
+	assert.False(t, handlerCalled)
+}
+
+func TestRateLimiterMiddleware_StorageFailureFailClosed(t *testing.T) {
+	config := ratelimiter.Config{
+		Requests:  3,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := ratelimiter.NewRateLimiter(newFailingStorage(), config)
+	middleware := NewRateLimiterMiddleware(rateLimiter)
+	middleware.StorageRetryAfter = 10 * time.Second
+
+	handlerCalled := false
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerCalled = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	// Falha de armazenamento é transitória: 503 com Retry-After, nunca 500
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "10", recorder.Header().Get("Retry-After"))
+	assert.False(t, handlerCalled)
+}
+
+func TestRateLimiterMiddleware_StorageFailureDefaultRetryAfter(t *testing.T) {
+	config := ratelimiter.Config{
+		Requests:  3,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := ratelimiter.NewRateLimiter(newFailingStorage(), config)
+	middleware := NewRateLimiterMiddleware(rateLimiter)
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "5", recorder.Header().Get("Retry-After"))
+}
+
+func TestRateLimiterMiddleware_StorageFailureFailOpen(t *testing.T) {
+	config := ratelimiter.Config{
+		Requests:  3,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := ratelimiter.NewRateLimiter(newFailingStorage(), config)
+	middleware := NewRateLimiterMiddleware(rateLimiter)
+	middleware.FailureMode = FailOpen
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	// Em fail-open a requisição segue sem limitação
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestRateLimiterMiddleware_StorageFailureDegraded(t *testing.T) {
+	config := ratelimiter.Config{
+		Requests:  3,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	tests := []struct {
+		name        string
+		failureMode FailureMode
+		expected    int
+		degraded    uint64
+	}{
+		{name: "fail-open", failureMode: FailOpen, expected: http.StatusOK, degraded: 2},
+		{name: "fail-closed", failureMode: FailClosed, expected: http.StatusServiceUnavailable, degraded: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rateLimiterMetrics := metrics.New()
+
+			var hookErrs []error
+			var hookScopes []string
+			rateLimiter := ratelimiter.NewRateLimiter(newFailingStorage(), config)
+			middleware := NewRateLimiterMiddleware(rateLimiter,
+				WithFailureMode(tt.failureMode),
+				WithMetrics(rateLimiterMetrics),
+				WithOnDegraded(func(r *http.Request, scope string, err error) {
+					hookScopes = append(hookScopes, scope)
+					hookErrs = append(hookErrs, err)
+				}),
+			)
+
+			handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.RemoteAddr = "192.168.1.1:12345"
+
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, req)
+				assert.Equal(t, tt.expected, recorder.Code)
+			}
+
+			// Apenas as requisições liberadas sem limitação são contadas e notificadas
+			assert.Equal(t, tt.degraded, rateLimiterMetrics.Degraded.Value(metrics.Labels{Scope: ScopeIP}))
+			assert.Len(t, hookErrs, int(tt.degraded))
+			for i := range hookErrs {
+				assert.Equal(t, ScopeIP, hookScopes[i])
+				assert.ErrorIs(t, hookErrs[i], errStorageDown)
+			}
+			assert.Zero(t, rateLimiterMetrics.Allowed.Total())
+		})
+	}
+}
+
+func TestRateLimiterMiddleware_GetClientIP(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxHops      int
+		setupRequest func(*http.Request)
+		expectedIP   string
+	}{
+		{
+			name: "Header X-Forwarded-For",
+			setupRequest: func(r *http.Request) {
+				r.Header.Set("X-Forwarded-For", "203.0.113.1, 198.51.100.1")
+			},
+			expectedIP: "203.0.113.1",
+		},
+		{
+			name: "Header X-Real-IP",
+			setupRequest: func(r *http.Request) {
+				r.Header.Set("X-Real-IP", "203.0.113.2")
+			},
+			expectedIP: "203.0.113.2",
+		},
+		{
+			name: "Fallback RemoteAddr",
+			setupRequest: func(r *http.Request) {
+				r.RemoteAddr = "192.168.1.1:12345"
+			},
+			expectedIP: "192.168.1.1",
+		},
+		{
+			name: "X-Forwarded-For com porta",
+			setupRequest: func(r *http.Request) {
+				r.Header.Set("X-Forwarded-For", "203.0.113.1:8080, 198.51.100.1")
+			},
+			expectedIP: "203.0.113.1",
+		},
+		{
+			name: "X-Forwarded-For IPv6 com porta",
+			setupRequest: func(r *http.Request) {
+				r.Header.Set("X-Forwarded-For", "[2001:db8::1]:443")
+			},
+			expectedIP: "2001:db8::1",
+		},
+		{
+			name: "X-Forwarded-For no limite de entradas",
+			setupRequest: func(r *http.Request) {
+				r.Header.Set("X-Forwarded-For", "203.0.113.1"+strings.Repeat(", 10.0.0.1", DefaultMaxForwardedHops-1))
+			},
+			expectedIP: "203.0.113.1",
+		},
+		{
+			name: "X-Forwarded-For acima do limite usa X-Real-IP",
+			setupRequest: func(r *http.Request) {
+				r.Header.Set("X-Forwarded-For", "203.0.113.1"+strings.Repeat(", 10.0.0.1", DefaultMaxForwardedHops))
+				r.Header.Set("X-Real-IP", "203.0.113.2")
+			},
+			expectedIP: "203.0.113.2",
+		},
+		{
+			name: "X-Forwarded-For muito longo usa RemoteAddr",
+			setupRequest: func(r *http.Request) {
+				r.Header.Set("X-Forwarded-For", strings.Repeat("10.0.0.1,", 100000))
+				r.RemoteAddr = "192.168.1.1:12345"
+			},
+			expectedIP: "192.168.1.1",
+		},
+		{
+			name:    "limite de entradas configurado",
+			maxHops: 2,
+			setupRequest: func(r *http.Request) {
+				r.Header.Set("X-Forwarded-For", "203.0.113.1, 10.0.0.1, 10.0.0.2")
+				r.RemoteAddr = "192.168.1.1:12345"
+			},
+			expectedIP: "192.168.1.1",
+		},
+		{
+			name: "X-Forwarded-For malformado",
+			setupRequest: func(r *http.Request) {
+				r.Header.Set("X-Forwarded-For", "unknown, 198.51.100.1")
+				r.RemoteAddr = "192.168.1.1:12345"
+			},
+			expectedIP: "192.168.1.1",
+		},
+		{
+			name: "X-Forwarded-For com primeira entrada vazia",
+			setupRequest: func(r *http.Request) {
+				r.Header.Set("X-Forwarded-For", " , 198.51.100.1")
+				r.Header.Set("X-Real-IP", "203.0.113.2")
+			},
+			expectedIP: "203.0.113.2",
+		},
+		{
+			name: "X-Real-IP malformado",
+			setupRequest: func(r *http.Request) {
+				r.Header.Set("X-Real-IP", "<script>")
+				r.RemoteAddr = "192.168.1.1:12345"
+			},
+			expectedIP: "192.168.1.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middleware := &RateLimiterMiddleware{MaxForwardedHops: tt.maxHops}
+
+			req := httptest.NewRequest("GET", "/", nil)
+			tt.setupRequest(req)
+
+			ip := middleware.getClientIP(req)
+			assert.Equal(t, tt.expectedIP, ip)
+		})
+	}
+}
+
+func TestRateLimiterMiddleware_ClientIPStrategy(t *testing.T) {
+	tests := []struct {
+		name       string
+		strategy   ClientIPStrategy
+		forwarded  string
+		realIP     string
+		expectedIP string
+	}{
+		{name: "XForwardedForFirst com ambos os headers", strategy: XForwardedForFirst, forwarded: "203.0.113.1", realIP: "203.0.113.2", expectedIP: "203.0.113.1"},
+		{name: "XForwardedForFirst com X-Forwarded-For inválido", strategy: XForwardedForFirst, forwarded: "unknown", realIP: "203.0.113.2", expectedIP: "203.0.113.2"},
+		{name: "RealIPFirst com ambos os headers", strategy: RealIPFirst, forwarded: "203.0.113.1", realIP: "203.0.113.2", expectedIP: "203.0.113.2"},
+		{name: "RealIPFirst com X-Real-IP inválido", strategy: RealIPFirst, forwarded: "203.0.113.1", realIP: "<script>", expectedIP: "203.0.113.1"},
+		{name: "RealIPFirst sem headers válidos", strategy: RealIPFirst, forwarded: "unknown", realIP: "<script>", expectedIP: "192.168.1.1"},
+		{name: "RemoteAddrOnly com ambos os headers", strategy: RemoteAddrOnly, forwarded: "203.0.113.1", realIP: "203.0.113.2", expectedIP: "192.168.1.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middleware := &RateLimiterMiddleware{ClientIPStrategy: tt.strategy}
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = "192.168.1.1:12345"
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+			req.Header.Set("X-Real-IP", tt.realIP)
+
+			assert.Equal(t, tt.expectedIP, middleware.getClientIP(req))
+		})
+	}
+}
+
+func TestRateLimiterMiddleware_IdempotencyKeyReplaysDecision(t *testing.T) {
+	storage := ratelimitertest.NewStorage(nil)
+	config := ratelimiter.Config{
+		Requests:  5,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := ratelimiter.NewRateLimiter(storage, config)
+	middleware := NewRateLimiterMiddleware(rateLimiter)
+	middleware.Idempotency = true
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(idempotencyKey string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		req.Header.Set(IdempotencyKeyHeader, idempotencyKey)
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// Retentativas com a mesma chave não consomem o limite, até DefaultMaxIdempotentReplays
+	// repetições
+	for i := 0; i <= ratelimiter.DefaultMaxIdempotentReplays; i++ {
+		assert.Equal(t, http.StatusOK, send("retry-1"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, send("retry-1"))
+
+	count, _, err := storage.Get(context.Background(), "ip:192.168.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// Novas chaves consomem os slots restantes e a seguinte excede o limite
+	for _, key := range []string{"retry-2", "retry-3", "retry-4", "retry-5"} {
+		assert.Equal(t, http.StatusOK, send(key))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, send("retry-6"))
+
+	// A retentativa de uma requisição negada não pode contornar o bloqueio ativo
+	assert.Equal(t, http.StatusTooManyRequests, send("retry-1"))
+}
+
+func TestRateLimiterMiddleware_IdempotencyReplayedDenialHeaders(t *testing.T) {
+	storage := ratelimitertest.NewStorage(nil)
+
+	// Sem bloqueio, a negação é repetida pela decisão registrada e não pelo bloqueio ativo
+	rateLimiter := ratelimiter.NewRateLimiter(storage, ratelimiter.Config{Requests: 1, Window: time.Minute})
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithIdempotency(true), WithQuotaHeaders(LegacyQuotaHeaders))
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(idempotencyKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		req.Header.Set(IdempotencyKeyHeader, idempotencyKey)
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusOK, send("first").Code)
+	assert.Equal(t, http.StatusTooManyRequests, send("second").Code)
+
+	// A retentativa negada informa quando tentar novamente e o limite, como a negação original
+	replayed := send("second")
+	assert.Equal(t, http.StatusTooManyRequests, replayed.Code)
+	assert.Equal(t, "60", replayed.Header().Get("Retry-After"))
+	assert.Equal(t, "1", replayed.Header().Get(LimitHeader))
+	assert.Equal(t, "0", replayed.Header().Get(RemainingHeader))
+}
+
+func TestRateLimiterMiddleware_IdempotencyDisabledCountsRetries(t *testing.T) {
+	storage := ratelimitertest.NewStorage(nil)
+	config := ratelimiter.Config{
+		Requests:  2,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := ratelimiter.NewRateLimiter(storage, config)
+	middleware := NewRateLimiterMiddleware(rateLimiter)
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Sem a opção habilitada, cada retentativa é contada normalmente
+	codes := make([]int, 0, 3)
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		req.Header.Set(IdempotencyKeyHeader, "retry-1")
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		codes = append(codes, recorder.Code)
+	}
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+}
+
+func TestRateLimiterMiddleware_RejectStatusCode(t *testing.T) {
+	tests := []struct {
+		name             string
+		rejectStatusCode int
+		expected         int
+	}{
+		{name: "padrão", expected: http.StatusTooManyRequests},
+		{name: "personalizado", rejectStatusCode: http.StatusServiceUnavailable, expected: http.StatusServiceUnavailable},
+		{name: "inválido usa o padrão", rejectStatusCode: http.StatusOK, expected: http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+				Requests:  1,
+				Window:    time.Second,
+				BlockTime: time.Minute,
+			})
+
+			middleware := NewRateLimiterMiddleware(rateLimiter)
+			middleware.RejectStatusCode = tt.rejectStatusCode
+
+			handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			var recorder *httptest.ResponseRecorder
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.RemoteAddr = "192.168.1.1:12345"
+				recorder = httptest.NewRecorder()
+				handler.ServeHTTP(recorder, req)
+			}
+
+			assert.Equal(t, tt.expected, recorder.Code)
+			assert.Equal(t, ScopeIP, recorder.Header().Get(ScopeHeader))
+		})
+	}
+}
+
+func TestRateLimiterMiddleware_ShadowMode(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+		Requests:  2,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	})
+
+	shadowMetrics := metrics.New()
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithShadowMode(true), WithMetrics(shadowMetrics))
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Todas as requisições passam; as que excedem o limite são apenas contadas
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Empty(t, recorder.Header().Get("Retry-After"))
+	}
+
+	assert.Equal(t, uint64(3), shadowMetrics.ShadowBlocks.Value(metrics.Labels{Scope: ScopeIP}))
+	assert.Equal(t, uint64(2), shadowMetrics.Allowed.Value(metrics.Labels{Scope: ScopeIP}))
+	assert.Zero(t, shadowMetrics.Rejected.Total())
+
+	// Sem o modo shadow, o mesmo estado rejeita a requisição
+	middleware.ShadowMode = false
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+	recorder := httptest.NewRecorder()
+	middleware.Handler(http.NotFoundHandler()).ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, uint64(3), shadowMetrics.ShadowBlocks.Total())
+	assert.Equal(t, uint64(1), shadowMetrics.Rejected.Value(metrics.Labels{Scope: ScopeIP}))
+}
+
+func TestRateLimiterMiddleware_MetricLabels(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+		Requests:  1,
+		Window:    time.Minute,
+		BlockTime: time.Minute,
+	})
+	rateLimiter.AddTokenConfig("abc123", ratelimiter.Config{Requests: 2, Window: time.Minute, BlockTime: time.Minute})
+
+	send := func(handler http.Handler, path, token string) {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		if token != "" {
+			req.Header.Set("API_KEY", token)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Sem RouteLabel, as séries são separadas apenas pelo escopo
+	scopeMetrics := metrics.New()
+	handler := NewRateLimiterMiddleware(rateLimiter, WithMetrics(scopeMetrics)).Handler(http.NotFoundHandler())
+
+	send(handler, "/orders/1", "")
+	send(handler, "/orders/2", "")
+	for i := 0; i < 3; i++ {
+		send(handler, "/orders/3", "abc123")
+	}
+
+	assert.Equal(t, uint64(1), scopeMetrics.Allowed.Value(metrics.Labels{Scope: ScopeIP}))
+	assert.Equal(t, uint64(1), scopeMetrics.Rejected.Value(metrics.Labels{Scope: ScopeIP}))
+	assert.Equal(t, uint64(2), scopeMetrics.Allowed.Value(metrics.Labels{Scope: ScopeToken}))
+	assert.Equal(t, uint64(1), scopeMetrics.Rejected.Value(metrics.Labels{Scope: ScopeToken}))
+
+	// Com RouteLabel, o rótulo route vem da função, nunca do caminho bruto
+	routeMetrics := metrics.New()
+	handler = NewRateLimiterMiddleware(rateLimiter,
+		WithMetrics(routeMetrics),
+		WithRouteLabel(func(r *http.Request) string {
+			if strings.HasPrefix(r.URL.Path, "/orders/") {
+				return "/orders/{id}"
+			}
+			return ""
+		}),
+	).Handler(http.NotFoundHandler())
+
+	rateLimiter.AddTokenConfig("def456", ratelimiter.Config{Requests: 1, Window: time.Minute, BlockTime: time.Minute})
+	send(handler, "/orders/1", "def456")
+	send(handler, "/orders/2", "def456")
+	send(handler, "/health", "def456")
+
+	assert.Equal(t, uint64(1), routeMetrics.Allowed.Value(metrics.Labels{Scope: ScopeToken, Route: "/orders/{id}"}))
+	assert.Equal(t, uint64(1), routeMetrics.Rejected.Value(metrics.Labels{Scope: ScopeToken, Route: "/orders/{id}"}))
+	assert.Equal(t, uint64(1), routeMetrics.Rejected.Value(metrics.Labels{Scope: ScopeToken}))
+	assert.Equal(t, uint64(2), routeMetrics.Rejected.Total())
+}
+
+func TestRateLimiterMiddleware_MaxConcurrent(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+		Requests:      100,
+		Window:        time.Minute,
+		BlockTime:     time.Minute,
+		MaxConcurrent: 2,
+	})
+	middleware := NewRateLimiterMiddleware(rateLimiter)
+
+	// Os handlers permanecem em andamento até que release seja fechado
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func() int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	var wg sync.WaitGroup
+	codes := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- serve()
+		}()
+	}
+	<-started
+	<-started
+
+	// Acima do limite de requisições simultâneas, as novas requisições são rejeitadas
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusTooManyRequests, serve())
+	}
+
+	close(release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		assert.Equal(t, http.StatusOK, code)
+	}
+
+	// Ao término dos handlers as vagas são liberadas
+	go func() { <-started }()
+	assert.Equal(t, http.StatusOK, serve())
+}
+
+func TestWrap_ResultInContext(t *testing.T) {
+	rateLimiter, _, _ := ratelimitertest.New(t, ratelimiter.Config{Requests: 3, Window: time.Second, BlockTime: time.Minute})
+
+	var (
+		seen  []ratelimiter.Result
+		found bool
+	)
+	handler := Wrap(rateLimiter, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var result ratelimiter.Result
+		result, found = ratelimiter.ResultFromContext(r.Context())
+		seen = append(seen, result)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.True(t, found)
+	}
+
+	resetAt := ratelimitertest.Epoch.Add(time.Second)
+	assert.Equal(t, []ratelimiter.Result{
+		{Allowed: true, Limit: 3, Window: time.Second, Count: 1, Remaining: 2, ResetAt: resetAt},
+		{Allowed: true, Limit: 3, Window: time.Second, Count: 2, Remaining: 1, ResetAt: resetAt},
+	}, seen)
+}
+
+func TestRateLimiterMiddleware_NoResultInContextWhenDisabled(t *testing.T) {
+	rateLimiter, _, _ := ratelimitertest.New(t, ratelimiter.Config{Requests: 3, Window: time.Second})
+
+	found := true
+	handler := Wrap(rateLimiter, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, found = ratelimiter.ResultFromContext(r.Context())
+	}), WithEnabled(false))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	assert.False(t, found)
+}
+
+func TestRateLimiterMiddleware_RetryAfterDecreasesDuringBlock(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ratelimiter.Config
+	}{
+		{
+			name:   "atomic check",
+			config: ratelimiter.Config{Requests: 1, Window: time.Second, BlockTime: time.Minute},
+		},
+		{
+			// Os tiers verificam o bloqueio antes da contagem em etapas
+			name: "stepwise check",
+			config: ratelimiter.Config{Requests: 1, Window: time.Second, BlockTime: time.Minute, Tiers: []ratelimiter.Config{
+				{Requests: 100, Window: time.Hour, BlockTime: time.Hour},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rateLimiter, _, fakeClock := ratelimitertest.New(t, tt.config)
+			handler := NewRateLimiterMiddleware(rateLimiter).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			send := func() *httptest.ResponseRecorder {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.RemoteAddr = "192.168.1.1:12345"
+
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, req)
+				return recorder
+			}
+
+			assert.Equal(t, http.StatusOK, send().Code)
+
+			recorder := send()
+			assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			assert.Equal(t, "60", recorder.Header().Get("Retry-After"))
+
+			// O Retry-After informa o tempo restante do bloqueio, não o BlockTime completo
+			fakeClock.Advance(20 * time.Second)
+			recorder = send()
+			assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			assert.Equal(t, "40", recorder.Header().Get("Retry-After"))
+			assert.Contains(t, recorder.Body.String(), `"retry_after_seconds":40`)
+
+			fakeClock.Advance(39*time.Second + 500*time.Millisecond)
+			recorder = send()
+			assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			assert.Equal(t, "1", recorder.Header().Get("Retry-After"))
+
+			fakeClock.Advance(500 * time.Millisecond)
+			assert.Equal(t, http.StatusOK, send().Code)
+		})
+	}
+}
+
+func TestRateLimiterMiddleware_RetryAfterOnAlreadyBlockedKey(t *testing.T) {
+	config := ratelimiter.Config{Requests: 5, Window: time.Second, BlockTime: time.Minute}
+	stepwise := config
+	stepwise.Tiers = []ratelimiter.Config{{Requests: 100, Window: time.Hour}}
+
+	tests := []struct {
+		name        string
+		config      ratelimiter.Config
+		limiterOpts []ratelimiter.Option
+		opts        []Option
+	}{
+		{name: "atomic check", config: config},
+		{name: "stepwise check", config: stepwise},
+		{name: "block cache", config: config, limiterOpts: []ratelimiter.Option{ratelimiter.WithBlockCache(time.Minute)}},
+		{name: "check without counting", config: config, opts: []Option{WithCountStatuses(http.StatusUnauthorized)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rateLimiter, storage, fakeClock := ratelimitertest.New(t, tt.config, tt.limiterOpts...)
+			handler := NewRateLimiterMiddleware(rateLimiter, tt.opts...).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			// Bloqueio aplicado fora desta instância (ex: por outra instância ou pelo painel
+			// administrativo), antes de qualquer requisição da chave
+			require.NoError(t, storage.Block(context.Background(), "ip:192.168.1.1", 90*time.Second))
+
+			send := func() *httptest.ResponseRecorder {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.RemoteAddr = "192.168.1.1:12345"
+
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, req)
+				return recorder
+			}
+
+			// A rejeição pelo bloqueio existente informa o tempo restante dele
+			recorder := send()
+			assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			assert.Equal(t, "90", recorder.Header().Get("Retry-After"))
+			assert.Contains(t, recorder.Body.String(), `"retry_after_seconds":90`)
+
+			fakeClock.Advance(30 * time.Second)
+			recorder = send()
+			assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			assert.Equal(t, "60", recorder.Header().Get("Retry-After"))
 		})
 	}
 }
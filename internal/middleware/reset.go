@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ErrNotRateLimited é retornado por ResetForRequest para requisições que não foram verificadas
+// pelo middleware (ex: ignoradas por Skip, com a limitação desligada ou liberadas por FailOpen)
+var ErrNotRateLimited = errors.New("requisição não verificada pelo rate limiter")
+
+// resetCtx é a chave de contexto da função que devolve o limite às identidades da requisição
+type resetCtx struct{}
+
+// contextWithReset retorna um contexto que carrega a função usada por ResetForRequest
+func contextWithReset(ctx context.Context, reset func(ctx context.Context) error) context.Context {
+	return context.WithValue(ctx, resetCtx{}, reset)
+}
+
+// ResetForRequest devolve o limite completo às identidades com que o middleware verificou a
+// requisição (KeyFunc, rota, método, token ou IP), removendo contadores e bloqueios. Usado
+// dentro do handler, permite que um login bem-sucedido zere as tentativas com falha
+// acumuladas. O limite global não é afetado.
+func ResetForRequest(r *http.Request) error {
+	reset, ok := r.Context().Value(resetCtx{}).(func(ctx context.Context) error)
+	if !ok {
+		return ErrNotRateLimited
+	}
+
+	return reset(r.Context())
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter/ratelimitertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loginHandler responde 401 para senhas erradas e, no login bem-sucedido, zera as tentativas
+// com falha do cliente
+func loginHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Password") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		require.NoError(t, ResetForRequest(r))
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestResetForRequest_IP(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+		Requests:  3,
+		Window:    time.Minute,
+		BlockTime: time.Minute,
+	})
+
+	// Apenas as falhas de login consomem o limite
+	handler := Wrap(rateLimiter, loginHandler(t), WithCountStatuses(http.StatusUnauthorized))
+
+	login := func(password string) int {
+		req := httptest.NewRequest("POST", "/login", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		req.Header.Set("X-Password", password)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, login("wrong"))
+	assert.Equal(t, http.StatusUnauthorized, login("wrong"))
+	assert.Equal(t, http.StatusOK, login("secret"))
+
+	// As falhas anteriores ao login foram descartadas: o limite recomeça, e a falha que o
+	// excede bloqueia o IP
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, http.StatusUnauthorized, login("wrong"), "tentativa %d", i+1)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, login("secret"))
+}
+
+func TestResetForRequest_KeyFunc(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+		Requests: 100,
+		Window:   time.Minute,
+	})
+
+	// As tentativas são contadas por usuário, independentemente do IP
+	userConfig := ratelimiter.Config{Requests: 2, Window: time.Minute, BlockTime: time.Minute}
+	handler := Wrap(rateLimiter, loginHandler(t), WithKeyFunc(func(r *http.Request) (string, ratelimiter.Config, bool) {
+		return "login:" + r.Header.Get("X-User"), userConfig, true
+	}))
+
+	login := func(user, password string) int {
+		req := httptest.NewRequest("POST", "/login", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		req.Header.Set("X-User", user)
+		req.Header.Set("X-Password", password)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, login("alice", "wrong"))
+	assert.Equal(t, http.StatusOK, login("alice", "secret"))
+
+	// O reset devolveu as duas requisições do limite, sem afetar outros usuários
+	assert.Equal(t, http.StatusUnauthorized, login("alice", "wrong"))
+	assert.Equal(t, http.StatusUnauthorized, login("alice", "wrong"))
+	assert.Equal(t, http.StatusTooManyRequests, login("alice", "secret"))
+
+	assert.Equal(t, http.StatusUnauthorized, login("bob", "wrong"))
+}
+
+func TestResetForRequest_IPAndToken(t *testing.T) {
+	limit := ratelimiter.Config{Requests: 2, Window: time.Minute, BlockTime: time.Minute}
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), limit,
+		ratelimiter.WithTokenConfig("abc123", limit))
+
+	// Com a ordem Both, o IP e o token são zerados
+	handler := Wrap(rateLimiter, loginHandler(t), WithCheckOrder(Both))
+
+	login := func(password string) int {
+		req := httptest.NewRequest("POST", "/login", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		req.Header.Set("API_KEY", "abc123")
+		req.Header.Set("X-Password", password)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, login("wrong"))
+		assert.Equal(t, http.StatusOK, login("secret"))
+	}
+}
+
+func TestResetForRequest_NotRateLimited(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+		Requests: 1,
+		Window:   time.Minute,
+	})
+
+	var resetErr error
+	handler := Wrap(rateLimiter, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resetErr = ResetForRequest(r)
+	}), WithSkip(SkipPaths("/health")))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+	assert.ErrorIs(t, resetErr, ErrNotRateLimited)
+
+	// Fora do middleware não há identidade a zerar
+	assert.ErrorIs(t, ResetForRequest(httptest.NewRequest("GET", "/", nil)), ErrNotRateLimited)
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+)
+
+// routeKeyNamespace é o namespace das identidades limitadas por rota
+const routeKeyNamespace = "route"
+
+// RouteLimit associa uma configuração de limite completa a uma rota
+type RouteLimit struct {
+	// Path é o caminho exato da rota (ex: "/login") ou, quando termina em "/", o prefixo das
+	// rotas que compartilham o orçamento (ex: "/api/reports/")
+	Path string
+
+	// Config é o limite aplicado, por IP, às requisições da rota, inclusive a janela, o tempo
+	// de bloqueio e as demais opções (ex: Tiers, Algorithm)
+	Config ratelimiter.Config
+}
+
+// routeLimiters são os limites por rota, do caminho mais longo para o mais curto
+type routeLimiters []RouteLimit
+
+// newRouteLimiters ordena as rotas para que a mais específica seja escolhida, descartando
+// caminhos vazios e repetidos (vale a primeira ocorrência)
+func newRouteLimiters(limits []RouteLimit) routeLimiters {
+	seen := make(map[string]struct{}, len(limits))
+	limiters := make(routeLimiters, 0, len(limits))
+	for _, limit := range limits {
+		if _, ok := seen[limit.Path]; ok || limit.Path == "" {
+			continue
+		}
+		seen[limit.Path] = struct{}{}
+		limiters = append(limiters, limit)
+	}
+
+	sort.SliceStable(limiters, func(i, j int) bool {
+		return len(limiters[i].Path) > len(limiters[j].Path)
+	})
+	return limiters
+}
+
+// match retorna o limite da rota mais específica que corresponde ao caminho
+func (l routeLimiters) match(path string) (RouteLimit, bool) {
+	for _, limit := range l {
+		if path == limit.Path || (strings.HasSuffix(limit.Path, "/") && strings.HasPrefix(path, limit.Path)) {
+			return limit, true
+		}
+	}
+	return RouteLimit{}, false
+}
+
+// key retorna a identidade do IP no orçamento da rota, com as partes unidas pelo separador das
+// chaves do rate limiter (ver ratelimiter.KeyNames), para que cada rota tenha contadores e
+// bloqueios próprios
+func (l RouteLimit) key(ip, separator string) string {
+	return routeKeyNamespace + separator + l.Path + separator + ip
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter/ratelimitertest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterMiddleware_RouteLimits(t *testing.T) {
+	rateLimiter, _, fakeClock := ratelimitertest.New(t, ratelimiter.Config{Requests: 100, Window: time.Second})
+
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithRouteLimits(
+		RouteLimit{Path: "/login", Config: ratelimiter.Config{Requests: 2, Window: time.Minute, BlockTime: 10 * time.Minute}},
+		RouteLimit{Path: "/search", Config: ratelimiter.Config{Requests: 2, Window: 10 * time.Second, BlockTime: 30 * time.Second}},
+	))
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// Cada rota tem o seu próprio orçamento: esgotar /login não afeta /search
+	assert.Equal(t, http.StatusOK, send("/login").Code)
+	assert.Equal(t, http.StatusOK, send("/login").Code)
+
+	recorder := send("/login")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, ScopeRoute, recorder.Header().Get(ScopeHeader))
+	assert.Equal(t, "600", recorder.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, send("/search").Code)
+	assert.Equal(t, http.StatusOK, send("/search").Code)
+
+	recorder = send("/search")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "30", recorder.Header().Get("Retry-After"))
+
+	// Rotas sem limite próprio seguem o limite por IP
+	assert.Equal(t, http.StatusOK, send("/orders").Code)
+
+	// Cada rota aplica o seu tempo de bloqueio
+	fakeClock.Advance(30 * time.Second)
+	assert.Equal(t, http.StatusOK, send("/search").Code)
+	assert.Equal(t, http.StatusTooManyRequests, send("/login").Code)
+
+	fakeClock.Advance(10 * time.Minute)
+	assert.Equal(t, http.StatusOK, send("/login").Code)
+}
+
+func TestRateLimiterMiddleware_RouteLimitsWindow(t *testing.T) {
+	rateLimiter, _, fakeClock := ratelimitertest.New(t, ratelimiter.Config{Requests: 100, Window: time.Second})
+
+	// Sem bloqueio, cada rota volta a aceitar requisições ao fim da sua própria janela
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithRouteLimits(
+		RouteLimit{Path: "/a", Config: ratelimiter.Config{Requests: 1, Window: time.Second}},
+		RouteLimit{Path: "/b", Config: ratelimiter.Config{Requests: 1, Window: time.Minute}},
+	))
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(path string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("/a"))
+	assert.Equal(t, http.StatusOK, send("/b"))
+	assert.Equal(t, http.StatusTooManyRequests, send("/a"))
+	assert.Equal(t, http.StatusTooManyRequests, send("/b"))
+
+	fakeClock.Advance(time.Second)
+	assert.Equal(t, http.StatusOK, send("/a"))
+	assert.Equal(t, http.StatusTooManyRequests, send("/b"))
+
+	fakeClock.Advance(time.Minute)
+	assert.Equal(t, http.StatusOK, send("/b"))
+}
+
+func TestRateLimiterMiddleware_RouteLimitsPrefix(t *testing.T) {
+	rateLimiter, _, _ := ratelimitertest.New(t, ratelimiter.Config{Requests: 100, Window: time.Second})
+
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithRouteLimits(
+		RouteLimit{Path: "/api/", Config: ratelimiter.Config{Requests: 2, Window: time.Minute}},
+		RouteLimit{Path: "/api/reports/", Config: ratelimiter.Config{Requests: 1, Window: time.Minute}},
+	))
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(path string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// Os caminhos sob um prefixo compartilham o orçamento, e o prefixo mais longo prevalece
+	assert.Equal(t, http.StatusOK, send("/api/reports/1"))
+	assert.Equal(t, http.StatusTooManyRequests, send("/api/reports/2"))
+
+	assert.Equal(t, http.StatusOK, send("/api/orders"))
+	assert.Equal(t, http.StatusOK, send("/api/users"))
+	assert.Equal(t, http.StatusTooManyRequests, send("/api/orders"))
+}
+
+func TestRouteLimit_Key(t *testing.T) {
+	limits := newRouteLimiters([]RouteLimit{
+		{Path: "/a"},
+		{Path: ""},
+		{Path: "/a", Config: ratelimiter.Config{Requests: 1}},
+		{Path: "/b"},
+	})
+	assert.Len(t, limits, 2)
+
+	a, ok := limits.match("/a")
+	assert.True(t, ok)
+	assert.Zero(t, a.Config.Requests)
+
+	b, _ := limits.match("/b")
+	assert.Equal(t, "route:/a:10.0.0.1", a.key("10.0.0.1", ":"))
+	assert.NotEqual(t, a.key("10.0.0.1", ":"), b.key("10.0.0.1", ":"))
+
+	// Caminhos sem "/" final não funcionam como prefixo
+	_, ok = limits.match("/a/1")
+	assert.False(t, ok)
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+)
+
+// sessionKeyNamespace é o namespace das identidades de sessão
+const sessionKeyNamespace = "session"
+
+// SessionKey limita por sessão de navegador, identificada pelo valor de um cookie (ex: o ID da
+// sessão), usando-a como KeyFunc em aplicações sem chave de API.
+//
+// O valor do cookie é controlado pelo cliente e não é validado: um cliente que envia um valor
+// novo a cada requisição recebe um orçamento novo a cada vez e escapa também da limitação por
+// IP, que não é aplicada às requisições com o cookie. Use SessionKey apenas com sessões
+// emitidas e validadas pelo servidor (ex: por um middleware de autenticação anterior, que
+// rejeite cookies desconhecidos) ou em conjunto com um limite independente do cookie, como o
+// limite global.
+type SessionKey struct {
+	// Cookie é o nome do cookie que identifica a sessão (ex: "session_id")
+	Cookie string
+
+	// Config é o limite aplicado a cada sessão
+	Config ratelimiter.Config
+
+	// Separator separa o namespace do resumo na chave e deve ser o mesmo das chaves do rate
+	// limiter (ver ratelimiter.KeyNames); WithSessionKey o preenche a partir do rate limiter.
+	// Vazio usa o separador padrão.
+	Separator string
+}
+
+// KeyFunc retorna a função para RateLimiterMiddleware.KeyFunc. O valor do cookie é resumido
+// com SHA-256, então o ID da sessão não é gravado no armazenamento. Requisições sem o cookie,
+// ou com ele vazio, não recebem a identidade de sessão e seguem a limitação por token e por IP.
+func (k SessionKey) KeyFunc() func(r *http.Request) (string, ratelimiter.Config, bool) {
+	prefix := sessionKeyNamespace + k.separator()
+
+	return func(r *http.Request) (string, ratelimiter.Config, bool) {
+		if k.Cookie == "" {
+			return "", ratelimiter.Config{}, false
+		}
+
+		cookie, err := r.Cookie(k.Cookie)
+		if err != nil || cookie.Value == "" {
+			return "", ratelimiter.Config{}, false
+		}
+
+		hash := sha256.Sum256([]byte(cookie.Value))
+		return prefix + hex.EncodeToString(hash[:16]), k.Config, true
+	}
+}
+
+// separator retorna o separador das chaves, com o padrão do rate limiter quando vazio
+func (k SessionKey) separator() string {
+	if k.Separator == "" {
+		return ratelimiter.DefaultKeyNames.Separator
+	}
+	return k.Separator
+}
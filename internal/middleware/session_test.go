@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter/ratelimitertest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterMiddleware_SessionKey(t *testing.T) {
+	ipConfig := ratelimiter.Config{Requests: 1, Window: time.Second, BlockTime: time.Minute}
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ipConfig)
+
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithSessionKey(SessionKey{
+		Cookie: "session_id",
+		Config: ratelimiter.Config{Requests: 2, Window: time.Second, BlockTime: time.Minute},
+	}))
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(session, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+		if session != "" {
+			req.AddCookie(&http.Cookie{Name: "session_id", Value: session})
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// A mesma sessão compartilha o orçamento mesmo vindo de IPs diferentes
+	assert.Equal(t, http.StatusOK, send("abc", "192.168.1.1:12345").Code)
+	assert.Equal(t, http.StatusOK, send("abc", "192.168.1.2:12345").Code)
+
+	recorder := send("abc", "192.168.1.3:12345")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, ScopeCustom, recorder.Header().Get(ScopeHeader))
+
+	// Outra sessão no mesmo IP tem orçamento próprio
+	assert.Equal(t, http.StatusOK, send("def", "192.168.1.1:12345").Code)
+
+	// Sem o cookie, vale a limitação por IP
+	assert.Equal(t, http.StatusOK, send("", "192.168.1.9:12345").Code)
+
+	recorder = send("", "192.168.1.9:12345")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, ScopeIP, recorder.Header().Get(ScopeHeader))
+}
+
+func TestSessionKey_KeyFunc(t *testing.T) {
+	config := ratelimiter.Config{Requests: 5, Window: time.Minute}
+	keyFunc := SessionKey{Cookie: "session_id", Config: config}.KeyFunc()
+
+	request := func(cookies ...*http.Cookie) *http.Request {
+		req := httptest.NewRequest("GET", "/", nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		return req
+	}
+
+	key, cfg, ok := keyFunc(request(&http.Cookie{Name: "session_id", Value: "secret-session"}))
+	assert.True(t, ok)
+	assert.Equal(t, config, cfg)
+	assert.True(t, strings.HasPrefix(key, sessionKeyNamespace+":"))
+
+	// O resumo tem tamanho fixo e não expõe o ID da sessão
+	assert.Len(t, key, len(sessionKeyNamespace+":")+32)
+	assert.NotContains(t, key, "secret-session")
+
+	other, _, _ := keyFunc(request(&http.Cookie{Name: "session_id", Value: "other-session"}))
+	assert.NotEqual(t, key, other)
+
+	// Cookies de outros nomes, ausentes ou vazios não geram identidade
+	_, _, ok = keyFunc(request(&http.Cookie{Name: "theme", Value: "dark"}))
+	assert.False(t, ok)
+
+	_, _, ok = keyFunc(request())
+	assert.False(t, ok)
+
+	_, _, ok = keyFunc(request(&http.Cookie{Name: "session_id", Value: ""}))
+	assert.False(t, ok)
+
+	// Sem nome de cookie configurado a identidade de sessão não é usada
+	_, _, ok = SessionKey{Config: config}.KeyFunc()(request(&http.Cookie{Name: "session_id", Value: "x"}))
+	assert.False(t, ok)
+}
+
+func TestWithSessionKey_KeySeparator(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{Requests: 1, Window: time.Second},
+		ratelimiter.WithKeyNames(ratelimiter.KeyNames{Separator: "."}))
+
+	// A identidade de sessão segue o separador das chaves do rate limiter
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithSessionKey(SessionKey{
+		Cookie: "session_id",
+		Config: ratelimiter.Config{Requests: 2, Window: time.Second},
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: "abc"})
+
+	key, _, ok := middleware.KeyFunc(req)
+	assert.True(t, ok)
+	assert.True(t, strings.HasPrefix(key, "session."), key)
+	assert.NotContains(t, key, ":")
+}
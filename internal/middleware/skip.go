@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// IsUpgradeRequest indica se a requisição pede a troca de protocolo (ex: WebSocket), ou seja,
+// se possui "Upgrade" entre os valores do header Connection e um header Upgrade. Pode ser usado
+// como Skip para isentar as conexões de longa duração da limitação.
+func IsUpgradeRequest(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+
+	for _, value := range r.Header.Values("Connection") {
+		for _, option := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(option), "upgrade") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// SkipPaths retorna um predicado para Skip que isenta as requisições cujo caminho é exatamente
+// um dos informados (ex: "/health", "/metrics")
+func SkipPaths(paths ...string) func(r *http.Request) bool {
+	skipped := make(map[string]struct{}, len(paths))
+	for _, path := range paths {
+		skipped[path] = struct{}{}
+	}
+
+	return func(r *http.Request) bool {
+		_, ok := skipped[r.URL.Path]
+		return ok
+	}
+}
+
+// skip indica se a requisição está isenta, pelo segredo de BypassHeader ou pelo predicado Skip
+func (m *RateLimiterMiddleware) skip(r *http.Request) bool {
+	return m.bypassed(r) || (m.Skip != nil && m.Skip(r))
+}
+
+// bypassed indica se a requisição traz o segredo de BypassHeader. A comparação em tempo
+// constante não revela, pelo tempo de resposta, quantos bytes do segredo foram acertados.
+func (m *RateLimiterMiddleware) bypassed(r *http.Request) bool {
+	if m.BypassHeader == "" || m.BypassSecret == "" {
+		return false
+	}
+
+	value := r.Header.Get(m.BypassHeader)
+	return subtle.ConstantTimeCompare([]byte(value), []byte(m.BypassSecret)) == 1
+}
@@ -0,0 +1,241 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter/ratelimitertest"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsUpgradeRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		connection []string
+		upgrade    string
+		expected   bool
+	}{
+		{name: "WebSocket", connection: []string{"Upgrade"}, upgrade: "websocket", expected: true},
+		{name: "Upgrade entre outras opções", connection: []string{"keep-alive, Upgrade"}, upgrade: "websocket", expected: true},
+		{name: "Upgrade em minúsculas", connection: []string{"upgrade"}, upgrade: "websocket", expected: true},
+		{name: "Upgrade em header repetido", connection: []string{"keep-alive", "Upgrade"}, upgrade: "websocket", expected: true},
+		{name: "sem header Upgrade", connection: []string{"Upgrade"}},
+		{name: "Connection sem Upgrade", connection: []string{"keep-alive"}, upgrade: "websocket"},
+		{name: "requisição comum"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/ws", nil)
+			for _, value := range tt.connection {
+				req.Header.Add("Connection", value)
+			}
+			if tt.upgrade != "" {
+				req.Header.Set("Upgrade", tt.upgrade)
+			}
+
+			assert.Equal(t, tt.expected, IsUpgradeRequest(req))
+		})
+	}
+}
+
+func TestRateLimiterMiddleware_SkipUpgradeRequests(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ratelimiter.Config{
+		Requests:  1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	})
+
+	middleware := NewRateLimiterMiddleware(rateLimiter)
+	middleware.Skip = IsUpgradeRequest
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	newRequest := func(upgrade bool) *http.Request {
+		req := httptest.NewRequest("GET", "/ws", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		if upgrade {
+			req.Header.Set("Connection", "Upgrade")
+			req.Header.Set("Upgrade", "websocket")
+		}
+		return req
+	}
+
+	// Requisições de upgrade não são contadas nem limitadas
+	for i := 0; i < 3; i++ {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, newRequest(true))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+
+	// As demais continuam limitadas, com o orçamento intacto
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, newRequest(false))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, newRequest(false))
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+
+	// Mesmo com o IP bloqueado, o upgrade é isento
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, newRequest(true))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestRateLimiterMiddleware_SkipDoesNotTouchStorage(t *testing.T) {
+	rateLimiter := ratelimiter.NewRateLimiter(unusedStorage{}, ratelimiter.Config{
+		Requests:  1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	})
+
+	middleware := NewRateLimiterMiddleware(rateLimiter)
+	middleware.Skip = func(r *http.Request) bool {
+		return true
+	}
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestSkipPaths(t *testing.T) {
+	skip := SkipPaths("/health", "/metrics")
+
+	assert.True(t, skip(httptest.NewRequest("GET", "/health", nil)))
+	assert.True(t, skip(httptest.NewRequest("GET", "/metrics?format=text", nil)))
+	assert.False(t, skip(httptest.NewRequest("GET", "/health/details", nil)))
+	assert.False(t, skip(httptest.NewRequest("GET", "/", nil)))
+	assert.False(t, SkipPaths()(httptest.NewRequest("GET", "/health", nil)))
+}
+
+func TestRateLimiterMiddleware_SkippedPathsNeverHitStorage(t *testing.T) {
+	store := &countingCallsStorage{Storage: ratelimitertest.NewStorage(nil)}
+	rateLimiter := ratelimiter.NewRateLimiter(store, ratelimiter.Config{
+		Requests:  1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	})
+
+	middleware := NewRateLimiterMiddleware(rateLimiter)
+	middleware.Skip = SkipPaths("/health")
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(path string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		req.Header.Set(DefaultAPIKeyHeader, "abc123")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, serve("/health"))
+	}
+	assert.Zero(t, store.calls)
+
+	// Demais caminhos continuam limitados; com o cliente bloqueado, o health check ainda passa
+	assert.Equal(t, http.StatusOK, serve("/"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("/"))
+	calls := store.calls
+	assert.NotZero(t, calls)
+
+	assert.Equal(t, http.StatusOK, serve("/health"))
+	assert.Equal(t, calls, store.calls)
+}
+
+func TestRateLimiterMiddleware_BypassSecret(t *testing.T) {
+	store := &countingCallsStorage{Storage: ratelimitertest.NewStorage(nil)}
+	rateLimiter := ratelimiter.NewRateLimiter(store, ratelimiter.Config{
+		Requests:  1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	})
+
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithBypassSecret("X-Internal-Secret", "s3cr3t"))
+
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(secret string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		if secret != "" {
+			req.Header.Set("X-Internal-Secret", secret)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// O segredo correto isenta a requisição sem consultar o armazenamento
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serve("s3cr3t"))
+	}
+	assert.Zero(t, store.calls)
+
+	// Segredos incorretos, inclusive prefixos do correto, são limitados normalmente
+	assert.Equal(t, http.StatusOK, serve("wrong"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("s3cr3"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("s3cr3t "))
+	assert.Equal(t, http.StatusTooManyRequests, serve(""))
+
+	// Com o IP bloqueado, o segredo correto ainda passa
+	assert.Equal(t, http.StatusOK, serve("s3cr3t"))
+}
+
+func TestRateLimiterMiddleware_BypassSecretDisabled(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Internal-Secret", "")
+
+	// Sem segredo configurado, um header vazio não isenta a requisição
+	middleware := &RateLimiterMiddleware{BypassHeader: "X-Internal-Secret"}
+	assert.False(t, middleware.skip(req))
+
+	middleware = &RateLimiterMiddleware{BypassSecret: "s3cr3t"}
+	req.Header.Set("X-Internal-Secret", "s3cr3t")
+	assert.False(t, middleware.skip(req))
+}
+
+// countingCallsStorage conta as chamadas que alteram ou consultam o limite
+type countingCallsStorage struct {
+	storage.Storage
+	calls int
+}
+
+func (s *countingCallsStorage) CheckAndIncrement(ctx context.Context, key string, limit storage.Limit) (storage.Decision, error) {
+	s.calls++
+	return s.Storage.CheckAndIncrement(ctx, key, limit)
+}
+
+func (s *countingCallsStorage) Increment(ctx context.Context, key string, expiration time.Duration) (int64, bool, error) {
+	s.calls++
+	return s.Storage.Increment(ctx, key, expiration)
+}
+
+func (s *countingCallsStorage) IncrementBy(ctx context.Context, key string, amount int64, expiration time.Duration) (int64, error) {
+	s.calls++
+	return s.Storage.IncrementBy(ctx, key, amount, expiration)
+}
+
+func (s *countingCallsStorage) IsBlocked(ctx context.Context, key string) (bool, time.Duration, error) {
+	s.calls++
+	return s.Storage.IsBlocked(ctx, key)
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter/ratelimitertest"
+	"github.com/stretchr/testify/assert"
+)
+
+// newWaitTestHandler cria um handler com MaxWait que conta as requisições atendidas
+func newWaitTestHandler(ipConfig ratelimiter.Config, maxWait time.Duration) (http.Handler, *int) {
+	rateLimiter := ratelimiter.NewRateLimiter(ratelimitertest.NewStorage(nil), ipConfig)
+	middleware := NewRateLimiterMiddleware(rateLimiter, WithMaxWait(maxWait))
+
+	served := 0
+	handler := middleware.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+		w.WriteHeader(http.StatusOK)
+	}))
+	return handler, &served
+}
+
+func TestRateLimiterMiddleware_MaxWaitEventuallyAllowed(t *testing.T) {
+	handler, served := newWaitTestHandler(ratelimiter.Config{Requests: 1, Window: 100 * time.Millisecond}, time.Second)
+
+	send := func() int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send())
+
+	// A requisição acima do limite aguarda a próxima janela em vez de ser rejeitada
+	start := time.Now()
+	assert.Equal(t, http.StatusOK, send())
+	assert.Greater(t, time.Since(start), 50*time.Millisecond)
+	assert.Equal(t, 2, *served)
+}
+
+func TestRateLimiterMiddleware_MaxWaitTimedOut(t *testing.T) {
+	handler, served := newWaitTestHandler(ratelimiter.Config{Requests: 1, Window: 10 * time.Second}, 50*time.Millisecond)
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusOK, send().Code)
+
+	// A próxima vaga está além de MaxWait: a rejeição é imediata
+	start := time.Now()
+	recorder := send()
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "10", recorder.Header().Get("Retry-After"))
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, 1, *served)
+}
+
+func TestRateLimiterMiddleware_MaxWaitCanceledRequest(t *testing.T) {
+	handler, served := newWaitTestHandler(ratelimiter.Config{Requests: 1, Window: 10 * time.Second}, time.Minute)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// O cliente desiste durante a espera: nada é respondido e o handler não é chamado
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	recorder := httptest.NewRecorder()
+	start := time.Now()
+	handler.ServeHTTP(recorder, req.WithContext(ctx))
+
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Empty(t, recorder.Body.String())
+	assert.Equal(t, 1, *served)
+}
@@ -0,0 +1,67 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+)
+
+// AnonymousKey é a chave de armazenamento do orçamento anônimo; com AnonymousPerIP, cada IP
+// usa AnonymousKey seguida do separador e do IP (ex: "anonymous:192.168.1.1")
+const AnonymousKey = "anonymous"
+
+// AnonymousScope define como o orçamento das requisições sem token é dividido
+type AnonymousScope int
+
+const (
+	// AnonymousShared mantém um único orçamento para todas as requisições sem token
+	AnonymousShared AnonymousScope = iota
+
+	// AnonymousPerIP mantém um orçamento anônimo por IP, separado do orçamento de CheckIP
+	AnonymousPerIP
+)
+
+// ErrAnonymousNotConfigured é retornado por CheckAnonymous e ResetAnonymous quando nenhuma
+// configuração anônima foi definida
+var ErrAnonymousNotConfigured = errors.New("configuração de limitação anônima não definida")
+
+// SetAnonymousConfig define o limite das requisições sem token, aplicado por CheckAnonymous
+// com o orçamento compartilhado ou por IP conforme scope (ex: 100 req/min para todo o
+// tráfego anônimo, independentemente do limite de cada IP)
+func (rl *RateLimiter) SetAnonymousConfig(config Config, scope AnonymousScope) {
+	rl.anonymousConfig = &config
+	rl.anonymousScope = scope
+}
+
+// AnonymousScope retorna como o orçamento anônimo é dividido
+func (rl *RateLimiter) AnonymousScope() AnonymousScope {
+	return rl.anonymousScope
+}
+
+// CheckAnonymous verifica se uma requisição sem token, vinda do IP informado, tem permissão
+// pelo limite anônimo. Com AnonymousShared o IP é ignorado e todas as instâncias que
+// compartilham o armazenamento dividem o mesmo contador (AnonymousKey).
+func (rl *RateLimiter) CheckAnonymous(ctx context.Context, ip string) (Result, error) {
+	if rl.anonymousConfig == nil {
+		return Result{}, ErrAnonymousNotConfigured
+	}
+
+	return rl.checkLimit(ctx, rl.keyPrefix+rl.anonymousKey(ip), *rl.anonymousConfig)
+}
+
+// ResetAnonymous devolve o limite completo ao orçamento anônimo do IP, como ResetIP. Com
+// AnonymousShared, zera o orçamento compartilhado por todas as requisições sem token.
+func (rl *RateLimiter) ResetAnonymous(ctx context.Context, ip string) error {
+	if rl.anonymousConfig == nil {
+		return ErrAnonymousNotConfigured
+	}
+
+	return rl.reset(ctx, rl.keyPrefix+rl.anonymousKey(ip), *rl.anonymousConfig)
+}
+
+// anonymousKey retorna a chave do orçamento anônimo do IP, sem o prefixo de SetKeyPrefix
+func (rl *RateLimiter) anonymousKey(ip string) string {
+	if rl.anonymousScope == AnonymousPerIP {
+		return AnonymousKey + rl.keys.separator + ip
+	}
+	return AnonymousKey
+}
@@ -0,0 +1,97 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_CheckAnonymousShared(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{Requests: 5, Window: time.Second, BlockTime: time.Minute},
+		WithClock(fakeClock), WithAnonymousConfig(Config{Requests: 2, Window: time.Second}, AnonymousShared))
+	ctx := context.Background()
+
+	// Todos os IPs dividem o mesmo orçamento anônimo
+	for _, ip := range []string{"192.168.1.1", "192.168.1.2"} {
+		result, err := rateLimiter.CheckAnonymous(ctx, ip)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+
+	result, err := rateLimiter.CheckAnonymous(ctx, "192.168.1.3")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// O orçamento de cada IP não é consumido, e Peek resolve a configuração anônima
+	result, err = rateLimiter.Peek(ctx, "ip:192.168.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), result.Remaining)
+
+	result, err = rateLimiter.Peek(ctx, AnonymousKey)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.Limit)
+	assert.Equal(t, int64(3), result.Count)
+
+	// ResetAnonymous zera o orçamento compartilhado
+	require.NoError(t, rateLimiter.ResetAnonymous(ctx, "192.168.1.3"))
+	result, err = rateLimiter.CheckAnonymous(ctx, "192.168.1.3")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestRateLimiter_CheckAnonymousPerIP(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{Requests: 5, Window: time.Second, BlockTime: time.Minute},
+		WithClock(fakeClock))
+	rateLimiter.SetAnonymousConfig(Config{Requests: 1, Window: time.Second, BlockTime: time.Minute}, AnonymousPerIP)
+	assert.Equal(t, AnonymousPerIP, rateLimiter.AnonymousScope())
+	ctx := context.Background()
+
+	result, err := rateLimiter.CheckAnonymous(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = rateLimiter.CheckAnonymous(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, result.Blocked)
+
+	// Cada IP tem o seu orçamento anônimo, separado do orçamento de CheckIP
+	result, err = rateLimiter.CheckAnonymous(ctx, "192.168.1.2")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	keys, err := rateLimiter.BlockedKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"anonymous:192.168.1.1"}, keys)
+
+	result, err = rateLimiter.Peek(ctx, "anonymous:192.168.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Limit)
+	assert.True(t, result.Blocked)
+}
+
+func TestRateLimiter_CheckAnonymousNotConfigured(t *testing.T) {
+	rateLimiter := NewRateLimiter(new(MockStorage), Config{Requests: 1, Window: time.Second})
+
+	_, err := rateLimiter.CheckAnonymous(context.Background(), "192.168.1.1")
+	assert.ErrorIs(t, err, ErrAnonymousNotConfigured)
+
+	err = rateLimiter.ResetAnonymous(context.Background(), "192.168.1.1")
+	assert.ErrorIs(t, err, ErrAnonymousNotConfigured)
+}
@@ -0,0 +1,136 @@
+package ratelimiter
+
+import (
+	"sync"
+	"time"
+)
+
+// maxCachedBlocks é o número máximo de bloqueios em cache. Com o cache cheio, novos bloqueios
+// não são guardados e as suas requisições consultam o armazenamento normalmente.
+const maxCachedBlocks = 10000
+
+// blockCache mantém em memória o instante até o qual cada chave está bloqueada, para que as
+// requisições de uma chave bloqueada sejam rejeitadas sem consultar o armazenamento. Apenas
+// bloqueios são guardados: uma decisão permitida sempre consulta o armazenamento.
+type blockCache struct {
+	maxTTL   time.Duration
+	capacity int
+
+	mu      sync.Mutex
+	entries map[string]cachedBlock
+
+	// nextSweep é o instante da próxima remoção das entradas expiradas. Como nenhuma entrada
+	// dura mais que maxTTL, varrer o cache uma vez a cada maxTTL basta para descartá-las.
+	nextSweep time.Time
+}
+
+// cachedBlock é um bloqueio em cache: blockedUntil é o fim do bloqueio, informado no
+// RetryAfter, e expiresAt o fim da validade da entrada, limitada a maxTTL
+type cachedBlock struct {
+	blockedUntil time.Time
+	expiresAt    time.Time
+}
+
+// newBlockCache cria um blockCache que guarda cada bloqueio por no máximo maxTTL
+func newBlockCache(maxTTL time.Duration) *blockCache {
+	return &blockCache{
+		maxTTL:   maxTTL,
+		capacity: maxCachedBlocks,
+		entries:  make(map[string]cachedBlock),
+	}
+}
+
+// get retorna o tempo restante do bloqueio em cache da chave, se houver
+func (c *blockCache) get(key string, now time.Time) (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return 0, false
+	}
+	if !now.Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return 0, false
+	}
+	return entry.blockedUntil.Sub(now), true
+}
+
+// set guarda o bloqueio da chave, com o tempo restante informado, por no máximo maxTTL. Com o
+// cache cheio, apenas as chaves já guardadas são atualizadas.
+func (c *blockCache) set(key string, now time.Time, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !now.Before(c.nextSweep) {
+		for cached, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, cached)
+			}
+		}
+		c.nextSweep = now.Add(c.maxTTL)
+	}
+
+	if _, cached := c.entries[key]; !cached && len(c.entries) >= c.capacity {
+		return
+	}
+	c.entries[key] = cachedBlock{
+		blockedUntil: now.Add(ttl),
+		expiresAt:    now.Add(min(ttl, c.maxTTL)),
+	}
+}
+
+// remove descarta o bloqueio em cache da chave
+func (c *blockCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+}
+
+// SetBlockCache habilita um cache local de bloqueios: depois que uma chave é vista bloqueada,
+// as suas requisições são rejeitadas sem consultar o armazenamento por até maxTTL (ou até o fim
+// do bloqueio, se antes). Reduz as idas ao Redis de chaves bloqueadas muito ativas, ao custo de
+// um desbloqueio feito por outra instância (ex: Reset no armazenamento) só ser percebido depois
+// que o bloqueio em cache expirar. Decisões permitidas nunca são guardadas. Zero desabilita o
+// cache.
+func (rl *RateLimiter) SetBlockCache(maxTTL time.Duration) {
+	if maxTTL <= 0 {
+		rl.blockCache = nil
+		return
+	}
+	rl.blockCache = newBlockCache(maxTTL)
+}
+
+// InvalidateBlockCache descarta o bloqueio em cache de uma chave de armazenamento (ex:
+// "ip:192.168.1.1", no formato de Peek, sem o prefixo de SetKeyPrefix), para que um
+// desbloqueio feito nesta instância valha imediatamente
+func (rl *RateLimiter) InvalidateBlockCache(key string) {
+	if rl.blockCache != nil {
+		rl.blockCache.remove(rl.keyPrefix + key)
+	}
+}
+
+// cachedBlock retorna a rejeição de uma chave com bloqueio em cache
+func (rl *RateLimiter) cachedBlock(key string, config Config) (Result, bool) {
+	if rl.blockCache == nil {
+		return Result{}, false
+	}
+
+	ttl, blocked := rl.blockCache.get(key, rl.clock.Now())
+	if !blocked {
+		return Result{}, false
+	}
+	return Result{Allowed: false, Limit: config.Requests, Window: config.Window, Blocked: true, RetryAfter: ttl}, true
+}
+
+// cacheBlock guarda o bloqueio de um resultado bloqueado com tempo restante conhecido
+func (rl *RateLimiter) cacheBlock(key string, result Result) {
+	if rl.blockCache != nil && result.Blocked && result.RetryAfter > 0 {
+		rl.blockCache.set(key, rl.clock.Now(), result.RetryAfter)
+	}
+}
@@ -0,0 +1,156 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_BlockCacheSkipsStorage(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	mockStorage := new(MockStorage)
+	config := Config{Requests: 1, Window: time.Second, BlockTime: time.Minute}
+	limit := storage.Limit{Requests: 1, Window: time.Second, BlockTime: time.Minute}
+
+	rateLimiter := NewRateLimiter(mockStorage, config, WithClock(fakeClock), WithBlockCache(10*time.Second))
+	ctx := context.Background()
+
+	mockStorage.On("CheckAndIncrement", ctx, "ip:192.168.1.1", limit).Return(storage.Decision{Allowed: true, Count: 1, TTL: time.Second}, nil).Once()
+	mockStorage.On("CheckAndIncrement", ctx, "ip:192.168.1.1", limit).Return(storage.Decision{Count: 2, Blocked: true, NewlyBlocked: true, TTL: time.Minute}, nil).Once()
+
+	result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, result.Blocked)
+
+	// Enquanto o bloqueio está em cache, as requisições não chegam ao armazenamento, e o
+	// RetryAfter é o tempo restante do bloqueio, não o da entrada em cache
+	for i := 0; i < 5; i++ {
+		fakeClock.Advance(time.Second)
+
+		result, err = rateLimiter.CheckIP(ctx, "192.168.1.1")
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.True(t, result.Blocked)
+		assert.Equal(t, time.Duration(59-i)*time.Second, result.RetryAfter)
+	}
+	mockStorage.AssertNumberOfCalls(t, "CheckAndIncrement", 2)
+
+	// Após a duração máxima do cache o armazenamento volta a ser consultado, o que percebe um
+	// desbloqueio feito por outra instância
+	fakeClock.Advance(5 * time.Second)
+	mockStorage.On("CheckAndIncrement", ctx, "ip:192.168.1.1", limit).Return(storage.Decision{Allowed: true, Count: 1, TTL: time.Second}, nil).Once()
+
+	result, err = rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	mockStorage.AssertNumberOfCalls(t, "CheckAndIncrement", 3)
+}
+
+func TestRateLimiter_BlockCacheDoesNotCacheAllowed(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	mockStorage := new(MockStorage)
+	config := Config{Requests: 100, Window: time.Second, BlockTime: time.Minute}
+
+	rateLimiter := NewRateLimiter(mockStorage, config, WithClock(fakeClock), WithBlockCache(time.Minute))
+	ctx := context.Background()
+
+	mockStorage.On("CheckAndIncrement", ctx, "ip:192.168.1.1", mock.Anything).Return(storage.Decision{Allowed: true, Count: 1, TTL: time.Second}, nil)
+
+	for i := 0; i < 3; i++ {
+		result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+	mockStorage.AssertNumberOfCalls(t, "CheckAndIncrement", 3)
+}
+
+func TestRateLimiter_BlockCacheFollowsBlockExpiry(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	config := Config{Requests: 1, Window: time.Second, BlockTime: 5 * time.Second}
+	rateLimiter := NewRateLimiter(memoryStorage, config, WithClock(fakeClock), WithBlockCache(time.Minute))
+	ctx := context.Background()
+
+	check := func() Result {
+		result, err := rateLimiter.CheckIP(ctx, "10.0.0.1")
+		require.NoError(t, err)
+		return result
+	}
+
+	assert.True(t, check().Allowed)
+	assert.True(t, check().Blocked)
+
+	// O cache não prolonga o bloqueio além do seu fim
+	fakeClock.Advance(5 * time.Second)
+	assert.True(t, check().Allowed)
+	assert.True(t, check().Blocked)
+
+	// Um desbloqueio nesta instância é aplicado imediatamente após InvalidateBlockCache
+	require.NoError(t, memoryStorage.Reset(ctx, "ip:10.0.0.1"))
+	assert.True(t, check().Blocked)
+
+	rateLimiter.InvalidateBlockCache("ip:10.0.0.1")
+	assert.True(t, check().Allowed)
+
+	// Zero desabilita o cache
+	rateLimiter.SetBlockCache(0)
+	assert.True(t, check().Blocked)
+	require.NoError(t, memoryStorage.Reset(ctx, "ip:10.0.0.1"))
+	assert.True(t, check().Allowed)
+}
+
+func TestBlockCache_Bounded(t *testing.T) {
+	now := time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC)
+	cache := newBlockCache(time.Minute)
+	cache.capacity = 3
+
+	for _, key := range []string{"a", "b", "c"} {
+		cache.set(key, now, time.Hour)
+	}
+
+	// Com o cache cheio, novas chaves não são guardadas, mas as existentes são atualizadas
+	cache.set("d", now, time.Hour)
+	_, ok := cache.get("d", now)
+	assert.False(t, ok)
+	assert.Len(t, cache.entries, 3)
+
+	cache.set("a", now, 30*time.Second)
+	ttl, ok := cache.get("a", now)
+	require.True(t, ok)
+	assert.Equal(t, 30*time.Second, ttl)
+
+	// As entradas expiradas são descartadas na varredura seguinte, liberando espaço
+	now = now.Add(time.Minute)
+	cache.set("d", now, time.Hour)
+	_, ok = cache.get("d", now)
+	assert.True(t, ok)
+	assert.Len(t, cache.entries, 1)
+}
+
+func TestBlockCache_SweepsOncePerMaxTTL(t *testing.T) {
+	now := time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC)
+	cache := newBlockCache(time.Minute)
+
+	cache.set("a", now, time.Second)
+
+	// Antes do intervalo de varredura, as entradas expiradas não são procuradas a cada inserção
+	now = now.Add(2 * time.Second)
+	cache.set("b", now, time.Second)
+	assert.Len(t, cache.entries, 2)
+
+	now = now.Add(time.Minute)
+	cache.set("c", now, time.Second)
+	assert.Len(t, cache.entries, 1)
+}
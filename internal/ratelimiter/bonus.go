@@ -0,0 +1,56 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// bonusKeySuffix é o sufixo da chave auxiliar com o bônus concedido por GrantBonus
+const bonusKeySuffix = "bonus"
+
+// ErrInvalidBonus é retornado por GrantBonus quando a quantidade ou a duração do bônus não
+// são positivas
+var ErrInvalidBonus = errors.New("bônus deve ter quantidade e duração positivas")
+
+// GrantBonus concede à chave (ex: "token:abc123", no formato de Peek) extra requisições além
+// do limite da sua configuração até ttl, por exemplo para liberar um pico pontual a um
+// cliente. Bônus concedidos enquanto outro está ativo se somam a ele e expiram junto com o
+// primeiro. O bônus eleva apenas Requests, não os Tiers, e não libera um bloqueio já ativo.
+//
+// Conceder um bônus habilita a consulta dos bônus nesta instância (ver SetBonusGrants); as
+// demais instâncias que compartilham o armazenamento devem habilitá-la explicitamente.
+func (rl *RateLimiter) GrantBonus(ctx context.Context, key string, extra int64, ttl time.Duration) error {
+	if extra <= 0 || ttl <= 0 {
+		return ErrInvalidBonus
+	}
+
+	_, err := rl.storage.IncrementBy(ctx, rl.subKey(rl.keyPrefix+key, bonusKeySuffix), extra, ttl)
+	if err != nil {
+		return storageError(ErrBonusFailed, err)
+	}
+
+	rl.bonusGrants.Store(true)
+	return nil
+}
+
+// SetBonusGrants define se as verificações consultam os bônus concedidos com GrantBonus, o
+// que custa uma leitura adicional do armazenamento por verificação. Desabilitada por padrão.
+func (rl *RateLimiter) SetBonusGrants(enabled bool) {
+	rl.bonusGrants.Store(enabled)
+}
+
+// withBonus soma à configuração o bônus ativo da chave, se houver
+func (rl *RateLimiter) withBonus(ctx context.Context, key string, config Config) (Config, error) {
+	if !rl.bonusGrants.Load() {
+		return config, nil
+	}
+
+	bonus, _, err := rl.storage.Get(ctx, rl.subKey(key, bonusKeySuffix))
+	if err != nil {
+		return Config{}, storageError(ErrBonusFailed, err)
+	}
+
+	config.Requests += bonus
+	return config, nil
+}
@@ -0,0 +1,128 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_GrantBonus(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{Requests: 2, Window: time.Second}, WithClock(fakeClock))
+	ctx := context.Background()
+
+	checkWindow := func(allowed int) {
+		t.Helper()
+		for i := 0; i < allowed; i++ {
+			result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+			require.NoError(t, err)
+			assert.True(t, result.Allowed, "requisição %d", i+1)
+			assert.Equal(t, int64(allowed), result.Limit)
+		}
+
+		result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+	}
+
+	require.NoError(t, rateLimiter.GrantBonus(ctx, "ip:192.168.1.1", 3, time.Minute))
+
+	// Enquanto o bônus está ativo, o limite efetivo é o da configuração mais o bônus
+	checkWindow(5)
+	fakeClock.Advance(time.Second)
+	checkWindow(5)
+
+	// Outros IPs mantêm o limite da configuração
+	result, err := rateLimiter.CheckIP(ctx, "192.168.1.2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.Limit)
+
+	// Quando o bônus expira, volta o limite da configuração
+	fakeClock.Advance(time.Minute)
+	checkWindow(2)
+}
+
+func TestRateLimiter_GrantBonusAccumulates(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{Requests: 2, Window: time.Minute}, WithClock(fakeClock))
+	ctx := context.Background()
+
+	// Bônus concedidos enquanto outro está ativo se somam a ele e expiram junto com o primeiro
+	require.NoError(t, rateLimiter.GrantBonus(ctx, "token:abc", 1, 10*time.Second))
+	fakeClock.Advance(5 * time.Second)
+	require.NoError(t, rateLimiter.GrantBonus(ctx, "token:abc", 2, time.Hour))
+
+	rateLimiter.AddTokenConfig("abc", Config{Requests: 2, Window: time.Minute})
+
+	result, err := rateLimiter.CheckToken(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), result.Limit)
+
+	fakeClock.Advance(5 * time.Second)
+
+	result, err = rateLimiter.CheckToken(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.Limit)
+}
+
+func TestRateLimiter_GrantBonusSharedStorage(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	config := Config{Requests: 1, Window: time.Second}
+	admin := NewRateLimiter(memoryStorage, config, WithClock(fakeClock))
+	ctx := context.Background()
+
+	require.NoError(t, admin.GrantBonus(ctx, "ip:192.168.1.1", 1, time.Minute))
+
+	// Outras instâncias só consultam os bônus quando habilitados
+	disabled := NewRateLimiter(memoryStorage, config, WithClock(fakeClock))
+	enabled := NewRateLimiter(memoryStorage, config, WithClock(fakeClock), WithBonusGrants(true))
+
+	result, err := disabled.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Limit)
+
+	result, err = enabled.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.Limit)
+	assert.True(t, result.Allowed)
+}
+
+func TestRateLimiter_GrantBonusErrors(t *testing.T) {
+	mockStorage := &MockStorage{}
+	rateLimiter := NewRateLimiter(mockStorage, Config{Requests: 1, Window: time.Second})
+	ctx := context.Background()
+
+	assert.ErrorIs(t, rateLimiter.GrantBonus(ctx, "ip:192.168.1.1", 0, time.Minute), ErrInvalidBonus)
+	assert.ErrorIs(t, rateLimiter.GrantBonus(ctx, "ip:192.168.1.1", 1, 0), ErrInvalidBonus)
+
+	storageErr := errors.New("connection refused")
+	mockStorage.On("IncrementBy", ctx, "ip:192.168.1.1:bonus", int64(1), time.Minute).Return(int64(0), storageErr).Once()
+
+	err := rateLimiter.GrantBonus(ctx, "ip:192.168.1.1", 1, time.Minute)
+	assert.ErrorIs(t, err, ErrBonusFailed)
+	assert.ErrorIs(t, err, ErrStorageUnavailable)
+
+	// Uma falha ao consultar o bônus falha a verificação
+	rateLimiter.SetBonusGrants(true)
+	mockStorage.On("Get", ctx, "ip:192.168.1.1:bonus").Return(int64(0), time.Duration(0), storageErr).Once()
+
+	_, err = rateLimiter.CheckIP(ctx, "192.168.1.1")
+	assert.ErrorIs(t, err, ErrBonusFailed)
+
+	mockStorage.AssertExpectations(t)
+}
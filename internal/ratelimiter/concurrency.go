@@ -0,0 +1,57 @@
+package ratelimiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultConcurrencyTTL é a expiração padrão das vagas de concorrência. Cada nova vaga renova a
+// expiração, de modo que vagas nunca liberadas (ex: queda da instância no meio da requisição)
+// são descartadas quando a chave fica esse tempo sem novas requisições.
+const DefaultConcurrencyTTL = time.Minute
+
+// concurrencyKeySuffix identifica o contador de vagas de concorrência de uma chave
+const concurrencyKeySuffix = "concurrency"
+
+// Release libera a vaga de concorrência ocupada pela requisição. Deve ser chamado quando a
+// requisição termina; não tem efeito em resultados sem vaga ocupada e pode ser chamado mais de
+// uma vez.
+func (r Result) Release() {
+	if r.release != nil {
+		r.release()
+	}
+}
+
+// acquire ocupa uma vaga de concorrência da chave, retornando a função que a libera, ou
+// acquired false quando todas as MaxConcurrent vagas estão em uso
+func (rl *RateLimiter) acquire(ctx context.Context, key string, config Config) (release func(), acquired bool, err error) {
+	slotsKey := rl.subKey(key, concurrencyKeySuffix)
+
+	ttl := rl.concurrencyTTL
+	if ttl <= 0 {
+		ttl = DefaultConcurrencyTTL
+	}
+
+	acquired, err = rl.storage.Acquire(ctx, slotsKey, config.MaxConcurrent, ttl)
+	if err != nil {
+		return nil, false, storageError(ErrAcquireFailed, err)
+	}
+	if !acquired {
+		return nil, false, nil
+	}
+
+	// A liberação ocorre ao fim da requisição, quando o seu contexto pode já estar cancelado
+	releaseCtx := context.WithoutCancel(ctx)
+
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			if err := rl.storage.Release(releaseCtx, slotsKey); err != nil {
+				rl.logger.Printf("Falha ao liberar vaga de concorrência da chave %s: %v", key, err)
+			}
+		})
+	}
+
+	return release, true, nil
+}
@@ -0,0 +1,159 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_MaxConcurrent(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1})
+	defer memoryStorage.Close()
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{
+		Requests:      100,
+		Window:        time.Minute,
+		BlockTime:     time.Minute,
+		MaxConcurrent: 2,
+	})
+	ctx := context.Background()
+
+	first, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, first.Allowed)
+
+	second, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, second.Allowed)
+
+	// Com as duas vagas ocupadas a terceira requisição é negada, mesmo dentro do limite por janela
+	third, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.False(t, third.Allowed)
+	assert.True(t, third.ConcurrencyExceeded)
+	assert.False(t, third.Blocked)
+	third.Release()
+
+	// Outras chaves têm suas próprias vagas
+	other, err := rateLimiter.CheckIP(ctx, "192.168.1.2")
+	require.NoError(t, err)
+	assert.True(t, other.Allowed)
+	other.Release()
+
+	// Liberar uma vaga permite uma nova requisição; liberações repetidas não têm efeito
+	first.Release()
+	first.Release()
+
+	fourth, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, fourth.Allowed)
+
+	fifth, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.False(t, fifth.Allowed)
+
+	second.Release()
+	fourth.Release()
+}
+
+func TestRateLimiter_MaxConcurrentStorage(t *testing.T) {
+	ctx := context.Background()
+	config := Config{Requests: 1, Window: time.Second, BlockTime: time.Minute, MaxConcurrent: 5}
+	limit := storage.Limit{Requests: 1, Window: time.Second, BlockTime: time.Minute}
+
+	mockStorage := new(MockStorage)
+	rateLimiter := NewRateLimiter(mockStorage, config, WithConcurrencyTTL(30*time.Second))
+
+	// A vaga é ocupada antes da contagem e mantida para a requisição permitida
+	mockStorage.On("Acquire", ctx, "ip:192.168.1.1:concurrency", int64(5), 30*time.Second).Return(true, nil).Twice()
+	mockStorage.On("CheckAndIncrement", ctx, "ip:192.168.1.1", limit).Return(storage.Decision{Allowed: true, Count: 1}, nil).Once()
+	mockStorage.On("Release", mock.Anything, "ip:192.168.1.1:concurrency").Return(nil).Twice()
+
+	result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	mockStorage.AssertNumberOfCalls(t, "Release", 0)
+	result.Release()
+
+	// A requisição negada pelo limite por janela libera a vaga imediatamente
+	mockStorage.On("CheckAndIncrement", ctx, "ip:192.168.1.1", limit).Return(storage.Decision{Count: 2, Blocked: true, NewlyBlocked: true, TTL: time.Minute}, nil).Once()
+
+	result, err = rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	result.Release()
+
+	mockStorage.AssertExpectations(t)
+	mockStorage.AssertNumberOfCalls(t, "Release", 2)
+}
+
+func TestRateLimiter_MaxConcurrentAcquireError(t *testing.T) {
+	ctx := context.Background()
+	errRedisDown := errors.New("redis indisponível")
+
+	mockStorage := new(MockStorage)
+	rateLimiter := NewRateLimiter(mockStorage, Config{Requests: 1, Window: time.Second, MaxConcurrent: 1})
+
+	mockStorage.On("Acquire", ctx, "ip:192.168.1.1:concurrency", int64(1), DefaultConcurrencyTTL).Return(false, errRedisDown).Once()
+
+	_, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	assert.ErrorIs(t, err, ErrAcquireFailed)
+	assert.ErrorIs(t, err, ErrStorageUnavailable)
+	assert.ErrorIs(t, err, errRedisDown)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRateLimiter_MaxConcurrentDenialDoesNotConsumeWindow(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1})
+	defer memoryStorage.Close()
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{
+		Requests:      3,
+		Window:        time.Minute,
+		BlockTime:     time.Minute,
+		MaxConcurrent: 1,
+	})
+	ctx := context.Background()
+
+	first, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	require.True(t, first.Allowed)
+
+	// Requisições negadas por concorrência não são contadas na janela
+	for i := 0; i < 10; i++ {
+		denied, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+		require.NoError(t, err)
+		assert.False(t, denied.Allowed)
+		assert.True(t, denied.ConcurrencyExceeded)
+		assert.Equal(t, int64(3), denied.Limit)
+	}
+	first.Release()
+
+	count, _, err := memoryStorage.Get(ctx, "ip:192.168.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// O restante da janela continua disponível
+	for i := 0; i < 2; i++ {
+		result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		result.Release()
+	}
+
+	// A requisição que excede o limite é negada e não mantém a vaga ocupada
+	result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.False(t, result.ConcurrencyExceeded)
+
+	slots, _, err := memoryStorage.Get(ctx, "ip:192.168.1.1:concurrency")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), slots)
+}
@@ -0,0 +1,21 @@
+package ratelimiter
+
+import "context"
+
+// resultCtx é a chave de contexto usada para transportar o resultado da verificação
+type resultCtx struct{}
+
+// ContextWithResult retorna um contexto que carrega o resultado da verificação da requisição,
+// para que os handlers exibam a cota do cliente (limite, restante, fim da janela) sem
+// consultar o armazenamento novamente. A vaga de concorrência não é transferida: Release no
+// resultado lido do contexto não tem efeito, e a liberação continua com quem fez a verificação.
+func ContextWithResult(ctx context.Context, result Result) context.Context {
+	result.release = nil
+	return context.WithValue(ctx, resultCtx{}, result)
+}
+
+// ResultFromContext extrai o resultado da verificação do contexto, se houver
+func ResultFromContext(ctx context.Context) (Result, bool) {
+	result, ok := ctx.Value(resultCtx{}).(Result)
+	return result, ok
+}
@@ -0,0 +1,49 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultFromContext(t *testing.T) {
+	_, ok := ResultFromContext(context.Background())
+	assert.False(t, ok)
+
+	resetAt := time.Date(2025, 1, 1, 0, 0, 1, 0, time.UTC)
+	ctx := ContextWithResult(context.Background(), Result{Allowed: true, Limit: 10, Count: 3, Remaining: 7, ResetAt: resetAt})
+
+	result, ok := ResultFromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, Result{Allowed: true, Limit: 10, Count: 3, Remaining: 7, ResetAt: resetAt}, result)
+}
+
+func TestContextWithResult_KeepsConcurrencySlot(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1})
+	defer memoryStorage.Close()
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{Requests: 100, Window: time.Minute, MaxConcurrent: 1})
+	ctx := context.Background()
+
+	result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// Release no resultado lido do contexto não libera a vaga de quem fez a verificação
+	fromContext, _ := ResultFromContext(ContextWithResult(ctx, result))
+	fromContext.Release()
+
+	denied, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, denied.ConcurrencyExceeded)
+
+	result.Release()
+	allowed, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, allowed.Allowed)
+	allowed.Release()
+}
@@ -0,0 +1,39 @@
+package ratelimiter
+
+import "time"
+
+const (
+	// DefaultRequests é o número padrão de requisições por janela
+	DefaultRequests int64 = 10
+
+	// DefaultWindow é a duração padrão da janela de contagem
+	DefaultWindow = time.Second
+
+	// DefaultBlockTime é a duração padrão do bloqueio aplicado quando o limite é excedido
+	DefaultBlockTime = 5 * time.Minute
+)
+
+// DefaultIPConfig é a configuração padrão da limitação por IP, a mesma aplicada pelo servidor
+// quando as variáveis RATE_LIMIT_IP_* não são definidas
+var DefaultIPConfig = Config{
+	Requests:  DefaultRequests,
+	Window:    DefaultWindow,
+	BlockTime: DefaultBlockTime,
+}
+
+// WithDefaults retorna uma cópia da configuração com Requests, Window e BlockTime zerados
+// preenchidos por DefaultRequests, DefaultWindow e DefaultBlockTime. Os demais campos mantêm
+// o significado do zero descrito em Config, e os Tiers não são alterados. Um BlockTime
+// negativo é preservado e mantém o bloqueio desabilitado.
+func (c Config) WithDefaults() Config {
+	if c.Requests == 0 {
+		c.Requests = DefaultRequests
+	}
+	if c.Window == 0 {
+		c.Window = DefaultWindow
+	}
+	if c.BlockTime == 0 {
+		c.BlockTime = DefaultBlockTime
+	}
+	return c
+}
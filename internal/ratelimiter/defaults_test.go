@@ -0,0 +1,79 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_WithDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		expected Config
+	}{
+		{
+			name:     "zero value",
+			config:   Config{},
+			expected: DefaultIPConfig,
+		},
+		{
+			name:     "keeps configured fields",
+			config:   Config{Requests: 100, Window: time.Minute, BlockTime: time.Hour},
+			expected: Config{Requests: 100, Window: time.Minute, BlockTime: time.Hour},
+		},
+		{
+			name:     "fills only zero fields",
+			config:   Config{Requests: 3, Burst: 2},
+			expected: Config{Requests: 3, Window: DefaultWindow, BlockTime: DefaultBlockTime, Burst: 2},
+		},
+		{
+			name:     "negative block time disables blocking",
+			config:   Config{Requests: 3, Window: time.Minute, BlockTime: -1},
+			expected: Config{Requests: 3, Window: time.Minute, BlockTime: -1},
+		},
+		{
+			name:     "tiers are untouched",
+			config:   Config{Tiers: []Config{{Requests: 1000}}},
+			expected: Config{Requests: DefaultRequests, Window: DefaultWindow, BlockTime: DefaultBlockTime, Tiers: []Config{{Requests: 1000}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.config.WithDefaults())
+		})
+	}
+}
+
+func TestConfig_WithDefaultsLimits(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	ctx := context.Background()
+
+	// A configuração zero rejeita todas as requisições
+	rateLimiter := NewRateLimiter(memoryStorage, Config{}, WithClock(fakeClock))
+	result, err := rateLimiter.CheckIP(ctx, "10.0.0.1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// Com os padrões, DefaultRequests são permitidas e a seguinte bloqueia por DefaultBlockTime
+	rateLimiter = NewRateLimiter(memoryStorage, Config{}.WithDefaults(), WithClock(fakeClock))
+	for i := int64(0); i < DefaultRequests; i++ {
+		result, err := rateLimiter.CheckIP(ctx, "10.0.0.2")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+
+	result, err = rateLimiter.CheckIP(ctx, "10.0.0.2")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, DefaultBlockTime, result.RetryAfter)
+}
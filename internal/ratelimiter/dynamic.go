@@ -0,0 +1,180 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+)
+
+// TokenConfigKeyName é o namespace dos hashes com a configuração de cada token no Redis,
+// seguido do separador das chaves e do token (ex: "token_config:abc123")
+const TokenConfigKeyName = "token_config"
+
+// DefaultDynamicConfigTTL é o tempo padrão em que uma configuração lida é reaproveitada
+const DefaultDynamicConfigTTL = 5 * time.Second
+
+// TokenConfigSource fornece configurações de tokens ausentes do mapa local, consultadas por
+// CheckToken antes de tratar o token como desconhecido
+type TokenConfigSource interface {
+	TokenConfig(ctx context.Context, token string) (Config, bool, error)
+}
+
+// HashReader lê todos os campos de um hash; um hash inexistente retorna um mapa vazio.
+// Implementado por storage.RedisStorage.
+type HashReader interface {
+	ReadHash(ctx context.Context, key string) (map[string]string, error)
+}
+
+// DynamicConfigOptions configura o DynamicConfigStore
+type DynamicConfigOptions struct {
+	// TTL é o intervalo após o qual uma configuração em cache é lida novamente, inclusive a
+	// ausência de configuração. Zero usa DefaultDynamicConfigTTL.
+	TTL time.Duration
+
+	// Clock é o relógio usado para expirar o cache. Nil usa o relógio do sistema.
+	Clock clock.Clock
+
+	// Separator separa TokenConfigKeyName do token no nome do hash e deve ser o mesmo das
+	// chaves do rate limiter (ver KeyNames). Vazio usa o separador padrão.
+	Separator string
+}
+
+// DynamicConfigStore lê a configuração de cada token de um hash compartilhado (ex: no Redis),
+// para que uma alteração feita uma única vez seja aplicada por todas as instâncias. O hash
+// "token_config<separador><token>" aceita os campos requests, window, block_time, block_jitter e
+// algorithm, com os mesmos padrões das variáveis de ambiente. As leituras são mantidas em
+// cache por TTL.
+type DynamicConfigStore struct {
+	reader    HashReader
+	keyPrefix string
+	ttl       time.Duration
+	clock     clock.Clock
+
+	mu    sync.Mutex
+	cache map[string]cachedTokenConfig
+}
+
+// cachedTokenConfig é uma configuração lida, ou a sua ausência, válida até expiresAt
+type cachedTokenConfig struct {
+	config    Config
+	found     bool
+	expiresAt time.Time
+}
+
+// NewDynamicConfigStore cria um DynamicConfigStore que lê as configurações do reader
+func NewDynamicConfigStore(reader HashReader, opts DynamicConfigOptions) *DynamicConfigStore {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultDynamicConfigTTL
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.New()
+	}
+	if opts.Separator == "" {
+		opts.Separator = DefaultKeyNames.Separator
+	}
+
+	return &DynamicConfigStore{
+		reader:    reader,
+		keyPrefix: TokenConfigKeyName + opts.Separator,
+		ttl:       opts.TTL,
+		clock:     opts.Clock,
+		cache:     make(map[string]cachedTokenConfig),
+	}
+}
+
+// TokenConfig retorna a configuração do token, lendo-a novamente quando o cache expira
+func (s *DynamicConfigStore) TokenConfig(ctx context.Context, token string) (Config, bool, error) {
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	cached, ok := s.cache[token]
+	s.mu.Unlock()
+
+	if ok && now.Before(cached.expiresAt) {
+		return cached.config, cached.found, nil
+	}
+
+	fields, err := s.reader.ReadHash(ctx, s.keyPrefix+token)
+	if err != nil {
+		return Config{}, false, err
+	}
+
+	cached = cachedTokenConfig{expiresAt: now.Add(s.ttl)}
+	if len(fields) > 0 {
+		cached.config, err = parseTokenConfigHash(fields)
+		if err != nil {
+			return Config{}, false, fmt.Errorf("configuração inválida para token %s: %w", token, err)
+		}
+		cached.found = true
+	}
+
+	s.mu.Lock()
+	s.cache[token] = cached
+	s.mu.Unlock()
+
+	return cached.config, cached.found, nil
+}
+
+// Invalidate descarta todas as configurações em cache, forçando uma nova leitura
+func (s *DynamicConfigStore) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cache = make(map[string]cachedTokenConfig)
+}
+
+// parseTokenConfigHash converte os campos do hash na configuração do token
+func parseTokenConfigHash(fields map[string]string) (Config, error) {
+	requests, err := strconv.ParseInt(fields["requests"], 10, 64)
+	if err != nil || requests <= 0 {
+		return Config{}, fmt.Errorf("requests deve ser um inteiro positivo, obtido %q", fields["requests"])
+	}
+
+	config := Config{
+		Requests:  requests,
+		Window:    time.Second,
+		BlockTime: 5 * time.Minute,
+		Algorithm: Algorithm(fields["algorithm"]),
+
+		WindowAlignment: WindowAlignment(fields["window_alignment"]),
+	}
+
+	durations := []struct {
+		field string
+		value *time.Duration
+	}{
+		{"window", &config.Window},
+		{"block_time", &config.BlockTime},
+		{"block_jitter", &config.BlockJitter},
+	}
+	for _, d := range durations {
+		value, ok := fields[d.field]
+		if !ok {
+			continue
+		}
+
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("duração inválida em %s: %w", d.field, err)
+		}
+		*d.value = duration
+	}
+
+	switch config.Algorithm {
+	case "", AlgorithmFixedWindow, AlgorithmLeakyBucket:
+	default:
+		return Config{}, fmt.Errorf("algoritmo desconhecido %q", config.Algorithm)
+	}
+
+	switch config.WindowAlignment {
+	case "", Rolling, Calendar:
+	default:
+		return Config{}, fmt.Errorf("alinhamento de janela desconhecido %q", config.WindowAlignment)
+	}
+
+	return config, nil
+}
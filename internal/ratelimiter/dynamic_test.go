@@ -0,0 +1,196 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHashReader é um HashReader em memória cujo conteúdo pode ser alterado entre verificações
+type fakeHashReader struct {
+	mu     sync.Mutex
+	hashes map[string]map[string]string
+	err    error
+	reads  int
+}
+
+func (f *fakeHashReader) ReadHash(ctx context.Context, key string) (map[string]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.reads++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.hashes[key], nil
+}
+
+func (f *fakeHashReader) set(key string, fields map[string]string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.hashes[key] = fields
+}
+
+func TestDynamicConfigStore_ReloadsAfterTTL(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	reader := &fakeHashReader{hashes: map[string]map[string]string{
+		"token_config:abc123": {"requests": "10", "window": "1s", "block_time": "1m"},
+	}}
+
+	store := NewDynamicConfigStore(reader, DynamicConfigOptions{TTL: 5 * time.Second, Clock: fakeClock})
+	ctx := context.Background()
+
+	config, found, err := store.TokenConfig(ctx, "abc123")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, Config{Requests: 10, Window: time.Second, BlockTime: time.Minute}, config)
+
+	// A alteração só é percebida após o TTL do cache
+	reader.set("token_config:abc123", map[string]string{"requests": "20", "window": "10s", "block_jitter": "5s", "algorithm": "leaky_bucket"})
+
+	config, _, err = store.TokenConfig(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), config.Requests)
+	assert.Equal(t, 1, reader.reads)
+
+	fakeClock.Advance(5 * time.Second)
+
+	config, found, err = store.TokenConfig(ctx, "abc123")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, Config{
+		Requests:    20,
+		Window:      10 * time.Second,
+		BlockTime:   5 * time.Minute,
+		BlockJitter: 5 * time.Second,
+		Algorithm:   AlgorithmLeakyBucket,
+	}, config)
+	assert.Equal(t, 2, reader.reads)
+
+	// Invalidate força uma nova leitura antes do TTL
+	store.Invalidate()
+
+	_, _, err = store.TokenConfig(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, 3, reader.reads)
+}
+
+func TestDynamicConfigStore_CachesMissingTokens(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	reader := &fakeHashReader{hashes: map[string]map[string]string{}}
+
+	store := NewDynamicConfigStore(reader, DynamicConfigOptions{Clock: fakeClock})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, found, err := store.TokenConfig(ctx, "unknown")
+		require.NoError(t, err)
+		assert.False(t, found)
+	}
+	assert.Equal(t, 1, reader.reads)
+
+	reader.set("token_config:unknown", map[string]string{"requests": "5"})
+	fakeClock.Advance(DefaultDynamicConfigTTL)
+
+	config, found, err := store.TokenConfig(ctx, "unknown")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, Config{Requests: 5, Window: time.Second, BlockTime: 5 * time.Minute}, config)
+}
+
+func TestDynamicConfigStore_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]string
+		errMsg string
+	}{
+		{name: "requests ausente", fields: map[string]string{"window": "1s"}, errMsg: "requests deve ser um inteiro positivo"},
+		{name: "requests não positivo", fields: map[string]string{"requests": "0"}, errMsg: "requests deve ser um inteiro positivo"},
+		{name: "duração inválida", fields: map[string]string{"requests": "10", "window": "1x"}, errMsg: "duração inválida em window"},
+		{name: "algoritmo desconhecido", fields: map[string]string{"requests": "10", "algorithm": "token_bucket"}, errMsg: "algoritmo desconhecido"},
+		{name: "alinhamento desconhecido", fields: map[string]string{"requests": "10", "window_alignment": "hourly"}, errMsg: "alinhamento de janela desconhecido"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &fakeHashReader{hashes: map[string]map[string]string{"token_config:abc123": tt.fields}}
+			store := NewDynamicConfigStore(reader, DynamicConfigOptions{})
+
+			_, _, err := store.TokenConfig(context.Background(), "abc123")
+			assert.ErrorContains(t, err, "configuração inválida para token abc123")
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestRateLimiter_CheckToken_DynamicConfig(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	reader := &fakeHashReader{hashes: map[string]map[string]string{
+		"token_config:abc123": {"requests": "10", "window": "1s", "block_time": "1m"},
+	}}
+
+	mockStorage := &MockStorage{}
+	rateLimiter := NewRateLimiter(mockStorage, Config{Requests: 1, Window: time.Second, BlockTime: time.Minute})
+	rateLimiter.AddTokenConfig("local", Config{Requests: 100, Window: time.Second})
+	rateLimiter.SetTokenConfigSource(NewDynamicConfigStore(reader, DynamicConfigOptions{TTL: time.Second, Clock: fakeClock}))
+
+	ctx := context.Background()
+
+	mockStorage.On("CheckAndIncrement", ctx, "token:abc123", storage.Limit{Requests: 10, Window: time.Second, BlockTime: time.Minute}).
+		Return(storage.Decision{Allowed: true, Count: 1}, nil).Once()
+
+	result, err := rateLimiter.CheckToken(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), result.Limit)
+
+	// O novo limite é aplicado quando o cache expira
+	reader.set("token_config:abc123", map[string]string{"requests": "50", "window": "1s", "block_time": "1m"})
+	fakeClock.Advance(time.Second)
+
+	mockStorage.On("CheckAndIncrement", ctx, "token:abc123", storage.Limit{Requests: 50, Window: time.Second, BlockTime: time.Minute}).
+		Return(storage.Decision{Allowed: true, Count: 2}, nil).Once()
+
+	result, err = rateLimiter.CheckToken(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(50), result.Limit)
+	assert.Equal(t, int64(48), result.Remaining)
+
+	// O mapa local tem precedência e não consulta a fonte
+	reads := reader.reads
+	mockStorage.On("CheckAndIncrement", ctx, "token:local", mock.Anything).
+		Return(storage.Decision{Allowed: true, Count: 1}, nil).Once()
+
+	result, err = rateLimiter.CheckToken(ctx, "local")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), result.Limit)
+	assert.Equal(t, reads, reader.reads)
+
+	// Tokens ausentes da fonte continuam desconhecidos
+	_, err = rateLimiter.CheckToken(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrUnknownToken)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRateLimiter_CheckToken_DynamicConfigError(t *testing.T) {
+	errRedisDown := errors.New("redis indisponível")
+	reader := &fakeHashReader{err: errRedisDown}
+
+	rateLimiter := NewRateLimiter(&MockStorage{}, Config{Requests: 1, Window: time.Second})
+	rateLimiter.SetTokenConfigSource(NewDynamicConfigStore(reader, DynamicConfigOptions{}))
+
+	_, err := rateLimiter.CheckToken(context.Background(), "abc123")
+	assert.ErrorIs(t, err, ErrTokenConfigFailed)
+	assert.ErrorIs(t, err, ErrStorageUnavailable)
+	assert.ErrorIs(t, err, errRedisDown)
+	assert.NotErrorIs(t, err, ErrUnknownToken)
+}
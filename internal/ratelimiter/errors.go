@@ -0,0 +1,50 @@
+package ratelimiter
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrStorageUnavailable é satisfeito, via errors.Is, por qualquer falha do armazenamento ao
+// avaliar uma requisição, independentemente da operação que falhou
+var ErrStorageUnavailable = errors.New("armazenamento indisponível")
+
+// Operações do armazenamento que podem falhar, usadas como StorageError.Op e verificáveis com
+// errors.Is
+var (
+	ErrReadFailed        = errors.New("falha ao ler contador")
+	ErrBlockCheckFailed  = errors.New("falha ao verificar se está bloqueado")
+	ErrIncrementFailed   = errors.New("falha ao incrementar contador")
+	ErrBlockFailed       = errors.New("falha ao bloquear chave")
+	ErrFirstSeenFailed   = errors.New("falha ao registrar primeiro contato")
+	ErrLeakyBucketFailed = errors.New("falha ao atualizar leaky bucket")
+	ErrIdempotencyFailed = errors.New("falha ao acessar decisão de idempotência")
+	ErrTokenConfigFailed = errors.New("falha ao ler configuração do token")
+	ErrAcquireFailed     = errors.New("falha ao ocupar vaga de concorrência")
+	ErrListBlockedFailed = errors.New("falha ao listar chaves bloqueadas")
+	ErrResetFailed       = errors.New("falha ao resetar chave")
+	ErrBonusFailed       = errors.New("falha ao acessar bônus da chave")
+)
+
+// StorageError descreve uma falha do armazenamento: Op identifica a operação (ex:
+// ErrBlockCheckFailed) e Err é a causa retornada pelo armazenamento. Satisfaz errors.Is tanto
+// para Op e ErrStorageUnavailable quanto para a causa.
+type StorageError struct {
+	Op  error
+	Err error
+}
+
+// Error implementa a interface error
+func (e *StorageError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Op, e.Err)
+}
+
+// Unwrap expõe a operação, ErrStorageUnavailable e a causa para errors.Is e errors.As
+func (e *StorageError) Unwrap() []error {
+	return []error{e.Op, ErrStorageUnavailable, e.Err}
+}
+
+// storageError envolve a falha do armazenamento na operação informada
+func storageError(op error, err error) error {
+	return &StorageError{Op: op, Err: err}
+}
@@ -0,0 +1,115 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_StorageErrors(t *testing.T) {
+	errRedisDown := errors.New("redis indisponível")
+	tiered := Config{
+		Requests:  1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+		Tiers:     []Config{{Requests: 100, Window: time.Hour}},
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+		setup  func(m *MockStorage)
+		op     error
+	}{
+		{
+			name:   "verificação e incremento atômicos",
+			config: Config{Requests: 1, Window: time.Second, BlockTime: time.Minute},
+			setup: func(m *MockStorage) {
+				m.On("CheckAndIncrement", mock.Anything, "ip:192.168.1.1", mock.Anything).Return(storage.Decision{}, errRedisDown)
+			},
+			op: ErrIncrementFailed,
+		},
+		{
+			name:   "verificação de bloqueio",
+			config: tiered,
+			setup: func(m *MockStorage) {
+				m.On("IsBlocked", mock.Anything, "ip:192.168.1.1").Return(false, time.Duration(0), errRedisDown)
+			},
+			op: ErrBlockCheckFailed,
+		},
+		{
+			name:   "incremento",
+			config: tiered,
+			setup: func(m *MockStorage) {
+				m.On("IsBlocked", mock.Anything, "ip:192.168.1.1").Return(false, time.Duration(0), nil)
+				m.On("Increment", mock.Anything, "ip:192.168.1.1", time.Second).Return(int64(0), false, errRedisDown)
+			},
+			op: ErrIncrementFailed,
+		},
+		{
+			name:   "bloqueio",
+			config: tiered,
+			setup: func(m *MockStorage) {
+				m.On("IsBlocked", mock.Anything, "ip:192.168.1.1").Return(false, time.Duration(0), nil)
+				m.On("Increment", mock.Anything, "ip:192.168.1.1", time.Second).Return(int64(2), false, nil)
+				m.On("Increment", mock.Anything, "ip:192.168.1.1:tier0", time.Hour).Return(int64(2), false, nil)
+				m.On("Block", mock.Anything, "ip:192.168.1.1", time.Minute).Return(errRedisDown)
+			},
+			op: ErrBlockFailed,
+		},
+		{
+			name:   "leaky bucket",
+			config: Config{Requests: 1, Window: time.Second, Algorithm: AlgorithmLeakyBucket},
+			setup: func(m *MockStorage) {
+				m.On("LeakyBucket", mock.Anything, "ip:192.168.1.1", int64(1), time.Second, mock.Anything).Return(false, time.Duration(0), errRedisDown)
+			},
+			op: ErrLeakyBucketFailed,
+		},
+	}
+
+	ops := []error{ErrReadFailed, ErrBlockCheckFailed, ErrIncrementFailed, ErrBlockFailed, ErrFirstSeenFailed, ErrLeakyBucketFailed, ErrIdempotencyFailed, ErrTokenConfigFailed}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := &MockStorage{}
+			tt.setup(mockStorage)
+
+			rateLimiter := NewRateLimiter(mockStorage, tt.config)
+
+			_, err := rateLimiter.CheckIP(context.Background(), "192.168.1.1")
+			require.Error(t, err)
+
+			assert.ErrorIs(t, err, ErrStorageUnavailable)
+			assert.ErrorIs(t, err, errRedisDown)
+			for _, op := range ops {
+				assert.Equal(t, op == tt.op, errors.Is(err, op), "operação %q", op)
+			}
+
+			var storageErr *StorageError
+			require.ErrorAs(t, err, &storageErr)
+			assert.Equal(t, tt.op, storageErr.Op)
+			assert.Equal(t, errRedisDown, storageErr.Err)
+			assert.Equal(t, tt.op.Error()+": redis indisponível", err.Error())
+
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRateLimiter_NonStorageErrorsAreNotStorageErrors(t *testing.T) {
+	rateLimiter := NewRateLimiter(&MockStorage{}, Config{Requests: 1, Window: time.Second, Algorithm: "token_bucket"})
+
+	_, err := rateLimiter.CheckIP(context.Background(), "192.168.1.1")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrStorageUnavailable)
+
+	_, err = rateLimiter.CheckToken(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrUnknownToken)
+	assert.NotErrorIs(t, err, ErrStorageUnavailable)
+}
@@ -0,0 +1,51 @@
+package ratelimiter
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// DefaultBlockEscalationReset é o período padrão sem bloqueios após o qual a escalada recomeça
+const DefaultBlockEscalationReset = 24 * time.Hour
+
+// offensesKeySuffix identifica o contador de bloqueios consecutivos da chave
+const offensesKeySuffix = "offenses"
+
+// escalationReset retorna o período sem bloqueios após o qual a escalada recomeça
+func (c Config) escalationReset() time.Duration {
+	if c.BlockEscalationReset > 0 {
+		return c.BlockEscalationReset
+	}
+	return DefaultBlockEscalationReset
+}
+
+// escalateBlock registra um novo bloqueio da chave e retorna blockTime multiplicado por
+// BlockEscalationFactor uma vez para cada bloqueio consecutivo anterior, limitado a MaxBlockTime
+func (rl *RateLimiter) escalateBlock(ctx context.Context, key string, config Config, blockTime time.Duration) (time.Duration, error) {
+	if config.BlockEscalationFactor <= 1 || blockTime <= 0 {
+		return blockTime, nil
+	}
+
+	offenses, err := rl.storage.IncrementSliding(ctx, rl.subKey(key, offensesKeySuffix), config.escalationReset())
+	if err != nil {
+		return 0, storageError(ErrBlockFailed, err)
+	}
+
+	return escalatedBlockTime(blockTime, config.BlockEscalationFactor, offenses, config.MaxBlockTime), nil
+}
+
+// escalatedBlockTime calcula blockTime * factor^(offenses-1), limitado a maxBlockTime quando
+// definido e ao maior time.Duration representável
+func escalatedBlockTime(blockTime time.Duration, factor float64, offenses int64, maxBlockTime time.Duration) time.Duration {
+	limit := time.Duration(math.MaxInt64)
+	if maxBlockTime > 0 {
+		limit = maxBlockTime
+	}
+
+	escalated := float64(blockTime) * math.Pow(factor, float64(offenses-1))
+	if escalated >= float64(limit) {
+		return limit
+	}
+	return time.Duration(escalated)
+}
@@ -0,0 +1,133 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_BlockEscalation(t *testing.T) {
+	tests := []struct {
+		name  string
+		tiers []Config
+	}{
+		{name: "decisão atômica"},
+		{name: "contagem em etapas", tiers: []Config{{Requests: 100, Window: time.Hour}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClock := clock.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+			memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+			defer memoryStorage.Close()
+
+			rateLimiter := NewRateLimiter(memoryStorage, Config{
+				Requests:              1,
+				Window:                time.Second,
+				BlockTime:             time.Minute,
+				BlockEscalationFactor: 2,
+				MaxBlockTime:          5 * time.Minute,
+				BlockEscalationReset:  time.Hour,
+				Tiers:                 tt.tiers,
+			}, WithClock(fakeClock))
+			ctx := context.Background()
+
+			// offend excede o limite e retorna a duração do bloqueio aplicado
+			offend := func() time.Duration {
+				t.Helper()
+				_, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+				require.NoError(t, err)
+				result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+				require.NoError(t, err)
+				require.True(t, result.Blocked)
+				return result.RetryAfter
+			}
+
+			// Cada reincidência dobra o bloqueio, até MaxBlockTime
+			for _, expected := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute} {
+				blockTime := offend()
+				assert.Equal(t, expected, blockTime)
+
+				// O bloqueio estendido é respeitado até o fim
+				fakeClock.Advance(blockTime - time.Second)
+				result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+				require.NoError(t, err)
+				assert.True(t, result.Blocked)
+				fakeClock.Advance(time.Second)
+			}
+
+			// Após o período sem bloqueios, a escalada recomeça
+			fakeClock.Advance(time.Hour)
+			assert.Equal(t, time.Minute, offend())
+		})
+	}
+}
+
+func TestRateLimiter_BlockEscalationDisabled(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{Requests: 1, Window: time.Second, BlockTime: time.Minute}, WithClock(fakeClock))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+		require.NoError(t, err)
+		result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+		require.NoError(t, err)
+		assert.Equal(t, time.Minute, result.RetryAfter)
+		fakeClock.Advance(time.Minute)
+	}
+
+	count, _, err := memoryStorage.Get(ctx, "ip:192.168.1.1:offenses")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestRateLimiter_BlockEscalationError(t *testing.T) {
+	ctx := context.Background()
+	errRedisDown := errors.New("redis indisponível")
+	config := Config{Requests: 1, Window: time.Second, BlockTime: time.Minute, BlockEscalationFactor: 2}
+
+	mockStorage := new(MockStorage)
+	rateLimiter := NewRateLimiter(mockStorage, config)
+
+	mockStorage.On("CheckAndIncrement", ctx, "ip:192.168.1.1", mock.Anything).Return(storage.Decision{Count: 2, Blocked: true, NewlyBlocked: true, TTL: time.Minute}, nil).Once()
+	mockStorage.On("IncrementSliding", ctx, "ip:192.168.1.1:offenses", DefaultBlockEscalationReset).Return(int64(0), errRedisDown).Once()
+
+	_, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	assert.ErrorIs(t, err, ErrBlockFailed)
+	assert.ErrorIs(t, err, errRedisDown)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestEscalatedBlockTime(t *testing.T) {
+	tests := []struct {
+		name         string
+		offenses     int64
+		factor       float64
+		maxBlockTime time.Duration
+		expected     time.Duration
+	}{
+		{name: "primeiro bloqueio", offenses: 1, factor: 2, expected: time.Minute},
+		{name: "terceiro bloqueio", offenses: 3, factor: 2, expected: 4 * time.Minute},
+		{name: "fator fracionário", offenses: 2, factor: 1.5, expected: 90 * time.Second},
+		{name: "limitado", offenses: 10, factor: 2, maxBlockTime: time.Hour, expected: time.Hour},
+		{name: "sem limite", offenses: 1000, factor: 2, expected: time.Duration(math.MaxInt64)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, escalatedBlockTime(time.Minute, tt.factor, tt.offenses, tt.maxBlockTime))
+		})
+	}
+}
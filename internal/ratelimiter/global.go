@@ -0,0 +1,29 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+)
+
+// GlobalKey é a chave de armazenamento do limite global, compartilhada por todos os clientes
+const GlobalKey = "global"
+
+// ErrGlobalNotConfigured é retornado por CheckGlobal quando nenhuma configuração global foi
+// definida
+var ErrGlobalNotConfigured = errors.New("configuração de limitação global não definida")
+
+// SetGlobalConfig define o limite global, aplicado por CheckGlobal ao total de requisições de
+// todos os clientes (ex: 10 mil req/s para proteger uma dependência frágil)
+func (rl *RateLimiter) SetGlobalConfig(config Config) {
+	rl.globalConfig = &config
+}
+
+// CheckGlobal verifica se o total de requisições, somando todos os clientes, permite mais uma.
+// Todas as instâncias que compartilham o armazenamento dividem o mesmo contador (GlobalKey).
+func (rl *RateLimiter) CheckGlobal(ctx context.Context) (Result, error) {
+	if rl.globalConfig == nil {
+		return Result{}, ErrGlobalNotConfigured
+	}
+
+	return rl.checkLimit(ctx, rl.keyPrefix+GlobalKey, *rl.globalConfig)
+}
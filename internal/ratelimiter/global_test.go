@@ -0,0 +1,59 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_CheckGlobalSharedAcrossClients(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	ipConfig := Config{Requests: 5, Window: time.Second, BlockTime: time.Minute}
+	rateLimiter := NewRateLimiter(memoryStorage, ipConfig, WithClock(fakeClock),
+		WithGlobalConfig(Config{Requests: 3, Window: time.Second}))
+	ctx := context.Background()
+
+	// Cada cliente fica dentro do seu limite, mas o total atinge o limite global
+	for i := 1; i <= 3; i++ {
+		result, err := rateLimiter.CheckGlobal(ctx)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+
+		result, err = rateLimiter.CheckIP(ctx, fmt.Sprintf("192.168.1.%d", i))
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+
+	result, err := rateLimiter.CheckGlobal(ctx)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.False(t, result.Blocked)
+
+	// A configuração global também é resolvida pelo Peek
+	result, err = rateLimiter.Peek(ctx, GlobalKey)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), result.Limit)
+
+	// Na janela seguinte o limite global é liberado
+	fakeClock.Advance(time.Second)
+
+	result, err = rateLimiter.CheckGlobal(ctx)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestRateLimiter_CheckGlobalNotConfigured(t *testing.T) {
+	rateLimiter := NewRateLimiter(new(MockStorage), Config{Requests: 1, Window: time.Second})
+
+	_, err := rateLimiter.CheckGlobal(context.Background())
+	assert.ErrorIs(t, err, ErrGlobalNotConfigured)
+}
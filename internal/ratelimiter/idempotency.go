@@ -0,0 +1,64 @@
+package ratelimiter
+
+import "context"
+
+// DefaultMaxIdempotentReplays é o número padrão de vezes que uma decisão permitida pode ser
+// repetida para a mesma chave de idempotência dentro da janela (ver SetMaxIdempotentReplays)
+const DefaultMaxIdempotentReplays = 3
+
+// idempotencyReplaysKeySuffix identifica o contador de repetições de uma chave de idempotência
+const idempotencyReplaysKeySuffix = "replays"
+
+// idempotencyKeyCtx é a chave de contexto usada para transportar a chave de idempotência
+type idempotencyKeyCtx struct{}
+
+// WithIdempotencyKey retorna um contexto que carrega a chave de idempotência da requisição.
+// Verificações feitas com esse contexto reaproveitam a decisão anterior para a mesma chave
+// dentro da janela, sem incrementar o contador novamente, até o limite de repetições de
+// SetMaxIdempotentReplays.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtx{}, key)
+}
+
+// idempotencyKeyFromContext extrai a chave de idempotência do contexto, se houver
+func idempotencyKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKeyCtx{}).(string)
+	return key, ok && key != ""
+}
+
+// SetMaxIdempotentReplays define quantas vezes uma decisão permitida pode ser repetida para a
+// mesma chave de idempotência dentro da janela. Valores menores ou iguais a zero usam
+// DefaultMaxIdempotentReplays. O limite efetivo nunca passa de Requests-1, para que as
+// repetições de uma única chave não excedam o limite da configuração.
+func (rl *RateLimiter) SetMaxIdempotentReplays(replays int64) {
+	rl.maxIdempotentReplays = replays
+}
+
+// replayDecision repete a decisão registrada para uma chave de idempotência. As repetições de
+// decisões permitidas são contadas e, acima do limite de repetições, negadas até o fim da
+// janela, para que reenviar a mesma chave não contorne o limite. As negações informam o limite
+// e, em RetryAfter, a janela, o máximo que a decisão registrada ainda pode durar.
+func (rl *RateLimiter) replayDecision(ctx context.Context, decisionKey string, allowed bool, config Config) (Result, error) {
+	denied := Result{Limit: config.Requests, Window: config.Window, RetryAfter: config.Window}
+	if !allowed {
+		return denied, nil
+	}
+
+	maxReplays := rl.maxIdempotentReplays
+	if maxReplays <= 0 {
+		maxReplays = DefaultMaxIdempotentReplays
+	}
+	if limit := config.Requests - 1; limit < maxReplays {
+		maxReplays = limit
+	}
+
+	replays, _, err := rl.storage.Increment(ctx, rl.subKey(decisionKey, idempotencyReplaysKeySuffix), config.Window)
+	if err != nil {
+		return Result{}, storageError(ErrIdempotencyFailed, err)
+	}
+	if replays > maxReplays {
+		return denied, nil
+	}
+
+	return Result{Allowed: true}, nil
+}
@@ -0,0 +1,84 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newIdempotencyTestRateLimiter(t *testing.T, opts ...Option) *RateLimiter {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	t.Cleanup(func() { memoryStorage.Close() })
+
+	return NewRateLimiter(memoryStorage, Config{Requests: 3, Window: time.Minute}, append(opts, WithClock(fakeClock))...)
+}
+
+func TestRateLimiter_IdempotentReplaysCannotExceedLimit(t *testing.T) {
+	// Mesmo com um limite de repetições maior, uma única chave não passa de Requests
+	rateLimiter := newIdempotencyTestRateLimiter(t, WithMaxIdempotentReplays(100))
+	ctx := WithIdempotencyKey(context.Background(), "retry")
+	config := Config{Requests: 3, Window: time.Minute}
+
+	allowed := 0
+	for i := 0; i < 50; i++ {
+		result, err := rateLimiter.CheckKey(ctx, "tenant", config)
+		require.NoError(t, err)
+		if result.Allowed {
+			allowed++
+		}
+	}
+	assert.Equal(t, 3, allowed)
+
+	// A requisição original é contada uma única vez
+	result, err := rateLimiter.CheckKey(WithIdempotencyKey(context.Background(), "other"), "tenant", config)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(2), result.Count)
+}
+
+func TestRateLimiter_IdempotentReplaysDefaultLimit(t *testing.T) {
+	rateLimiter := newIdempotencyTestRateLimiter(t)
+	ctx := WithIdempotencyKey(context.Background(), "retry")
+	config := Config{Requests: 10, Window: time.Minute}
+
+	for i := 0; i <= DefaultMaxIdempotentReplays; i++ {
+		result, err := rateLimiter.CheckKey(ctx, "tenant", config)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed, "repetição %d", i)
+	}
+
+	result, err := rateLimiter.CheckKey(ctx, "tenant", config)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Minute, result.RetryAfter)
+
+	// Outras chaves de idempotência continuam sendo avaliadas normalmente
+	result, err = rateLimiter.CheckKey(WithIdempotencyKey(context.Background(), "other"), "tenant", config)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(2), result.Count)
+}
+
+func TestRateLimiter_IdempotentReplayCountError(t *testing.T) {
+	errRedisDown := errors.New("connection refused")
+	ctx := WithIdempotencyKey(context.Background(), "retry")
+
+	mockStorage := new(MockStorage)
+	mockStorage.On("IsBlocked", ctx, "ip:192.168.1.1").Return(false, time.Duration(0), nil).Once()
+	mockStorage.On("GetDecision", ctx, "ip:192.168.1.1:retry").Return(true, true, nil).Once()
+	mockStorage.On("Increment", ctx, "ip:192.168.1.1:retry:replays", time.Second).Return(int64(0), false, errRedisDown).Once()
+
+	rateLimiter := NewRateLimiter(mockStorage, Config{Requests: 5, Window: time.Second, BlockTime: time.Minute})
+
+	_, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	assert.ErrorIs(t, err, ErrIdempotencyFailed)
+	assert.ErrorIs(t, err, errRedisDown)
+	mockStorage.AssertExpectations(t)
+}
@@ -0,0 +1,74 @@
+package ratelimiter
+
+import "context"
+
+// withoutCountingCtx é a chave de contexto que desativa a contagem das verificações
+type withoutCountingCtx struct{}
+
+// WithoutCounting retorna um contexto em que as verificações apenas consultam o estado da
+// chave, sem contabilizar a requisição: ela é rejeitada se a chave estiver bloqueada ou se
+// algum limite já tiver sido excedido na janela atual. Permite decidir uma requisição cuja
+// contagem depende da resposta (ex: apenas tentativas de login com falha), repetindo a
+// verificação com o contexto original quando ela deve ser contada. O leaky bucket, as vagas
+// de concorrência e as decisões de idempotência não são consultados.
+func WithoutCounting(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withoutCountingCtx{}, true)
+}
+
+// countingDisabled indica se o contexto foi criado por WithoutCounting
+func countingDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(withoutCountingCtx{}).(bool)
+	return disabled
+}
+
+// inspect verifica a chave sem contabilizar a requisição (ver WithoutCounting)
+func (rl *RateLimiter) inspect(ctx context.Context, key string, config Config) (Result, error) {
+	if config.Algorithm == "" {
+		config.Algorithm = rl.defaultAlgorithm
+	}
+	if config.Algorithm == AlgorithmLeakyBucket {
+		return Result{Allowed: true, Limit: config.Requests, Window: config.Window, Remaining: config.Requests}, nil
+	}
+
+	result, blocked, err := rl.checkBlocked(ctx, key, config)
+	if err != nil || blocked {
+		return result, err
+	}
+
+	count, ttl, err := rl.storage.Get(ctx, key)
+	if err != nil {
+		return Result{}, storageError(ErrReadFailed, err)
+	}
+
+	result = Result{
+		Allowed:   true,
+		Limit:     config.Requests,
+		Window:    config.Window,
+		Count:     count,
+		Remaining: max(config.Requests-count, 0),
+	}
+	if ttl > 0 {
+		result.ResetAt = rl.clock.Now().Add(ttl)
+	}
+
+	// O excedente do Burst ainda é aceito pela contagem
+	if count > config.Requests+config.Burst {
+		result.Allowed = false
+		result.RetryAfter = ttl
+	}
+
+	for i, tier := range config.Tiers {
+		tierCount, tierTTL, err := rl.storage.Get(ctx, rl.tierKey(key, i))
+		if err != nil {
+			return Result{}, storageError(ErrReadFailed, err)
+		}
+
+		if tierCount > tier.Requests {
+			result.Allowed = false
+			result.Remaining = 0
+			result.RetryAfter = max(result.RetryAfter, tierTTL)
+		}
+	}
+
+	return result, nil
+}
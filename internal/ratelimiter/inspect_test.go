@@ -0,0 +1,78 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_WithoutCounting(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	config := Config{Requests: 2, Window: time.Minute, BlockTime: time.Minute}
+	rateLimiter := NewRateLimiter(memoryStorage, config, WithClock(fakeClock))
+
+	ctx := context.Background()
+	inspectCtx := WithoutCounting(ctx)
+
+	// A consulta não consome o limite
+	for i := 0; i < 5; i++ {
+		result, err := rateLimiter.CheckIP(inspectCtx, "10.0.0.1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Zero(t, result.Count)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := rateLimiter.CheckIP(ctx, "10.0.0.1")
+		require.NoError(t, err)
+	}
+
+	// No limite, ainda sem excedê-lo, a requisição é permitida
+	result, err := rateLimiter.CheckIP(inspectCtx, "10.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(2), result.Count)
+	assert.Zero(t, result.Remaining)
+
+	// Excedido o limite, a chave bloqueada é rejeitada
+	_, err = rateLimiter.CheckIP(ctx, "10.0.0.1")
+	require.NoError(t, err)
+
+	result, err = rateLimiter.CheckIP(inspectCtx, "10.0.0.1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.True(t, result.Blocked)
+}
+
+func TestRateLimiter_WithoutCountingTiers(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	// Sem tempo de bloqueio, o excesso é rejeitado até a janela do tier terminar
+	config := Config{Requests: 10, Window: time.Minute, Tiers: []Config{{Requests: 1, Window: time.Hour}}}
+	rateLimiter := NewRateLimiter(memoryStorage, config, WithClock(fakeClock))
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := rateLimiter.CheckIP(ctx, "10.0.0.1")
+		require.NoError(t, err)
+	}
+
+	result, err := rateLimiter.CheckIP(WithoutCounting(ctx), "10.0.0.1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Hour, result.RetryAfter)
+
+	count, _, err := memoryStorage.Get(ctx, "ip:10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
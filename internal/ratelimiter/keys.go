@@ -0,0 +1,93 @@
+package ratelimiter
+
+// KeyNames define os namespaces e o separador das chaves geradas pelo rate limiter, para que
+// elas sigam as convenções de nomes já adotadas no armazenamento (ex: "ip.192.168.1.1" quando
+// ":" é reservado para outras ferramentas). Campos vazios usam os valores de DefaultKeyNames.
+// As chaves de Peek, BlockedKeys e InvalidateBlockCache seguem os nomes configurados.
+type KeyNames struct {
+	// Separator separa o namespace do restante da chave e os sufixos das chaves auxiliares
+	// (ex: "<chave>:burst")
+	Separator string
+
+	// IP é o namespace das chaves de CheckIP
+	IP string
+
+	// Token é o namespace das chaves de CheckToken
+	Token string
+
+	// IPToken é o namespace das chaves de CheckIPToken
+	IPToken string
+
+	// Custom é o namespace das chaves de CheckKey e ReserveKey
+	Custom string
+}
+
+// DefaultKeyNames são os nomes padrão das chaves (ex: "ip:192.168.1.1", "token:abc123")
+var DefaultKeyNames = KeyNames{
+	Separator: ":",
+	IP:        "ip",
+	Token:     "token",
+	IPToken:   "iptoken",
+	Custom:    "custom",
+}
+
+// keyPrefixes são os prefixos das chaves de armazenamento, pré-calculados a partir de KeyNames
+// e concatenados diretamente para evitar alocações de fmt.Sprintf a cada requisição
+type keyPrefixes struct {
+	names     KeyNames
+	separator string
+	ip        string
+	token     string
+	ipToken   string
+	custom    string
+}
+
+// prefixes calcula os prefixos das chaves, preenchendo os campos vazios com DefaultKeyNames
+func (n KeyNames) prefixes() keyPrefixes {
+	n = n.withDefaults()
+
+	return keyPrefixes{
+		names:     n,
+		separator: n.Separator,
+		ip:        n.IP + n.Separator,
+		token:     n.Token + n.Separator,
+		ipToken:   n.IPToken + n.Separator,
+		custom:    n.Custom + n.Separator,
+	}
+}
+
+// withDefaults preenche os campos vazios com DefaultKeyNames
+func (n KeyNames) withDefaults() KeyNames {
+	if n.Separator == "" {
+		n.Separator = DefaultKeyNames.Separator
+	}
+	if n.IP == "" {
+		n.IP = DefaultKeyNames.IP
+	}
+	if n.Token == "" {
+		n.Token = DefaultKeyNames.Token
+	}
+	if n.IPToken == "" {
+		n.IPToken = DefaultKeyNames.IPToken
+	}
+	if n.Custom == "" {
+		n.Custom = DefaultKeyNames.Custom
+	}
+	return n
+}
+
+// SetKeyNames define os namespaces e o separador das chaves (ver KeyNames). Deve ser chamado
+// antes do uso: chaves já gravadas com os nomes anteriores deixam de ser consultadas.
+func (rl *RateLimiter) SetKeyNames(names KeyNames) {
+	rl.keys = names.prefixes()
+}
+
+// KeyNames retorna os nomes de chaves em uso, com os padrões aplicados
+func (rl *RateLimiter) KeyNames() KeyNames {
+	return rl.keys.names
+}
+
+// subKey retorna a chave auxiliar de key com o sufixo informado (ex: "<chave>:burst")
+func (rl *RateLimiter) subKey(key, suffix string) string {
+	return key + rl.keys.separator + suffix
+}
@@ -0,0 +1,184 @@
+package ratelimiter
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keyRecordingStorage registra as chaves recebidas por cada operação do armazenamento
+type keyRecordingStorage struct {
+	*storage.MemoryStorage
+
+	mu   sync.Mutex
+	keys map[string]struct{}
+}
+
+func (s *keyRecordingStorage) record(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[key] = struct{}{}
+}
+
+func (s *keyRecordingStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, bool, error) {
+	s.record(key)
+	return s.MemoryStorage.Increment(ctx, key, window)
+}
+
+func (s *keyRecordingStorage) IncrementBy(ctx context.Context, key string, amount int64, window time.Duration) (int64, error) {
+	s.record(key)
+	return s.MemoryStorage.IncrementBy(ctx, key, amount, window)
+}
+
+func (s *keyRecordingStorage) IncrementSliding(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	s.record(key)
+	return s.MemoryStorage.IncrementSliding(ctx, key, ttl)
+}
+
+func (s *keyRecordingStorage) CheckAndIncrement(ctx context.Context, key string, limit storage.Limit) (storage.Decision, error) {
+	s.record(key)
+	return s.MemoryStorage.CheckAndIncrement(ctx, key, limit)
+}
+
+func (s *keyRecordingStorage) Get(ctx context.Context, key string) (int64, time.Duration, error) {
+	s.record(key)
+	return s.MemoryStorage.Get(ctx, key)
+}
+
+func (s *keyRecordingStorage) IsBlocked(ctx context.Context, key string) (bool, time.Duration, error) {
+	s.record(key)
+	return s.MemoryStorage.IsBlocked(ctx, key)
+}
+
+func (s *keyRecordingStorage) Block(ctx context.Context, key string, duration time.Duration) error {
+	s.record(key)
+	return s.MemoryStorage.Block(ctx, key, duration)
+}
+
+func (s *keyRecordingStorage) GetDecision(ctx context.Context, key string) (bool, bool, error) {
+	s.record(key)
+	return s.MemoryStorage.GetDecision(ctx, key)
+}
+
+func (s *keyRecordingStorage) SetDecision(ctx context.Context, key string, allowed bool, ttl time.Duration) error {
+	s.record(key)
+	return s.MemoryStorage.SetDecision(ctx, key, allowed, ttl)
+}
+
+func (s *keyRecordingStorage) FirstSeen(ctx context.Context, key string, now time.Time, ttl time.Duration) (time.Time, error) {
+	s.record(key)
+	return s.MemoryStorage.FirstSeen(ctx, key, now, ttl)
+}
+
+func (s *keyRecordingStorage) Acquire(ctx context.Context, key string, limit int64, ttl time.Duration) (bool, error) {
+	s.record(key)
+	return s.MemoryStorage.Acquire(ctx, key, limit, ttl)
+}
+
+func TestRateLimiter_KeyNames(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{
+		CleanupInterval: -1,
+		KeyNames:        storage.KeyNames{Separator: ".", Blocked: "ban"},
+	})
+	defer memoryStorage.Close()
+	recorder := &keyRecordingStorage{MemoryStorage: memoryStorage, keys: make(map[string]struct{})}
+
+	names := KeyNames{Separator: ".", IP: "addr", Token: "key", IPToken: "addrkey", Custom: "id"}
+	rateLimiter := NewRateLimiter(recorder, Config{
+		Requests:              1,
+		Window:                time.Second,
+		BlockTime:             time.Minute,
+		BlockEscalationFactor: 2,
+		MaxConcurrent:         1,
+		Tiers:                 []Config{{Requests: 100, Window: time.Hour}},
+	}, WithKeyNames(names), WithKeyPrefix("app."), WithOnBlock(func(ctx context.Context, key string, config Config) {}))
+	rateLimiter.AddTokenConfig("abc123", Config{Requests: 1, Burst: 1, Window: time.Second})
+	rateLimiter.SetIPTokenConfig(Config{Requests: 1, Window: time.Second})
+	ctx := context.Background()
+
+	assert.Equal(t, names, rateLimiter.KeyNames())
+
+	// IP: contador, tier, vagas de concorrência, bloqueio e escalada
+	result, err := rateLimiter.CheckIP(ctx, "10.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	result.Release()
+
+	result, err = rateLimiter.CheckIP(ctx, "10.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, result.Blocked)
+
+	// Token com burst, par de IP e token, identidade arbitrária e idempotência
+	for i := 0; i < 2; i++ {
+		result, err = rateLimiter.CheckToken(ctx, "abc123")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+	_, err = rateLimiter.CheckIPToken(ctx, "10.0.0.2", "abc123")
+	require.NoError(t, err)
+	_, err = rateLimiter.CheckKey(ctx, "tenant", Config{Requests: 1, Window: time.Second})
+	require.NoError(t, err)
+	_, err = rateLimiter.CheckKey(WithIdempotencyKey(ctx, "retry"), "tenant", Config{Requests: 5, Window: time.Second})
+	require.NoError(t, err)
+
+	// As chaves no formato de Peek seguem os nomes configurados
+	peeked, err := rateLimiter.Peek(ctx, "key.abc123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), peeked.Limit)
+
+	blocked, err := rateLimiter.BlockedKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"addr.10.0.0.1"}, blocked)
+
+	for _, key := range []string{
+		"app.addr.10.0.0.1",
+		"app.addr.10.0.0.1.tier0",
+		"app.addr.10.0.0.1.concurrency",
+		"app.addr.10.0.0.1.offenses",
+		"app.addr.10.0.0.1.block_notice",
+		"app.key.abc123",
+		"app.key.abc123.burst",
+		"app.addrkey.10.0.0.2." + hashToken("abc123"),
+		"app.id.tenant",
+		"app.id.tenant.retry",
+	} {
+		assert.Contains(t, recorder.keys, key)
+	}
+	for key := range recorder.keys {
+		assert.True(t, strings.HasPrefix(key, "app."), key)
+		assert.NotContains(t, key, ":")
+	}
+}
+
+func TestDynamicConfigStore_KeySeparator(t *testing.T) {
+	reader := &fakeHashReader{hashes: map[string]map[string]string{
+		"token_config.abc123": {"requests": "10"},
+	}}
+
+	// O hash da configuração segue o separador das chaves do rate limiter
+	store := NewDynamicConfigStore(reader, DynamicConfigOptions{Separator: "."})
+	config, found, err := store.TokenConfig(context.Background(), "abc123")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, int64(10), config.Requests)
+
+	_, found, err = NewDynamicConfigStore(reader, DynamicConfigOptions{}).TokenConfig(context.Background(), "abc123")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestKeyNames_Defaults(t *testing.T) {
+	rateLimiter := NewRateLimiter(new(MockStorage), Config{Requests: 1, Window: time.Second})
+	assert.Equal(t, DefaultKeyNames, rateLimiter.KeyNames())
+
+	// Campos vazios mantêm os nomes padrão
+	rateLimiter.SetKeyNames(KeyNames{Token: "apikey"})
+	assert.Equal(t, KeyNames{Separator: ":", IP: "ip", Token: "apikey", IPToken: "iptoken", Custom: "custom"}, rateLimiter.KeyNames())
+	assert.Equal(t, "token:abc:burst", NewRateLimiter(new(MockStorage), Config{}).burstKey("token:abc"))
+}
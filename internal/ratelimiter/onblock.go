@@ -0,0 +1,45 @@
+package ratelimiter
+
+import (
+	"context"
+	"time"
+)
+
+// blockNoticeKeySuffix identifica o registro que evita notificações repetidas do mesmo bloqueio
+const blockNoticeKeySuffix = "block_notice"
+
+// SetOnBlock define o hook chamado quando uma chave passa a ser bloqueada (ex: para disparar um
+// alerta). O hook recebe a chave de armazenamento, incluindo o prefixo de SetKeyPrefix, e a
+// configuração que a bloqueou. É chamado uma única vez por bloqueio: requisições rejeitadas por
+// um bloqueio já ativo não o disparam. A chamada é síncrona, na requisição que causou o
+// bloqueio, então o hook deve retornar rapidamente.
+func (rl *RateLimiter) SetOnBlock(onBlock func(ctx context.Context, key string, config Config)) {
+	rl.onBlock = onBlock
+}
+
+// notifyBlock chama o hook OnBlock para um bloqueio aplicado atomicamente pelo armazenamento,
+// que já garante uma única notificação por bloqueio
+func (rl *RateLimiter) notifyBlock(ctx context.Context, key string, config Config) {
+	if rl.onBlock != nil {
+		rl.onBlock(ctx, key, config)
+	}
+}
+
+// notifyBlockOnce chama o hook OnBlock para um bloqueio aplicado com Storage.Block, que pode ser
+// repetido por requisições concorrentes. Um registro com a duração do bloqueio garante que
+// apenas a primeira delas notifique; se o registro falhar, a notificação ocorre mesmo assim.
+func (rl *RateLimiter) notifyBlockOnce(ctx context.Context, key string, config Config, blockTime time.Duration) {
+	if rl.onBlock == nil {
+		return
+	}
+
+	now := rl.clock.Now()
+	notifiedAt, err := rl.storage.FirstSeen(ctx, rl.subKey(key, blockNoticeKeySuffix), now, blockTime)
+	if err != nil {
+		rl.logger.Printf("Falha ao registrar notificação de bloqueio da chave %s: %v", key, err)
+	} else if notifiedAt.Before(now) {
+		return
+	}
+
+	rl.onBlock(ctx, key, config)
+}
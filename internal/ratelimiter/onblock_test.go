@@ -0,0 +1,142 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// blockRecorder registra as chamadas do hook OnBlock
+type blockRecorder struct {
+	keys    []string
+	configs []Config
+}
+
+func (b *blockRecorder) onBlock(ctx context.Context, key string, config Config) {
+	b.keys = append(b.keys, key)
+	b.configs = append(b.configs, config)
+}
+
+func TestRateLimiter_OnBlock(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{
+			name:   "decisão atômica",
+			config: Config{Requests: 2, Window: time.Second, BlockTime: time.Minute},
+		},
+		{
+			// Limites adicionais exigem a contagem em etapas, com Storage.Block
+			name: "contagem em etapas",
+			config: Config{
+				Requests:  2,
+				Window:    time.Second,
+				BlockTime: time.Minute,
+				Tiers:     []Config{{Requests: 100, Window: time.Hour}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClock := clock.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+			memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+			defer memoryStorage.Close()
+
+			recorder := &blockRecorder{}
+			rateLimiter := NewRateLimiter(memoryStorage, tt.config,
+				WithClock(fakeClock),
+				WithKeyPrefix("api:"),
+				WithOnBlock(recorder.onBlock),
+			)
+			ctx := context.Background()
+
+			check := func() Result {
+				t.Helper()
+				result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+				require.NoError(t, err)
+				return result
+			}
+
+			for i := 0; i < 2; i++ {
+				assert.True(t, check().Allowed)
+			}
+			assert.Empty(t, recorder.keys)
+
+			assert.True(t, check().Blocked)
+			assert.Equal(t, []string{"api:ip:192.168.1.1"}, recorder.keys)
+			assert.Equal(t, tt.config, recorder.configs[0])
+
+			// Requisições rejeitadas pelo bloqueio já ativo não disparam o hook
+			fakeClock.Advance(30 * time.Second)
+			assert.True(t, check().Blocked)
+			assert.Len(t, recorder.keys, 1)
+
+			// Um novo bloqueio após o fim do anterior é notificado novamente
+			fakeClock.Advance(30 * time.Second)
+			for i := 0; i < 2; i++ {
+				assert.True(t, check().Allowed)
+			}
+			assert.True(t, check().Blocked)
+			assert.Len(t, recorder.keys, 2)
+		})
+	}
+}
+
+func TestRateLimiter_OnBlockRepeatedBlock(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	config := Config{Requests: 1, Window: time.Second, BlockTime: time.Minute, Burst: 1}
+
+	mockStorage := new(MockStorage)
+	recorder := &blockRecorder{}
+	rateLimiter := NewRateLimiter(mockStorage, config,
+		WithClock(clock.NewFakeClock(start)),
+		WithOnBlock(recorder.onBlock),
+	)
+
+	// Duas requisições concorrentes excedem o limite antes de o bloqueio ser registrado; apenas
+	// a primeira a registrar a notificação dispara o hook
+	mockStorage.On("IsBlocked", ctx, "ip:192.168.1.1").Return(false, time.Duration(0), nil)
+	mockStorage.On("Increment", ctx, "ip:192.168.1.1", time.Second).Return(int64(3), false, nil)
+	mockStorage.On("Block", ctx, "ip:192.168.1.1", time.Minute).Return(nil)
+	mockStorage.On("FirstSeen", ctx, "ip:192.168.1.1:block_notice", start, time.Minute).Return(start, nil).Once()
+	mockStorage.On("FirstSeen", ctx, "ip:192.168.1.1:block_notice", start, time.Minute).Return(start.Add(-time.Millisecond), nil).Once()
+
+	for i := 0; i < 2; i++ {
+		result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+		require.NoError(t, err)
+		assert.True(t, result.Blocked)
+	}
+
+	assert.Len(t, recorder.keys, 1)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRateLimiter_OnBlockNoticeError(t *testing.T) {
+	ctx := context.Background()
+	config := Config{Requests: 1, Window: time.Second, BlockTime: time.Minute, Burst: 1}
+
+	mockStorage := new(MockStorage)
+	recorder := &blockRecorder{}
+	rateLimiter := NewRateLimiter(mockStorage, config, WithOnBlock(recorder.onBlock))
+
+	mockStorage.On("IsBlocked", ctx, "ip:192.168.1.1").Return(false, time.Duration(0), nil)
+	mockStorage.On("Increment", ctx, "ip:192.168.1.1", time.Second).Return(int64(3), false, nil)
+	mockStorage.On("Block", ctx, "ip:192.168.1.1", time.Minute).Return(nil)
+	mockStorage.On("FirstSeen", ctx, "ip:192.168.1.1:block_notice", mock.Anything, time.Minute).Return(time.Time{}, errors.New("redis indisponível"))
+
+	// Sem o registro da notificação o hook é chamado mesmo assim, e a requisição não falha
+	result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, result.Blocked)
+	assert.Len(t, recorder.keys, 1)
+}
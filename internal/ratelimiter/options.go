@@ -0,0 +1,146 @@
+package ratelimiter
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+)
+
+// Option configura o RateLimiter em NewRateLimiter
+type Option func(*RateLimiter)
+
+// WithAlgorithm define o algoritmo aplicado às configurações sem Algorithm definido. Sem esta
+// opção, a janela fixa é usada.
+func WithAlgorithm(algorithm Algorithm) Option {
+	return func(rl *RateLimiter) {
+		rl.defaultAlgorithm = algorithm
+	}
+}
+
+// WithConcurrencyTTL define a expiração das vagas de concorrência (ver DefaultConcurrencyTTL)
+func WithConcurrencyTTL(ttl time.Duration) Option {
+	return func(rl *RateLimiter) {
+		rl.concurrencyTTL = ttl
+	}
+}
+
+// WithKeyNames define os namespaces e o separador das chaves (ver KeyNames)
+func WithKeyNames(names KeyNames) Option {
+	return func(rl *RateLimiter) {
+		rl.SetKeyNames(names)
+	}
+}
+
+// WithKeyPrefix define o namespace aplicado a todas as chaves (ver SetKeyPrefix)
+func WithKeyPrefix(prefix string) Option {
+	return func(rl *RateLimiter) {
+		rl.keyPrefix = prefix
+	}
+}
+
+// WithClock define o relógio usado nas decisões que dependem do instante atual (ver SetClock)
+func WithClock(clock clock.Clock) Option {
+	return func(rl *RateLimiter) {
+		rl.clock = clock
+	}
+}
+
+// WithLogger define o logger das mensagens do rate limiter. Sem esta opção, o logger padrão
+// do pacote log é usado.
+func WithLogger(logger *log.Logger) Option {
+	return func(rl *RateLimiter) {
+		rl.logger = logger
+	}
+}
+
+// WithTokenConfig adiciona a configuração de um token (ver AddTokenConfig)
+func WithTokenConfig(token string, config Config) Option {
+	return func(rl *RateLimiter) {
+		rl.tokens[token] = config
+	}
+}
+
+// WithResetOnConfigChange define se as alterações de configuração de tokens zeram os seus
+// contadores e bloqueios (ver SetResetOnConfigChange)
+func WithResetOnConfigChange(reset bool) Option {
+	return func(rl *RateLimiter) {
+		rl.resetOnConfigChange = reset
+	}
+}
+
+// WithTokenConfigSource define a fonte de configurações de tokens (ver SetTokenConfigSource)
+func WithTokenConfigSource(source TokenConfigSource) Option {
+	return func(rl *RateLimiter) {
+		rl.tokenSource = source
+	}
+}
+
+// WithUnknownTokenPolicy define o tratamento de tokens sem configuração (ver
+// SetUnknownTokenPolicy)
+func WithUnknownTokenPolicy(policy UnknownTokenPolicy) Option {
+	return func(rl *RateLimiter) {
+		rl.unknownTokenPolicy = policy
+	}
+}
+
+// WithDefaultTokenConfig define a configuração de tokens desconhecidos com a política
+// AllowWithDefault (ver SetDefaultTokenConfig)
+func WithDefaultTokenConfig(config Config) Option {
+	return func(rl *RateLimiter) {
+		rl.defaultToken = &config
+	}
+}
+
+// WithOnBlock define o hook chamado quando uma chave passa a ser bloqueada (ver SetOnBlock)
+func WithOnBlock(onBlock func(ctx context.Context, key string, config Config)) Option {
+	return func(rl *RateLimiter) {
+		rl.onBlock = onBlock
+	}
+}
+
+// WithGlobalConfig define o limite global de todos os clientes (ver SetGlobalConfig)
+func WithGlobalConfig(config Config) Option {
+	return func(rl *RateLimiter) {
+		rl.globalConfig = &config
+	}
+}
+
+// WithAnonymousConfig define o limite das requisições sem token (ver SetAnonymousConfig)
+func WithAnonymousConfig(config Config, scope AnonymousScope) Option {
+	return func(rl *RateLimiter) {
+		rl.SetAnonymousConfig(config, scope)
+	}
+}
+
+// WithIPTokenConfig define a configuração de cada par de IP e token (ver SetIPTokenConfig)
+func WithIPTokenConfig(config Config) Option {
+	return func(rl *RateLimiter) {
+		rl.ipTokenConfig = &config
+	}
+}
+
+// WithBonusGrants define se as verificações consultam os bônus concedidos com GrantBonus (ver
+// SetBonusGrants)
+func WithBonusGrants(enabled bool) Option {
+	return func(rl *RateLimiter) {
+		rl.SetBonusGrants(enabled)
+	}
+}
+
+// WithBlockCache habilita o cache local de bloqueios com a duração máxima informada (ver
+// SetBlockCache)
+func WithBlockCache(maxTTL time.Duration) Option {
+	return func(rl *RateLimiter) {
+		rl.SetBlockCache(maxTTL)
+	}
+}
+
+// WithMaxIdempotentReplays limita as repetições de uma decisão por chave de idempotência (ver
+// SetMaxIdempotentReplays)
+func WithMaxIdempotentReplays(replays int64) Option {
+	return func(rl *RateLimiter) {
+		rl.maxIdempotentReplays = replays
+	}
+}
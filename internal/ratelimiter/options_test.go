@@ -0,0 +1,84 @@
+package ratelimiter
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRateLimiter_WithKeyPrefix(t *testing.T) {
+	mockStorage := &MockStorage{}
+	config := Config{Requests: 5, Window: time.Second, BlockTime: time.Minute}
+
+	rateLimiter := NewRateLimiter(mockStorage, config,
+		WithKeyPrefix("myapp:"),
+		WithTokenConfig("abc123", config),
+	)
+
+	ctx := context.Background()
+	limit := storage.Limit{Requests: 5, Window: time.Second, BlockTime: time.Minute}
+	mockStorage.On("CheckAndIncrement", ctx, "myapp:ip:192.168.1.1", limit).Return(storage.Decision{Allowed: true, Count: 1}, nil).Once()
+	mockStorage.On("CheckAndIncrement", ctx, "myapp:token:abc123", limit).Return(storage.Decision{Allowed: true, Count: 1}, nil).Once()
+
+	_, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	_, err = rateLimiter.CheckToken(ctx, "abc123")
+	require.NoError(t, err)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestNewRateLimiter_WithAlgorithm(t *testing.T) {
+	mockStorage := &MockStorage{}
+	rateLimiter := NewRateLimiter(mockStorage, Config{Requests: 2, Window: 2 * time.Second},
+		WithAlgorithm(AlgorithmLeakyBucket),
+		WithTokenConfig("fixed", Config{Requests: 2, Window: time.Second, Algorithm: AlgorithmFixedWindow}),
+	)
+
+	ctx := context.Background()
+
+	// Configurações sem algoritmo usam o algoritmo padrão
+	mockStorage.On("LeakyBucket", ctx, "ip:192.168.1.1", int64(2), time.Second, mock.Anything).Return(true, time.Duration(0), nil).Once()
+
+	result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// Um algoritmo explícito tem precedência
+	mockStorage.On("CheckAndIncrement", ctx, "token:fixed", mock.Anything).Return(storage.Decision{Allowed: true, Count: 1}, nil).Once()
+
+	_, err = rateLimiter.CheckToken(ctx, "fixed")
+	require.NoError(t, err)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestNewRateLimiter_WithClockAndLogger(t *testing.T) {
+	mockStorage := &MockStorage{}
+	start := time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC)
+	var output bytes.Buffer
+
+	rateLimiter := NewRateLimiter(mockStorage, Config{Requests: 1, Window: time.Second, GracePeriod: time.Hour},
+		WithClock(clock.NewFakeClock(start)),
+		WithLogger(log.New(&output, "", 0)),
+	)
+
+	ctx := context.Background()
+	mockStorage.On("Increment", ctx, "ip:192.168.1.1", time.Second).Return(int64(2), false, nil).Once()
+	mockStorage.On("FirstSeen", ctx, "ip:192.168.1.1", start, time.Hour+time.Second).Return(start, nil).Once()
+
+	result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, result.InGracePeriod)
+	assert.Contains(t, output.String(), "Chave ip:192.168.1.1 excedeu o limite de 1 requisições durante o período de carência")
+
+	mockStorage.AssertExpectations(t)
+}
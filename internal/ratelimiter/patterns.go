@@ -0,0 +1,39 @@
+package ratelimiter
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// tokenPattern associa uma expressão regular já compilada à configuração de uma família de
+// tokens
+type tokenPattern struct {
+	pattern string
+	re      *regexp.Regexp
+	config  Config
+}
+
+// AddTokenPattern adiciona a configuração de uma família de tokens (ex: "free_.*"), aplicada
+// aos tokens sem configuração própria. O padrão é uma expressão regular que deve corresponder
+// ao token inteiro. Quando mais de um padrão corresponde, vale o primeiro adicionado, então os
+// padrões mais específicos devem ser adicionados antes dos mais genéricos. A expressão é
+// compilada uma única vez; um padrão inválido retorna erro.
+func (rl *RateLimiter) AddTokenPattern(pattern string, config Config) error {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return fmt.Errorf("padrão de token inválido %q: %w", pattern, err)
+	}
+
+	rl.tokenPatterns = append(rl.tokenPatterns, tokenPattern{pattern: pattern, re: re, config: config})
+	return nil
+}
+
+// matchTokenPattern retorna a configuração do primeiro padrão que corresponde ao token
+func (rl *RateLimiter) matchTokenPattern(token string) (Config, bool) {
+	for _, p := range rl.tokenPatterns {
+		if p.re.MatchString(token) {
+			return p.config, true
+		}
+	}
+	return Config{}, false
+}
@@ -0,0 +1,81 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_TokenPatterns(t *testing.T) {
+	ipConfig := Config{Requests: 10, Window: time.Second}
+	freeConfig := Config{Requests: 2, Window: time.Second, BlockTime: time.Minute}
+	proConfig := Config{Requests: 5, Window: time.Second, BlockTime: time.Minute}
+	vipConfig := Config{Requests: 50, Window: time.Second}
+	ctx := context.Background()
+
+	mockStorage := new(MockStorage)
+	rateLimiter := NewRateLimiter(mockStorage, ipConfig, WithTokenConfig("pro_vip", vipConfig))
+	require.NoError(t, rateLimiter.AddTokenPattern(`free_\w+`, freeConfig))
+	require.NoError(t, rateLimiter.AddTokenPattern(`pro_.*`, proConfig))
+
+	tests := []struct {
+		token  string
+		config Config
+	}{
+		{token: "free_abc", config: freeConfig},
+		{token: "pro_xyz", config: proConfig},
+		// A configuração exata tem precedência sobre o padrão
+		{token: "pro_vip", config: vipConfig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			limit := storage.Limit{Requests: tt.config.Requests, Window: tt.config.Window, BlockTime: tt.config.BlockTime}
+			mockStorage.On("CheckAndIncrement", ctx, "token:"+tt.token, limit).Return(storage.Decision{Allowed: true, Count: 1}, nil).Once()
+
+			result, err := rateLimiter.CheckToken(ctx, tt.token)
+			require.NoError(t, err)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, tt.config.Requests, result.Limit)
+		})
+	}
+
+	// O padrão deve corresponder ao token inteiro
+	for _, token := range []string{"xfree_abc", "free_", "enterprise_1"} {
+		_, err := rateLimiter.CheckToken(ctx, token)
+		assert.ErrorIs(t, err, ErrUnknownToken, token)
+	}
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRateLimiter_TokenPatternsFirstMatch(t *testing.T) {
+	rateLimiter := NewRateLimiter(new(MockStorage), Config{Requests: 10, Window: time.Second})
+
+	specific := Config{Requests: 1, Window: time.Second}
+	generic := Config{Requests: 100, Window: time.Second}
+	require.NoError(t, rateLimiter.AddTokenPattern(`free_test_.*`, specific))
+	require.NoError(t, rateLimiter.AddTokenPattern(`free_.*`, generic))
+
+	config, exists, err := rateLimiter.tokenConfig(context.Background(), "free_test_1")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, specific, config)
+
+	config, exists, err = rateLimiter.tokenConfig(context.Background(), "free_abc")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, generic, config)
+}
+
+func TestRateLimiter_AddTokenPatternInvalid(t *testing.T) {
+	rateLimiter := NewRateLimiter(new(MockStorage), Config{Requests: 10, Window: time.Second})
+
+	err := rateLimiter.AddTokenPattern(`free_(`, Config{Requests: 1, Window: time.Second})
+	assert.ErrorContains(t, err, "padrão de token inválido")
+	assert.Empty(t, rateLimiter.tokenPatterns)
+}
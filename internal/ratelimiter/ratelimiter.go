@@ -2,85 +2,1054 @@ package ratelimiter
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
+	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
 	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
 )
 
-// Config armazena a configuração do rate limiter
+// Algorithm identifica o algoritmo de limitação aplicado a uma configuração
+type Algorithm string
+
+const (
+	// AlgorithmFixedWindow conta as requisições em uma janela fixa e bloqueia a chave ao exceder o limite
+	AlgorithmFixedWindow Algorithm = "fixed_window"
+
+	// AlgorithmLeakyBucket modela uma fila que escoa a uma taxa constante de Requests por Window,
+	// com capacidade de Requests, rejeitando o excedente quando a fila está cheia
+	AlgorithmLeakyBucket Algorithm = "leaky_bucket"
+)
+
+// WindowAlignment define quando as janelas de contagem recomeçam
+type WindowAlignment string
+
+const (
+	// Rolling inicia a janela na primeira requisição da chave, que recomeça Window depois
+	// (padrão)
+	Rolling WindowAlignment = "rolling"
+
+	// Calendar alinha as janelas às fronteiras do relógio múltiplas de Window, em UTC (ex: o
+	// início de cada segundo ou minuto), de modo que todas as chaves recomeçam juntas
+	Calendar WindowAlignment = "calendar"
+)
+
+// Config armazena a configuração do rate limiter. O valor zero não é utilizável: sem Requests
+// todas as requisições são rejeitadas e sem Window os contadores não expiram corretamente. Use
+// DefaultIPConfig ou Config.WithDefaults para partir de valores razoáveis.
 type Config struct {
-	Requests  int64
-	Window    time.Duration
+	// Requests é o número de requisições permitidas por janela. Zero rejeita todas as
+	// requisições.
+	Requests int64
+
+	// Window é a duração da janela de contagem. Deve ser positiva; WithDefaults usa
+	// DefaultWindow quando zero.
+	Window time.Duration
+
+	// WindowAlignment define se a janela recomeça Window depois da primeira requisição da chave
+	// (Rolling, padrão quando vazio) ou nas fronteiras do relógio (Calendar: com Window de um
+	// minuto, sempre no início de cada minuto). Aplica-se à janela principal e aos Tiers do
+	// algoritmo de janela fixa.
+	WindowAlignment WindowAlignment
+
+	// SoftLimit é a contagem a partir da qual as requisições ainda permitidas são sinalizadas
+	// com Result.SoftLimitExceeded, para que o cliente reduza o ritmo antes de ser bloqueado.
+	// Deve ser menor que Requests; zero desabilita o aviso. Aplica-se somente ao algoritmo de
+	// janela fixa.
+	SoftLimit int64
+
+	// Burst são requisições adicionais aceitas acima de Requests em uma janela, consumidas uma
+	// única vez a cada BurstWindow: a janela aceita até Requests + Burst, mas depois que o
+	// excedente é gasto vale apenas Requests até o BurstWindow terminar. Aplica-se somente ao
+	// algoritmo de janela fixa.
+	Burst int64
+
+	// BurstWindow é o período em que Burst pode ser consumido uma única vez. Zero usa
+	// DefaultBurstWindows janelas.
+	BurstWindow time.Duration
+
+	// BlockTime é a duração do bloqueio aplicado quando o limite é excedido. Zero ou negativo
+	// desabilita o bloqueio: as requisições excedentes são apenas rejeitadas até a janela
+	// terminar. Como WithDefaults preenche o zero com DefaultBlockTime, use um valor negativo
+	// para desabilitar o bloqueio nesse caso.
 	BlockTime time.Duration
+
+	// BlockJitter adiciona a cada bloqueio uma duração aleatória entre zero e BlockJitter, para
+	// que chaves bloqueadas ao mesmo tempo não sejam liberadas todas juntas. Zero mantém o
+	// bloqueio exato.
+	BlockJitter time.Duration
+
+	// BlockEscalationFactor multiplica o tempo de bloqueio a cada bloqueio consecutivo da chave
+	// (BlockTime, BlockTime*fator, BlockTime*fator², ...), até MaxBlockTime. Valores até 1
+	// desabilitam a escalada.
+	BlockEscalationFactor float64
+
+	// MaxBlockTime limita o tempo de bloqueio escalado por BlockEscalationFactor. Zero não limita.
+	MaxBlockTime time.Duration
+
+	// BlockEscalationReset é o período sem novos bloqueios, contado a partir do início do último
+	// bloqueio, após o qual a escalada recomeça de BlockTime. Deve ser maior que os bloqueios
+	// aplicados, já que uma chave bloqueada não volta a ser bloqueada antes do fim do bloqueio.
+	// Zero usa DefaultBlockEscalationReset.
+	BlockEscalationReset time.Duration
+
+	// Algorithm define o algoritmo de limitação; vazio equivale a AlgorithmFixedWindow
+	Algorithm Algorithm
+
+	// Tiers são limites adicionais avaliados junto com o limite principal (ex: 10 req/s e
+	// 1000 req/h), cada um com seu próprio contador. A requisição é negada se qualquer limite
+	// for excedido e a chave é bloqueada pelo maior BlockTime entre os limites excedidos.
+	// Apenas Requests, Window e BlockTime de cada tier são considerados, e os tiers se aplicam
+	// somente ao algoritmo de janela fixa.
+	Tiers []Config
+
+	// GracePeriod é o período, contado a partir do primeiro contato da chave, durante o qual
+	// exceder o limite é apenas registrado em log em vez de bloquear. Zero desabilita a carência.
+	GracePeriod time.Duration
+
+	// MaxConcurrent limita as requisições simultâneas (em andamento) da chave, além do limite
+	// por janela. Cada requisição permitida ocupa uma vaga até Result.Release. A vaga é ocupada
+	// antes da contagem, então requisições negadas por concorrência não consomem o limite por
+	// janela. Zero não limita.
+	MaxConcurrent int64
+}
+
+// DefaultBurstWindows é o número de janelas do BurstWindow padrão
+const DefaultBurstWindows = 10
+
+// burstWindow retorna o período em que o Burst pode ser consumido uma única vez
+func (c Config) burstWindow() time.Duration {
+	if c.BurstWindow > 0 {
+		return c.BurstWindow
+	}
+	return c.Window * DefaultBurstWindows
+}
+
+// longestBlockTime retorna o maior tempo de bloqueio entre o limite principal e os adicionais,
+// considerando o limite da escalada quando ela está habilitada
+func (c Config) longestBlockTime() time.Duration {
+	blockTime := c.BlockTime
+	for _, tier := range c.Tiers {
+		blockTime = max(blockTime, tier.BlockTime)
+	}
+	if c.BlockEscalationFactor > 1 {
+		blockTime = max(blockTime, c.MaxBlockTime)
+	}
+	return blockTime
+}
+
+// WithoutBlock retorna uma cópia da configuração que apenas rejeita as requisições excedentes
+// até o fim da janela, sem bloquear a chave (ex: tokens de clientes pagantes, para os quais
+// um pico breve não deve resultar em minutos de bloqueio). O bloqueio, a variação e a escalada
+// são desabilitados também nos Tiers; o BlockTime negativo é preservado por WithDefaults.
+func (c Config) WithoutBlock() Config {
+	c.BlockTime = -1
+	c.BlockJitter = 0
+	c.BlockEscalationFactor = 0
+	c.MaxBlockTime = 0
+
+	if c.Tiers != nil {
+		tiers := make([]Config, len(c.Tiers))
+		for i, tier := range c.Tiers {
+			tiers[i] = tier.WithoutBlock()
+		}
+		c.Tiers = tiers
+	}
+	return c
+}
+
+// Result descreve o resultado de uma verificação de limite
+type Result struct {
+	// Allowed indica se a requisição tem permissão para prosseguir
+	Allowed bool
+
+	// RetryAfter é o tempo estimado até que uma nova requisição seja aceita, quando conhecido
+	RetryAfter time.Duration
+
+	// Limit é o número máximo de requisições permitidas na janela
+	Limit int64
+
+	// Window é a duração da janela do limite informado em Limit, quando conhecida
+	Window time.Duration
+
+	// Count é o número de requisições contabilizadas na janela atual
+	Count int64
+
+	// Remaining é o número de requisições ainda disponíveis na janela atual
+	Remaining int64
+
+	// Blocked indica que a chave está bloqueada
+	Blocked bool
+
+	// ResetAt é o instante em que a janela atual termina, quando conhecido
+	ResetAt time.Time
+
+	// InGracePeriod indica que o limite foi excedido, mas a requisição foi permitida por estar
+	// dentro do período de carência da chave
+	InGracePeriod bool
+
+	// ConcurrencyExceeded indica que a requisição foi negada por exceder MaxConcurrent
+	ConcurrencyExceeded bool
+
+	// SoftLimitExceeded indica que a requisição foi permitida, mas a contagem da janela
+	// ultrapassou o SoftLimit da configuração
+	SoftLimitExceeded bool
+
+	// release libera a vaga de concorrência ocupada pela requisição, se houver
+	release func()
 }
 
+// ErrUnknownToken é retornado por CheckToken quando o token não possui configuração e a
+// política para tokens desconhecidos não aplica uma configuração padrão
+var ErrUnknownToken = errors.New("token desconhecido")
+
+// UnknownTokenPolicy define o tratamento de tokens sem configuração
+type UnknownTokenPolicy int
+
+const (
+	// FallbackToIP aplica o limite de IP às requisições com token desconhecido (padrão)
+	FallbackToIP UnknownTokenPolicy = iota
+
+	// Reject rejeita as requisições com token desconhecido
+	Reject
+
+	// AllowWithDefault limita cada token desconhecido com a configuração padrão de tokens
+	AllowWithDefault
+)
+
+// ErrIPTokenNotConfigured é retornado por CheckIPToken quando nenhuma configuração para pares
+// de IP e token foi definida
+var ErrIPTokenNotConfigured = errors.New("configuração de limitação por IP e token não definida")
+
 // RateLimiter gerencia a lógica de limitação de taxa
 type RateLimiter struct {
-	storage  storage.Storage
-	ipConfig Config
-	tokens   map[string]Config
+	storage         storage.Storage
+	ipConfigMu      sync.RWMutex
+	ipConfig        Config
+	tokensMu        sync.RWMutex
+	tokens          map[string]Config
+	tokenPatterns   []tokenPattern
+	tokenSource     TokenConfigSource
+	ipTokenConfig   *Config
+	globalConfig    *Config
+	anonymousConfig *Config
+	anonymousScope  AnonymousScope
+	clock           clock.Clock
+	logger          *log.Logger
+	onBlock         func(ctx context.Context, key string, config Config)
+
+	// sleep aguarda entre as verificações de Wait; substituído nos testes para avançar um
+	// relógio falso
+	sleep func(ctx context.Context, d time.Duration) error
+
+	defaultAlgorithm   Algorithm
+	concurrencyTTL     time.Duration
+	unknownTokenPolicy UnknownTokenPolicy
+	defaultToken       *Config
+	keyPrefix          string
+	keys               keyPrefixes
+	blockCache         *blockCache
+
+	// resetOnConfigChange zera os contadores e bloqueios dos tokens cuja configuração é
+	// alterada por UpdateTokenConfig e SetTokenConfigs
+	resetOnConfigChange bool
+
+	// bonusGrants habilita a consulta dos bônus de GrantBonus em cada verificação
+	bonusGrants atomic.Bool
+
+	// maxIdempotentReplays limita as repetições de uma decisão por chave de idempotência (ver
+	// SetMaxIdempotentReplays)
+	maxIdempotentReplays int64
 }
 
-// NewRateLimiter cria uma nova instância do rate limiter
-func NewRateLimiter(storage storage.Storage, ipConfig Config) *RateLimiter {
-	return &RateLimiter{
+// NewRateLimiter cria uma nova instância do rate limiter, aplicando as opções informadas em
+// ordem
+func NewRateLimiter(storage storage.Storage, ipConfig Config, opts ...Option) *RateLimiter {
+	rl := &RateLimiter{
 		storage:  storage,
 		ipConfig: ipConfig,
 		tokens:   make(map[string]Config),
+		keys:     DefaultKeyNames.prefixes(),
+		clock:    clock.New(),
+		logger:   log.Default(),
+		sleep:    sleepContext,
+	}
+
+	for _, opt := range opts {
+		opt(rl)
 	}
+
+	return rl
+}
+
+// Storage retorna o armazenamento usado pelo rate limiter, para operações personalizadas (ex:
+// ferramentas administrativas). As chaves informadas ao armazenamento devem incluir o prefixo
+// definido em SetKeyPrefix.
+func (rl *RateLimiter) Storage() storage.Storage {
+	return rl.storage
+}
+
+// SetClock substitui o relógio usado nas decisões que dependem do instante atual
+func (rl *RateLimiter) SetClock(clock clock.Clock) {
+	rl.clock = clock
+}
+
+// Clock retorna o relógio usado nas decisões, referência de Result.ResetAt
+func (rl *RateLimiter) Clock() clock.Clock {
+	return rl.clock
+}
+
+// SetIPConfig substitui a configuração de IP em tempo de execução (ex: ao recarregar a
+// configuração), com segurança para verificações concorrentes. Os contadores e bloqueios
+// existentes são mantidos e passam a ser avaliados com o novo limite.
+func (rl *RateLimiter) SetIPConfig(config Config) {
+	rl.ipConfigMu.Lock()
+	defer rl.ipConfigMu.Unlock()
+
+	rl.ipConfig = config
+}
+
+// IPConfig retorna a configuração de IP atual
+func (rl *RateLimiter) IPConfig() Config {
+	rl.ipConfigMu.RLock()
+	defer rl.ipConfigMu.RUnlock()
+
+	return rl.ipConfig
 }
 
 // AddTokenConfig adiciona uma configuração de token
 func (rl *RateLimiter) AddTokenConfig(token string, config Config) {
+	rl.tokensMu.Lock()
+	defer rl.tokensMu.Unlock()
+
+	rl.tokens[token] = config
+}
+
+// UpdateTokenConfig substitui a configuração de um token em tempo de execução. Com
+// SetResetOnConfigChange, se a configuração anterior for diferente, o contador e o bloqueio do
+// token são removidos do armazenamento para que o novo limite valha desde já.
+func (rl *RateLimiter) UpdateTokenConfig(ctx context.Context, token string, config Config) error {
+	rl.tokensMu.Lock()
+	previous, existed := rl.tokens[token]
 	rl.tokens[token] = config
+	rl.tokensMu.Unlock()
+
+	if !existed || reflect.DeepEqual(previous, config) {
+		return nil
+	}
+	return rl.resetToken(ctx, token, previous, config)
+}
+
+// SetTokenConfigs substitui todas as configurações de tokens em tempo de execução (ex: ao
+// recarregar a configuração). Com SetResetOnConfigChange, os tokens alterados ou removidos têm
+// o contador e o bloqueio removidos do armazenamento.
+func (rl *RateLimiter) SetTokenConfigs(ctx context.Context, configs map[string]Config) error {
+	tokens := make(map[string]Config, len(configs))
+	for token, config := range configs {
+		tokens[token] = config
+	}
+
+	rl.tokensMu.Lock()
+	previous := rl.tokens
+	rl.tokens = tokens
+	rl.tokensMu.Unlock()
+
+	var errs []error
+	for token, previousConfig := range previous {
+		config, exists := tokens[token]
+		if exists && reflect.DeepEqual(previousConfig, config) {
+			continue
+		}
+		if err := rl.resetToken(ctx, token, previousConfig, config); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// SetResetOnConfigChange define se UpdateTokenConfig e SetTokenConfigs zeram os contadores e
+// bloqueios dos tokens cuja configuração muda. Sem isso, contadores da configuração anterior
+// continuam valendo até a janela ou o bloqueio expirarem.
+func (rl *RateLimiter) SetResetOnConfigChange(reset bool) {
+	rl.resetOnConfigChange = reset
+}
+
+// resetToken remove o contador, o excedente de burst, os contadores dos tiers das
+// configurações informadas e o bloqueio do token. Os contadores por par de IP e token
+// (CheckIPToken) não são removidos, já que dependem de cada IP.
+func (rl *RateLimiter) resetToken(ctx context.Context, token string, configs ...Config) error {
+	if !rl.resetOnConfigChange {
+		return nil
+	}
+
+	return rl.reset(ctx, rl.keyPrefix+rl.keys.token+token, configs...)
+}
+
+// SetTokenConfigSource define uma fonte de configurações (ex: DynamicConfigStore) consultada
+// por CheckToken para tokens ausentes do mapa local
+func (rl *RateLimiter) SetTokenConfigSource(source TokenConfigSource) {
+	rl.tokenSource = source
+}
+
+// SetKeyPrefix define um namespace (ex: "myapp:") aplicado a todas as chaves geradas pelo
+// rate limiter, para que serviços que compartilham o mesmo armazenamento não colidam
+func (rl *RateLimiter) SetKeyPrefix(prefix string) {
+	rl.keyPrefix = prefix
+}
+
+// SetUnknownTokenPolicy define o tratamento de tokens sem configuração
+func (rl *RateLimiter) SetUnknownTokenPolicy(policy UnknownTokenPolicy) {
+	rl.unknownTokenPolicy = policy
+}
+
+// UnknownTokenPolicy retorna a política aplicada a tokens sem configuração
+func (rl *RateLimiter) UnknownTokenPolicy() UnknownTokenPolicy {
+	return rl.unknownTokenPolicy
+}
+
+// SetDefaultTokenConfig define a configuração aplicada a tokens desconhecidos com a política
+// AllowWithDefault. Sem ela, a configuração de IP é usada.
+func (rl *RateLimiter) SetDefaultTokenConfig(config Config) {
+	rl.defaultToken = &config
+}
+
+// SetIPTokenConfig define a configuração aplicada a cada par de IP e token em CheckIPToken
+func (rl *RateLimiter) SetIPTokenConfig(config Config) {
+	rl.ipTokenConfig = &config
 }
 
 // CheckIP verifica se um endereço IP tem permissão para fazer uma requisição
-func (rl *RateLimiter) CheckIP(ctx context.Context, ip string) (bool, error) {
-	key := fmt.Sprintf("ip:%s", ip)
-	return rl.checkLimit(ctx, key, rl.ipConfig)
+func (rl *RateLimiter) CheckIP(ctx context.Context, ip string) (Result, error) {
+	key := rl.keyPrefix + rl.keys.ip + ip
+	return rl.checkLimit(ctx, key, rl.IPConfig())
+}
+
+// CheckToken verifica se um token tem permissão para fazer uma requisição. Para tokens sem
+// configuração retorna ErrUnknownToken, exceto com a política AllowWithDefault; cabe ao
+// chamador aplicar o limite de IP ou rejeitar a requisição conforme UnknownTokenPolicy.
+func (rl *RateLimiter) CheckToken(ctx context.Context, token string) (Result, error) {
+	config, exists, err := rl.tokenConfig(ctx, token)
+	if err != nil {
+		return Result{}, err
+	}
+	if !exists {
+		return Result{}, ErrUnknownToken
+	}
+
+	key := rl.keyPrefix + rl.keys.token + token
+	return rl.checkLimit(ctx, key, config)
+}
+
+// TokenConfig resolve a configuração de um token como CheckToken, indicando se ela existe,
+// para identidades derivadas do token fora das chaves de CheckToken (ex: por host de destino)
+func (rl *RateLimiter) TokenConfig(ctx context.Context, token string) (Config, bool, error) {
+	return rl.tokenConfig(ctx, token)
 }
 
-// CheckToken verifica se um token tem permissão para fazer uma requisição
-func (rl *RateLimiter) CheckToken(ctx context.Context, token string) (bool, error) {
+// tokenConfig resolve a configuração de um token: o mapa local tem precedência sobre a fonte
+// de configurações, que por sua vez tem precedência sobre os padrões de AddTokenPattern, e a
+// configuração padrão é aplicada a tokens desconhecidos quando a política é AllowWithDefault
+func (rl *RateLimiter) tokenConfig(ctx context.Context, token string) (Config, bool, error) {
+	rl.tokensMu.RLock()
 	config, exists := rl.tokens[token]
+	rl.tokensMu.RUnlock()
+	if exists {
+		return config, true, nil
+	}
+
+	if rl.tokenSource != nil {
+		config, exists, err := rl.tokenSource.TokenConfig(ctx, token)
+		if err != nil {
+			return Config{}, false, storageError(ErrTokenConfigFailed, err)
+		}
+		if exists {
+			return config, true, nil
+		}
+	}
+
+	if config, exists := rl.matchTokenPattern(token); exists {
+		return config, true, nil
+	}
+
+	if rl.unknownTokenPolicy != AllowWithDefault {
+		return Config{}, false, nil
+	}
+	if rl.defaultToken != nil {
+		return *rl.defaultToken, true, nil
+	}
+	return rl.IPConfig(), true, nil
+}
+
+// CheckIPToken verifica se o par de IP e token tem permissão para fazer uma requisição, de
+// modo que cada IP tenha seu próprio orçamento para um mesmo token. O token é armazenado
+// apenas como hash na chave.
+func (rl *RateLimiter) CheckIPToken(ctx context.Context, ip, token string) (Result, error) {
+	if rl.ipTokenConfig == nil {
+		return Result{}, ErrIPTokenNotConfigured
+	}
+
+	key := rl.keyPrefix + rl.keys.ipToken + ip + rl.keys.separator + hashToken(token)
+	return rl.checkLimit(ctx, key, *rl.ipTokenConfig)
+}
+
+// CheckIPWithToken verifica o limite do IP usando a configuração do token no lugar da de IP,
+// de modo que o token eleve o teto do IP sem ter um orçamento próprio. Tokens sem
+// configuração são tratados como em CheckToken, retornando ErrUnknownToken exceto com a
+// política AllowWithDefault.
+func (rl *RateLimiter) CheckIPWithToken(ctx context.Context, ip, token string) (Result, error) {
+	config, exists, err := rl.tokenConfig(ctx, token)
+	if err != nil {
+		return Result{}, err
+	}
 	if !exists {
-		// Se a configuração do token não existe, volta para limitação baseada em IP
-		return true, nil
+		return Result{}, ErrUnknownToken
 	}
 
-	key := fmt.Sprintf("token:%s", token)
+	key := rl.keyPrefix + rl.keys.ip + ip
 	return rl.checkLimit(ctx, key, config)
 }
 
-// checkLimit executa a verificação de limitação de taxa
-func (rl *RateLimiter) checkLimit(ctx context.Context, key string, config Config) (bool, error) {
-	// Primeiro verifica se a chave está atualmente bloqueada
-	blocked, err := rl.storage.IsBlocked(ctx, key)
+// hashToken retorna um identificador estável do token que não expõe o seu valor no armazenamento
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:16])
+}
+
+// CheckKey verifica se uma identidade arbitrária (ex: ID do usuário ou tenant) tem permissão
+// para fazer uma requisição, usando a configuração informada
+func (rl *RateLimiter) CheckKey(ctx context.Context, key string, config Config) (Result, error) {
+	storageKey := rl.keyPrefix + rl.keys.custom + key
+	return rl.checkLimit(ctx, storageKey, config)
+}
+
+// Peek consulta o uso atual de uma chave de armazenamento (ex: "ip:192.168.1.1" ou
+// "token:abc123") sem consumir uma requisição. O limite considerado é o do token, para
+// chaves de tokens configurados, o global, para GlobalKey, o anônimo, para as chaves de
+// CheckAnonymous, ou o de IP nos demais casos. Reflete o contador da janela fixa; o estado de
+// leaky buckets não é consultado. A chave é informada sem o
+// prefixo definido em SetKeyPrefix. Para chaves bloqueadas, RetryAfter é o tempo restante do
+// bloqueio.
+func (rl *RateLimiter) Peek(ctx context.Context, key string) (Result, error) {
+	config, err := rl.configForKey(ctx, key)
+	if err != nil {
+		return Result{}, err
+	}
+	key = rl.keyPrefix + key
+
+	count, ttl, err := rl.storage.Get(ctx, key)
 	if err != nil {
-		return false, fmt.Errorf("falha ao verificar se está bloqueado: %w", err)
+		return Result{}, storageError(ErrReadFailed, err)
+	}
+
+	blocked, blockTTL, err := rl.storage.IsBlocked(ctx, key)
+	if err != nil {
+		return Result{}, storageError(ErrBlockCheckFailed, err)
+	}
+
+	return rl.peekResult(config, storage.KeyState{Count: count, TTL: ttl, Blocked: blocked, BlockTTL: blockTTL}), nil
+}
+
+// PeekMany equivale a Peek para várias chaves, lidas do armazenamento de uma só vez (ver
+// Storage.GetMany) em vez de uma ida por chave. Os resultados seguem a ordem das chaves.
+// Destinada a ferramentas administrativas que consultam muitas chaves.
+func (rl *RateLimiter) PeekMany(ctx context.Context, keys []string) ([]Result, error) {
+	configs := make([]Config, len(keys))
+	storageKeys := make([]string, len(keys))
+	for i, key := range keys {
+		config, err := rl.configForKey(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		configs[i] = config
+		storageKeys[i] = rl.keyPrefix + key
+	}
+
+	states, err := rl.storage.GetMany(ctx, storageKeys)
+	if err != nil {
+		return nil, storageError(ErrReadFailed, err)
+	}
+
+	results := make([]Result, len(keys))
+	for i, state := range states {
+		results[i] = rl.peekResult(configs[i], state)
+	}
+
+	return results, nil
+}
+
+// peekResult monta o resultado de Peek a partir do estado da chave no armazenamento
+func (rl *RateLimiter) peekResult(config Config, state storage.KeyState) Result {
+	remaining := config.Requests - state.Count
+	if remaining < 0 || state.Blocked {
+		remaining = 0
+	}
+
+	result := Result{
+		Allowed:   !state.Blocked && remaining > 0,
+		Limit:     config.Requests,
+		Window:    config.Window,
+		Count:     state.Count,
+		Remaining: remaining,
+		Blocked:   state.Blocked,
+	}
+	if state.Blocked {
+		result.RetryAfter = state.BlockTTL
+	}
+	if state.TTL > 0 {
+		result.ResetAt = rl.clock.Now().Add(state.TTL)
+	}
+
+	return result
+}
+
+// BlockedKeys lista as chaves de armazenamento atualmente bloqueadas (ex: "ip:192.168.1.1"),
+// sem o prefixo definido em SetKeyPrefix, no mesmo formato aceito por Peek. Percorre todas as
+// chaves do armazenamento (SCAN no Redis), então é destinada a consultas operacionais e não
+// deve ser chamada por requisição.
+func (rl *RateLimiter) BlockedKeys(ctx context.Context) ([]string, error) {
+	pattern := "*"
+	if !strings.ContainsAny(rl.keyPrefix, "*?") {
+		pattern = rl.keyPrefix + "*"
+	}
+
+	blocked, err := rl.storage.ListBlocked(ctx, pattern)
+	if err != nil {
+		return nil, storageError(ErrListBlockedFailed, err)
+	}
+
+	keys := make([]string, 0, len(blocked))
+	for _, key := range blocked {
+		if key, ok := strings.CutPrefix(key, rl.keyPrefix); ok {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// configForKey resolve a configuração aplicável a uma chave de armazenamento
+func (rl *RateLimiter) configForKey(ctx context.Context, key string) (Config, error) {
+	if token, ok := strings.CutPrefix(key, rl.keys.token); ok {
+		config, exists, err := rl.tokenConfig(ctx, token)
+		if err != nil {
+			return Config{}, err
+		}
+		if exists {
+			return config, nil
+		}
+	}
+
+	if strings.HasPrefix(key, rl.keys.ipToken) && rl.ipTokenConfig != nil {
+		return *rl.ipTokenConfig, nil
+	}
+
+	if key == GlobalKey && rl.globalConfig != nil {
+		return *rl.globalConfig, nil
+	}
+
+	if rl.anonymousConfig != nil && (key == AnonymousKey || strings.HasPrefix(key, AnonymousKey+rl.keys.separator)) {
+		return *rl.anonymousConfig, nil
+	}
+
+	return rl.IPConfig(), nil
+}
+
+// checkLimit executa a verificação de limitação de taxa e, quando MaxConcurrent está definido,
+// mantém uma vaga de concorrência ocupada para as requisições permitidas
+func (rl *RateLimiter) checkLimit(ctx context.Context, key string, config Config) (Result, error) {
+	if result, blocked := rl.cachedBlock(key, config); blocked {
+		return result, nil
+	}
+
+	// O bônus concedido com GrantBonus eleva o limite da chave enquanto estiver ativo
+	config, err := rl.withBonus(ctx, key, config)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if countingDisabled(ctx) {
+		result, err := rl.inspect(ctx, key, config)
+		rl.cacheBlock(key, result)
+		return result, err
+	}
+
+	// A vaga de concorrência é ocupada antes da contagem, para que requisições negadas por
+	// concorrência não consumam o limite por janela, e liberada se a requisição for negada
+	var release func()
+	if config.MaxConcurrent > 0 {
+		var acquired bool
+		release, acquired, err = rl.acquire(ctx, key, config)
+		if err != nil {
+			return Result{}, err
+		}
+		if !acquired {
+			return Result{Limit: config.Requests, Window: config.Window, ConcurrencyExceeded: true}, nil
+		}
+	}
+
+	result, err := rl.checkRate(ctx, key, config)
+	rl.cacheBlock(key, result)
+	if err != nil || !result.Allowed {
+		if release != nil {
+			release()
+		}
+		return result, err
+	}
+
+	result.SoftLimitExceeded = config.SoftLimit > 0 && result.Count > config.SoftLimit
+	result.release = release
+	return result, nil
+}
+
+// checkRate aplica o limite por janela (ou o leaky bucket) à requisição
+func (rl *RateLimiter) checkRate(ctx context.Context, key string, config Config) (Result, error) {
+	if config.Algorithm == "" {
+		config.Algorithm = rl.defaultAlgorithm
+	}
+
+	idempotencyKey, hasIdempotencyKey := idempotencyKeyFromContext(ctx)
+	if !hasIdempotencyKey {
+		return rl.evaluate(ctx, key, config)
+	}
+
+	// Uma decisão registrada não substitui um bloqueio ativo
+	result, blocked, err := rl.checkBlocked(ctx, key, config)
+	if err != nil || blocked {
+		return result, err
+	}
+
+	// Repete a decisão anterior quando a requisição já foi vista com a mesma chave de idempotência
+	decisionKey := rl.subKey(key, idempotencyKey)
+
+	allowed, found, err := rl.storage.GetDecision(ctx, decisionKey)
+	if err != nil {
+		return Result{}, storageError(ErrIdempotencyFailed, err)
+	}
+	if found {
+		return rl.replayDecision(ctx, decisionKey, allowed, config)
+	}
+
+	result, err = rl.evaluate(ctx, key, config)
+	if err != nil {
+		return Result{}, err
+	}
+
+	err = rl.storage.SetDecision(ctx, decisionKey, result.Allowed, config.Window)
+	if err != nil {
+		return Result{}, storageError(ErrIdempotencyFailed, err)
+	}
+
+	return result, nil
+}
+
+// checkBlocked verifica se a chave está atualmente bloqueada (o leaky bucket e as
+// configurações sem tempo de bloqueio não aplicam bloqueios), com o tempo restante do bloqueio
+// em RetryAfter. O bloqueio e os contadores são
+// chaves independentes que o armazenamento pode descartar separadamente (ex: evicção do Redis
+// sob pressão de memória): o bloqueio prevalece mesmo sem contador, e sem o bloqueio a
+// contagem recomeça do que restou, voltando a bloquear a chave quando um limite é excedido.
+func (rl *RateLimiter) checkBlocked(ctx context.Context, key string, config Config) (Result, bool, error) {
+	if config.Algorithm == AlgorithmLeakyBucket || config.longestBlockTime() <= 0 {
+		return Result{}, false, nil
+	}
+
+	blocked, ttl, err := rl.storage.IsBlocked(ctx, key)
+	if err != nil {
+		return Result{}, false, storageError(ErrBlockCheckFailed, err)
 	}
 
 	if blocked {
-		return false, nil
+		return Result{Allowed: false, Limit: config.Requests, Window: config.Window, Blocked: true, RetryAfter: ttl}, true, nil
+	}
+	return Result{}, false, nil
+}
+
+// evaluate aplica o algoritmo configurado à requisição
+func (rl *RateLimiter) evaluate(ctx context.Context, key string, config Config) (Result, error) {
+	switch config.Algorithm {
+	case "", AlgorithmFixedWindow:
+		// Limites simples são decididos atomicamente pelo armazenamento; tiers, período de
+		// carência e requisições com peso exigem a contagem em etapas
+		if len(config.Tiers) == 0 && config.GracePeriod <= 0 && config.Burst <= 0 && weightFromContext(ctx) == 1 {
+			return rl.checkAndIncrement(ctx, key, config)
+		}
+
+		result, blocked, err := rl.checkBlocked(ctx, key, config)
+		if err != nil || blocked {
+			return result, err
+		}
+		return rl.countRequest(ctx, key, config)
+	case AlgorithmLeakyBucket:
+		return rl.drip(ctx, key, config)
+	default:
+		return Result{}, fmt.Errorf("algoritmo de limitação desconhecido: %q", config.Algorithm)
+	}
+}
+
+// checkAndIncrement decide a requisição em uma única operação atômica do armazenamento
+func (rl *RateLimiter) checkAndIncrement(ctx context.Context, key string, config Config) (Result, error) {
+	decision, err := rl.storage.CheckAndIncrement(ctx, key, storage.Limit{
+		Requests:  config.Requests,
+		Window:    rl.windowTTL(config.Window, config.WindowAlignment),
+		BlockTime: blockDuration(config.BlockTime, config.BlockJitter),
+	})
+	if err != nil {
+		return Result{}, storageError(ErrIncrementFailed, err)
 	}
 
+	result := Result{
+		Allowed: decision.Allowed,
+		Limit:   config.Requests,
+		Window:  config.Window,
+		Count:   decision.Count,
+		Blocked: decision.Blocked,
+	}
+
+	if decision.Allowed {
+		result.Remaining = config.Requests - decision.Count
+		if decision.TTL > 0 {
+			result.ResetAt = rl.clock.Now().Add(decision.TTL)
+		}
+		return result, nil
+	}
+
+	if decision.NewlyBlocked {
+		// O armazenamento aplica o bloqueio base; reincidências o estendem para a duração escalada
+		blockTime, err := rl.escalateBlock(ctx, key, config, config.BlockTime)
+		if err != nil {
+			return Result{}, err
+		}
+		if blockTime != config.BlockTime {
+			blockTime = blockDuration(blockTime, config.BlockJitter)
+			if err := rl.storage.Block(ctx, key, blockTime); err != nil {
+				return Result{}, storageError(ErrBlockFailed, err)
+			}
+			decision.TTL = blockTime
+		}
+
+		rl.notifyBlock(ctx, key, config)
+	}
+
+	result.RetryAfter = decision.TTL
+	return result, nil
+}
+
+// countRequest contabiliza a requisição no limite principal e nos limites adicionais (Tiers)
+// e bloqueia a chave quando algum deles é excedido
+func (rl *RateLimiter) countRequest(ctx context.Context, key string, config Config) (Result, error) {
+	weight := weightFromContext(ctx)
+	window := rl.windowTTL(config.Window, config.WindowAlignment)
+
 	// Incrementa o contador e obtém a contagem atual
-	count, err := rl.storage.Increment(ctx, key, config.Window)
+	count, err := rl.increment(ctx, key, weight, window)
 	if err != nil {
-		return false, fmt.Errorf("falha ao incrementar contador: %w", err)
+		return Result{}, storageError(ErrIncrementFailed, err)
 	}
 
-	// Verifica se o limite foi excedido
+	// Registra o primeiro contato da chave no início de cada janela para o período de carência
+	if config.GracePeriod > 0 && count == weight {
+		_, err = rl.firstSeen(ctx, key, config)
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	result := Result{
+		Allowed:   true,
+		Limit:     config.Requests,
+		Window:    config.Window,
+		Count:     count,
+		Remaining: config.Requests - count,
+	}
+
+	// Acompanha o limite mais restritivo entre os excedidos
+	var exceeded bool
+	var blockTime, retryAfter time.Duration
 	if count > config.Requests {
-		// Bloqueia a chave pela duração especificada
-		err = rl.storage.Block(ctx, key, config.BlockTime)
+		withinBurst, err := rl.consumeBurst(ctx, key, config, count, weight)
 		if err != nil {
-			return false, fmt.Errorf("falha ao bloquear chave: %w", err)
+			return Result{}, err
+		}
+
+		if withinBurst {
+			result.Remaining = 0
+		} else {
+			exceeded = true
+			blockTime = config.BlockTime
+			retryAfter = window
 		}
+	}
+
+	// Contabiliza a requisição nos limites adicionais, cada um com seu próprio contador
+	for i, tier := range config.Tiers {
+		tierWindow := rl.windowTTL(tier.Window, config.WindowAlignment)
+		tierCount, err := rl.increment(ctx, rl.tierKey(key, i), weight, tierWindow)
+		if err != nil {
+			return Result{}, storageError(ErrIncrementFailed, err)
+		}
+
+		// O resultado reporta o limite mais próximo de ser atingido
+		if tierRemaining := tier.Requests - tierCount; tierRemaining < result.Remaining {
+			result.Limit = tier.Requests
+			result.Window = tier.Window
+			result.Count = tierCount
+			result.Remaining = tierRemaining
+		}
+
+		if tierCount > tier.Requests {
+			exceeded = true
+			blockTime = max(blockTime, tier.BlockTime)
+			retryAfter = max(retryAfter, tierWindow)
+		}
+	}
+
+	if !exceeded {
+		return result, nil
+	}
+	result.Remaining = 0
+
+	// Durante o período de carência o excesso é apenas registrado
+	if config.GracePeriod > 0 {
+		firstSeen, err := rl.firstSeen(ctx, key, config)
+		if err != nil {
+			return Result{}, err
+		}
+
+		if rl.clock.Now().Sub(firstSeen) < config.GracePeriod {
+			rl.logger.Printf("Chave %s excedeu o limite de %d requisições durante o período de carência", key, result.Limit)
+			result.InGracePeriod = true
+			return result, nil
+		}
+	}
+
+	result.Allowed = false
+
+	// Sem tempo de bloqueio, apenas rejeita até a janela terminar
+	if blockTime <= 0 {
+		result.RetryAfter = retryAfter
+		return result, nil
+	}
+
+	// Bloqueia a chave pela duração mais restritiva entre os limites excedidos, escalada para
+	// reincidências
+	blockTime, err = rl.escalateBlock(ctx, key, config, blockTime)
+	if err != nil {
+		return Result{}, err
+	}
+	blockTime = blockDuration(blockTime, config.BlockJitter)
+	err = rl.storage.Block(ctx, key, blockTime)
+	if err != nil {
+		return Result{}, storageError(ErrBlockFailed, err)
+	}
+
+	rl.notifyBlockOnce(ctx, key, config, blockTime)
+
+	result.Blocked = true
+	result.RetryAfter = blockTime
+	return result, nil
+}
+
+// blockDuration aplica ao tempo de bloqueio uma variação aleatória em [0, jitter]
+func blockDuration(blockTime, jitter time.Duration) time.Duration {
+	if blockTime <= 0 || jitter <= 0 {
+		return blockTime
+	}
+	return blockTime + time.Duration(rand.Int63n(int64(jitter)+1))
+}
+
+// consumeBurst indica se a requisição acima de Requests cabe no Burst, consumindo o peso da
+// requisição do excedente disponível no BurstWindow
+func (rl *RateLimiter) consumeBurst(ctx context.Context, key string, config Config, count, weight int64) (bool, error) {
+	if config.Burst <= 0 || count > config.Requests+config.Burst {
 		return false, nil
 	}
 
-	return true, nil
+	burstCount, err := rl.increment(ctx, rl.burstKey(key), weight, config.burstWindow())
+	if err != nil {
+		return false, storageError(ErrIncrementFailed, err)
+	}
+
+	return burstCount <= config.Burst, nil
+}
+
+// windowTTL retorna a expiração de um contador iniciado agora: a própria window com Rolling ou
+// o tempo até a próxima fronteira múltipla de window com Calendar. O armazenamento só aplica a
+// expiração ao criar o contador, então ela também marca o fim da janela para os incrementos
+// seguintes.
+func (rl *RateLimiter) windowTTL(window time.Duration, alignment WindowAlignment) time.Duration {
+	if alignment != Calendar || window <= 0 {
+		return window
+	}
+
+	now := rl.clock.Now()
+	ttl := now.Truncate(window).Add(window).Sub(now)
+
+	// O armazenamento trabalha com resolução de milissegundos
+	return max(ttl, time.Millisecond)
+}
+
+// increment soma o peso da requisição ao contador da chave
+func (rl *RateLimiter) increment(ctx context.Context, key string, weight int64, window time.Duration) (int64, error) {
+	if weight == 1 {
+		count, _, err := rl.storage.Increment(ctx, key, window)
+		return count, err
+	}
+	return rl.storage.IncrementBy(ctx, key, weight, window)
+}
+
+// burstKey retorna a chave de armazenamento do excedente consumido
+func (rl *RateLimiter) burstKey(key string) string {
+	return rl.subKey(key, "burst")
+}
+
+// tierKey retorna a chave de armazenamento do contador do limite adicional na posição index de
+// Tiers. A chave usa a posição, e não a janela, para que tiers com a mesma janela (ex: limites
+// distintos para a mesma hora) tenham contadores independentes.
+func (rl *RateLimiter) tierKey(key string, index int) string {
+	return rl.subKey(key, fmt.Sprintf("tier%d", index))
+}
+
+// firstSeen obtém o primeiro contato da chave, registrando-o se necessário. O registro é
+// mantido enquanto a chave estiver ativa e sobrevive ao bloqueio, para que a carência não
+// recomece quando o bloqueio expira.
+func (rl *RateLimiter) firstSeen(ctx context.Context, key string, config Config) (time.Time, error) {
+	ttl := config.GracePeriod + config.longestBlockTime() + config.BlockJitter + config.Window
+
+	firstSeen, err := rl.storage.FirstSeen(ctx, key, rl.clock.Now(), ttl)
+	if err != nil {
+		return time.Time{}, storageError(ErrFirstSeenFailed, err)
+	}
+
+	return firstSeen, nil
+}
+
+// drip adiciona a requisição ao leaky bucket da chave, que escoa uma requisição a cada
+// Window/Requests e comporta no máximo Requests requisições pendentes
+func (rl *RateLimiter) drip(ctx context.Context, key string, config Config) (Result, error) {
+	if config.Requests <= 0 {
+		return Result{Allowed: false, RetryAfter: config.Window}, nil
+	}
+
+	// O storage trabalha com resolução de microssegundos
+	leakInterval := config.Window / time.Duration(config.Requests)
+	if leakInterval < time.Microsecond {
+		leakInterval = time.Microsecond
+	}
+
+	allowed, wait, err := rl.storage.LeakyBucket(ctx, key, config.Requests, leakInterval, rl.clock.Now())
+	if err != nil {
+		return Result{}, storageError(ErrLeakyBucketFailed, err)
+	}
+
+	return Result{Allowed: allowed, RetryAfter: wait}, nil
 }
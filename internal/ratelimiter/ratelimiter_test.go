@@ -2,11 +2,16 @@ package ratelimiter
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockStorage é uma implementação mock da interface Storage
@@ -14,14 +19,35 @@ type MockStorage struct {
 	mock.Mock
 }
 
-func (m *MockStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+func (m *MockStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, bool, error) {
 	args := m.Called(ctx, key, window)
+	return args.Get(0).(int64), args.Bool(1), args.Error(2)
+}
+
+func (m *MockStorage) IncrementBy(ctx context.Context, key string, amount int64, window time.Duration) (int64, error) {
+	args := m.Called(ctx, key, amount, window)
 	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *MockStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+func (m *MockStorage) CheckAndIncrement(ctx context.Context, key string, limit storage.Limit) (storage.Decision, error) {
+	args := m.Called(ctx, key, limit)
+	return args.Get(0).(storage.Decision), args.Error(1)
+}
+
+func (m *MockStorage) Get(ctx context.Context, key string) (int64, time.Duration, error) {
 	args := m.Called(ctx, key)
-	return args.Bool(0), args.Error(1)
+	return args.Get(0).(int64), args.Get(1).(time.Duration), args.Error(2)
+}
+
+func (m *MockStorage) IsBlocked(ctx context.Context, key string) (bool, time.Duration, error) {
+	args := m.Called(ctx, key)
+	return args.Bool(0), args.Get(1).(time.Duration), args.Error(2)
+}
+
+func (m *MockStorage) GetMany(ctx context.Context, keys []string) ([]storage.KeyState, error) {
+	args := m.Called(ctx, keys)
+	states, _ := args.Get(0).([]storage.KeyState)
+	return states, args.Error(1)
 }
 
 func (m *MockStorage) Block(ctx context.Context, key string, duration time.Duration) error {
@@ -29,6 +55,57 @@ func (m *MockStorage) Block(ctx context.Context, key string, duration time.Durat
 	return args.Error(0)
 }
 
+func (m *MockStorage) ListBlocked(ctx context.Context, pattern string) ([]string, error) {
+	args := m.Called(ctx, pattern)
+	keys, _ := args.Get(0).([]string)
+	return keys, args.Error(1)
+}
+
+func (m *MockStorage) Reset(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockStorage) LeakyBucket(ctx context.Context, key string, capacity int64, leakInterval time.Duration, now time.Time) (bool, time.Duration, error) {
+	args := m.Called(ctx, key, capacity, leakInterval, now)
+	return args.Bool(0), args.Get(1).(time.Duration), args.Error(2)
+}
+
+func (m *MockStorage) FirstSeen(ctx context.Context, key string, now time.Time, ttl time.Duration) (time.Time, error) {
+	args := m.Called(ctx, key, now, ttl)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockStorage) GetDecision(ctx context.Context, key string) (bool, bool, error) {
+	args := m.Called(ctx, key)
+	return args.Bool(0), args.Bool(1), args.Error(2)
+}
+
+func (m *MockStorage) SetDecision(ctx context.Context, key string, allowed bool, ttl time.Duration) error {
+	args := m.Called(ctx, key, allowed, ttl)
+	return args.Error(0)
+}
+
+func (m *MockStorage) IncrementSliding(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	args := m.Called(ctx, key, ttl)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockStorage) Acquire(ctx context.Context, key string, limit int64, ttl time.Duration) (bool, error) {
+	args := m.Called(ctx, key, limit, ttl)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) Release(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockStorage) Healthy(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
 func (m *MockStorage) Close() error {
 	args := m.Called()
 	return args.Error(0)
@@ -48,16 +125,18 @@ func TestRateLimiter_CheckIP_AllowedRequests(t *testing.T) {
 	ip := "192.168.1.1"
 
 	// Chamadas de armazenamento mockadas - cada solicitação deve ser permitida
+	limit := storage.Limit{Requests: 5, Window: time.Second, BlockTime: time.Minute}
 	for i := 1; i <= 5; i++ {
-		mockStorage.On("IsBlocked", ctx, "ip:"+ip).Return(false, nil).Once()
-		mockStorage.On("Increment", ctx, "ip:"+ip, time.Second).Return(int64(i), nil).Once()
+		mockStorage.On("CheckAndIncrement", ctx, "ip:"+ip, limit).
+			Return(storage.Decision{Allowed: true, Count: int64(i), TTL: time.Second}, nil).Once()
 	}
 
 	// As primeiras 5 solicitações devem ser permitidas
-	for i := 0; i < 5; i++ {
-		allowed, err := rateLimiter.CheckIP(ctx, ip)
+	for i := 1; i <= 5; i++ {
+		result, err := rateLimiter.CheckIP(ctx, ip)
 		assert.NoError(t, err)
-		assert.True(t, allowed)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, int64(5-i), result.Remaining)
 	}
 
 	mockStorage.AssertExpectations(t)
@@ -77,14 +156,16 @@ func TestRateLimiter_CheckIP_ExceedsLimit(t *testing.T) {
 	ip := "192.168.1.1"
 
 	// Chamadas de armazenamento mockadas para limite excedido
-	mockStorage.On("IsBlocked", ctx, "ip:"+ip).Return(false, nil).Once()
-	mockStorage.On("Increment", ctx, "ip:"+ip, time.Second).Return(int64(3), nil).Once()
-	mockStorage.On("Block", ctx, "ip:"+ip, time.Minute).Return(nil).Once()
+	limit := storage.Limit{Requests: 2, Window: time.Second, BlockTime: time.Minute}
+	mockStorage.On("CheckAndIncrement", ctx, "ip:"+ip, limit).
+		Return(storage.Decision{Count: 3, Blocked: true, NewlyBlocked: true, TTL: time.Minute}, nil).Once()
 
 	// A 3ª solicitação deve ser bloqueada (excede o limite de 2)
-	allowed, err := rateLimiter.CheckIP(ctx, ip)
+	result, err := rateLimiter.CheckIP(ctx, ip)
 	assert.NoError(t, err)
-	assert.False(t, allowed)
+	assert.False(t, result.Allowed)
+	assert.True(t, result.Blocked)
+	assert.Equal(t, time.Minute, result.RetryAfter)
 
 	mockStorage.AssertExpectations(t)
 }
@@ -103,13 +184,17 @@ func TestRateLimiter_CheckIP_AlreadyBlocked(t *testing.T) {
 	ip := "192.168.1.1"
 
 	// Chamadas de armazenamento mockadas para IP já bloqueado
-	mockStorage.On("IsBlocked", ctx, "ip:"+ip).Return(true, nil).Once()
-	// Nota: Quando já bloqueado, Increment não deve ser chamado
+	// Nota: Quando já bloqueado, o armazenamento não contabiliza a requisição
+	limit := storage.Limit{Requests: 5, Window: time.Second, BlockTime: time.Minute}
+	mockStorage.On("CheckAndIncrement", ctx, "ip:"+ip, limit).
+		Return(storage.Decision{Blocked: true, TTL: 30 * time.Second}, nil).Once()
 
-	// A solicitação deve ser bloqueada
-	allowed, err := rateLimiter.CheckIP(ctx, ip)
+	// A solicitação deve ser bloqueada pelo tempo restante do bloqueio
+	result, err := rateLimiter.CheckIP(ctx, ip)
 	assert.NoError(t, err)
-	assert.False(t, allowed)
+	assert.False(t, result.Allowed)
+	assert.True(t, result.Blocked)
+	assert.Equal(t, 30*time.Second, result.RetryAfter)
 
 	mockStorage.AssertExpectations(t)
 }
@@ -136,13 +221,14 @@ func TestRateLimiter_CheckToken_ValidToken(t *testing.T) {
 	ctx := context.Background()
 
 	// Chamadas de armazenamento mockadas
-	mockStorage.On("IsBlocked", ctx, "token:"+token).Return(false, nil).Once()
-	mockStorage.On("Increment", ctx, "token:"+token, time.Second).Return(int64(1), nil).Once()
+	limit := storage.Limit{Requests: 10, Window: time.Second, BlockTime: 2 * time.Minute}
+	mockStorage.On("CheckAndIncrement", ctx, "token:"+token, limit).
+		Return(storage.Decision{Allowed: true, Count: 1, TTL: time.Second}, nil).Once()
 
 	// Solicitação com token válido deve ser permitida
-	allowed, err := rateLimiter.CheckToken(ctx, token)
+	result, err := rateLimiter.CheckToken(ctx, token)
 	assert.NoError(t, err)
-	assert.True(t, allowed)
+	assert.True(t, result.Allowed)
 
 	mockStorage.AssertExpectations(t)
 }
@@ -160,11 +246,50 @@ func TestRateLimiter_CheckToken_InvalidToken(t *testing.T) {
 	ctx := context.Background()
 	token := "invalid_token"
 
-	// Solicitação com token inválido deve ser permitida (reverte para limitação por IP)
+	// Token inválido retorna ErrUnknownToken para que o chamador aplique a política
 	// Nenhuma chamada de armazenamento deve ser feita para token inválido
-	allowed, err := rateLimiter.CheckToken(ctx, token)
+	_, err := rateLimiter.CheckToken(ctx, token)
+	assert.ErrorIs(t, err, ErrUnknownToken)
+	assert.Equal(t, FallbackToIP, rateLimiter.UnknownTokenPolicy())
+
+	rateLimiter.SetUnknownTokenPolicy(Reject)
+	_, err = rateLimiter.CheckToken(ctx, token)
+	assert.ErrorIs(t, err, ErrUnknownToken)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRateLimiter_CheckToken_UnknownTokenAllowWithDefault(t *testing.T) {
+	mockStorage := &MockStorage{}
+	ipConfig := Config{
+		Requests:  5,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := NewRateLimiter(mockStorage, ipConfig)
+	rateLimiter.SetUnknownTokenPolicy(AllowWithDefault)
+
+	ctx := context.Background()
+
+	// Sem configuração padrão, o token desconhecido usa a configuração de IP na sua própria chave
+	mockStorage.On("CheckAndIncrement", ctx, "token:unknown", storage.Limit{Requests: 5, Window: time.Second, BlockTime: time.Minute}).
+		Return(storage.Decision{Allowed: true, Count: 1, TTL: time.Second}, nil).Once()
+
+	result, err := rateLimiter.CheckToken(ctx, "unknown")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(5), result.Limit)
+
+	// Com configuração padrão, ela é aplicada
+	rateLimiter.SetDefaultTokenConfig(Config{Requests: 1, Window: time.Minute, BlockTime: time.Hour})
+	mockStorage.On("CheckAndIncrement", ctx, "token:unknown", storage.Limit{Requests: 1, Window: time.Minute, BlockTime: time.Hour}).
+		Return(storage.Decision{Count: 2, Blocked: true, NewlyBlocked: true, TTL: time.Hour}, nil).Once()
+
+	result, err = rateLimiter.CheckToken(ctx, "unknown")
 	assert.NoError(t, err)
-	assert.True(t, allowed)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Hour, result.RetryAfter)
 
 	mockStorage.AssertExpectations(t)
 }
@@ -191,14 +316,1228 @@ func TestRateLimiter_CheckToken_ExceedsLimit(t *testing.T) {
 	ctx := context.Background()
 
 	// Chamadas de armazenamento mockadas para limite excedido
-	mockStorage.On("IsBlocked", ctx, "token:"+token).Return(false, nil).Once()
-	mockStorage.On("Increment", ctx, "token:"+token, time.Second).Return(int64(2), nil).Once()
-	mockStorage.On("Block", ctx, "token:"+token, time.Minute*2).Return(nil).Once()
+	limit := storage.Limit{Requests: 1, Window: time.Second, BlockTime: 2 * time.Minute}
+	mockStorage.On("CheckAndIncrement", ctx, "token:"+token, limit).
+		Return(storage.Decision{Count: 2, Blocked: true, NewlyBlocked: true, TTL: 2 * time.Minute}, nil).Once()
 
 	// A 2ª solicitação deve ser bloqueada (excede o limite de 1)
-	allowed, err := rateLimiter.CheckToken(ctx, token)
+	result, err := rateLimiter.CheckToken(ctx, token)
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRateLimiter_CheckIP_LeakyBucket(t *testing.T) {
+	mockStorage := &MockStorage{}
+	config := Config{
+		Requests:  10,
+		Window:    time.Second,
+		Algorithm: AlgorithmLeakyBucket,
+	}
+
+	rateLimiter := NewRateLimiter(mockStorage, config)
+
+	ctx := context.Background()
+	ip := "192.168.1.1"
+
+	// A capacidade é Requests e o intervalo de escoamento é Window/Requests; não há verificação de bloqueio
+	mockStorage.On("LeakyBucket", ctx, "ip:"+ip, int64(10), 100*time.Millisecond, mock.Anything).Return(true, time.Duration(0), nil).Once()
+	mockStorage.On("LeakyBucket", ctx, "ip:"+ip, int64(10), 100*time.Millisecond, mock.Anything).Return(false, 250*time.Millisecond, nil).Once()
+
+	result, err := rateLimiter.CheckIP(ctx, ip)
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// Quando o bucket transborda, o tempo de espera estimado é repassado
+	result, err = rateLimiter.CheckIP(ctx, ip)
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 250*time.Millisecond, result.RetryAfter)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRateLimiter_CheckIP_UnknownAlgorithm(t *testing.T) {
+	mockStorage := &MockStorage{}
+	config := Config{
+		Requests:  10,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+		Algorithm: Algorithm("token_bucket"),
+	}
+
+	rateLimiter := NewRateLimiter(mockStorage, config)
+
+	ctx := context.Background()
+
+	// O algoritmo desconhecido é rejeitado antes de qualquer acesso ao armazenamento
+	_, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	assert.Error(t, err)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRateLimiter_CheckKey(t *testing.T) {
+	mockStorage := &MockStorage{}
+	ipConfig := Config{
+		Requests:  5,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := NewRateLimiter(mockStorage, ipConfig)
+
+	// A configuração informada é usada no lugar da configuração de IP
+	userConfig := Config{
+		Requests:  1,
+		Window:    time.Minute,
+		BlockTime: time.Hour,
+	}
+
+	ctx := context.Background()
+	key := "user:42"
+
+	limit := storage.Limit{Requests: 1, Window: time.Minute, BlockTime: time.Hour}
+	mockStorage.On("CheckAndIncrement", ctx, "custom:"+key, limit).
+		Return(storage.Decision{Allowed: true, Count: 1, TTL: time.Minute}, nil).Once()
+	mockStorage.On("CheckAndIncrement", ctx, "custom:"+key, limit).
+		Return(storage.Decision{Count: 2, Blocked: true, NewlyBlocked: true, TTL: time.Hour}, nil).Once()
+
+	result, err := rateLimiter.CheckKey(ctx, key, userConfig)
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = rateLimiter.CheckKey(ctx, key, userConfig)
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRateLimiter_CheckIP_GracePeriod(t *testing.T) {
+	mockStorage := &MockStorage{}
+	config := Config{
+		Requests:    1,
+		Window:      time.Second,
+		BlockTime:   time.Minute,
+		GracePeriod: time.Hour,
+	}
+
+	rateLimiter := NewRateLimiter(mockStorage, config)
+
+	// Relógio controlado pelo teste
+	start := time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC)
+	fakeClock := clock.NewFakeClock(start)
+	rateLimiter.SetClock(fakeClock)
+
+	ctx := context.Background()
+	key := "ip:192.168.1.1"
+	ttl := config.GracePeriod + config.BlockTime + config.Window
+
+	// Primeiro contato: o instante é registrado
+	mockStorage.On("IsBlocked", ctx, key).Return(false, time.Duration(0), nil)
+	mockStorage.On("Increment", ctx, key, time.Second).Return(int64(1), true, nil).Once()
+	mockStorage.On("FirstSeen", ctx, key, start, ttl).Return(start, nil).Once()
+
+	result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.False(t, result.InGracePeriod)
+
+	// Excesso dentro do período de carência: permitido e sinalizado, sem bloqueio
+	fakeClock.Advance(30 * time.Minute)
+	mockStorage.On("Increment", ctx, key, time.Second).Return(int64(2), false, nil).Once()
+	mockStorage.On("FirstSeen", ctx, key, fakeClock.Now(), ttl).Return(start, nil).Once()
+
+	result, err = rateLimiter.CheckIP(ctx, "192.168.1.1")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.True(t, result.InGracePeriod)
+	mockStorage.AssertNotCalled(t, "Block", ctx, key, time.Minute)
+
+	// Após o período de carência o bloqueio volta a ser aplicado
+	fakeClock.Set(start.Add(time.Hour + time.Second))
+	mockStorage.On("Increment", ctx, key, time.Second).Return(int64(3), false, nil).Once()
+	mockStorage.On("FirstSeen", ctx, key, fakeClock.Now(), ttl).Return(start, nil).Once()
+	mockStorage.On("Block", ctx, key, time.Minute).Return(nil).Once()
+
+	result, err = rateLimiter.CheckIP(ctx, "192.168.1.1")
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.False(t, result.InGracePeriod)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRateLimiter_Peek(t *testing.T) {
+	mockStorage := &MockStorage{}
+	ipConfig := Config{
+		Requests:  5,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := NewRateLimiter(mockStorage, ipConfig)
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	rateLimiter.SetClock(fakeClock)
+
+	ctx := context.Background()
+	key := "ip:192.168.1.1"
+
+	mockStorage.On("Get", ctx, key).Return(int64(3), 400*time.Millisecond, nil).Twice()
+	mockStorage.On("IsBlocked", ctx, key).Return(false, time.Duration(0), nil).Twice()
+
+	// Consultas repetidas não alteram a contagem
+	for i := 0; i < 2; i++ {
+		result, err := rateLimiter.Peek(ctx, key)
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.False(t, result.Blocked)
+		assert.Equal(t, int64(3), result.Count)
+		assert.Equal(t, int64(5), result.Limit)
+		assert.Equal(t, int64(2), result.Remaining)
+		assert.Equal(t, fakeClock.Now().Add(400*time.Millisecond), result.ResetAt)
+	}
+
+	mockStorage.AssertExpectations(t)
+	mockStorage.AssertNotCalled(t, "Increment", mock.Anything, mock.Anything, mock.Anything)
+	mockStorage.AssertNotCalled(t, "Block", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRateLimiter_Peek_TokenBlocked(t *testing.T) {
+	mockStorage := &MockStorage{}
+	ipConfig := Config{
+		Requests:  5,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	rateLimiter := NewRateLimiter(mockStorage, ipConfig)
+	rateLimiter.AddTokenConfig("abc123", Config{
+		Requests:  100,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	})
+
+	ctx := context.Background()
+	key := "token:abc123"
+
+	// Chave bloqueada sem contador ativo
+	mockStorage.On("Get", ctx, key).Return(int64(0), time.Duration(0), nil).Once()
+	mockStorage.On("IsBlocked", ctx, key).Return(true, time.Duration(0), nil).Once()
+
+	result, err := rateLimiter.Peek(ctx, key)
 	assert.NoError(t, err)
-	assert.False(t, allowed)
+	assert.False(t, result.Allowed)
+	assert.True(t, result.Blocked)
+	assert.Equal(t, int64(100), result.Limit)
+	assert.Equal(t, int64(0), result.Remaining)
+	assert.True(t, result.ResetAt.IsZero())
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRateLimiter_PeekMany(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{Requests: 2, Window: time.Second, BlockTime: time.Minute},
+		WithClock(fakeClock))
+	rateLimiter.AddTokenConfig("abc123", Config{Requests: 10, Window: time.Second, BlockTime: time.Minute})
+
+	ctx := context.Background()
+	_, err := rateLimiter.CheckIP(ctx, "10.0.0.1")
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = rateLimiter.CheckIP(ctx, "10.0.0.2")
+		require.NoError(t, err)
+	}
+	_, err = rateLimiter.CheckToken(ctx, "abc123")
+	require.NoError(t, err)
+	fakeClock.Advance(300 * time.Millisecond)
+
+	// A leitura em lote coincide com consultas individuais, inclusive para chaves bloqueadas
+	// ou sem contador
+	keys := []string{"ip:10.0.0.1", "ip:10.0.0.2", "token:abc123", "ip:10.0.0.3"}
+	results, err := rateLimiter.PeekMany(ctx, keys)
+	require.NoError(t, err)
+	require.Len(t, results, len(keys))
+
+	for i, key := range keys {
+		expected, err := rateLimiter.Peek(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, expected, results[i], key)
+	}
+	assert.True(t, results[1].Blocked)
+	assert.Equal(t, int64(9), results[2].Remaining)
+}
+
+func TestRateLimiter_PeekManyStorageError(t *testing.T) {
+	mockStorage := &MockStorage{}
+	rateLimiter := NewRateLimiter(mockStorage, Config{Requests: 5, Window: time.Second})
+
+	ctx := context.Background()
+	mockStorage.On("GetMany", ctx, []string{"ip:192.168.1.1"}).Return(nil, errors.New("connection refused")).Once()
+
+	_, err := rateLimiter.PeekMany(ctx, []string{"ip:192.168.1.1"})
+	assert.ErrorIs(t, err, ErrReadFailed)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRateLimiter_CounterEvictedMidWindow(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{name: "operação atômica", config: Config{Requests: 3, Window: time.Minute, BlockTime: time.Minute}},
+		{name: "contagem em etapas", config: Config{Requests: 3, Window: time.Minute, BlockTime: time.Minute,
+			Tiers: []Config{{Requests: 100, Window: time.Hour, BlockTime: time.Minute}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+			memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+			defer memoryStorage.Close()
+
+			rateLimiter := NewRateLimiter(memoryStorage, tt.config, WithClock(fakeClock))
+			ctx := context.Background()
+
+			for i := 0; i < 2; i++ {
+				result, err := rateLimiter.CheckIP(ctx, "10.0.0.1")
+				require.NoError(t, err)
+				require.True(t, result.Allowed)
+			}
+
+			// O armazenamento descarta o contador (ex: evicção do Redis): a contagem recomeça
+			require.NoError(t, memoryStorage.Reset(ctx, "ip:10.0.0.1"))
+
+			for i := 0; i < 3; i++ {
+				result, err := rateLimiter.CheckIP(ctx, "10.0.0.1")
+				require.NoError(t, err)
+				assert.True(t, result.Allowed)
+				assert.Equal(t, int64(i+1), result.Count)
+			}
+
+			// O limite continua valendo para a nova contagem
+			result, err := rateLimiter.CheckIP(ctx, "10.0.0.1")
+			require.NoError(t, err)
+			assert.False(t, result.Allowed)
+			assert.True(t, result.Blocked)
+		})
+	}
+}
+
+func TestRateLimiter_CounterEvictedWhileBlocked(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	tier := Config{Requests: 2, Window: time.Hour, BlockTime: time.Minute}
+	config := Config{Requests: 100, Window: time.Minute, BlockTime: time.Minute, Tiers: []Config{tier}}
+	rateLimiter := NewRateLimiter(memoryStorage, config, WithClock(fakeClock))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := rateLimiter.CheckIP(ctx, "10.0.0.1")
+		require.NoError(t, err)
+	}
+
+	// Sem o contador do tier, o bloqueio ainda prevalece
+	require.NoError(t, memoryStorage.Reset(ctx, rateLimiter.tierKey("ip:10.0.0.1", 0)))
+
+	result, err := rateLimiter.CheckIP(ctx, "10.0.0.1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.True(t, result.Blocked)
+
+	// Expirado o bloqueio, a chave recomeça do zero
+	fakeClock.Advance(time.Minute)
+
+	result, err = rateLimiter.CheckIP(ctx, "10.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestRateLimiter_BlockEvictedWithCounterAboveLimit(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	tier := Config{Requests: 2, Window: time.Hour, BlockTime: time.Minute}
+	config := Config{Requests: 100, Window: time.Minute, BlockTime: time.Minute, Tiers: []Config{tier}}
+	rateLimiter := NewRateLimiter(memoryStorage, config, WithClock(fakeClock))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := rateLimiter.CheckIP(ctx, "10.0.0.1")
+		require.NoError(t, err)
+	}
+
+	// O armazenamento descarta o bloqueio, mas o contador do tier continua acima do limite
+	require.NoError(t, memoryStorage.Reset(ctx, "ip:10.0.0.1"))
+
+	// A próxima requisição excede o tier novamente e restaura o bloqueio
+	result, err := rateLimiter.CheckIP(ctx, "10.0.0.1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.True(t, result.Blocked)
+
+	blocked, _, err := memoryStorage.IsBlocked(ctx, "ip:10.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+}
+
+func TestRateLimiter_BlockedKeys(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{Requests: 1, Window: time.Minute, BlockTime: time.Minute},
+		WithClock(fakeClock), WithKeyPrefix("app:"))
+	rateLimiter.AddTokenConfig("abc123", Config{Requests: 1, Window: time.Minute, BlockTime: 2 * time.Minute})
+	ctx := context.Background()
+
+	keys, err := rateLimiter.BlockedKeys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	for i := 0; i < 2; i++ {
+		_, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+		require.NoError(t, err)
+		_, err = rateLimiter.CheckToken(ctx, "abc123")
+		require.NoError(t, err)
+	}
+	_, err = rateLimiter.CheckIP(ctx, "192.168.1.2")
+	require.NoError(t, err)
+
+	// Bloqueios de outros serviços no mesmo armazenamento não são listados
+	require.NoError(t, memoryStorage.Block(ctx, "other:ip:192.168.1.3", time.Minute))
+
+	// As chaves são listadas sem o prefixo, no formato aceito por Peek
+	keys, err = rateLimiter.BlockedKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ip:192.168.1.1", "token:abc123"}, keys)
+
+	result, err := rateLimiter.Peek(ctx, keys[0])
+	require.NoError(t, err)
+	assert.True(t, result.Blocked)
+
+	// Bloqueios expirados deixam de ser listados
+	fakeClock.Advance(time.Minute)
+	keys, err = rateLimiter.BlockedKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"token:abc123"}, keys)
+}
+
+func TestRateLimiter_BlockedKeysError(t *testing.T) {
+	mockStorage := &MockStorage{}
+	rateLimiter := NewRateLimiter(mockStorage, Config{Requests: 1, Window: time.Second})
+	ctx := context.Background()
+	errRedisDown := errors.New("redis indisponível")
 
+	mockStorage.On("ListBlocked", ctx, "*").Return(nil, errRedisDown).Once()
+
+	keys, err := rateLimiter.BlockedKeys(ctx)
+	assert.Nil(t, keys)
+	assert.ErrorIs(t, err, ErrListBlockedFailed)
+	assert.ErrorIs(t, err, ErrStorageUnavailable)
+	assert.ErrorIs(t, err, errRedisDown)
 	mockStorage.AssertExpectations(t)
 }
+
+func TestRateLimiter_UpdateTokenConfig(t *testing.T) {
+	tests := []struct {
+		name            string
+		reset           bool
+		expectedAllowed bool
+	}{
+		{name: "mantém o contador", reset: false, expectedAllowed: false},
+		{name: "reseta o contador", reset: true, expectedAllowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1})
+			defer memoryStorage.Close()
+
+			rateLimiter := NewRateLimiter(memoryStorage, Config{Requests: 1, Window: time.Minute},
+				WithTokenConfig("abc123", Config{Requests: 10, Window: time.Minute, BlockTime: time.Minute}),
+				WithResetOnConfigChange(tt.reset),
+			)
+			ctx := context.Background()
+
+			for i := 0; i < 5; i++ {
+				_, err := rateLimiter.CheckToken(ctx, "abc123")
+				require.NoError(t, err)
+			}
+
+			// Com o limite reduzido para 5, a contagem anterior já esgotaria a janela
+			err := rateLimiter.UpdateTokenConfig(ctx, "abc123", Config{Requests: 5, Window: time.Minute, BlockTime: time.Minute})
+			require.NoError(t, err)
+
+			result, err := rateLimiter.CheckToken(ctx, "abc123")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedAllowed, result.Allowed)
+			assert.Equal(t, int64(5), result.Limit)
+		})
+	}
+}
+
+func TestRateLimiter_UpdateTokenConfigClearsBlock(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1})
+	defer memoryStorage.Close()
+
+	config := Config{Requests: 1, Window: time.Minute, BlockTime: time.Hour}
+	rateLimiter := NewRateLimiter(memoryStorage, config, WithResetOnConfigChange(true))
+	rateLimiter.AddTokenConfig("abc123", config)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := rateLimiter.CheckToken(ctx, "abc123")
+		require.NoError(t, err)
+	}
+	blocked, _, err := memoryStorage.IsBlocked(ctx, "token:abc123")
+	require.NoError(t, err)
+	require.True(t, blocked)
+
+	// Reaplicar a mesma configuração não remove o bloqueio
+	require.NoError(t, rateLimiter.UpdateTokenConfig(ctx, "abc123", config))
+	blocked, _, err = memoryStorage.IsBlocked(ctx, "token:abc123")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+
+	require.NoError(t, rateLimiter.UpdateTokenConfig(ctx, "abc123", Config{Requests: 2, Window: time.Minute, BlockTime: time.Hour}))
+	result, err := rateLimiter.CheckToken(ctx, "abc123")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestRateLimiter_SetTokenConfigs(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1})
+	defer memoryStorage.Close()
+
+	tiered := Config{Requests: 10, Window: time.Minute, Tiers: []Config{{Requests: 3, Window: time.Hour}}}
+	rateLimiter := NewRateLimiter(memoryStorage, Config{Requests: 1, Window: time.Minute},
+		WithTokenConfig("changed", tiered),
+		WithTokenConfig("kept", Config{Requests: 10, Window: time.Minute}),
+		WithResetOnConfigChange(true),
+	)
+	ctx := context.Background()
+
+	for _, token := range []string{"changed", "kept"} {
+		for i := 0; i < 3; i++ {
+			_, err := rateLimiter.CheckToken(ctx, token)
+			require.NoError(t, err)
+		}
+	}
+
+	err := rateLimiter.SetTokenConfigs(ctx, map[string]Config{
+		"changed": {Requests: 5, Window: time.Minute, Tiers: tiered.Tiers},
+		"kept":    {Requests: 10, Window: time.Minute},
+	})
+	require.NoError(t, err)
+
+	// O token alterado tem o contador principal e o dos tiers zerados
+	result, err := rateLimiter.CheckToken(ctx, "changed")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(1), result.Count)
+
+	// O token sem alterações mantém a contagem
+	count, _, err := memoryStorage.Get(ctx, "token:kept")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestRateLimiter_UpdateTokenConfigResetError(t *testing.T) {
+	mockStorage := &MockStorage{}
+	rateLimiter := NewRateLimiter(mockStorage, Config{Requests: 1, Window: time.Second}, WithResetOnConfigChange(true))
+	rateLimiter.AddTokenConfig("abc123", Config{Requests: 10, Window: time.Minute})
+	ctx := context.Background()
+	errRedisDown := errors.New("redis indisponível")
+
+	mockStorage.On("Reset", ctx, "token:abc123").Return(errRedisDown).Once()
+
+	err := rateLimiter.UpdateTokenConfig(ctx, "abc123", Config{Requests: 5, Window: time.Minute})
+	assert.ErrorIs(t, err, ErrResetFailed)
+	assert.ErrorIs(t, err, errRedisDown)
+	mockStorage.AssertExpectations(t)
+
+	// A nova configuração é aplicada mesmo com a falha ao resetar
+	config, exists, err := rateLimiter.tokenConfig(ctx, "abc123")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, int64(5), config.Requests)
+}
+
+func TestRateLimiter_SetIPConfig(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1})
+	defer memoryStorage.Close()
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{Requests: 2, Window: time.Minute, BlockTime: time.Minute})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+
+	// O novo limite vale a partir da próxima verificação, mantendo a contagem atual
+	rateLimiter.SetIPConfig(Config{Requests: 5, Window: time.Minute, BlockTime: time.Minute})
+	assert.Equal(t, int64(5), rateLimiter.IPConfig().Requests)
+
+	result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(5), result.Limit)
+	assert.Equal(t, int64(2), result.Remaining)
+
+	// Tokens desconhecidos com AllowWithDefault, sem configuração padrão, também seguem o
+	// novo limite de IP
+	rateLimiter.SetUnknownTokenPolicy(AllowWithDefault)
+
+	result, err = rateLimiter.CheckToken(ctx, "unknown")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), result.Limit)
+}
+
+func TestRateLimiter_SetIPConfigDuringChecks(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1})
+	defer memoryStorage.Close()
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{Requests: 1000, Window: time.Minute})
+	ctx := context.Background()
+
+	// Executado com -race, detecta acessos à configuração de IP sem sincronização
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+				assert.NoError(t, err)
+				assert.Contains(t, []int64{1000, 2000}, result.Limit)
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 100; j++ {
+			requests := int64(1000)
+			if j%2 == 0 {
+				requests = 2000
+			}
+			rateLimiter.SetIPConfig(Config{Requests: requests, Window: time.Minute})
+		}
+	}()
+
+	wg.Wait()
+}
+
+func TestRateLimiter_SoftLimit(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1})
+	defer memoryStorage.Close()
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{Requests: 5, SoftLimit: 3, Window: time.Minute, BlockTime: time.Minute})
+	ctx := context.Background()
+
+	// Apenas as requisições permitidas acima do SoftLimit são sinalizadas
+	for i, expected := range []bool{false, false, false, true, true} {
+		result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed, "requisição %d", i+1)
+		assert.Equal(t, expected, result.SoftLimitExceeded, "requisição %d", i+1)
+	}
+
+	result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.False(t, result.SoftLimitExceeded)
+
+	// Sem SoftLimit o aviso não é emitido
+	result, err = rateLimiter.CheckKey(ctx, "tenant:1", Config{Requests: 1, Window: time.Minute})
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.False(t, result.SoftLimitExceeded)
+}
+
+func TestRateLimiter_CheckIP_ZeroBlockTimeRejectsWithoutBlocking(t *testing.T) {
+	mockStorage := &MockStorage{}
+	config := Config{
+		Requests: 2,
+		Window:   time.Second,
+	}
+
+	rateLimiter := NewRateLimiter(mockStorage, config)
+
+	ctx := context.Background()
+	ip := "192.168.1.1"
+
+	// Sem tempo de bloqueio o armazenamento não cria chave de bloqueio
+	limit := storage.Limit{Requests: 2, Window: time.Second}
+	mockStorage.On("CheckAndIncrement", ctx, "ip:"+ip, limit).
+		Return(storage.Decision{Count: 3, TTL: 400 * time.Millisecond}, nil).Once()
+	mockStorage.On("CheckAndIncrement", ctx, "ip:"+ip, limit).
+		Return(storage.Decision{Allowed: true, Count: 1, TTL: time.Second}, nil).Once()
+
+	// A requisição excedente é apenas rejeitada até a janela terminar
+	result, err := rateLimiter.CheckIP(ctx, ip)
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.False(t, result.Blocked)
+	assert.Equal(t, 400*time.Millisecond, result.RetryAfter)
+
+	// Na janela seguinte a chave volta a ser aceita imediatamente
+	result, err = rateLimiter.CheckIP(ctx, ip)
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	mockStorage.AssertExpectations(t)
+	mockStorage.AssertNotCalled(t, "IsBlocked", mock.Anything, mock.Anything)
+	mockStorage.AssertNotCalled(t, "Block", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRateLimiter_CheckIP_TieredLimits(t *testing.T) {
+	mockStorage := &MockStorage{}
+	config := Config{
+		Requests:  10,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+		Tiers: []Config{
+			{Requests: 1000, Window: time.Hour, BlockTime: time.Hour},
+		},
+	}
+
+	rateLimiter := NewRateLimiter(mockStorage, config)
+
+	ctx := context.Background()
+	ip := "192.168.1.1"
+
+	// Dentro dos dois limites a requisição é permitida e reporta o limite mais próximo
+	mockStorage.On("IsBlocked", ctx, "ip:"+ip).Return(false, time.Duration(0), nil).Twice()
+	mockStorage.On("Increment", ctx, "ip:"+ip, time.Second).Return(int64(1), true, nil).Once()
+	mockStorage.On("Increment", ctx, "ip:"+ip+":tier0", time.Hour).Return(int64(995), false, nil).Once()
+
+	result, err := rateLimiter.CheckIP(ctx, ip)
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(1000), result.Limit)
+	assert.Equal(t, int64(5), result.Remaining)
+
+	// O limite por segundo passa, mas o limite por hora é excedido
+	mockStorage.On("Increment", ctx, "ip:"+ip, time.Second).Return(int64(2), false, nil).Once()
+	mockStorage.On("Increment", ctx, "ip:"+ip+":tier0", time.Hour).Return(int64(1001), false, nil).Once()
+	mockStorage.On("Block", ctx, "ip:"+ip, time.Hour).Return(nil).Once()
+
+	result, err = rateLimiter.CheckIP(ctx, ip)
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.True(t, result.Blocked)
+	assert.Equal(t, time.Hour, result.RetryAfter)
+	assert.Equal(t, int64(1000), result.Limit)
+	assert.Equal(t, int64(0), result.Remaining)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRateLimiter_TiersWithSameWindowHaveSeparateCounters(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{
+		Requests:  100,
+		Window:    time.Minute,
+		BlockTime: time.Minute,
+		Tiers: []Config{
+			{Requests: 10, Window: time.Hour},
+			{Requests: 3, Window: time.Hour},
+		},
+	}, WithClock(fakeClock))
+	ctx := context.Background()
+
+	// Cada requisição é contada uma vez em cada tier, mesmo com a mesma janela
+	for i := 0; i < 3; i++ {
+		result, err := rateLimiter.CheckIP(ctx, "10.0.0.1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed, "requisição %d", i+1)
+	}
+
+	for i := range rateLimiter.IPConfig().Tiers {
+		count, _, err := memoryStorage.Get(ctx, rateLimiter.tierKey("ip:10.0.0.1", i))
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+	}
+
+	result, err := rateLimiter.CheckIP(ctx, "10.0.0.1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, int64(3), result.Limit)
+}
+
+func TestRateLimiter_CheckToken_TieredLimitsUseMostRestrictiveBlock(t *testing.T) {
+	mockStorage := &MockStorage{}
+	rateLimiter := NewRateLimiter(mockStorage, Config{Requests: 10, Window: time.Second})
+	rateLimiter.AddTokenConfig("abc123", Config{
+		Requests:  5,
+		Window:    time.Second,
+		BlockTime: 10 * time.Minute,
+		Tiers: []Config{
+			{Requests: 100, Window: time.Hour, BlockTime: time.Minute},
+		},
+	})
+
+	ctx := context.Background()
+
+	// Ambos os limites excedidos: o bloqueio usa o maior BlockTime
+	mockStorage.On("IsBlocked", ctx, "token:abc123").Return(false, time.Duration(0), nil).Once()
+	mockStorage.On("Increment", ctx, "token:abc123", time.Second).Return(int64(6), false, nil).Once()
+	mockStorage.On("Increment", ctx, "token:abc123:tier0", time.Hour).Return(int64(101), false, nil).Once()
+	mockStorage.On("Block", ctx, "token:abc123", 10*time.Minute).Return(nil).Once()
+
+	result, err := rateLimiter.CheckToken(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 10*time.Minute, result.RetryAfter)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRateLimiter_CheckIPWithToken(t *testing.T) {
+	mockStorage := &MockStorage{}
+	rateLimiter := NewRateLimiter(mockStorage, Config{Requests: 2, Window: time.Second, BlockTime: time.Minute})
+	rateLimiter.AddTokenConfig("abc123", Config{Requests: 100, Window: time.Second, BlockTime: time.Minute})
+
+	ctx := context.Background()
+
+	// A chave é a do IP, com o limite do token
+	mockStorage.On("CheckAndIncrement", ctx, "ip:192.168.1.1", storage.Limit{Requests: 100, Window: time.Second, BlockTime: time.Minute}).
+		Return(storage.Decision{Allowed: true, Count: 3, TTL: time.Second}, nil).Once()
+
+	result, err := rateLimiter.CheckIPWithToken(ctx, "192.168.1.1", "abc123")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(100), result.Limit)
+	assert.Equal(t, int64(97), result.Remaining)
+
+	// Tokens desconhecidos seguem a política, como em CheckToken
+	_, err = rateLimiter.CheckIPWithToken(ctx, "192.168.1.1", "unknown")
+	assert.ErrorIs(t, err, ErrUnknownToken)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRateLimiter_CheckIPToken(t *testing.T) {
+	mockStorage := &MockStorage{}
+	rateLimiter := NewRateLimiter(mockStorage, Config{Requests: 10, Window: time.Second})
+
+	ctx := context.Background()
+
+	// Sem configuração dedicada a verificação falha
+	_, err := rateLimiter.CheckIPToken(ctx, "192.168.1.1", "abc123")
+	assert.ErrorIs(t, err, ErrIPTokenNotConfigured)
+
+	rateLimiter.SetIPTokenConfig(Config{Requests: 2, Window: time.Second, BlockTime: time.Minute})
+
+	// Cada IP possui sua própria chave para o mesmo token, que aparece apenas como hash
+	keyA := "iptoken:192.168.1.1:" + hashToken("abc123")
+	keyB := "iptoken:192.168.1.2:" + hashToken("abc123")
+	assert.NotContains(t, keyA, "abc123")
+
+	limit := storage.Limit{Requests: 2, Window: time.Second, BlockTime: time.Minute}
+	mockStorage.On("CheckAndIncrement", ctx, keyA, limit).
+		Return(storage.Decision{Count: 3, Blocked: true, NewlyBlocked: true, TTL: time.Minute}, nil).Once()
+	mockStorage.On("CheckAndIncrement", ctx, keyB, limit).
+		Return(storage.Decision{Allowed: true, Count: 1, TTL: time.Second}, nil).Once()
+
+	result, err := rateLimiter.CheckIPToken(ctx, "192.168.1.1", "abc123")
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	result, err = rateLimiter.CheckIPToken(ctx, "192.168.1.2", "abc123")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(2), result.Limit)
+
+	mockStorage.AssertExpectations(t)
+}
+
+// nopStorage é um Storage sem efeitos usado nos benchmarks para medir apenas o rate limiter
+type nopStorage struct {
+	storage.AlwaysHealthy
+}
+
+func (nopStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, bool, error) {
+	return 1, true, nil
+}
+
+func (nopStorage) IncrementBy(ctx context.Context, key string, amount int64, window time.Duration) (int64, error) {
+	return amount, nil
+}
+
+func (nopStorage) CheckAndIncrement(ctx context.Context, key string, limit storage.Limit) (storage.Decision, error) {
+	return storage.Decision{Allowed: true, Count: 1, TTL: limit.Window}, nil
+}
+
+func (nopStorage) Get(ctx context.Context, key string) (int64, time.Duration, error) {
+	return 0, 0, nil
+}
+
+func (nopStorage) IsBlocked(ctx context.Context, key string) (bool, time.Duration, error) {
+	return false, 0, nil
+}
+
+func (nopStorage) GetMany(ctx context.Context, keys []string) ([]storage.KeyState, error) {
+	return make([]storage.KeyState, len(keys)), nil
+}
+
+func (nopStorage) Block(ctx context.Context, key string, duration time.Duration) error {
+	return nil
+}
+
+func (nopStorage) ListBlocked(ctx context.Context, pattern string) ([]string, error) {
+	return nil, nil
+}
+
+func (nopStorage) Reset(ctx context.Context, key string) error {
+	return nil
+}
+
+func (nopStorage) LeakyBucket(ctx context.Context, key string, capacity int64, leakInterval time.Duration, now time.Time) (bool, time.Duration, error) {
+	return true, 0, nil
+}
+
+func (nopStorage) FirstSeen(ctx context.Context, key string, now time.Time, ttl time.Duration) (time.Time, error) {
+	return now, nil
+}
+
+func (nopStorage) GetDecision(ctx context.Context, key string) (bool, bool, error) {
+	return false, false, nil
+}
+
+func (nopStorage) SetDecision(ctx context.Context, key string, allowed bool, ttl time.Duration) error {
+	return nil
+}
+
+func (nopStorage) IncrementSliding(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return 1, nil
+}
+
+func (nopStorage) Acquire(ctx context.Context, key string, limit int64, ttl time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (nopStorage) Release(ctx context.Context, key string) error {
+	return nil
+}
+
+func (nopStorage) Close() error {
+	return nil
+}
+
+func BenchmarkRateLimiter_CheckIP(b *testing.B) {
+	rateLimiter := NewRateLimiter(nopStorage{}, Config{Requests: 10, Window: time.Second, BlockTime: time.Minute})
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = rateLimiter.CheckIP(ctx, "192.168.1.1")
+	}
+}
+
+func BenchmarkRateLimiter_CheckToken(b *testing.B) {
+	rateLimiter := NewRateLimiter(nopStorage{}, Config{Requests: 10, Window: time.Second, BlockTime: time.Minute})
+	rateLimiter.AddTokenConfig("abc123", Config{Requests: 100, Window: time.Second, BlockTime: time.Minute})
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = rateLimiter.CheckToken(ctx, "abc123")
+	}
+}
+
+func TestRateLimiter_KeyPrefix(t *testing.T) {
+	mockStorage := &MockStorage{}
+	config := Config{
+		Requests:  1,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+	}
+
+	// Dois limitadores com prefixos diferentes compartilham o mesmo armazenamento
+	appA := NewRateLimiter(mockStorage, config)
+	appA.SetKeyPrefix("app-a:")
+	appB := NewRateLimiter(mockStorage, config)
+	appB.SetKeyPrefix("app-b:")
+
+	ctx := context.Background()
+	ip := "192.168.1.1"
+
+	// O limite do primeiro serviço é excedido e bloqueado apenas no seu namespace
+	limit := storage.Limit{Requests: 1, Window: time.Second, BlockTime: time.Minute}
+	mockStorage.On("CheckAndIncrement", ctx, "app-a:ip:"+ip, limit).
+		Return(storage.Decision{Count: 2, Blocked: true, NewlyBlocked: true, TTL: time.Minute}, nil).Once()
+
+	result, err := appA.CheckIP(ctx, ip)
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// O segundo serviço mantém o seu próprio contador para o mesmo IP
+	mockStorage.On("CheckAndIncrement", ctx, "app-b:ip:"+ip, limit).
+		Return(storage.Decision{Allowed: true, Count: 1, TTL: time.Second}, nil).Once()
+
+	result, err = appB.CheckIP(ctx, ip)
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// Peek recebe a chave sem prefixo e consulta a chave do seu namespace
+	mockStorage.On("Get", ctx, "app-a:ip:"+ip).Return(int64(0), time.Duration(0), nil).Once()
+	mockStorage.On("IsBlocked", ctx, "app-a:ip:"+ip).Return(true, time.Duration(0), nil).Once()
+
+	result, err = appA.Peek(ctx, "ip:"+ip)
+	assert.NoError(t, err)
+	assert.True(t, result.Blocked)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRateLimiter_BlockJitter(t *testing.T) {
+	const samples = 500
+	blockTime := time.Minute
+	jitter := 10 * time.Second
+
+	assertWithinJitter := func(t *testing.T, durations []time.Duration) {
+		distinct := make(map[time.Duration]struct{})
+		for _, d := range durations {
+			assert.GreaterOrEqual(t, d, blockTime)
+			assert.LessOrEqual(t, d, blockTime+jitter)
+			distinct[d] = struct{}{}
+		}
+		assert.Greater(t, len(distinct), 1, "o bloqueio deve variar entre as amostras")
+	}
+
+	t.Run("verificação atômica", func(t *testing.T) {
+		mockStorage := &MockStorage{}
+		rateLimiter := NewRateLimiter(mockStorage, Config{
+			Requests:    1,
+			Window:      time.Second,
+			BlockTime:   blockTime,
+			BlockJitter: jitter,
+		})
+
+		var durations []time.Duration
+		mockStorage.On("CheckAndIncrement", mock.Anything, "ip:192.168.1.1", mock.Anything).
+			Run(func(args mock.Arguments) {
+				durations = append(durations, args.Get(2).(storage.Limit).BlockTime)
+			}).
+			Return(storage.Decision{Count: 2, Blocked: true, NewlyBlocked: true}, nil)
+
+		for i := 0; i < samples; i++ {
+			_, err := rateLimiter.CheckIP(context.Background(), "192.168.1.1")
+			assert.NoError(t, err)
+		}
+
+		assert.Len(t, durations, samples)
+		assertWithinJitter(t, durations)
+	})
+
+	t.Run("bloqueio por limite adicional", func(t *testing.T) {
+		mockStorage := &MockStorage{}
+		rateLimiter := NewRateLimiter(mockStorage, Config{
+			Requests:    1,
+			Window:      time.Second,
+			BlockTime:   blockTime,
+			BlockJitter: jitter,
+			Tiers:       []Config{{Requests: 100, Window: time.Hour, BlockTime: time.Second}},
+		})
+
+		var durations []time.Duration
+		mockStorage.On("IsBlocked", mock.Anything, "ip:192.168.1.1").Return(false, time.Duration(0), nil)
+		mockStorage.On("Increment", mock.Anything, "ip:192.168.1.1", time.Second).Return(int64(2), false, nil)
+		mockStorage.On("Increment", mock.Anything, "ip:192.168.1.1:tier0", time.Hour).Return(int64(2), false, nil)
+		mockStorage.On("Block", mock.Anything, "ip:192.168.1.1", mock.Anything).
+			Run(func(args mock.Arguments) {
+				durations = append(durations, args.Get(2).(time.Duration))
+			}).
+			Return(nil)
+
+		for i := 0; i < samples; i++ {
+			result, err := rateLimiter.CheckIP(context.Background(), "192.168.1.1")
+			assert.NoError(t, err)
+			assert.Equal(t, durations[len(durations)-1], result.RetryAfter)
+		}
+
+		assert.Len(t, durations, samples)
+		assertWithinJitter(t, durations)
+	})
+}
+
+func TestConfig_WithoutBlock(t *testing.T) {
+	config := Config{
+		Requests:              100,
+		Window:                time.Second,
+		BlockTime:             time.Minute,
+		BlockJitter:           10 * time.Second,
+		BlockEscalationFactor: 2,
+		MaxBlockTime:          time.Hour,
+		SoftLimit:             80,
+		Tiers:                 []Config{{Requests: 1000, Window: time.Hour, BlockTime: time.Hour}},
+	}
+
+	rejectOnly := config.WithoutBlock()
+	assert.Negative(t, rejectOnly.BlockTime)
+	assert.Zero(t, rejectOnly.BlockJitter)
+	assert.Zero(t, rejectOnly.BlockEscalationFactor)
+	assert.Zero(t, rejectOnly.MaxBlockTime)
+	assert.Negative(t, rejectOnly.Tiers[0].BlockTime)
+	assert.LessOrEqual(t, rejectOnly.longestBlockTime(), time.Duration(0))
+
+	// Os limites são mantidos, e a configuração original não é alterada
+	assert.Equal(t, int64(100), rejectOnly.Requests)
+	assert.Equal(t, int64(80), rejectOnly.SoftLimit)
+	assert.Equal(t, time.Hour, config.Tiers[0].BlockTime)
+
+	// WithDefaults não volta a habilitar o bloqueio
+	assert.Negative(t, rejectOnly.WithDefaults().BlockTime)
+}
+
+func TestRateLimiter_RejectOnlyTokenRecoversAtWindowReset(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	ipConfig := Config{Requests: 2, Window: time.Second, BlockTime: 5 * time.Minute}
+	rateLimiter := NewRateLimiter(memoryStorage, ipConfig, WithClock(fakeClock))
+	rateLimiter.AddTokenConfig("paid", Config{Requests: 2, Window: time.Second, BlockTime: 5 * time.Minute}.WithoutBlock())
+	ctx := context.Background()
+
+	exceed := func(check func() (Result, error)) Result {
+		for i := 0; i < 2; i++ {
+			result, err := check()
+			require.NoError(t, err)
+			require.True(t, result.Allowed)
+		}
+		result, err := check()
+		require.NoError(t, err)
+		return result
+	}
+
+	checkToken := func() (Result, error) { return rateLimiter.CheckToken(ctx, "paid") }
+	checkIP := func() (Result, error) { return rateLimiter.CheckIP(ctx, "192.168.1.1") }
+
+	// O token excedente é apenas rejeitado, enquanto o IP é bloqueado
+	result := exceed(checkToken)
+	assert.False(t, result.Allowed)
+	assert.False(t, result.Blocked)
+
+	result = exceed(checkIP)
+	assert.False(t, result.Allowed)
+	assert.True(t, result.Blocked)
+
+	// Na janela seguinte o token volta a ser permitido, mas o IP continua bloqueado
+	fakeClock.Advance(time.Second)
+
+	result, err := checkToken()
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = checkIP()
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.True(t, result.Blocked)
+}
+
+func TestRateLimiter_Storage(t *testing.T) {
+	mockStorage := &MockStorage{}
+	rateLimiter := NewRateLimiter(mockStorage, Config{Requests: 1, Window: time.Second})
+
+	assert.Same(t, mockStorage, rateLimiter.Storage())
+}
+
+func TestRateLimiter_CheckIP_Burst(t *testing.T) {
+	mockStorage := &MockStorage{}
+	config := Config{
+		Requests:    2,
+		Window:      time.Second,
+		Burst:       2,
+		BurstWindow: 10 * time.Second,
+	}
+
+	rateLimiter := NewRateLimiter(mockStorage, config)
+
+	ctx := context.Background()
+	key := "ip:192.168.1.1"
+
+	check := func() Result {
+		result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+		require.NoError(t, err)
+		return result
+	}
+
+	// Primeira janela: o limite estável e o burst são aceitos
+	for count := int64(1); count <= 4; count++ {
+		mockStorage.On("Increment", ctx, key, time.Second).Return(count, count == 1, nil).Once()
+	}
+	mockStorage.On("Increment", ctx, key+":burst", 10*time.Second).Return(int64(1), true, nil).Once()
+	mockStorage.On("Increment", ctx, key+":burst", 10*time.Second).Return(int64(2), false, nil).Once()
+
+	for i := 0; i < 4; i++ {
+		assert.True(t, check().Allowed, "requisição %d", i+1)
+	}
+
+	// Acima de Requests + Burst a requisição é rejeitada sem consumir o burst
+	mockStorage.On("Increment", ctx, key, time.Second).Return(int64(5), false, nil).Once()
+
+	result := check()
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+
+	// Segunda janela: com o burst consumido, vale apenas o limite estável
+	mockStorage.On("Increment", ctx, key, time.Second).Return(int64(1), true, nil).Once()
+	mockStorage.On("Increment", ctx, key, time.Second).Return(int64(2), false, nil).Once()
+	mockStorage.On("Increment", ctx, key, time.Second).Return(int64(3), false, nil).Once()
+	mockStorage.On("Increment", ctx, key+":burst", 10*time.Second).Return(int64(3), false, nil).Once()
+
+	assert.True(t, check().Allowed)
+	assert.True(t, check().Allowed)
+	assert.False(t, check().Allowed)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRateLimiter_ServerClockAlignsInstances(t *testing.T) {
+	serverNow := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	serverClock := clock.NewFakeClock(serverNow)
+	fetch := func(ctx context.Context) (time.Time, error) { return serverClock.Now(), nil }
+
+	// O armazenamento compartilhado segue o horário do servidor
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: serverClock})
+	defer memoryStorage.Close()
+
+	config := Config{Requests: 10, Window: time.Minute}
+	skews := []time.Duration{-90 * time.Second, 0, 45 * time.Second}
+
+	var resets []time.Time
+	for _, skew := range skews {
+		local := clock.NewFakeClock(serverNow.Add(skew))
+		rateLimiter := NewRateLimiter(memoryStorage, config,
+			WithClock(storage.NewServerClock(fetch, storage.ServerClockOptions{Clock: local})),
+		)
+
+		result, err := rateLimiter.CheckIP(context.Background(), "192.168.1.1")
+		require.NoError(t, err)
+		resets = append(resets, result.ResetAt)
+	}
+
+	// Todas as instâncias reportam o mesmo fim de janela, independentemente do relógio local
+	for _, resetAt := range resets {
+		assert.Equal(t, serverNow.Add(time.Minute), resetAt)
+	}
+}
@@ -0,0 +1,210 @@
+// Package ratelimitertest fornece utilitários para testar integrações com o rate limiter (ex:
+// middlewares e adaptadores) sem depender do Redis: um armazenamento em memória com injeção
+// de falhas e um relógio controlado pelo teste.
+package ratelimitertest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+)
+
+// Epoch é o instante inicial dos relógios criados por NewClock
+var Epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// NewClock cria um relógio parado em Epoch, que avança apenas com Advance ou Set
+func NewClock() *clock.FakeClock {
+	return clock.NewFakeClock(Epoch)
+}
+
+// Storage é um armazenamento em memória para testes, baseado em storage.MemoryStorage. Com
+// Fail, todas as operações passam a retornar o erro informado, simulando um armazenamento
+// indisponível.
+type Storage struct {
+	*storage.MemoryStorage
+
+	mu  sync.Mutex
+	err error
+}
+
+var _ storage.Storage = (*Storage)(nil)
+
+// NewStorage cria um armazenamento em memória que usa o relógio informado para janelas e
+// bloqueios. Nil usa o relógio do sistema. Não há rotina de limpeza em segundo plano.
+func NewStorage(c clock.Clock) *Storage {
+	return &Storage{
+		MemoryStorage: storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: c}),
+	}
+}
+
+// Fail faz com que as operações seguintes retornem err. Nil restaura o funcionamento normal,
+// com o estado anterior à falha preservado.
+func (s *Storage) Fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = err
+}
+
+// failure retorna o erro definido por Fail, se houver
+func (s *Storage) failure() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// Increment incrementa o contador para uma chave específica e retorna a contagem atual e se
+// ele foi criado por este incremento
+func (s *Storage) Increment(ctx context.Context, key string, window time.Duration) (int64, bool, error) {
+	if err := s.failure(); err != nil {
+		return 0, false, err
+	}
+	return s.MemoryStorage.Increment(ctx, key, window)
+}
+
+// IncrementBy soma amount ao contador de uma chave e retorna a contagem atual
+func (s *Storage) IncrementBy(ctx context.Context, key string, amount int64, window time.Duration) (int64, error) {
+	if err := s.failure(); err != nil {
+		return 0, err
+	}
+	return s.MemoryStorage.IncrementBy(ctx, key, amount, window)
+}
+
+// IncrementSliding incrementa o contador de uma chave renovando a sua expiração
+func (s *Storage) IncrementSliding(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	if err := s.failure(); err != nil {
+		return 0, err
+	}
+	return s.MemoryStorage.IncrementSliding(ctx, key, ttl)
+}
+
+// CheckAndIncrement verifica o bloqueio, incrementa o contador e bloqueia a chave ao exceder o
+// limite
+func (s *Storage) CheckAndIncrement(ctx context.Context, key string, limit storage.Limit) (storage.Decision, error) {
+	if err := s.failure(); err != nil {
+		return storage.Decision{}, err
+	}
+	return s.MemoryStorage.CheckAndIncrement(ctx, key, limit)
+}
+
+// Get lê o contador de uma chave e o tempo restante da sua janela
+func (s *Storage) Get(ctx context.Context, key string) (int64, time.Duration, error) {
+	if err := s.failure(); err != nil {
+		return 0, 0, err
+	}
+	return s.MemoryStorage.Get(ctx, key)
+}
+
+// IsBlocked verifica se uma chave está atualmente bloqueada e retorna o tempo restante do
+// bloqueio
+func (s *Storage) IsBlocked(ctx context.Context, key string) (bool, time.Duration, error) {
+	if err := s.failure(); err != nil {
+		return false, 0, err
+	}
+	return s.MemoryStorage.IsBlocked(ctx, key)
+}
+
+// GetMany lê os contadores e os bloqueios de várias chaves
+func (s *Storage) GetMany(ctx context.Context, keys []string) ([]storage.KeyState, error) {
+	if err := s.failure(); err != nil {
+		return nil, err
+	}
+	return s.MemoryStorage.GetMany(ctx, keys)
+}
+
+// Block bloqueia uma chave pela duração especificada e zera o seu contador
+func (s *Storage) Block(ctx context.Context, key string, duration time.Duration) error {
+	if err := s.failure(); err != nil {
+		return err
+	}
+	return s.MemoryStorage.Block(ctx, key, duration)
+}
+
+// ListBlocked lista as chaves bloqueadas que correspondem ao padrão
+func (s *Storage) ListBlocked(ctx context.Context, pattern string) ([]string, error) {
+	if err := s.failure(); err != nil {
+		return nil, err
+	}
+	return s.MemoryStorage.ListBlocked(ctx, pattern)
+}
+
+// Reset remove o contador, o bloqueio e o leaky bucket de uma chave
+func (s *Storage) Reset(ctx context.Context, key string) error {
+	if err := s.failure(); err != nil {
+		return err
+	}
+	return s.MemoryStorage.Reset(ctx, key)
+}
+
+// LeakyBucket adiciona uma requisição ao leaky bucket da chave
+func (s *Storage) LeakyBucket(ctx context.Context, key string, capacity int64, leakInterval time.Duration, now time.Time) (bool, time.Duration, error) {
+	if err := s.failure(); err != nil {
+		return false, 0, err
+	}
+	return s.MemoryStorage.LeakyBucket(ctx, key, capacity, leakInterval, now)
+}
+
+// FirstSeen registra o primeiro contato da chave e retorna o instante registrado
+func (s *Storage) FirstSeen(ctx context.Context, key string, now time.Time, ttl time.Duration) (time.Time, error) {
+	if err := s.failure(); err != nil {
+		return time.Time{}, err
+	}
+	return s.MemoryStorage.FirstSeen(ctx, key, now, ttl)
+}
+
+// GetDecision obtém a decisão registrada para uma chave de idempotência, se existir
+func (s *Storage) GetDecision(ctx context.Context, key string) (bool, bool, error) {
+	if err := s.failure(); err != nil {
+		return false, false, err
+	}
+	return s.MemoryStorage.GetDecision(ctx, key)
+}
+
+// SetDecision registra a decisão para uma chave de idempotência pela duração especificada
+func (s *Storage) SetDecision(ctx context.Context, key string, allowed bool, ttl time.Duration) error {
+	if err := s.failure(); err != nil {
+		return err
+	}
+	return s.MemoryStorage.SetDecision(ctx, key, allowed, ttl)
+}
+
+// Acquire ocupa uma vaga de concorrência da chave
+func (s *Storage) Acquire(ctx context.Context, key string, limit int64, ttl time.Duration) (bool, error) {
+	if err := s.failure(); err != nil {
+		return false, err
+	}
+	return s.MemoryStorage.Acquire(ctx, key, limit, ttl)
+}
+
+// Release libera uma vaga de concorrência da chave
+func (s *Storage) Release(ctx context.Context, key string) error {
+	if err := s.failure(); err != nil {
+		return err
+	}
+	return s.MemoryStorage.Release(ctx, key)
+}
+
+// Healthy reporta o erro definido por Fail, se houver
+func (s *Storage) Healthy(ctx context.Context) error {
+	return s.failure()
+}
+
+// New cria um rate limiter com a configuração de IP e as opções informadas sobre um Storage e um
+// relógio de teste, que são retornados para que o teste controle o tempo e simule falhas. O
+// armazenamento é fechado ao fim do teste.
+func New(tb testing.TB, ipConfig ratelimiter.Config, opts ...ratelimiter.Option) (*ratelimiter.RateLimiter, *Storage, *clock.FakeClock) {
+	tb.Helper()
+
+	fakeClock := NewClock()
+	s := NewStorage(fakeClock)
+	tb.Cleanup(func() { s.Close() })
+
+	opts = append([]ratelimiter.Option{ratelimiter.WithClock(fakeClock)}, opts...)
+	return ratelimiter.NewRateLimiter(s, ipConfig, opts...), s, fakeClock
+}
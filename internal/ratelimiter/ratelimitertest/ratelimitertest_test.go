@@ -0,0 +1,93 @@
+package ratelimitertest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/ratelimiter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClock(t *testing.T) {
+	fakeClock := NewClock()
+	assert.Equal(t, Epoch, fakeClock.Now())
+
+	fakeClock.Advance(time.Second)
+	assert.Equal(t, Epoch.Add(time.Second), fakeClock.Now())
+}
+
+func TestNew(t *testing.T) {
+	rateLimiter, _, fakeClock := New(t, ratelimiter.Config{Requests: 2, Window: time.Second, BlockTime: time.Minute})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+
+	result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, result.Blocked)
+
+	// O bloqueio segue o relógio do teste
+	fakeClock.Advance(time.Minute)
+	result, err = rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestNew_Options(t *testing.T) {
+	rateLimiter, s, _ := New(t, ratelimiter.Config{Requests: 1, Window: time.Second}, ratelimiter.WithKeyPrefix("api:"))
+
+	_, err := rateLimiter.CheckIP(context.Background(), "192.168.1.1")
+	require.NoError(t, err)
+
+	count, _, err := s.Get(context.Background(), "api:ip:192.168.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestStorage_Fail(t *testing.T) {
+	errDown := errors.New("connection refused")
+	rateLimiter, s, _ := New(t, ratelimiter.Config{Requests: 10, Window: time.Second})
+	ctx := context.Background()
+
+	_, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+
+	s.Fail(errDown)
+	_, err = rateLimiter.CheckIP(ctx, "192.168.1.1")
+	assert.ErrorIs(t, err, errDown)
+	assert.ErrorIs(t, err, ratelimiter.ErrStorageUnavailable)
+	assert.ErrorIs(t, s.Healthy(ctx), errDown)
+
+	// Todas as operações falham
+	_, _, err = s.Increment(ctx, "k", time.Second)
+	assert.ErrorIs(t, err, errDown)
+	_, _, err = s.Get(ctx, "k")
+	assert.ErrorIs(t, err, errDown)
+	_, _, err = s.IsBlocked(ctx, "k")
+	assert.ErrorIs(t, err, errDown)
+	assert.ErrorIs(t, s.Block(ctx, "k", time.Second), errDown)
+	_, _, err = s.LeakyBucket(ctx, "k", 1, time.Second, Epoch)
+	assert.ErrorIs(t, err, errDown)
+	_, err = s.FirstSeen(ctx, "k", Epoch, time.Second)
+	assert.ErrorIs(t, err, errDown)
+	_, _, err = s.GetDecision(ctx, "k")
+	assert.ErrorIs(t, err, errDown)
+	assert.ErrorIs(t, s.SetDecision(ctx, "k", true, time.Second), errDown)
+	_, err = s.Acquire(ctx, "k", 1, time.Second)
+	assert.ErrorIs(t, err, errDown)
+	assert.ErrorIs(t, s.Release(ctx, "k"), errDown)
+
+	// Ao restaurar o armazenamento, o estado anterior à falha é preservado
+	s.Fail(nil)
+	assert.NoError(t, s.Healthy(ctx))
+	result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.Count)
+}
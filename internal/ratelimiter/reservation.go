@@ -0,0 +1,97 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrReservationDone é retornado por Commit quando a reserva já foi confirmada ou cancelada
+var ErrReservationDone = errors.New("reserva já confirmada ou cancelada")
+
+// Reservation é uma verificação feita por Reserve cuja contagem fica pendente até Commit,
+// permitindo contar a requisição apenas depois de conhecer o seu desfecho (ex: somente
+// tentativas de login com falha). Cancel descarta a reserva sem contá-la.
+type Reservation struct {
+	// Result é o resultado da verificação, sem a requisição contada. Com Allowed falso a
+	// identidade está bloqueada ou já excedeu o limite, e Commit não a contabiliza.
+	Result Result
+
+	rl     *RateLimiter
+	ctx    context.Context
+	key    string
+	config Config
+
+	mu   sync.Mutex
+	done bool
+}
+
+// Reserve verifica o bloqueio e os limites de uma chave de armazenamento (ex:
+// "ip:192.168.1.1" ou "token:abc123", no formato de Peek) sem contabilizar a requisição. A
+// configuração é resolvida como em Peek. A chave é informada sem o prefixo definido em
+// SetKeyPrefix.
+func (rl *RateLimiter) Reserve(ctx context.Context, key string) (*Reservation, error) {
+	config, err := rl.configForKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return rl.reserve(ctx, rl.keyPrefix+key, config)
+}
+
+// ReserveKey equivale a Reserve para uma identidade arbitrária, com a configuração informada
+// (ver CheckKey)
+func (rl *RateLimiter) ReserveKey(ctx context.Context, key string, config Config) (*Reservation, error) {
+	return rl.reserve(ctx, rl.keyPrefix+rl.keys.custom+key, config)
+}
+
+// reserve verifica a chave sem contá-la e guarda o necessário para Commit. A confirmação usa
+// o contexto da reserva sem o seu cancelamento, pois costuma ocorrer depois da resposta, e a
+// configuração com o bônus ativo no momento da reserva.
+func (rl *RateLimiter) reserve(ctx context.Context, key string, config Config) (*Reservation, error) {
+	config, err := rl.withBonus(ctx, key, config)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := rl.inspect(ctx, key, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reservation{
+		Result: result,
+		rl:     rl,
+		ctx:    context.WithoutCancel(ctx),
+		key:    key,
+		config: config,
+	}, nil
+}
+
+// Commit contabiliza a requisição reservada, aplicando o bloqueio se ela exceder o limite, e
+// retorna o resultado da contagem. Reservas rejeitadas não são contadas e retornam o próprio
+// Result. Pode ser chamado uma única vez, e nunca depois de Cancel.
+func (r *Reservation) Commit() (Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.done {
+		return Result{}, ErrReservationDone
+	}
+	r.done = true
+
+	if !r.Result.Allowed {
+		return r.Result, nil
+	}
+
+	// A requisição já terminou, então não ocupa vagas de concorrência
+	return r.rl.checkRate(r.ctx, r.key, r.config)
+}
+
+// Cancel descarta a reserva sem contabilizar a requisição. Não tem efeito depois de Commit.
+func (r *Reservation) Cancel() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.done = true
+}
@@ -0,0 +1,144 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newReservationTestLimiter(t *testing.T, config Config) (*RateLimiter, *storage.MemoryStorage, *clock.FakeClock) {
+	t.Helper()
+
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	t.Cleanup(func() { memoryStorage.Close() })
+
+	return NewRateLimiter(memoryStorage, config, WithClock(fakeClock)), memoryStorage, fakeClock
+}
+
+func TestReservation_Commit(t *testing.T) {
+	rateLimiter, memoryStorage, fakeClock := newReservationTestLimiter(t, Config{Requests: 2, Window: time.Minute, BlockTime: time.Minute})
+	ctx := context.Background()
+
+	for i := int64(1); i <= 3; i++ {
+		reservation, err := rateLimiter.Reserve(ctx, "ip:10.0.0.1")
+		require.NoError(t, err)
+		assert.True(t, reservation.Result.Allowed)
+		assert.Equal(t, i-1, reservation.Result.Count)
+
+		result, err := reservation.Commit()
+		require.NoError(t, err)
+		assert.Equal(t, i <= 2, result.Allowed)
+	}
+
+	// A confirmação que excedeu o limite bloqueou a chave
+	blocked, _, err := memoryStorage.IsBlocked(ctx, "ip:10.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+
+	reservation, err := rateLimiter.Reserve(ctx, "ip:10.0.0.1")
+	require.NoError(t, err)
+	assert.False(t, reservation.Result.Allowed)
+	assert.True(t, reservation.Result.Blocked)
+
+	// Reservas rejeitadas não são contadas
+	result, err := reservation.Commit()
+	require.NoError(t, err)
+	assert.Equal(t, reservation.Result, result)
+
+	fakeClock.Advance(time.Minute)
+	reservation, err = rateLimiter.Reserve(ctx, "ip:10.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, reservation.Result.Allowed)
+	assert.Zero(t, reservation.Result.Count)
+}
+
+func TestReservation_Cancel(t *testing.T) {
+	rateLimiter, memoryStorage, _ := newReservationTestLimiter(t, Config{Requests: 1, Window: time.Minute, BlockTime: time.Minute})
+	ctx := context.Background()
+
+	// Reservas canceladas não consomem o limite
+	for i := 0; i < 3; i++ {
+		reservation, err := rateLimiter.Reserve(ctx, "ip:10.0.0.1")
+		require.NoError(t, err)
+		assert.True(t, reservation.Result.Allowed)
+		reservation.Cancel()
+
+		_, err = reservation.Commit()
+		assert.ErrorIs(t, err, ErrReservationDone)
+	}
+
+	count, _, err := memoryStorage.Get(ctx, "ip:10.0.0.1")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// Cada reserva é confirmada uma única vez
+	reservation, err := rateLimiter.Reserve(ctx, "ip:10.0.0.1")
+	require.NoError(t, err)
+	_, err = reservation.Commit()
+	require.NoError(t, err)
+	_, err = reservation.Commit()
+	assert.ErrorIs(t, err, ErrReservationDone)
+	reservation.Cancel()
+
+	count, _, err = memoryStorage.Get(ctx, "ip:10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestRateLimiter_ReserveKey(t *testing.T) {
+	rateLimiter, _, _ := newReservationTestLimiter(t, Config{Requests: 100, Window: time.Minute})
+	ctx := context.Background()
+	userConfig := Config{Requests: 1, Window: time.Minute, BlockTime: time.Minute}
+
+	// A confirmação usa a configuração da identidade, mesmo com o contexto da reserva cancelado
+	reserveCtx, cancel := context.WithCancel(ctx)
+	reservation, err := rateLimiter.ReserveKey(reserveCtx, "user:42", userConfig)
+	require.NoError(t, err)
+	cancel()
+
+	result, err := reservation.Commit()
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	reservation, err = rateLimiter.ReserveKey(ctx, "user:42", userConfig)
+	require.NoError(t, err)
+	result, err = reservation.Commit()
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.True(t, result.Blocked)
+
+	peek, err := rateLimiter.Peek(ctx, "custom:user:42")
+	require.NoError(t, err)
+	assert.True(t, peek.Blocked)
+}
+
+func TestReservation_CommitWithBonus(t *testing.T) {
+	rateLimiter, memoryStorage, _ := newReservationTestLimiter(t, Config{Requests: 2, Window: time.Minute, BlockTime: time.Minute})
+	ctx := context.Background()
+
+	require.NoError(t, rateLimiter.GrantBonus(ctx, "ip:10.0.0.1", 3, time.Hour))
+
+	// Reserva e confirmação usam o limite com o bônus, como CheckIP
+	for i := int64(1); i <= 6; i++ {
+		reservation, err := rateLimiter.Reserve(ctx, "ip:10.0.0.1")
+		require.NoError(t, err)
+		assert.True(t, reservation.Result.Allowed, "reserva %d", i)
+		assert.Equal(t, int64(5), reservation.Result.Limit)
+
+		result, err := reservation.Commit()
+		require.NoError(t, err)
+		assert.Equal(t, i <= 5, result.Allowed, "confirmação %d", i)
+		assert.Equal(t, int64(5), result.Limit)
+	}
+
+	// Apenas a confirmação que excedeu o limite com o bônus bloqueou a chave
+	blocked, _, err := memoryStorage.IsBlocked(ctx, "ip:10.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+}
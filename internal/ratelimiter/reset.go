@@ -0,0 +1,61 @@
+package ratelimiter
+
+import "context"
+
+// ResetIP devolve o limite completo ao IP, removendo o seu contador, o excedente de burst, os
+// contadores dos tiers da configuração de IP e o bloqueio (ex: após um login bem-sucedido
+// zerar as tentativas com falha)
+func (rl *RateLimiter) ResetIP(ctx context.Context, ip string) error {
+	return rl.reset(ctx, rl.keyPrefix+rl.keys.ip+ip, rl.IPConfig())
+}
+
+// ResetToken devolve o limite completo ao token, como ResetIP. Os tiers considerados são os
+// da configuração atual do token, se houver.
+func (rl *RateLimiter) ResetToken(ctx context.Context, token string) error {
+	config, _, err := rl.tokenConfig(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	return rl.reset(ctx, rl.keyPrefix+rl.keys.token+token, config)
+}
+
+// ResetIPToken devolve o limite completo ao par de IP e token verificado por CheckIPToken
+func (rl *RateLimiter) ResetIPToken(ctx context.Context, ip, token string) error {
+	if rl.ipTokenConfig == nil {
+		return ErrIPTokenNotConfigured
+	}
+
+	key := rl.keyPrefix + rl.keys.ipToken + ip + rl.keys.separator + hashToken(token)
+	return rl.reset(ctx, key, *rl.ipTokenConfig)
+}
+
+// ResetKey devolve o limite completo a uma identidade verificada por CheckKey com a
+// configuração informada
+func (rl *RateLimiter) ResetKey(ctx context.Context, key string, config Config) error {
+	return rl.reset(ctx, rl.keyPrefix+rl.keys.custom+key, config)
+}
+
+// reset remove o contador, o excedente de burst, os contadores dos tiers das configurações
+// informadas e o bloqueio da chave de armazenamento, inclusive do cache de bloqueios local.
+// O histórico da escalada de bloqueios é mantido.
+func (rl *RateLimiter) reset(ctx context.Context, key string, configs ...Config) error {
+	keys := []string{key, rl.burstKey(key)}
+	for _, config := range configs {
+		for i := range config.Tiers {
+			keys = append(keys, rl.tierKey(key, i))
+		}
+	}
+
+	if rl.blockCache != nil {
+		rl.blockCache.remove(key)
+	}
+
+	for _, key := range keys {
+		if err := rl.storage.Reset(ctx, key); err != nil {
+			return storageError(ErrResetFailed, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_ResetIP(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	// Os tiers também são zerados, e o bloqueio em cache não sobrevive ao reset
+	ipConfig := Config{
+		Requests:  2,
+		Window:    time.Second,
+		BlockTime: time.Minute,
+		Tiers:     []Config{{Requests: 3, Window: time.Hour}},
+	}
+	rateLimiter := NewRateLimiter(memoryStorage, ipConfig, WithClock(fakeClock), WithBlockCache(time.Minute))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+		require.NoError(t, err)
+	}
+
+	result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, result.Blocked)
+
+	require.NoError(t, rateLimiter.ResetIP(ctx, "192.168.1.1"))
+
+	// O limite da janela e o do tier voltam a estar completos
+	for i := 0; i < 2; i++ {
+		result, err = rateLimiter.CheckIP(ctx, "192.168.1.1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+
+	fakeClock.Advance(time.Second)
+
+	result, err = rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestRateLimiter_ResetTokenAndKey(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	limit := Config{Requests: 1, Window: time.Second, BlockTime: time.Minute}
+	rateLimiter := NewRateLimiter(memoryStorage, limit, WithClock(fakeClock),
+		WithTokenConfig("abc123", limit), WithIPTokenConfig(limit))
+	ctx := context.Background()
+
+	checks := map[string]func() (Result, error){
+		"token":    func() (Result, error) { return rateLimiter.CheckToken(ctx, "abc123") },
+		"ip_token": func() (Result, error) { return rateLimiter.CheckIPToken(ctx, "192.168.1.1", "abc123") },
+		"key":      func() (Result, error) { return rateLimiter.CheckKey(ctx, "user:42", limit) },
+	}
+	resets := map[string]func() error{
+		"token":    func() error { return rateLimiter.ResetToken(ctx, "abc123") },
+		"ip_token": func() error { return rateLimiter.ResetIPToken(ctx, "192.168.1.1", "abc123") },
+		"key":      func() error { return rateLimiter.ResetKey(ctx, "user:42", limit) },
+	}
+
+	for name, check := range checks {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				_, err := check()
+				require.NoError(t, err)
+			}
+
+			result, err := check()
+			require.NoError(t, err)
+			assert.True(t, result.Blocked)
+
+			require.NoError(t, resets[name]())
+
+			result, err = check()
+			require.NoError(t, err)
+			assert.True(t, result.Allowed)
+		})
+	}
+}
+
+func TestRateLimiter_ResetIPTokenNotConfigured(t *testing.T) {
+	rateLimiter := NewRateLimiter(storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1}),
+		Config{Requests: 1, Window: time.Second})
+
+	err := rateLimiter.ResetIPToken(context.Background(), "192.168.1.1", "abc123")
+	assert.ErrorIs(t, err, ErrIPTokenNotConfigured)
+}
+
+func TestRateLimiter_ResetStorageError(t *testing.T) {
+	mockStorage := &MockStorage{}
+	rateLimiter := NewRateLimiter(mockStorage, Config{Requests: 1, Window: time.Second})
+	ctx := context.Background()
+	errRedisDown := errors.New("redis indisponível")
+
+	mockStorage.On("Reset", ctx, "ip:192.168.1.1").Return(errRedisDown).Once()
+
+	err := rateLimiter.ResetIP(ctx, "192.168.1.1")
+	assert.ErrorIs(t, err, ErrResetFailed)
+	assert.ErrorIs(t, err, errRedisDown)
+	mockStorage.AssertExpectations(t)
+}
@@ -0,0 +1,129 @@
+package ratelimiter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+)
+
+// DefaultTierResolutionTTL é o tempo padrão em que o plano resolvido para um token é
+// reaproveitado
+const DefaultTierResolutionTTL = 5 * time.Second
+
+// maxCachedTiers é o número de resoluções em cache a partir do qual as expiradas são descartadas
+const maxCachedTiers = 10000
+
+// TierResolver retorna o plano (ex: "free", "pro" ou "enterprise") de um token, geralmente
+// consultado em um banco de dados. ok falso indica um token sem plano.
+type TierResolver func(ctx context.Context, token string) (tier string, ok bool)
+
+// TierOptions configura o TierConfigSource
+type TierOptions struct {
+	// TTL é o intervalo após o qual a resolução em cache é feita novamente, inclusive a de
+	// tokens sem plano. Zero usa DefaultTierResolutionTTL.
+	TTL time.Duration
+
+	// Clock é o relógio usado para expirar o cache. Nil usa o relógio do sistema.
+	Clock clock.Clock
+}
+
+// TierConfigSource é uma TokenConfigSource que resolve o plano de cada token com um
+// TierResolver e aplica a configuração do plano, de modo que os limites sejam definidos por
+// plano em vez de por token. Cada token mantém o seu próprio contador. Tokens sem plano, ou
+// com um plano ausente do mapa, são tratados como desconhecidos.
+type TierConfigSource struct {
+	resolve TierResolver
+	tiers   map[string]Config
+	ttl     time.Duration
+	clock   clock.Clock
+
+	mu    sync.Mutex
+	cache map[string]cachedTier
+}
+
+// cachedTier é o plano resolvido para um token, ou a sua ausência, válido até expiresAt
+type cachedTier struct {
+	tier      string
+	found     bool
+	expiresAt time.Time
+}
+
+// NewTierConfigSource cria um TierConfigSource com o resolver e a configuração de cada plano
+func NewTierConfigSource(resolve TierResolver, tiers map[string]Config, opts TierOptions) *TierConfigSource {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTierResolutionTTL
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.New()
+	}
+
+	configs := make(map[string]Config, len(tiers))
+	for tier, config := range tiers {
+		configs[tier] = config
+	}
+
+	return &TierConfigSource{
+		resolve: resolve,
+		tiers:   configs,
+		ttl:     opts.TTL,
+		clock:   opts.Clock,
+		cache:   make(map[string]cachedTier),
+	}
+}
+
+// TokenConfig retorna a configuração do plano do token, resolvendo-o novamente quando o
+// cache expira
+func (s *TierConfigSource) TokenConfig(ctx context.Context, token string) (Config, bool, error) {
+	tier, found := s.Tier(ctx, token)
+	if !found {
+		return Config{}, false, nil
+	}
+
+	config, exists := s.tiers[tier]
+	return config, exists, nil
+}
+
+// Tier retorna o plano do token, usando a resolução em cache enquanto ela for válida
+func (s *TierConfigSource) Tier(ctx context.Context, token string) (string, bool) {
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	cached, ok := s.cache[token]
+	s.mu.Unlock()
+
+	if ok && now.Before(cached.expiresAt) {
+		return cached.tier, cached.found
+	}
+
+	cached = cachedTier{expiresAt: now.Add(s.ttl)}
+	cached.tier, cached.found = s.resolve(ctx, token)
+
+	s.mu.Lock()
+	if len(s.cache) >= maxCachedTiers {
+		s.pruneExpired(now)
+	}
+	s.cache[token] = cached
+	s.mu.Unlock()
+
+	return cached.tier, cached.found
+}
+
+// Invalidate descarta todas as resoluções em cache (ex: após a mudança de plano de um cliente)
+func (s *TierConfigSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cache = make(map[string]cachedTier)
+}
+
+// pruneExpired remove do cache as resoluções expiradas, para que tokens usados uma única vez
+// não acumulem. Deve ser chamado com mu bloqueado.
+func (s *TierConfigSource) pruneExpired(now time.Time) {
+	for token, cached := range s.cache {
+		if !now.Before(cached.expiresAt) {
+			delete(s.cache, token)
+		}
+	}
+}
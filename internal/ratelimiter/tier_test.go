@@ -0,0 +1,149 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTierResolver resolve os planos de um mapa que pode ser alterado entre verificações
+type fakeTierResolver struct {
+	mu      sync.Mutex
+	tiers   map[string]string
+	resolve int
+}
+
+func (f *fakeTierResolver) Resolve(ctx context.Context, token string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.resolve++
+	tier, ok := f.tiers[token]
+	return tier, ok
+}
+
+func (f *fakeTierResolver) set(token, tier string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.tiers[token] = tier
+}
+
+var testTiers = map[string]Config{
+	"free":       {Requests: 1, Window: time.Minute, BlockTime: time.Minute},
+	"pro":        {Requests: 3, Window: time.Minute, BlockTime: time.Minute},
+	"enterprise": {Requests: 100, Window: time.Minute},
+}
+
+func TestRateLimiter_CheckTokenWithTiers(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	resolver := &fakeTierResolver{tiers: map[string]string{
+		"free_key": "free",
+		"pro_key":  "pro",
+		"legacy":   "gold",
+	}}
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{Requests: 10, Window: time.Minute},
+		WithClock(fakeClock),
+		WithTokenConfigSource(NewTierConfigSource(resolver.Resolve, testTiers, TierOptions{Clock: fakeClock})),
+	)
+	rateLimiter.AddTokenConfig("static", Config{Requests: 2, Window: time.Minute})
+	ctx := context.Background()
+
+	allowed := func(token string, n int) []bool {
+		var results []bool
+		for i := 0; i < n; i++ {
+			result, err := rateLimiter.CheckToken(ctx, token)
+			require.NoError(t, err)
+			results = append(results, result.Allowed)
+		}
+		return results
+	}
+
+	// Cada token recebe o limite do seu plano, com um contador próprio
+	assert.Equal(t, []bool{true, false}, allowed("free_key", 2))
+	assert.Equal(t, []bool{true, true, true, false}, allowed("pro_key", 4))
+
+	// O mapa local tem precedência sobre os planos
+	assert.Equal(t, []bool{true, true, false}, allowed("static", 3))
+
+	// Tokens sem plano, ou com um plano não configurado, são desconhecidos
+	for _, token := range []string{"missing", "legacy"} {
+		_, err := rateLimiter.CheckToken(ctx, token)
+		assert.ErrorIs(t, err, ErrUnknownToken, token)
+	}
+}
+
+func TestTierConfigSource_CachesResolutions(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	resolver := &fakeTierResolver{tiers: map[string]string{"abc123": "free"}}
+
+	source := NewTierConfigSource(resolver.Resolve, testTiers, TierOptions{TTL: 10 * time.Second, Clock: fakeClock})
+	ctx := context.Background()
+
+	config, found, err := source.TokenConfig(ctx, "abc123")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, testTiers["free"], config)
+
+	// A mudança de plano só é percebida após o TTL do cache
+	resolver.set("abc123", "enterprise")
+
+	config, _, err = source.TokenConfig(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, testTiers["free"], config)
+	assert.Equal(t, 1, resolver.resolve)
+
+	fakeClock.Advance(10 * time.Second)
+
+	config, _, err = source.TokenConfig(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, testTiers["enterprise"], config)
+	assert.Equal(t, 2, resolver.resolve)
+
+	// A ausência de plano também fica em cache
+	_, found, err = source.TokenConfig(ctx, "unknown")
+	require.NoError(t, err)
+	assert.False(t, found)
+	_, found, err = source.TokenConfig(ctx, "unknown")
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Equal(t, 3, resolver.resolve)
+
+	// Invalidate força uma nova resolução antes do TTL
+	resolver.set("abc123", "pro")
+	source.Invalidate()
+
+	tier, found := source.Tier(ctx, "abc123")
+	assert.True(t, found)
+	assert.Equal(t, "pro", tier)
+	assert.Equal(t, 4, resolver.resolve)
+}
+
+func TestTierConfigSource_PrunesExpiredResolutions(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	resolver := &fakeTierResolver{tiers: map[string]string{}}
+
+	source := NewTierConfigSource(resolver.Resolve, testTiers, TierOptions{TTL: time.Second, Clock: fakeClock})
+	ctx := context.Background()
+
+	for i := 0; i < maxCachedTiers; i++ {
+		source.Tier(ctx, fmt.Sprintf("token-%d", i))
+	}
+	assert.Len(t, source.cache, maxCachedTiers)
+
+	// Com o cache cheio, as resoluções expiradas são descartadas antes de uma nova entrada
+	fakeClock.Advance(time.Second)
+	source.Tier(ctx, "fresh")
+	assert.Len(t, source.cache, 1)
+}
@@ -0,0 +1,41 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+)
+
+// TypedLimiter limita identidades de um tipo próprio (ex: TenantID) em vez de strings, sobre
+// o CheckKey de um RateLimiter. Cada identidade é convertida na chave do armazenamento pela
+// função de formatação; limitadores de tipos diferentes que compartilham o RateLimiter devem
+// usar formatos distintos (ex: "tenant:" + id) para não dividirem contadores.
+type TypedLimiter[K comparable] struct {
+	rl     *RateLimiter
+	config Config
+	format func(K) string
+}
+
+// NewTypedLimiter cria um TypedLimiter que aplica a configuração informada às identidades do
+// tipo K. Com format nil, as identidades são formatadas com fmt.Sprint.
+func NewTypedLimiter[K comparable](rl *RateLimiter, config Config, format func(K) string) *TypedLimiter[K] {
+	if format == nil {
+		format = func(k K) string { return fmt.Sprint(k) }
+	}
+
+	return &TypedLimiter[K]{rl: rl, config: config, format: format}
+}
+
+// Key retorna a identidade formatada, como informada a CheckKey
+func (l *TypedLimiter[K]) Key(k K) string {
+	return l.format(k)
+}
+
+// Check verifica se a identidade tem permissão para fazer uma requisição (ver CheckKey)
+func (l *TypedLimiter[K]) Check(ctx context.Context, k K) (Result, error) {
+	return l.rl.CheckKey(ctx, l.format(k), l.config)
+}
+
+// Reserve verifica a identidade sem contabilizar a requisição até Commit (ver ReserveKey)
+func (l *TypedLimiter[K]) Reserve(ctx context.Context, k K) (*Reservation, error) {
+	return l.rl.ReserveKey(ctx, l.format(k), l.config)
+}
@@ -0,0 +1,108 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tenantID string
+
+type userID int
+
+func TestTypedLimiter_Check(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{Requests: 100, Window: time.Second}, WithClock(fakeClock))
+	ctx := context.Background()
+
+	tenants := NewTypedLimiter(rateLimiter, Config{Requests: 2, Window: time.Second, BlockTime: time.Minute},
+		func(id tenantID) string { return "tenant:" + string(id) })
+
+	for i := 0; i < 2; i++ {
+		result, err := tenants.Check(ctx, "acme")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+
+	result, err := tenants.Check(ctx, "acme")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.True(t, result.Blocked)
+
+	// Cada identidade tem o seu próprio contador
+	result, err = tenants.Check(ctx, "globex")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// A chave é a mesma usada por CheckKey
+	assert.Equal(t, "tenant:acme", tenants.Key("acme"))
+	result, err = rateLimiter.CheckKey(ctx, "tenant:acme", Config{Requests: 2, Window: time.Second, BlockTime: time.Minute})
+	require.NoError(t, err)
+	assert.True(t, result.Blocked)
+}
+
+func TestTypedLimiter_DefaultFormat(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{Requests: 100, Window: time.Second}, WithClock(fakeClock))
+	ctx := context.Background()
+
+	users := NewTypedLimiter[userID](rateLimiter, Config{Requests: 1, Window: time.Second}, nil)
+	assert.Equal(t, "42", users.Key(42))
+
+	result, err := users.Check(ctx, 42)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = users.Check(ctx, 42)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// A janela recomeça normalmente
+	fakeClock.Advance(time.Second)
+
+	result, err = users.Check(ctx, 42)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestTypedLimiter_Reserve(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{Requests: 100, Window: time.Second}, WithClock(fakeClock))
+	ctx := context.Background()
+
+	tenants := NewTypedLimiter(rateLimiter, Config{Requests: 1, Window: time.Second},
+		func(id tenantID) string { return "tenant:" + string(id) })
+
+	// Uma reserva cancelada não consome o limite
+	reservation, err := tenants.Reserve(ctx, "acme")
+	require.NoError(t, err)
+	assert.True(t, reservation.Result.Allowed)
+	reservation.Cancel()
+
+	result, err := tenants.Check(ctx, "acme")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// A confirmação conta a requisição na mesma chave de Check
+	reservation, err = tenants.Reserve(ctx, "acme")
+	require.NoError(t, err)
+	assert.Zero(t, reservation.Result.Remaining)
+
+	result, err = reservation.Commit()
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+}
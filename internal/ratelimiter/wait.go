@@ -0,0 +1,49 @@
+package ratelimiter
+
+import (
+	"context"
+	"time"
+)
+
+// Wait executa check e, enquanto a requisição for rejeitada com um RetryAfter conhecido que
+// caiba no que resta de maxWait, aguarda esse tempo e verifica novamente, suavizando picos em
+// vez de rejeitá-los de imediato (ex: Wait(ctx, time.Second, func(ctx context.Context)
+// (Result, error) { return rl.CheckIP(ctx, ip) })). Quando a espera não cabe em maxWait, o
+// resultado rejeitado é retornado sem esperar; com o contexto cancelado durante a espera, o
+// último resultado é retornado com o erro do contexto.
+//
+// Cada nova verificação conta como uma requisição, como faria uma retentativa do cliente.
+// Bloqueios mais longos que maxWait nunca são aguardados.
+func (rl *RateLimiter) Wait(ctx context.Context, maxWait time.Duration, check func(ctx context.Context) (Result, error)) (Result, error) {
+	remaining := maxWait
+	for {
+		result, err := check(ctx)
+		if err != nil || result.Allowed {
+			return result, err
+		}
+
+		delay := result.RetryAfter
+		if delay <= 0 || delay > remaining {
+			return result, nil
+		}
+
+		if err := rl.sleep(ctx, delay); err != nil {
+			return result, err
+		}
+		remaining -= delay
+	}
+}
+
+// sleepContext aguarda a duração informada ou o cancelamento do contexto, o que ocorrer
+// primeiro
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
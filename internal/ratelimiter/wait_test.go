@@ -0,0 +1,149 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWaitTestLimiter(t *testing.T, config Config) (*RateLimiter, *clock.FakeClock, *[]time.Duration) {
+	t.Helper()
+
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	t.Cleanup(func() { memoryStorage.Close() })
+
+	// As esperas avançam o relógio falso em vez de dormir
+	var sleeps []time.Duration
+	rateLimiter := NewRateLimiter(memoryStorage, config, WithClock(fakeClock))
+	rateLimiter.sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		fakeClock.Advance(d)
+		return nil
+	}
+
+	return rateLimiter, fakeClock, &sleeps
+}
+
+func TestRateLimiter_WaitEventuallyAllowed(t *testing.T) {
+	rateLimiter, fakeClock, sleeps := newWaitTestLimiter(t, Config{Requests: 1, Window: time.Second})
+	ctx := context.Background()
+
+	checkIP := func(ctx context.Context) (Result, error) {
+		return rateLimiter.CheckIP(ctx, "192.168.1.1")
+	}
+
+	result, err := rateLimiter.Wait(ctx, time.Second, checkIP)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Empty(t, *sleeps)
+
+	// A segunda requisição aguarda o fim da janela em vez de ser rejeitada
+	fakeClock.Advance(300 * time.Millisecond)
+
+	result, err = rateLimiter.Wait(ctx, time.Second, checkIP)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, []time.Duration{700 * time.Millisecond}, *sleeps)
+}
+
+func TestRateLimiter_WaitTimedOut(t *testing.T) {
+	rateLimiter, _, sleeps := newWaitTestLimiter(t, Config{Requests: 1, Window: time.Second})
+	ctx := context.Background()
+
+	checkIP := func(ctx context.Context) (Result, error) {
+		return rateLimiter.CheckIP(ctx, "192.168.1.1")
+	}
+
+	_, err := rateLimiter.Wait(ctx, 0, checkIP)
+	require.NoError(t, err)
+
+	// A próxima vaga está além da espera máxima: a rejeição é imediata
+	result, err := rateLimiter.Wait(ctx, 500*time.Millisecond, checkIP)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Empty(t, *sleeps)
+}
+
+func TestRateLimiter_WaitDoesNotWaitForLongBlocks(t *testing.T) {
+	rateLimiter, _, sleeps := newWaitTestLimiter(t, Config{Requests: 1, Window: time.Second, BlockTime: time.Minute})
+	ctx := context.Background()
+
+	checkIP := func(ctx context.Context) (Result, error) {
+		return rateLimiter.CheckIP(ctx, "192.168.1.1")
+	}
+
+	_, err := rateLimiter.Wait(ctx, time.Second, checkIP)
+	require.NoError(t, err)
+
+	result, err := rateLimiter.Wait(ctx, time.Second, checkIP)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.True(t, result.Blocked)
+	assert.Empty(t, *sleeps)
+}
+
+func TestRateLimiter_WaitRechecksUntilMaxWait(t *testing.T) {
+	rateLimiter, _, sleeps := newWaitTestLimiter(t, Config{Requests: 1, Window: time.Second})
+	ctx := context.Background()
+
+	// Uma verificação que continua rejeitando (ex: outros clientes ocupam cada nova vaga)
+	// esgota a espera máxima
+	calls := 0
+	result, err := rateLimiter.Wait(ctx, time.Second, func(ctx context.Context) (Result, error) {
+		calls++
+		return Result{RetryAfter: 400 * time.Millisecond}, nil
+	})
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []time.Duration{400 * time.Millisecond, 400 * time.Millisecond}, *sleeps)
+}
+
+func TestRateLimiter_WaitContextCanceled(t *testing.T) {
+	rateLimiter, _, _ := newWaitTestLimiter(t, Config{Requests: 1, Window: time.Second})
+	rateLimiter.sleep = sleepContext
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	done := make(chan struct{})
+
+	var result Result
+	var err error
+	go func() {
+		defer close(done)
+		result, err = rateLimiter.Wait(ctx, time.Hour, func(ctx context.Context) (Result, error) {
+			calls++
+			return Result{RetryAfter: time.Minute}, nil
+		})
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Wait não respeitou o cancelamento do contexto")
+	}
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRateLimiter_WaitReturnsCheckError(t *testing.T) {
+	rateLimiter, _, sleeps := newWaitTestLimiter(t, Config{Requests: 1, Window: time.Second})
+	storageErr := errors.New("armazenamento indisponível")
+
+	_, err := rateLimiter.Wait(context.Background(), time.Second, func(ctx context.Context) (Result, error) {
+		return Result{}, storageErr
+	})
+	assert.ErrorIs(t, err, storageErr)
+	assert.Empty(t, *sleeps)
+}
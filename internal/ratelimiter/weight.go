@@ -0,0 +1,22 @@
+package ratelimiter
+
+import "context"
+
+// weightCtx é a chave de contexto usada para transportar o peso da requisição
+type weightCtx struct{}
+
+// WithWeight retorna um contexto em que a requisição consome weight unidades do limite em vez
+// de uma (ex: os bytes do corpo, para limitar a banda). Pesos negativos são tratados como zero.
+// Requisições com peso diferente de um são contadas em etapas, sem a decisão atômica do
+// armazenamento, e o peso é ignorado pelo leaky bucket.
+func WithWeight(ctx context.Context, weight int64) context.Context {
+	return context.WithValue(ctx, weightCtx{}, max(weight, 0))
+}
+
+// weightFromContext extrai o peso da requisição do contexto; sem peso definido, vale um
+func weightFromContext(ctx context.Context) int64 {
+	if weight, ok := ctx.Value(weightCtx{}).(int64); ok {
+		return weight
+	}
+	return 1
+}
@@ -0,0 +1,79 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_WithWeight(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1})
+	defer memoryStorage.Close()
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{Requests: 1000, Window: time.Minute, BlockTime: time.Minute})
+	ctx := context.Background()
+
+	steps := []struct {
+		weight            int64
+		expectedAllowed   bool
+		expectedRemaining int64
+	}{
+		{weight: 600, expectedAllowed: true, expectedRemaining: 400},
+		{weight: 400, expectedAllowed: true, expectedRemaining: 0},
+		{weight: 0, expectedAllowed: true, expectedRemaining: 0},
+		{weight: 1, expectedAllowed: false, expectedRemaining: 0},
+	}
+
+	for i, step := range steps {
+		result, err := rateLimiter.CheckIP(WithWeight(ctx, step.weight), "192.168.1.1")
+		require.NoError(t, err)
+		assert.Equal(t, step.expectedAllowed, result.Allowed, "requisição %d", i+1)
+		assert.Equal(t, step.expectedRemaining, result.Remaining, "requisição %d", i+1)
+	}
+
+	// Exceder o orçamento bloqueia a chave também para requisições sem peso
+	result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.True(t, result.Blocked)
+}
+
+func TestRateLimiter_WithWeightTiers(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{
+		Requests: 1000,
+		Window:   time.Second,
+		Tiers:    []Config{{Requests: 1500, Window: time.Hour}},
+	}, WithClock(fakeClock))
+	ctx := context.Background()
+
+	result, err := rateLimiter.CheckIP(WithWeight(ctx, 800), "192.168.1.1")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// O tier também é consumido pelo peso da requisição
+	count, _, err := memoryStorage.Get(ctx, rateLimiter.tierKey("ip:192.168.1.1", 0))
+	require.NoError(t, err)
+	assert.Equal(t, int64(800), count)
+
+	// Na janela seguinte o limite principal tem orçamento, mas o tier não
+	fakeClock.Advance(time.Second)
+	result, err = rateLimiter.CheckIP(WithWeight(ctx, 800), "192.168.1.1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, int64(1500), result.Limit)
+}
+
+func TestWeightFromContext(t *testing.T) {
+	assert.Equal(t, int64(1), weightFromContext(context.Background()))
+	assert.Equal(t, int64(512), weightFromContext(WithWeight(context.Background(), 512)))
+	assert.Equal(t, int64(0), weightFromContext(WithWeight(context.Background(), -1)))
+}
@@ -0,0 +1,80 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/cleibson/goexpert-rate-limiter/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_WindowAlignment(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 30, 250*int(time.Millisecond), time.UTC)
+
+	tests := []struct {
+		name      string
+		alignment WindowAlignment
+		tiers     []Config
+		resetAt   time.Time
+	}{
+		{name: "rolling", alignment: Rolling, resetAt: start.Add(time.Minute)},
+		{name: "padrão", alignment: "", resetAt: start.Add(time.Minute)},
+		{name: "calendar", alignment: Calendar, resetAt: time.Date(2025, 1, 1, 12, 1, 0, 0, time.UTC)},
+		{name: "calendar em etapas", alignment: Calendar, tiers: []Config{{Requests: 100, Window: time.Hour}}, resetAt: time.Date(2025, 1, 1, 12, 1, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClock := clock.NewFakeClock(start)
+			memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+			defer memoryStorage.Close()
+
+			rateLimiter := NewRateLimiter(memoryStorage, Config{
+				Requests:        2,
+				Window:          time.Minute,
+				WindowAlignment: tt.alignment,
+				Tiers:           tt.tiers,
+			}, WithClock(fakeClock))
+			ctx := context.Background()
+
+			result, err := rateLimiter.CheckIP(ctx, "192.168.1.1")
+			require.NoError(t, err)
+			require.True(t, result.Allowed)
+
+			_, ttl, err := memoryStorage.Get(ctx, "ip:192.168.1.1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.resetAt, start.Add(ttl))
+
+			_, err = rateLimiter.CheckIP(ctx, "192.168.1.1")
+			require.NoError(t, err)
+
+			// A janela recomeça no instante esperado, e não antes
+			fakeClock.Set(tt.resetAt.Add(-time.Millisecond))
+			result, err = rateLimiter.CheckIP(ctx, "192.168.1.1")
+			require.NoError(t, err)
+			assert.False(t, result.Allowed)
+
+			fakeClock.Set(tt.resetAt)
+			result, err = rateLimiter.CheckIP(ctx, "192.168.1.1")
+			require.NoError(t, err)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, int64(1), result.Count)
+		})
+	}
+}
+
+func TestRateLimiter_WindowAlignmentCalendarResetAt(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 1, 1, 12, 0, 59, 900*int(time.Millisecond), time.UTC))
+	memoryStorage := storage.NewMemoryStorage(storage.MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+	defer memoryStorage.Close()
+
+	rateLimiter := NewRateLimiter(memoryStorage, Config{Requests: 10, Window: time.Second, WindowAlignment: Calendar}, WithClock(fakeClock))
+
+	// Uma chave que começa perto da fronteira recomeça no próximo segundo cheio
+	result, err := rateLimiter.CheckIP(context.Background(), "192.168.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 1, 12, 1, 0, 0, time.UTC), result.ResetAt)
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+)
+
+// AuditEvent descreve um bloqueio aplicado a uma chave
+type AuditEvent struct {
+	// KeyType é o tipo da chave (ex: "ip", "token"), obtido do segmento anterior ao primeiro
+	// separador (AuditOptions.KeySeparator)
+	KeyType string
+
+	// Key é a chave bloqueada, sem o KeyPrefix configurado em AuditOptions
+	Key string
+
+	// Duration é a duração do bloqueio
+	Duration time.Duration
+
+	// Time é o instante do bloqueio
+	Time time.Time
+}
+
+// AuditSink recebe os eventos de bloqueio amostrados pelo AuditStorage. É chamado de forma
+// síncrona na requisição que causou o bloqueio, então deve retornar rapidamente.
+type AuditSink interface {
+	RecordBlock(event AuditEvent)
+}
+
+// AuditSinkFunc adapta uma função ao AuditSink
+type AuditSinkFunc func(event AuditEvent)
+
+// RecordBlock chama f(event)
+func (f AuditSinkFunc) RecordBlock(event AuditEvent) {
+	f(event)
+}
+
+// AuditOptions configura o AuditStorage
+type AuditOptions struct {
+	// SampleRate é a fração dos bloqueios registrados, entre 0 e 1. Zero registra todos.
+	SampleRate float64
+
+	// KeyPrefix é o namespace removido das chaves antes de identificar o seu tipo (ex: o
+	// prefixo definido em RateLimiter.SetKeyPrefix)
+	KeyPrefix string
+
+	// KeySeparator separa o tipo da chave do restante (ex: o separador definido em
+	// ratelimiter.KeyNames); vazio usa DefaultKeySeparator
+	KeySeparator string
+
+	// Clock é a fonte do instante dos eventos. Nil usa o relógio do sistema.
+	Clock clock.Clock
+}
+
+// AuditStorage encapsula um Storage e envia ao sink uma amostra dos bloqueios aplicados, seja
+// por Block ou por CheckAndIncrement. Os eventos são derivados das próprias chamadas, sem
+// consultas adicionais ao armazenamento, e as requisições permitidas não geram eventos.
+type AuditStorage struct {
+	Storage
+
+	sink         AuditSink
+	sampleRate   float64
+	keyPrefix    string
+	keySeparator string
+	clock        clock.Clock
+	random       func() float64
+}
+
+// NewAuditStorage cria um decorator de auditoria em torno do armazenamento informado
+func NewAuditStorage(inner Storage, sink AuditSink, opts AuditOptions) *AuditStorage {
+	if opts.SampleRate <= 0 || opts.SampleRate > 1 {
+		opts.SampleRate = 1
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.New()
+	}
+	if opts.KeySeparator == "" {
+		opts.KeySeparator = DefaultKeySeparator
+	}
+
+	return &AuditStorage{
+		Storage:      inner,
+		sink:         sink,
+		sampleRate:   opts.SampleRate,
+		keyPrefix:    opts.KeyPrefix,
+		keySeparator: opts.KeySeparator,
+		clock:        opts.Clock,
+		random:       rand.Float64,
+	}
+}
+
+// CheckAndIncrement delega ao armazenamento e registra a chave quando ela é bloqueada pela
+// requisição
+func (a *AuditStorage) CheckAndIncrement(ctx context.Context, key string, limit Limit) (Decision, error) {
+	decision, err := a.Storage.CheckAndIncrement(ctx, key, limit)
+	if err == nil && decision.NewlyBlocked {
+		a.record(key, decision.TTL)
+	}
+	return decision, err
+}
+
+// Block delega ao armazenamento e registra o bloqueio aplicado
+func (a *AuditStorage) Block(ctx context.Context, key string, duration time.Duration) error {
+	err := a.Storage.Block(ctx, key, duration)
+	if err == nil {
+		a.record(key, duration)
+	}
+	return err
+}
+
+// record envia o evento ao sink conforme a taxa de amostragem
+func (a *AuditStorage) record(key string, duration time.Duration) {
+	if a.sampleRate < 1 && a.random() >= a.sampleRate {
+		return
+	}
+
+	key = strings.TrimPrefix(key, a.keyPrefix)
+	keyType, _, _ := strings.Cut(key, a.keySeparator)
+
+	a.sink.RecordBlock(AuditEvent{
+		KeyType:  keyType,
+		Key:      key,
+		Duration: duration,
+		Time:     a.clock.Now(),
+	})
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decisionStorage retorna a decisão configurada em CheckAndIncrement e conta as chamadas
+type decisionStorage struct {
+	Storage
+	decision Decision
+	err      error
+	calls    int
+}
+
+func (s *decisionStorage) CheckAndIncrement(ctx context.Context, key string, limit Limit) (Decision, error) {
+	s.calls++
+	return s.decision, s.err
+}
+
+func (s *decisionStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, bool, error) {
+	s.calls++
+	return 1, true, s.err
+}
+
+func (s *decisionStorage) IsBlocked(ctx context.Context, key string) (bool, time.Duration, error) {
+	s.calls++
+	return false, 0, s.err
+}
+
+func (s *decisionStorage) Block(ctx context.Context, key string, duration time.Duration) error {
+	s.calls++
+	return s.err
+}
+
+// recordSink acumula os eventos recebidos
+func recordSink(events *[]AuditEvent) AuditSink {
+	return AuditSinkFunc(func(event AuditEvent) {
+		*events = append(*events, event)
+	})
+}
+
+func TestAuditStorage_RecordsOnlyBlocks(t *testing.T) {
+	now := time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC)
+	inner := &decisionStorage{decision: Decision{Allowed: true, Count: 1, TTL: time.Second}}
+
+	var events []AuditEvent
+	audit := NewAuditStorage(inner, recordSink(&events), AuditOptions{
+		KeyPrefix: "myapp:",
+		Clock:     clock.NewFakeClock(now),
+	})
+	ctx := context.Background()
+	limit := Limit{Requests: 1, Window: time.Second, BlockTime: time.Minute}
+
+	// Requisições permitidas não geram eventos nem chamadas adicionais ao armazenamento
+	_, err := audit.CheckAndIncrement(ctx, "myapp:ip:192.168.1.1", limit)
+	require.NoError(t, err)
+	_, _, err = audit.Increment(ctx, "myapp:ip:192.168.1.1", time.Second)
+	require.NoError(t, err)
+	_, _, err = audit.IsBlocked(ctx, "myapp:ip:192.168.1.1")
+	require.NoError(t, err)
+	assert.Empty(t, events)
+	assert.Equal(t, 3, inner.calls)
+
+	// Chave já bloqueada não gera um novo evento
+	inner.decision = Decision{Blocked: true, TTL: 30 * time.Second}
+	_, err = audit.CheckAndIncrement(ctx, "myapp:ip:192.168.1.1", limit)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	// Bloqueio aplicado pela verificação atômica
+	inner.decision = Decision{Count: 2, Blocked: true, NewlyBlocked: true, TTL: time.Minute}
+	_, err = audit.CheckAndIncrement(ctx, "myapp:ip:192.168.1.1", limit)
+	require.NoError(t, err)
+
+	// Bloqueio aplicado diretamente
+	err = audit.Block(ctx, "myapp:token:abc123", 2*time.Minute)
+	require.NoError(t, err)
+
+	assert.Equal(t, []AuditEvent{
+		{KeyType: "ip", Key: "ip:192.168.1.1", Duration: time.Minute, Time: now},
+		{KeyType: "token", Key: "token:abc123", Duration: 2 * time.Minute, Time: now},
+	}, events)
+	assert.Equal(t, 6, inner.calls)
+}
+
+func TestAuditStorage_KeySeparator(t *testing.T) {
+	var events []AuditEvent
+	audit := NewAuditStorage(&decisionStorage{}, recordSink(&events), AuditOptions{KeyPrefix: "myapp.", KeySeparator: "."})
+
+	require.NoError(t, audit.Block(context.Background(), "myapp.token.abc123", time.Minute))
+
+	require.Len(t, events, 1)
+	assert.Equal(t, "token", events[0].KeyType)
+	assert.Equal(t, "token.abc123", events[0].Key)
+}
+
+func TestAuditStorage_FailedBlockIsNotRecorded(t *testing.T) {
+	inner := &decisionStorage{err: errRedisDown}
+
+	var events []AuditEvent
+	audit := NewAuditStorage(inner, recordSink(&events), AuditOptions{})
+
+	err := audit.Block(context.Background(), "ip:192.168.1.1", time.Minute)
+	assert.ErrorIs(t, err, errRedisDown)
+
+	_, err = audit.CheckAndIncrement(context.Background(), "ip:192.168.1.1", Limit{})
+	assert.ErrorIs(t, err, errRedisDown)
+
+	assert.Empty(t, events)
+}
+
+func TestAuditStorage_Sampling(t *testing.T) {
+	inner := &decisionStorage{}
+
+	var events []AuditEvent
+	audit := NewAuditStorage(inner, recordSink(&events), AuditOptions{SampleRate: 0.25})
+
+	samples := []float64{0.1, 0.24, 0.25, 0.9}
+	audit.random = func() float64 {
+		sample := samples[0]
+		samples = samples[1:]
+		return sample
+	}
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, audit.Block(context.Background(), "ip:192.168.1.1", time.Minute))
+	}
+
+	// Apenas as amostras abaixo da taxa são registradas
+	assert.Len(t, events, 2)
+	assert.Equal(t, 4, inner.calls)
+}
@@ -0,0 +1,296 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+)
+
+// DefaultAWSCredentialsRefreshWindow é a antecedência, em relação à expiração, com que
+// credenciais temporárias em cache são renovadas
+const DefaultAWSCredentialsRefreshWindow = 5 * time.Minute
+
+// Endpoints padrão das credenciais de tarefas do ECS e do serviço de metadados do EC2
+const (
+	defaultContainerCredentialsHost = "http://169.254.170.2"
+	defaultIMDSEndpoint             = "http://169.254.169.254"
+)
+
+// defaultAWSCredentialsTimeout limita as consultas aos endpoints de credenciais, que só
+// respondem dentro da AWS
+const defaultAWSCredentialsTimeout = time.Second
+
+// ErrAWSCredentialsNotFound indica que nenhuma fonte de credenciais está disponível
+var ErrAWSCredentialsNotFound = errors.New("credenciais da AWS não encontradas")
+
+// AWSCredentials são as credenciais que assinam as requisições à AWS. Expires, quando
+// definido, é o instante em que credenciais temporárias deixam de valer.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expires         time.Time
+}
+
+// AWSCredentialsProvider fornece as credenciais de cada requisição ao DynamoDB. É chamado a
+// cada requisição, então implementações que buscam credenciais remotamente devem mantê-las em
+// cache até perto da expiração (ver NewCachedAWSCredentials). As credenciais do AWS SDK
+// (aws.CredentialsProvider) podem ser usadas com um adaptador AWSCredentialsProviderFunc.
+type AWSCredentialsProvider interface {
+	Retrieve(ctx context.Context) (AWSCredentials, error)
+}
+
+// AWSCredentialsProviderFunc adapta uma função a AWSCredentialsProvider
+type AWSCredentialsProviderFunc func(ctx context.Context) (AWSCredentials, error)
+
+// Retrieve chama a função
+func (f AWSCredentialsProviderFunc) Retrieve(ctx context.Context) (AWSCredentials, error) {
+	return f(ctx)
+}
+
+// StaticAWSCredentials fornece sempre as mesmas credenciais
+type StaticAWSCredentials AWSCredentials
+
+// Retrieve retorna as credenciais fixas
+func (s StaticAWSCredentials) Retrieve(ctx context.Context) (AWSCredentials, error) {
+	return AWSCredentials(s), nil
+}
+
+// EnvAWSCredentials lê AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY e AWS_SESSION_TOKEN a cada
+// chamada, de modo que credenciais renovadas no ambiente (ex: no Lambda) sejam usadas sem
+// recriar o armazenamento
+type EnvAWSCredentials struct{}
+
+// Retrieve lê as credenciais das variáveis de ambiente
+func (EnvAWSCredentials) Retrieve(ctx context.Context) (AWSCredentials, error) {
+	creds := AWSCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return AWSCredentials{}, ErrAWSCredentialsNotFound
+	}
+	return creds, nil
+}
+
+// ContainerAWSCredentials obtém as credenciais do perfil IAM da tarefa no ECS (e em outros
+// ambientes com o mesmo endpoint), indicado por AWS_CONTAINER_CREDENTIALS_RELATIVE_URI ou
+// AWS_CONTAINER_CREDENTIALS_FULL_URI, com o token de AWS_CONTAINER_AUTHORIZATION_TOKEN quando
+// definido. Sem cache: use NewCachedAWSCredentials.
+type ContainerAWSCredentials struct {
+	// Client executa as consultas. Nil usa um cliente com timeout de um segundo.
+	Client *http.Client
+}
+
+// Retrieve consulta o endpoint de credenciais do contêiner
+func (c ContainerAWSCredentials) Retrieve(ctx context.Context) (AWSCredentials, error) {
+	url := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if relative := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); relative != "" {
+		url = defaultContainerCredentialsHost + relative
+	}
+	if url == "" {
+		return AWSCredentials{}, ErrAWSCredentialsNotFound
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	body, err := doCredentialsRequest(credentialsClient(c.Client), req)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("falha ao obter credenciais do contêiner: %w", err)
+	}
+	return parseAWSCredentials(body)
+}
+
+// IMDSAWSCredentials obtém as credenciais do perfil IAM da instância EC2 pelo serviço de
+// metadados (IMDSv2). Sem cache: use NewCachedAWSCredentials.
+type IMDSAWSCredentials struct {
+	// Endpoint substitui o endereço do serviço de metadados. Vazio usa
+	// AWS_EC2_METADATA_SERVICE_ENDPOINT ou http://169.254.169.254.
+	Endpoint string
+
+	// Client executa as consultas. Nil usa um cliente com timeout de um segundo.
+	Client *http.Client
+}
+
+// Retrieve obtém um token de sessão do IMDSv2, o nome do perfil da instância e as suas
+// credenciais
+func (m IMDSAWSCredentials) Retrieve(ctx context.Context) (AWSCredentials, error) {
+	endpoint := m.Endpoint
+	if endpoint == "" {
+		endpoint = os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT")
+	}
+	if endpoint == "" {
+		endpoint = defaultIMDSEndpoint
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	client := credentialsClient(m.Client)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+
+	token, err := doCredentialsRequest(client, req)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("falha ao obter token do serviço de metadados: %w", err)
+	}
+
+	get := func(path string) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-aws-ec2-metadata-token", string(token))
+		return doCredentialsRequest(client, req)
+	}
+
+	const credentialsPath = "/latest/meta-data/iam/security-credentials/"
+	roles, err := get(credentialsPath)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("falha ao obter perfil IAM da instância: %w", err)
+	}
+	role, _, _ := strings.Cut(strings.TrimSpace(string(roles)), "\n")
+	if role == "" {
+		return AWSCredentials{}, ErrAWSCredentialsNotFound
+	}
+
+	body, err := get(credentialsPath + role)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("falha ao obter credenciais da instância: %w", err)
+	}
+	return parseAWSCredentials(body)
+}
+
+// credentialsClient retorna o cliente informado ou um com defaultAWSCredentialsTimeout
+func credentialsClient(client *http.Client) *http.Client {
+	if client == nil {
+		return &http.Client{Timeout: defaultAWSCredentialsTimeout}
+	}
+	return client
+}
+
+// doCredentialsRequest executa a consulta e retorna o corpo das respostas 200
+func doCredentialsRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+// parseAWSCredentials decodifica as credenciais no formato dos endpoints do ECS e do EC2
+func parseAWSCredentials(body []byte) (AWSCredentials, error) {
+	var document struct {
+		AccessKeyID     string    `json:"AccessKeyId"`
+		SecretAccessKey string    `json:"SecretAccessKey"`
+		Token           string    `json:"Token"`
+		Expiration      time.Time `json:"Expiration"`
+	}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return AWSCredentials{}, fmt.Errorf("credenciais inválidas: %w", err)
+	}
+	if document.AccessKeyID == "" || document.SecretAccessKey == "" {
+		return AWSCredentials{}, fmt.Errorf("credenciais inválidas: chaves ausentes")
+	}
+
+	return AWSCredentials{
+		AccessKeyID:     document.AccessKeyID,
+		SecretAccessKey: document.SecretAccessKey,
+		SessionToken:    document.Token,
+		Expires:         document.Expiration,
+	}, nil
+}
+
+// CachedAWSCredentials mantém em cache as credenciais de outro provedor, renovando-as
+// DefaultAWSCredentialsRefreshWindow antes de expirarem. Credenciais sem Expires não são
+// renovadas.
+type CachedAWSCredentials struct {
+	provider AWSCredentialsProvider
+	clock    clock.Clock
+
+	mu    sync.Mutex
+	creds AWSCredentials
+	valid bool
+}
+
+// NewCachedAWSCredentials envolve o provedor com o cache de credenciais. Um clock nil usa o
+// relógio do sistema.
+func NewCachedAWSCredentials(provider AWSCredentialsProvider, clk clock.Clock) *CachedAWSCredentials {
+	if clk == nil {
+		clk = clock.New()
+	}
+	return &CachedAWSCredentials{provider: provider, clock: clk}
+}
+
+// Retrieve retorna as credenciais em cache ou as renova quando estão perto de expirar
+func (c *CachedAWSCredentials) Retrieve(ctx context.Context) (AWSCredentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.valid && (c.creds.Expires.IsZero() || c.clock.Now().Before(c.creds.Expires.Add(-DefaultAWSCredentialsRefreshWindow))) {
+		return c.creds, nil
+	}
+
+	creds, err := c.provider.Retrieve(ctx)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+
+	c.creds, c.valid = creds, true
+	return creds, nil
+}
+
+// AWSCredentialsChain consulta os provedores em ordem e retorna as credenciais do primeiro
+// que as encontrar. Provedores sem credenciais retornam ErrAWSCredentialsNotFound; as demais
+// falhas interrompem a consulta.
+type AWSCredentialsChain []AWSCredentialsProvider
+
+// Retrieve retorna as credenciais do primeiro provedor disponível
+func (c AWSCredentialsChain) Retrieve(ctx context.Context) (AWSCredentials, error) {
+	for _, provider := range c {
+		creds, err := provider.Retrieve(ctx)
+		if errors.Is(err, ErrAWSCredentialsNotFound) {
+			continue
+		}
+		return creds, err
+	}
+	return AWSCredentials{}, ErrAWSCredentialsNotFound
+}
+
+// DefaultAWSCredentials retorna a cadeia padrão de credenciais: as variáveis de ambiente, lidas
+// a cada requisição; o perfil da tarefa no ECS, quando o seu endpoint está configurado; e o
+// perfil da instância EC2. As credenciais temporárias do ECS e do EC2 ficam em cache e são
+// renovadas antes de expirar.
+func DefaultAWSCredentials() AWSCredentialsProvider {
+	chain := AWSCredentialsChain{EnvAWSCredentials{}}
+	if os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "" || os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "" {
+		chain = append(chain, NewCachedAWSCredentials(ContainerAWSCredentials{}, nil))
+	}
+	return append(chain, NewCachedAWSCredentials(IMDSAWSCredentials{}, nil))
+}
@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvAWSCredentials_ReadsOnEveryCall(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID1")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret1")
+	t.Setenv("AWS_SESSION_TOKEN", "token1")
+
+	creds, err := EnvAWSCredentials{}.Retrieve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, AWSCredentials{AccessKeyID: "AKID1", SecretAccessKey: "secret1", SessionToken: "token1"}, creds)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID2")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret2")
+	t.Setenv("AWS_SESSION_TOKEN", "token2")
+
+	creds, err = EnvAWSCredentials{}.Retrieve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "AKID2", creds.AccessKeyID)
+	assert.Equal(t, "token2", creds.SessionToken)
+}
+
+func TestEnvAWSCredentials_NotFound(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+
+	_, err := EnvAWSCredentials{}.Retrieve(context.Background())
+	assert.ErrorIs(t, err, ErrAWSCredentialsNotFound)
+}
+
+func TestContainerAWSCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/creds", r.URL.Path)
+		assert.Equal(t, "auth-token", r.Header.Get("Authorization"))
+		w.Write([]byte(`{"AccessKeyId":"ASIA1","SecretAccessKey":"secret","Token":"session","Expiration":"2025-07-21T11:00:00Z"}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", server.URL+"/creds")
+	t.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN", "auth-token")
+
+	creds, err := ContainerAWSCredentials{}.Retrieve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, AWSCredentials{
+		AccessKeyID:     "ASIA1",
+		SecretAccessKey: "secret",
+		SessionToken:    "session",
+		Expires:         time.Date(2025, 7, 21, 11, 0, 0, 0, time.UTC),
+	}, creds)
+}
+
+func TestContainerAWSCredentials_NotConfigured(t *testing.T) {
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", "")
+
+	_, err := ContainerAWSCredentials{}.Retrieve(context.Background())
+	assert.ErrorIs(t, err, ErrAWSCredentialsNotFound)
+}
+
+func TestIMDSAWSCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			assert.Equal(t, http.MethodPut, r.Method)
+			assert.Equal(t, "21600", r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds"))
+			w.Write([]byte("imds-token"))
+			return
+		}
+
+		assert.Equal(t, "imds-token", r.Header.Get("X-aws-ec2-metadata-token"))
+		switch r.URL.Path {
+		case "/latest/meta-data/iam/security-credentials/":
+			w.Write([]byte("app-role\n"))
+		case "/latest/meta-data/iam/security-credentials/app-role":
+			w.Write([]byte(`{"Code":"Success","AccessKeyId":"ASIA2","SecretAccessKey":"secret","Token":"session","Expiration":"2025-07-21T16:00:00Z"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	creds, err := IMDSAWSCredentials{Endpoint: server.URL}.Retrieve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ASIA2", creds.AccessKeyID)
+	assert.Equal(t, "session", creds.SessionToken)
+	assert.Equal(t, time.Date(2025, 7, 21, 16, 0, 0, 0, time.UTC), creds.Expires)
+}
+
+func TestIMDSAWSCredentials_Unavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	_, err := IMDSAWSCredentials{Endpoint: server.URL}.Retrieve(context.Background())
+	assert.ErrorContains(t, err, "status 403")
+}
+
+func TestCachedAWSCredentials_RefreshesBeforeExpiry(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 7, 21, 10, 0, 0, 0, time.UTC))
+
+	calls := 0
+	provider := AWSCredentialsProviderFunc(func(ctx context.Context) (AWSCredentials, error) {
+		calls++
+		return AWSCredentials{
+			AccessKeyID:     "ASIA" + string(rune('0'+calls)),
+			SecretAccessKey: "secret",
+			Expires:         fakeClock.Now().Add(time.Hour),
+		}, nil
+	})
+	cached := NewCachedAWSCredentials(provider, fakeClock)
+
+	creds, err := cached.Retrieve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ASIA1", creds.AccessKeyID)
+
+	// Dentro da validade, as credenciais vêm do cache
+	fakeClock.Advance(50 * time.Minute)
+	creds, err = cached.Retrieve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ASIA1", creds.AccessKeyID)
+	assert.Equal(t, 1, calls)
+
+	// A menos de DefaultAWSCredentialsRefreshWindow da expiração, são renovadas
+	fakeClock.Advance(6 * time.Minute)
+	creds, err = cached.Retrieve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ASIA2", creds.AccessKeyID)
+	assert.Equal(t, 2, calls)
+}
+
+func TestCachedAWSCredentials_DoesNotCacheErrors(t *testing.T) {
+	errUnavailable := errors.New("indisponível")
+
+	calls := 0
+	provider := AWSCredentialsProviderFunc(func(ctx context.Context) (AWSCredentials, error) {
+		calls++
+		if calls == 1 {
+			return AWSCredentials{}, errUnavailable
+		}
+		return AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, nil
+	})
+	cached := NewCachedAWSCredentials(provider, nil)
+
+	_, err := cached.Retrieve(context.Background())
+	assert.ErrorIs(t, err, errUnavailable)
+
+	creds, err := cached.Retrieve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "AKID", creds.AccessKeyID)
+}
+
+func TestAWSCredentialsChain(t *testing.T) {
+	notFound := AWSCredentialsProviderFunc(func(ctx context.Context) (AWSCredentials, error) {
+		return AWSCredentials{}, ErrAWSCredentialsNotFound
+	})
+	static := StaticAWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}
+
+	creds, err := AWSCredentialsChain{notFound, static}.Retrieve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "AKID", creds.AccessKeyID)
+
+	_, err = AWSCredentialsChain{notFound}.Retrieve(context.Background())
+	assert.ErrorIs(t, err, ErrAWSCredentialsNotFound)
+
+	// Outras falhas interrompem a cadeia
+	errUnavailable := errors.New("indisponível")
+	failing := AWSCredentialsProviderFunc(func(ctx context.Context) (AWSCredentials, error) {
+		return AWSCredentials{}, errUnavailable
+	})
+	_, err = AWSCredentialsChain{failing, static}.Retrieve(context.Background())
+	assert.ErrorIs(t, err, errUnavailable)
+}
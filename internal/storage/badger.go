@@ -0,0 +1,575 @@
+//go:build badger
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/dgraph-io/badger/v4"
+)
+
+// badgerTTLMargin é somado à expiração nativa do Badger, que tem resolução de segundos, para
+// que as entradas nunca sejam removidas antes da expiração registrada no valor
+const badgerTTLMargin = time.Second
+
+// errBadgerClosed é retornado por Healthy depois que o banco foi fechado
+var errBadgerClosed = errors.New("banco Badger fechado")
+
+// BadgerOptions configura o BadgerStorage
+type BadgerOptions struct {
+	// Dir é o diretório do banco, criado se não existir. Os dados sobrevivem a reinícios da
+	// aplicação.
+	Dir string
+
+	// KeyPrefix é o namespace (ex: "myapp:") aplicado a todas as chaves, inclusive às de
+	// bloqueio
+	KeyPrefix string
+
+	// KeyNames define o separador e o namespace dos bloqueios nas chaves auxiliares; vazio
+	// usa "blocked:" e os demais padrões
+	KeyNames KeyNames
+
+	// Clock é a fonte de tempo usada para janelas e bloqueios. Nil usa o relógio do sistema.
+	Clock clock.Clock
+}
+
+// badgerItem é o valor de uma chave do BadgerStorage. Apenas os campos do tipo de registro
+// correspondente (contador, vagas de concorrência, bucket, primeiro contato ou decisão) são usados.
+type badgerItem struct {
+	ExpireAt time.Time `json:"expire_at"`
+
+	Count     int64     `json:"count,omitempty"`
+	Level     float64   `json:"level,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+	At        time.Time `json:"at,omitempty"`
+	Allowed   bool      `json:"allowed,omitempty"`
+}
+
+// BadgerStorage implementa Storage sobre um banco Badger embutido, para implantações de uma
+// única instância que precisam manter contadores e bloqueios entre reinícios sem o Redis. As
+// chaves seguem o mesmo esquema do RedisStorage.
+//
+// Cada operação é uma transação serializável do Badger, repetida em caso de conflito com uma
+// transação concorrente, o que torna os incrementos e CheckAndIncrement atômicos. Os merge
+// operators do Badger não são usados porque não aceitam expiração por chave. Cada valor guarda
+// a sua expiração, verificada nas leituras com o Clock configurado, e a entrada recebe o TTL
+// nativo do Badger para ser descartada nas compactações.
+type BadgerStorage struct {
+	db    *badger.DB
+	clock clock.Clock
+
+	// Prefixos pré-calculados com o namespace configurado em KeyPrefix
+	keyPrefix       string
+	blockedPrefix   string
+	bucketPrefix    string
+	firstSeenPrefix string
+	decisionPrefix  string
+}
+
+var _ Storage = (*BadgerStorage)(nil)
+
+// NewBadgerStorage abre o banco Badger em opts.Dir. Apenas um processo pode abrir o mesmo
+// diretório por vez.
+func NewBadgerStorage(opts BadgerOptions) (*BadgerStorage, error) {
+	if opts.Clock == nil {
+		opts.Clock = clock.New()
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(opts.Dir).WithLogger(nil))
+	if err != nil {
+		return nil, fmt.Errorf("falha ao abrir banco Badger: %w", err)
+	}
+
+	prefixes := opts.KeyNames.prefixes(opts.KeyPrefix)
+
+	return &BadgerStorage{
+		db:              db,
+		clock:           opts.Clock,
+		keyPrefix:       opts.KeyPrefix,
+		blockedPrefix:   prefixes.blocked,
+		bucketPrefix:    prefixes.bucket,
+		firstSeenPrefix: prefixes.firstSeen,
+		decisionPrefix:  prefixes.decision,
+	}, nil
+}
+
+// Increment incrementa o contador para uma chave específica e retorna a contagem atual e se
+// ele foi criado por este incremento
+func (b *BadgerStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, bool, error) {
+	return b.incrementBy(ctx, key, 1, window)
+}
+
+// IncrementBy soma amount ao contador de uma chave e retorna a contagem atual
+func (b *BadgerStorage) IncrementBy(ctx context.Context, key string, amount int64, window time.Duration) (int64, error) {
+	count, _, err := b.incrementBy(ctx, key, amount, window)
+	return count, err
+}
+
+// incrementBy soma amount ao contador de uma chave em uma transação e retorna a contagem atual
+// e se o contador foi criado pelo incremento
+func (b *BadgerStorage) incrementBy(ctx context.Context, key string, amount int64, window time.Duration) (int64, bool, error) {
+	var count int64
+	var isNew bool
+	err := b.update(ctx, func(txn *badger.Txn, now time.Time) error {
+		counter, created, err := b.increment(txn, b.keyPrefix+key, amount, window, now)
+		if err != nil {
+			return err
+		}
+		count, isNew = counter.Count, created
+		return nil
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf("falha ao incrementar contador: %w", err)
+	}
+
+	return count, isNew, nil
+}
+
+// IncrementSliding incrementa o contador de uma chave renovando a sua expiração
+func (b *BadgerStorage) IncrementSliding(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	var count int64
+	err := b.update(ctx, func(txn *badger.Txn, now time.Time) error {
+		counter, err := getBadgerItem(txn, b.keyPrefix+key, now)
+		if err != nil {
+			return err
+		}
+		if counter == nil {
+			counter = &badgerItem{}
+		}
+
+		counter.Count++
+		counter.ExpireAt = now.Add(ttl)
+		count = counter.Count
+		return setBadgerItem(txn, b.keyPrefix+key, counter, now)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("falha ao incrementar contador: %w", err)
+	}
+
+	return count, nil
+}
+
+// CheckAndIncrement verifica o bloqueio, incrementa o contador e bloqueia a chave ao exceder o
+// limite em uma única transação
+func (b *BadgerStorage) CheckAndIncrement(ctx context.Context, key string, limit Limit) (Decision, error) {
+	var decision Decision
+	err := b.update(ctx, func(txn *badger.Txn, now time.Time) error {
+		blocked, err := getBadgerItem(txn, b.blockedPrefix+key, now)
+		if err != nil {
+			return err
+		}
+		if blocked != nil {
+			decision = Decision{Blocked: true, TTL: blocked.ExpireAt.Sub(now)}
+			return nil
+		}
+
+		counter, _, err := b.increment(txn, b.keyPrefix+key, 1, limit.Window, now)
+		if err != nil {
+			return err
+		}
+
+		if counter.Count <= limit.Requests {
+			decision = Decision{Allowed: true, Count: counter.Count, TTL: counter.ExpireAt.Sub(now)}
+			return nil
+		}
+
+		if limit.BlockTime > 0 {
+			decision = Decision{Count: counter.Count, Blocked: true, NewlyBlocked: true, TTL: limit.BlockTime}
+			return b.block(txn, key, now.Add(limit.BlockTime), now)
+		}
+
+		decision = Decision{Count: counter.Count, TTL: counter.ExpireAt.Sub(now)}
+		return nil
+	})
+	if err != nil {
+		return Decision{}, fmt.Errorf("falha ao verificar limite: %w", err)
+	}
+
+	return decision, nil
+}
+
+// Get lê o contador de uma chave e o tempo restante da sua janela
+func (b *BadgerStorage) Get(ctx context.Context, key string) (int64, time.Duration, error) {
+	var count int64
+	var ttl time.Duration
+	err := b.view(func(txn *badger.Txn, now time.Time) error {
+		counter, err := getBadgerItem(txn, b.keyPrefix+key, now)
+		if counter != nil {
+			count, ttl = counter.Count, counter.ExpireAt.Sub(now)
+		}
+		return err
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("falha ao ler contador: %w", err)
+	}
+
+	return count, ttl, nil
+}
+
+// IsBlocked verifica se uma chave está atualmente bloqueada e retorna o tempo restante do
+// bloqueio
+func (b *BadgerStorage) IsBlocked(ctx context.Context, key string) (bool, time.Duration, error) {
+	var blocked bool
+	var ttl time.Duration
+	err := b.view(func(txn *badger.Txn, now time.Time) error {
+		item, err := getBadgerItem(txn, b.blockedPrefix+key, now)
+		if item != nil {
+			blocked, ttl = true, item.ExpireAt.Sub(now)
+		}
+		return err
+	})
+	if err != nil {
+		return false, 0, fmt.Errorf("falha ao verificar se a chave está bloqueada: %w", err)
+	}
+
+	return blocked, ttl, nil
+}
+
+// GetMany lê os contadores e os bloqueios das chaves em uma única transação de leitura
+func (b *BadgerStorage) GetMany(ctx context.Context, keys []string) ([]KeyState, error) {
+	states := make([]KeyState, len(keys))
+	err := b.view(func(txn *badger.Txn, now time.Time) error {
+		for i, key := range keys {
+			counter, err := getBadgerItem(txn, b.keyPrefix+key, now)
+			if err != nil {
+				return err
+			}
+			if counter != nil {
+				states[i].Count, states[i].TTL = counter.Count, counter.ExpireAt.Sub(now)
+			}
+
+			blocked, err := getBadgerItem(txn, b.blockedPrefix+key, now)
+			if err != nil {
+				return err
+			}
+			if blocked != nil {
+				states[i].Blocked, states[i].BlockTTL = true, blocked.ExpireAt.Sub(now)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("falha ao ler chaves: %w", err)
+	}
+
+	return states, nil
+}
+
+// Block bloqueia uma chave pela duração especificada e zera o seu contador
+func (b *BadgerStorage) Block(ctx context.Context, key string, duration time.Duration) error {
+	err := b.update(ctx, func(txn *badger.Txn, now time.Time) error {
+		return b.block(txn, key, now.Add(duration), now)
+	})
+	if err != nil {
+		return fmt.Errorf("falha ao bloquear chave: %w", err)
+	}
+
+	return nil
+}
+
+// ListBlocked lista as chaves bloqueadas que correspondem ao padrão, percorrendo as chaves de
+// bloqueio do namespace
+func (b *BadgerStorage) ListBlocked(ctx context.Context, pattern string) ([]string, error) {
+	re := globPattern(pattern)
+
+	var keys []string
+	err := b.view(func(txn *badger.Txn, now time.Time) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(b.blockedPrefix)
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			var blocked badgerItem
+			err := it.Item().Value(func(value []byte) error {
+				return json.Unmarshal(value, &blocked)
+			})
+			if err != nil {
+				return err
+			}
+			if !now.Before(blocked.ExpireAt) {
+				continue
+			}
+
+			if key := strings.TrimPrefix(string(it.Item().Key()), b.blockedPrefix); re.MatchString(key) {
+				keys = append(keys, key)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("falha ao listar chaves bloqueadas: %w", err)
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Reset remove o contador, o bloqueio e o leaky bucket de uma chave
+func (b *BadgerStorage) Reset(ctx context.Context, key string) error {
+	err := b.update(ctx, func(txn *badger.Txn, now time.Time) error {
+		for _, k := range []string{b.keyPrefix + key, b.blockedPrefix + key, b.bucketPrefix + key} {
+			if err := txn.Delete([]byte(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("falha ao resetar chave: %w", err)
+	}
+
+	return nil
+}
+
+// LeakyBucket adiciona uma requisição ao leaky bucket da chave
+func (b *BadgerStorage) LeakyBucket(ctx context.Context, key string, capacity int64, leakInterval time.Duration, now time.Time) (bool, time.Duration, error) {
+	bucketKey := b.bucketPrefix + key
+
+	var allowed bool
+	var wait time.Duration
+	err := b.update(ctx, func(txn *badger.Txn, _ time.Time) error {
+		bucket, err := getBadgerItem(txn, bucketKey, now)
+		if err != nil {
+			return err
+		}
+
+		// Escoa as requisições correspondentes ao tempo decorrido desde a última atualização
+		level := 0.0
+		if bucket != nil {
+			elapsed := math.Max(0, float64(now.Sub(bucket.UpdatedAt)))
+			level = math.Max(0, bucket.Level-elapsed/float64(leakInterval))
+		}
+
+		if level+1 > float64(capacity) {
+			allowed = false
+			wait = time.Duration(math.Ceil((level + 1 - float64(capacity)) * float64(leakInterval)))
+			return nil
+		}
+
+		allowed, wait = true, 0
+		bucket = &badgerItem{Level: level + 1, UpdatedAt: now}
+		bucket.ExpireAt = now.Add(time.Duration(math.Ceil(bucket.Level * float64(leakInterval))))
+		return setBadgerItem(txn, bucketKey, bucket, now)
+	})
+	if err != nil {
+		return false, 0, fmt.Errorf("falha ao atualizar leaky bucket: %w", err)
+	}
+
+	return allowed, wait, nil
+}
+
+// FirstSeen registra o primeiro contato da chave e retorna o instante registrado
+func (b *BadgerStorage) FirstSeen(ctx context.Context, key string, now time.Time, ttl time.Duration) (time.Time, error) {
+	firstSeenKey := b.firstSeenPrefix + key
+
+	var at time.Time
+	err := b.update(ctx, func(txn *badger.Txn, _ time.Time) error {
+		item, err := getBadgerItem(txn, firstSeenKey, now)
+		if err != nil {
+			return err
+		}
+		if item == nil {
+			item = &badgerItem{At: now}
+		}
+
+		item.ExpireAt = now.Add(ttl)
+		at = item.At
+		return setBadgerItem(txn, firstSeenKey, item, now)
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("falha ao registrar primeiro contato: %w", err)
+	}
+
+	return at, nil
+}
+
+// GetDecision obtém a decisão registrada para uma chave de idempotência, se existir
+func (b *BadgerStorage) GetDecision(ctx context.Context, key string) (bool, bool, error) {
+	var allowed, found bool
+	err := b.view(func(txn *badger.Txn, now time.Time) error {
+		item, err := getBadgerItem(txn, b.decisionPrefix+key, now)
+		if item != nil {
+			allowed, found = item.Allowed, true
+		}
+		return err
+	})
+	if err != nil {
+		return false, false, fmt.Errorf("falha ao obter decisão: %w", err)
+	}
+
+	return allowed, found, nil
+}
+
+// SetDecision registra a decisão para uma chave de idempotência pela duração especificada
+func (b *BadgerStorage) SetDecision(ctx context.Context, key string, allowed bool, ttl time.Duration) error {
+	err := b.update(ctx, func(txn *badger.Txn, now time.Time) error {
+		return setBadgerItem(txn, b.decisionPrefix+key, &badgerItem{Allowed: allowed, ExpireAt: now.Add(ttl)}, now)
+	})
+	if err != nil {
+		return fmt.Errorf("falha ao registrar decisão: %w", err)
+	}
+
+	return nil
+}
+
+// Acquire ocupa uma vaga de concorrência da chave
+func (b *BadgerStorage) Acquire(ctx context.Context, key string, limit int64, ttl time.Duration) (bool, error) {
+	slotsKey := b.keyPrefix + key
+
+	var acquired bool
+	err := b.update(ctx, func(txn *badger.Txn, now time.Time) error {
+		slots, err := getBadgerItem(txn, slotsKey, now)
+		if err != nil {
+			return err
+		}
+		if limit <= 0 || (slots != nil && slots.Count >= limit) {
+			acquired = false
+			return nil
+		}
+		if slots == nil {
+			slots = &badgerItem{}
+		}
+
+		slots.Count++
+		slots.ExpireAt = now.Add(ttl)
+		acquired = true
+		return setBadgerItem(txn, slotsKey, slots, now)
+	})
+	if err != nil {
+		return false, fmt.Errorf("falha ao ocupar vaga de concorrência: %w", err)
+	}
+
+	return acquired, nil
+}
+
+// Release libera uma vaga de concorrência da chave
+func (b *BadgerStorage) Release(ctx context.Context, key string) error {
+	slotsKey := b.keyPrefix + key
+
+	err := b.update(ctx, func(txn *badger.Txn, now time.Time) error {
+		slots, err := getBadgerItem(txn, slotsKey, now)
+		if err != nil || slots == nil {
+			return err
+		}
+
+		slots.Count--
+		if slots.Count <= 0 {
+			return txn.Delete([]byte(slotsKey))
+		}
+		return setBadgerItem(txn, slotsKey, slots, now)
+	})
+	if err != nil {
+		return fmt.Errorf("falha ao liberar vaga de concorrência: %w", err)
+	}
+
+	return nil
+}
+
+// Healthy verifica se o banco ainda está aberto
+func (b *BadgerStorage) Healthy(ctx context.Context) error {
+	if b.db.IsClosed() {
+		return errBadgerClosed
+	}
+	return nil
+}
+
+// Close fecha o banco, gravando os dados pendentes em disco. Pode ser chamado mais de uma vez.
+func (b *BadgerStorage) Close() error {
+	if b.db.IsClosed() {
+		return nil
+	}
+	return b.db.Close()
+}
+
+// update executa fn em uma transação de escrita, repetindo-a enquanto ela conflitar com uma
+// transação concorrente e o contexto não for cancelado
+func (b *BadgerStorage) update(ctx context.Context, fn func(txn *badger.Txn, now time.Time) error) error {
+	for {
+		err := b.db.Update(func(txn *badger.Txn) error {
+			return fn(txn, b.clock.Now())
+		})
+		if !errors.Is(err, badger.ErrConflict) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// view executa fn em uma transação de leitura
+func (b *BadgerStorage) view(fn func(txn *badger.Txn, now time.Time) error) error {
+	return b.db.View(func(txn *badger.Txn) error {
+		return fn(txn, b.clock.Now())
+	})
+}
+
+// increment soma amount ao contador da chave, iniciando uma nova janela quando não há contador
+// válido, e indica se a janela foi iniciada
+func (b *BadgerStorage) increment(txn *badger.Txn, key string, amount int64, window time.Duration, now time.Time) (*badgerItem, bool, error) {
+	counter, err := getBadgerItem(txn, key, now)
+	if err != nil {
+		return nil, false, err
+	}
+	isNew := counter == nil
+	if isNew {
+		counter = &badgerItem{ExpireAt: now.Add(window)}
+	}
+
+	counter.Count += amount
+	return counter, isNew, setBadgerItem(txn, key, counter, now)
+}
+
+// block registra o bloqueio da chave até blockedUntil e remove o seu contador
+func (b *BadgerStorage) block(txn *badger.Txn, key string, blockedUntil, now time.Time) error {
+	err := setBadgerItem(txn, b.blockedPrefix+key, &badgerItem{ExpireAt: blockedUntil}, now)
+	if err != nil {
+		return err
+	}
+	return txn.Delete([]byte(b.keyPrefix + key))
+}
+
+// getBadgerItem lê a chave na transação, retornando nil se ela não existe ou expirou
+func getBadgerItem(txn *badger.Txn, key string, now time.Time) (*badgerItem, error) {
+	entry, err := txn.Get([]byte(key))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var item badgerItem
+	err = entry.Value(func(value []byte) error {
+		return json.Unmarshal(value, &item)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !now.Before(item.ExpireAt) {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+// setBadgerItem grava a chave na transação com o TTL nativo correspondente à sua expiração
+func setBadgerItem(txn *badger.Txn, key string, item *badgerItem, now time.Time) error {
+	value, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	ttl := item.ExpireAt.Sub(now) + badgerTTLMargin
+	return txn.SetEntry(badger.NewEntry([]byte(key), value).WithTTL(ttl))
+}
@@ -0,0 +1,227 @@
+//go:build badger
+
+package storage
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Testes contra um banco Badger em um diretório temporário, executados com:
+//
+//	go test -tags badger ./internal/storage/
+
+func newTestBadgerStorage(t *testing.T, dir string) (*BadgerStorage, *clock.FakeClock) {
+	t.Helper()
+
+	fakeClock := clock.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	s, err := NewBadgerStorage(BadgerOptions{Dir: dir, KeyPrefix: "app:", Clock: fakeClock})
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s, fakeClock
+}
+
+func TestBadgerStorage_Increment(t *testing.T) {
+	s, fakeClock := newTestBadgerStorage(t, t.TempDir())
+	ctx := context.Background()
+
+	// Apenas o incremento que cria o contador inicia a janela
+	for i := int64(1); i <= 3; i++ {
+		count, isNew, err := s.Increment(ctx, "ip:1", time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, i, count)
+		assert.Equal(t, i == 1, isNew)
+	}
+
+	count, err := s.IncrementBy(ctx, "ip:1", 10, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(13), count)
+
+	// A janela não desliza com novos incrementos
+	fakeClock.Advance(500 * time.Millisecond)
+	count, ttl, err := s.Get(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(13), count)
+	assert.Equal(t, 500*time.Millisecond, ttl)
+
+	fakeClock.Advance(500 * time.Millisecond)
+	count, isNew, err := s.Increment(ctx, "ip:1", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.True(t, isNew)
+}
+
+func TestBadgerStorage_CheckAndIncrement(t *testing.T) {
+	s, fakeClock := newTestBadgerStorage(t, t.TempDir())
+	ctx := context.Background()
+	limit := Limit{Requests: 2, Window: time.Second, BlockTime: time.Minute}
+
+	for i := int64(1); i <= 2; i++ {
+		decision, err := s.CheckAndIncrement(ctx, "ip:1", limit)
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, i, decision.Count)
+	}
+
+	decision, err := s.CheckAndIncrement(ctx, "ip:1", limit)
+	require.NoError(t, err)
+	assert.Equal(t, Decision{Count: 3, Blocked: true, NewlyBlocked: true, TTL: time.Minute}, decision)
+
+	// O contador é zerado pelo bloqueio
+	count, _, err := s.Get(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	fakeClock.Advance(30 * time.Second)
+	decision, err = s.CheckAndIncrement(ctx, "ip:1", limit)
+	require.NoError(t, err)
+	assert.Equal(t, Decision{Blocked: true, TTL: 30 * time.Second}, decision)
+
+	fakeClock.Advance(30 * time.Second)
+	decision, err = s.CheckAndIncrement(ctx, "ip:1", limit)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, int64(1), decision.Count)
+}
+
+func TestBadgerStorage_CheckAndIncrementConcurrent(t *testing.T) {
+	s, _ := newTestBadgerStorage(t, t.TempDir())
+	ctx := context.Background()
+	limit := Limit{Requests: 50, Window: time.Minute, BlockTime: time.Minute}
+
+	var allowed, newlyBlocked atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			decision, err := s.CheckAndIncrement(ctx, "ip:1", limit)
+			if !assert.NoError(t, err) {
+				return
+			}
+			if decision.Allowed {
+				allowed.Add(1)
+			}
+			if decision.NewlyBlocked {
+				newlyBlocked.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Os conflitos entre transações são repetidos: o limite é exato e o bloqueio, único
+	assert.Equal(t, int64(50), allowed.Load())
+	assert.Equal(t, int64(1), newlyBlocked.Load())
+}
+
+func TestBadgerStorage_BlockListAndReset(t *testing.T) {
+	s, fakeClock := newTestBadgerStorage(t, t.TempDir())
+	ctx := context.Background()
+
+	_, _, err := s.Increment(ctx, "ip:1", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, s.Block(ctx, "ip:1", time.Second))
+	require.NoError(t, s.Block(ctx, "ip:2", time.Minute))
+	require.NoError(t, s.Block(ctx, "token:abc", time.Minute))
+
+	blocked, _, err := s.IsBlocked(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+
+	count, _, err := s.Get(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	keys, err := s.ListBlocked(ctx, "ip:*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ip:1", "ip:2"}, keys)
+
+	// Bloqueios expirados não são listados
+	fakeClock.Advance(time.Second)
+	keys, err = s.ListBlocked(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ip:2", "token:abc"}, keys)
+
+	require.NoError(t, s.Reset(ctx, "ip:2"))
+	require.NoError(t, s.Reset(ctx, "ip:inexistente"))
+
+	blocked, _, err = s.IsBlocked(ctx, "ip:2")
+	require.NoError(t, err)
+	assert.False(t, blocked)
+}
+
+func TestBadgerStorage_AuxiliaryRecords(t *testing.T) {
+	s, fakeClock := newTestBadgerStorage(t, t.TempDir())
+	ctx := context.Background()
+	now := fakeClock.Now()
+
+	// Leaky bucket com capacidade 1 escoando uma requisição por segundo
+	allowed, _, err := s.LeakyBucket(ctx, "ip:1", 1, time.Second, now)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, wait, err := s.LeakyBucket(ctx, "ip:1", 1, time.Second, now)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Second, wait)
+
+	// Primeiro contato preservado entre chamadas
+	first, err := s.FirstSeen(ctx, "ip:1", now, time.Minute)
+	require.NoError(t, err)
+	second, err := s.FirstSeen(ctx, "ip:1", now.Add(time.Second), time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	// Decisões de idempotência
+	require.NoError(t, s.SetDecision(ctx, "ip:1:req", false, time.Minute))
+	allowed, found, err := s.GetDecision(ctx, "ip:1:req")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.False(t, allowed)
+
+	// Vagas de concorrência
+	acquired, err := s.Acquire(ctx, "ip:1:concurrency", 1, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = s.Acquire(ctx, "ip:1:concurrency", 1, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	require.NoError(t, s.Release(ctx, "ip:1:concurrency"))
+	acquired, err = s.Acquire(ctx, "ip:1:concurrency", 1, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+}
+
+func TestBadgerStorage_PersistsAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	s, _ := newTestBadgerStorage(t, dir)
+	_, err := s.IncrementBy(ctx, "ip:1", 5, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, s.Block(ctx, "ip:2", time.Minute))
+	require.NoError(t, s.Close())
+
+	assert.Error(t, s.Healthy(ctx))
+
+	// Reabre o mesmo diretório, como após um reinício da aplicação
+	reopened, _ := newTestBadgerStorage(t, dir)
+	require.NoError(t, reopened.Healthy(ctx))
+
+	count, _, err := reopened.Get(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), count)
+
+	blocked, _, err := reopened.IsBlocked(ctx, "ip:2")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+}
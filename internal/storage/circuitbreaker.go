@@ -0,0 +1,334 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+)
+
+// ErrCircuitOpen é retornado enquanto o circuit breaker está aberto, sem consultar o
+// armazenamento encapsulado
+var ErrCircuitOpen = errors.New("circuit breaker aberto: armazenamento indisponível")
+
+// Valores padrão do circuit breaker
+const (
+	DefaultFailureThreshold = 5
+	DefaultCooldown         = 10 * time.Second
+)
+
+// CircuitState representa o estado do circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed encaminha as chamadas ao armazenamento normalmente
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen rejeita as chamadas imediatamente com ErrCircuitOpen
+	CircuitOpen
+
+	// CircuitHalfOpen permite uma única chamada de teste para decidir se o circuito fecha
+	CircuitHalfOpen
+)
+
+// String retorna o nome do estado
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerOptions configura o circuit breaker
+type CircuitBreakerOptions struct {
+	// FailureThreshold é o número de falhas consecutivas que abre o circuito.
+	// Zero usa DefaultFailureThreshold.
+	FailureThreshold int
+
+	// Cooldown é o tempo que o circuito permanece aberto antes de permitir uma chamada de
+	// teste. Zero usa DefaultCooldown.
+	Cooldown time.Duration
+
+	// Clock é a fonte de tempo usada para medir o cooldown. Nil usa o relógio do sistema.
+	Clock clock.Clock
+}
+
+// CircuitBreakerStorage encapsula um Storage e interrompe as chamadas após falhas
+// consecutivas, para que uma indisponibilidade do armazenamento não faça toda requisição
+// aguardar o timeout completo. Os erros são propagados ao chamador, que aplica o seu modo de
+// falha.
+type CircuitBreakerStorage struct {
+	inner            Storage
+	failureThreshold int
+	cooldown         time.Duration
+	clock            clock.Clock
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreakerStorage cria um circuit breaker em torno do armazenamento informado
+func NewCircuitBreakerStorage(inner Storage, opts CircuitBreakerOptions) *CircuitBreakerStorage {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = DefaultFailureThreshold
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = DefaultCooldown
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.New()
+	}
+
+	return &CircuitBreakerStorage{
+		inner:            inner,
+		failureThreshold: opts.FailureThreshold,
+		cooldown:         opts.Cooldown,
+		clock:            opts.Clock,
+	}
+}
+
+// State retorna o estado atual do circuito
+func (c *CircuitBreakerStorage) State() CircuitState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == CircuitOpen && c.clock.Now().Sub(c.openedAt) >= c.cooldown {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+// allow decide se uma chamada pode ser encaminhada ao armazenamento encapsulado
+func (c *CircuitBreakerStorage) allow() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == CircuitOpen {
+		if c.clock.Now().Sub(c.openedAt) < c.cooldown {
+			return ErrCircuitOpen
+		}
+		c.state = CircuitHalfOpen
+	}
+
+	// No estado semiaberto apenas uma chamada de teste é permitida por vez
+	if c.state == CircuitHalfOpen {
+		if c.probing {
+			return ErrCircuitOpen
+		}
+		c.probing = true
+	}
+
+	return nil
+}
+
+// record atualiza o estado do circuito com o resultado de uma chamada
+func (c *CircuitBreakerStorage) record(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.probing = false
+
+	// Chamadas canceladas pelo cliente não indicam falha do armazenamento
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	if err == nil {
+		c.state = CircuitClosed
+		c.failures = 0
+		return
+	}
+
+	c.failures++
+	if c.state == CircuitHalfOpen || c.failures >= c.failureThreshold {
+		c.state = CircuitOpen
+		c.openedAt = c.clock.Now()
+		c.failures = 0
+	}
+}
+
+// call executa fn respeitando o estado do circuito
+func (c *CircuitBreakerStorage) call(fn func() error) error {
+	err := c.allow()
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+	c.record(err)
+	return err
+}
+
+// Increment incrementa o contador através do circuit breaker
+func (c *CircuitBreakerStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, bool, error) {
+	var count int64
+	var isNew bool
+	err := c.call(func() (err error) {
+		count, isNew, err = c.inner.Increment(ctx, key, window)
+		return err
+	})
+	return count, isNew, err
+}
+
+// IncrementBy soma amount ao contador através do circuit breaker
+func (c *CircuitBreakerStorage) IncrementBy(ctx context.Context, key string, amount int64, window time.Duration) (int64, error) {
+	var count int64
+	err := c.call(func() (err error) {
+		count, err = c.inner.IncrementBy(ctx, key, amount, window)
+		return err
+	})
+	return count, err
+}
+
+// CheckAndIncrement decide a requisição através do circuit breaker
+func (c *CircuitBreakerStorage) CheckAndIncrement(ctx context.Context, key string, limit Limit) (Decision, error) {
+	var decision Decision
+	err := c.call(func() (err error) {
+		decision, err = c.inner.CheckAndIncrement(ctx, key, limit)
+		return err
+	})
+	return decision, err
+}
+
+// Get lê o contador através do circuit breaker
+func (c *CircuitBreakerStorage) Get(ctx context.Context, key string) (int64, time.Duration, error) {
+	var count int64
+	var ttl time.Duration
+	err := c.call(func() (err error) {
+		count, ttl, err = c.inner.Get(ctx, key)
+		return err
+	})
+	return count, ttl, err
+}
+
+// IsBlocked verifica o bloqueio através do circuit breaker
+func (c *CircuitBreakerStorage) IsBlocked(ctx context.Context, key string) (bool, time.Duration, error) {
+	var blocked bool
+	var ttl time.Duration
+	err := c.call(func() (err error) {
+		blocked, ttl, err = c.inner.IsBlocked(ctx, key)
+		return err
+	})
+	return blocked, ttl, err
+}
+
+// GetMany lê os contadores e os bloqueios das chaves através do circuit breaker
+func (c *CircuitBreakerStorage) GetMany(ctx context.Context, keys []string) ([]KeyState, error) {
+	var states []KeyState
+	err := c.call(func() (err error) {
+		states, err = c.inner.GetMany(ctx, keys)
+		return err
+	})
+	return states, err
+}
+
+// Block bloqueia a chave através do circuit breaker
+func (c *CircuitBreakerStorage) Block(ctx context.Context, key string, duration time.Duration) error {
+	return c.call(func() error {
+		return c.inner.Block(ctx, key, duration)
+	})
+}
+
+// LeakyBucket atualiza o leaky bucket através do circuit breaker
+func (c *CircuitBreakerStorage) LeakyBucket(ctx context.Context, key string, capacity int64, leakInterval time.Duration, now time.Time) (bool, time.Duration, error) {
+	var allowed bool
+	var wait time.Duration
+	err := c.call(func() (err error) {
+		allowed, wait, err = c.inner.LeakyBucket(ctx, key, capacity, leakInterval, now)
+		return err
+	})
+	return allowed, wait, err
+}
+
+// FirstSeen registra o primeiro contato através do circuit breaker
+func (c *CircuitBreakerStorage) FirstSeen(ctx context.Context, key string, now time.Time, ttl time.Duration) (time.Time, error) {
+	var firstSeen time.Time
+	err := c.call(func() (err error) {
+		firstSeen, err = c.inner.FirstSeen(ctx, key, now, ttl)
+		return err
+	})
+	return firstSeen, err
+}
+
+// GetDecision obtém a decisão de idempotência através do circuit breaker
+func (c *CircuitBreakerStorage) GetDecision(ctx context.Context, key string) (bool, bool, error) {
+	var allowed, found bool
+	err := c.call(func() (err error) {
+		allowed, found, err = c.inner.GetDecision(ctx, key)
+		return err
+	})
+	return allowed, found, err
+}
+
+// SetDecision registra a decisão de idempotência através do circuit breaker
+func (c *CircuitBreakerStorage) SetDecision(ctx context.Context, key string, allowed bool, ttl time.Duration) error {
+	return c.call(func() error {
+		return c.inner.SetDecision(ctx, key, allowed, ttl)
+	})
+}
+
+// ListBlocked lista as chaves bloqueadas através do circuit breaker
+func (c *CircuitBreakerStorage) ListBlocked(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+	err := c.call(func() (err error) {
+		keys, err = c.inner.ListBlocked(ctx, pattern)
+		return err
+	})
+	return keys, err
+}
+
+// Reset remove o contador e o bloqueio da chave através do circuit breaker
+func (c *CircuitBreakerStorage) Reset(ctx context.Context, key string) error {
+	return c.call(func() error {
+		return c.inner.Reset(ctx, key)
+	})
+}
+
+// IncrementSliding incrementa um contador com expiração renovada através do circuit breaker
+func (c *CircuitBreakerStorage) IncrementSliding(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	var count int64
+	err := c.call(func() (err error) {
+		count, err = c.inner.IncrementSliding(ctx, key, ttl)
+		return err
+	})
+	return count, err
+}
+
+// Acquire ocupa uma vaga de concorrência através do circuit breaker
+func (c *CircuitBreakerStorage) Acquire(ctx context.Context, key string, limit int64, ttl time.Duration) (bool, error) {
+	var acquired bool
+	err := c.call(func() (err error) {
+		acquired, err = c.inner.Acquire(ctx, key, limit, ttl)
+		return err
+	})
+	return acquired, err
+}
+
+// Release libera uma vaga de concorrência através do circuit breaker
+func (c *CircuitBreakerStorage) Release(ctx context.Context, key string) error {
+	return c.call(func() error {
+		return c.inner.Release(ctx, key)
+	})
+}
+
+// Healthy consulta o armazenamento encapsulado diretamente, mesmo com o circuito aberto, para
+// que verificações de saúde reflitam o estado real da dependência
+func (c *CircuitBreakerStorage) Healthy(ctx context.Context) error {
+	return c.inner.Healthy(ctx)
+}
+
+// Close fecha o armazenamento encapsulado
+func (c *CircuitBreakerStorage) Close() error {
+	return c.inner.Close()
+}
@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	errRedisDown = errors.New("redis indisponível")
+	errTransient = errors.New("conexão reiniciada")
+)
+
+// stubStorage é um Storage que conta as chamadas e retorna o erro configurado. As primeiras
+// failFirst chamadas falham com errTransient.
+type stubStorage struct {
+	mu        sync.Mutex
+	err       error
+	failFirst int
+	calls     int
+}
+
+func (s *stubStorage) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+func (s *stubStorage) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func (s *stubStorage) call() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.calls <= s.failFirst {
+		return errTransient
+	}
+	return s.err
+}
+
+func (s *stubStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, bool, error) {
+	if err := s.call(); err != nil {
+		return 0, false, err
+	}
+	return 1, true, nil
+}
+
+func (s *stubStorage) IncrementBy(ctx context.Context, key string, amount int64, window time.Duration) (int64, error) {
+	if err := s.call(); err != nil {
+		return 0, err
+	}
+	return amount, nil
+}
+
+func (s *stubStorage) CheckAndIncrement(ctx context.Context, key string, limit Limit) (Decision, error) {
+	if err := s.call(); err != nil {
+		return Decision{}, err
+	}
+	return Decision{Allowed: true, Count: 1, TTL: limit.Window}, nil
+}
+
+func (s *stubStorage) Get(ctx context.Context, key string) (int64, time.Duration, error) {
+	return 0, 0, s.call()
+}
+
+func (s *stubStorage) IsBlocked(ctx context.Context, key string) (bool, time.Duration, error) {
+	return false, 0, s.call()
+}
+
+func (s *stubStorage) GetMany(ctx context.Context, keys []string) ([]KeyState, error) {
+	return make([]KeyState, len(keys)), s.call()
+}
+
+func (s *stubStorage) Block(ctx context.Context, key string, duration time.Duration) error {
+	return s.call()
+}
+
+func (s *stubStorage) ListBlocked(ctx context.Context, pattern string) ([]string, error) {
+	return nil, s.call()
+}
+
+func (s *stubStorage) Reset(ctx context.Context, key string) error {
+	return s.call()
+}
+
+func (s *stubStorage) LeakyBucket(ctx context.Context, key string, capacity int64, leakInterval time.Duration, now time.Time) (bool, time.Duration, error) {
+	return true, 0, s.call()
+}
+
+func (s *stubStorage) FirstSeen(ctx context.Context, key string, now time.Time, ttl time.Duration) (time.Time, error) {
+	return now, s.call()
+}
+
+func (s *stubStorage) GetDecision(ctx context.Context, key string) (bool, bool, error) {
+	return false, false, s.call()
+}
+
+func (s *stubStorage) SetDecision(ctx context.Context, key string, allowed bool, ttl time.Duration) error {
+	return s.call()
+}
+
+func (s *stubStorage) IncrementSliding(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return 1, s.call()
+}
+
+func (s *stubStorage) Acquire(ctx context.Context, key string, limit int64, ttl time.Duration) (bool, error) {
+	return true, s.call()
+}
+
+func (s *stubStorage) Release(ctx context.Context, key string) error {
+	return s.call()
+}
+
+func (s *stubStorage) Healthy(ctx context.Context) error {
+	return s.call()
+}
+
+func (s *stubStorage) Close() error {
+	return nil
+}
+
+func TestCircuitBreakerStorage_Lifecycle(t *testing.T) {
+	inner := &stubStorage{err: errRedisDown}
+	fakeClock := clock.NewFakeClock(time.Unix(0, 0))
+	breaker := NewCircuitBreakerStorage(inner, CircuitBreakerOptions{
+		FailureThreshold: 3,
+		Cooldown:         10 * time.Second,
+		Clock:            fakeClock,
+	})
+
+	ctx := context.Background()
+
+	// Fechado: as falhas são propagadas até atingir o limite de falhas consecutivas
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, CircuitClosed, breaker.State())
+		_, _, err := breaker.Increment(ctx, "ip:1", time.Second)
+		assert.ErrorIs(t, err, errRedisDown)
+	}
+
+	// Aberto: as chamadas falham imediatamente sem consultar o armazenamento
+	assert.Equal(t, CircuitOpen, breaker.State())
+	_, _, err := breaker.IsBlocked(ctx, "ip:1")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 3, inner.callCount())
+
+	// Semiaberto após o cooldown: uma chamada de teste que falha reabre o circuito
+	fakeClock.Advance(10 * time.Second)
+	assert.Equal(t, CircuitHalfOpen, breaker.State())
+	_, _, err = breaker.Increment(ctx, "ip:1", time.Second)
+	assert.ErrorIs(t, err, errRedisDown)
+	assert.Equal(t, CircuitOpen, breaker.State())
+	assert.Equal(t, 4, inner.callCount())
+
+	// Semiaberto novamente: uma chamada de teste bem-sucedida fecha o circuito
+	inner.setErr(nil)
+	fakeClock.Advance(10 * time.Second)
+	assert.Equal(t, CircuitHalfOpen, breaker.State())
+	count, _, err := breaker.Increment(ctx, "ip:1", time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.Equal(t, CircuitClosed, breaker.State())
+
+	// Fechado: as chamadas voltam a ser encaminhadas
+	_, _, err = breaker.IsBlocked(ctx, "ip:1")
+	assert.NoError(t, err)
+	assert.Equal(t, 6, inner.callCount())
+}
+
+func TestCircuitBreakerStorage_SuccessResetsFailures(t *testing.T) {
+	inner := &stubStorage{}
+	breaker := NewCircuitBreakerStorage(inner, CircuitBreakerOptions{FailureThreshold: 2})
+
+	ctx := context.Background()
+
+	// Falhas intercaladas com sucesso não são consecutivas e não abrem o circuito
+	inner.setErr(errRedisDown)
+	_ = breaker.Block(ctx, "ip:1", time.Minute)
+	inner.setErr(nil)
+	assert.NoError(t, breaker.Block(ctx, "ip:1", time.Minute))
+	inner.setErr(errRedisDown)
+	_ = breaker.Block(ctx, "ip:1", time.Minute)
+
+	assert.Equal(t, CircuitClosed, breaker.State())
+}
+
+func TestCircuitBreakerStorage_CanceledCallsDoNotCount(t *testing.T) {
+	inner := &stubStorage{err: context.Canceled}
+	breaker := NewCircuitBreakerStorage(inner, CircuitBreakerOptions{FailureThreshold: 2})
+
+	ctx := context.Background()
+
+	// Requisições abandonadas pelo cliente não abrem o circuito
+	for i := 0; i < 5; i++ {
+		assert.ErrorIs(t, breaker.Block(ctx, "ip:1", time.Minute), context.Canceled)
+	}
+	assert.Equal(t, CircuitClosed, breaker.State())
+	assert.Equal(t, 5, inner.callCount())
+}
+
+func TestCircuitBreakerStorage_HalfOpenAllowsSingleProbe(t *testing.T) {
+	inner := &blockingStorage{
+		stubStorage: stubStorage{err: errRedisDown},
+		started:     make(chan struct{}),
+		release:     make(chan struct{}),
+	}
+	fakeClock := clock.NewFakeClock(time.Unix(0, 0))
+	breaker := NewCircuitBreakerStorage(inner, CircuitBreakerOptions{FailureThreshold: 1, Cooldown: time.Second, Clock: fakeClock})
+
+	ctx := context.Background()
+
+	// Abre o circuito com uma falha
+	_, _, _ = breaker.IsBlocked(ctx, "ip:1")
+	assert.Equal(t, CircuitOpen, breaker.State())
+
+	inner.setErr(nil)
+	fakeClock.Advance(time.Second)
+
+	// A chamada de teste fica em andamento até release ser fechado
+	done := make(chan error)
+	go func() {
+		_, _, err := breaker.Increment(ctx, "ip:1", time.Second)
+		done <- err
+	}()
+	<-inner.started
+
+	// Enquanto isso as demais chamadas são rejeitadas
+	_, _, err := breaker.IsBlocked(ctx, "ip:1")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	close(inner.release)
+	assert.NoError(t, <-done)
+	assert.Equal(t, CircuitClosed, breaker.State())
+}
+
+func TestCircuitBreakerStorage_HealthyBypassesOpenCircuit(t *testing.T) {
+	inner := &stubStorage{err: errRedisDown}
+	breaker := NewCircuitBreakerStorage(inner, CircuitBreakerOptions{FailureThreshold: 1})
+
+	ctx := context.Background()
+
+	_, _, _ = breaker.IsBlocked(ctx, "ip:1")
+	assert.Equal(t, CircuitOpen, breaker.State())
+
+	// A verificação de saúde reflete o armazenamento real, não o estado do circuito
+	assert.ErrorIs(t, breaker.Healthy(ctx), errRedisDown)
+
+	inner.setErr(nil)
+	assert.NoError(t, breaker.Healthy(ctx))
+}
+
+// blockingStorage segura Increment até release ser fechado
+type blockingStorage struct {
+	stubStorage
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, bool, error) {
+	s.started <- struct{}{}
+	<-s.release
+	return s.stubStorage.Increment(ctx, key, window)
+}
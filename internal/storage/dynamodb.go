@@ -0,0 +1,928 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+)
+
+// DefaultDynamoDBTimeout é o timeout padrão das requisições ao DynamoDB
+const DefaultDynamoDBTimeout = 5 * time.Second
+
+// dynamoDBBatchGetLimit é o número máximo de itens lidos por requisição BatchGetItem
+const dynamoDBBatchGetLimit = 100
+
+// dynamoDBMaxAttempts limita as tentativas das operações que disputam o mesmo item com
+// escritas condicionais concorrentes
+const dynamoDBMaxAttempts = 5
+
+// errDynamoDBConflict indica que as escritas condicionais não prevaleceram após
+// dynamoDBMaxAttempts tentativas
+var errDynamoDBConflict = errors.New("escritas concorrentes no mesmo item do DynamoDB")
+
+// errDynamoDBUnprocessed indica que o DynamoDB não processou todos os itens de um BatchGetItem
+// após dynamoDBMaxAttempts requisições
+var errDynamoDBUnprocessed = errors.New("itens não processados pelo DynamoDB")
+
+// DynamoDBError é um erro retornado pela API do DynamoDB
+type DynamoDBError struct {
+	// StatusCode é o status HTTP da resposta
+	StatusCode int
+
+	// Type é o tipo do erro (ex: "ConditionalCheckFailedException")
+	Type string
+
+	// Message é a descrição do erro
+	Message string
+}
+
+// Error descreve o erro da API
+func (e *DynamoDBError) Error() string {
+	return fmt.Sprintf("dynamodb: %s: %s (status %d)", e.Type, e.Message, e.StatusCode)
+}
+
+// DynamoDBOptions configura o DynamoDBStorage
+type DynamoDBOptions struct {
+	// Table é a tabela usada, com chave de partição "pk" do tipo string. Habilite o TTL da
+	// tabela no atributo "ttl" para que o DynamoDB remova os itens expirados.
+	Table string
+
+	// Region é a região AWS da tabela. Vazio usa AWS_REGION.
+	Region string
+
+	// Endpoint substitui o endpoint regional (ex: "http://localhost:8000" para o
+	// dynamodb-local)
+	Endpoint string
+
+	// Credentials fornece as credenciais de cada requisição, consultado a cada chamada para que
+	// credenciais temporárias sejam renovadas. Nil usa AccessKeyID, SecretAccessKey e
+	// SessionToken quando informados ou, sem eles, DefaultAWSCredentials.
+	Credentials AWSCredentialsProvider
+
+	// AccessKeyID, SecretAccessKey e SessionToken são credenciais fixas, usadas quando
+	// Credentials é nil
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// KeyPrefix é o namespace (ex: "myapp:") aplicado a todas as chaves, inclusive às de
+	// bloqueio, para que serviços que compartilham a mesma tabela não colidam
+	KeyPrefix string
+
+	// KeyNames define o separador e o namespace dos bloqueios nas chaves auxiliares; vazio
+	// usa "blocked:" e os demais padrões
+	KeyNames KeyNames
+
+	// HTTPClient executa as requisições. Nil usa um cliente com DefaultDynamoDBTimeout.
+	HTTPClient *http.Client
+
+	// Clock é a fonte de tempo usada para janelas e bloqueios. Nil usa o relógio do sistema.
+	Clock clock.Clock
+}
+
+// DynamoDBStorage implementa Storage sobre uma tabela do DynamoDB, acessada pela API HTTP com
+// requisições assinadas (Signature Version 4), para implantações sem Redis. Os contadores usam
+// UpdateItem condicional com ADD, e cada item guarda a sua expiração em milissegundos
+// ("expires_at"), verificada nas leituras, e em segundos ("ttl") para a limpeza pelo TTL do
+// DynamoDB, que pode atrasar a remoção em até alguns dias.
+//
+// Ao contrário do script Lua do Redis, CheckAndIncrement combina escritas condicionais em itens
+// distintos (contador e bloqueio) sem transação: o limite e o bloqueio único continuam
+// garantidos, mas uma requisição concorrente ao bloqueio pode ser contada na janela seguinte.
+type DynamoDBStorage struct {
+	client   *http.Client
+	endpoint string
+	region   string
+	table    string
+	creds    AWSCredentialsProvider
+	clock    clock.Clock
+
+	// Prefixos pré-calculados com o namespace configurado em KeyPrefix
+	keyPrefix       string
+	blockedPrefix   string
+	bucketPrefix    string
+	firstSeenPrefix string
+	decisionPrefix  string
+}
+
+// NewDynamoDBStorage cria um armazenamento sobre a tabela do DynamoDB informada
+func NewDynamoDBStorage(opts DynamoDBOptions) *DynamoDBStorage {
+	if opts.Region == "" {
+		opts.Region = os.Getenv("AWS_REGION")
+	}
+	if opts.Endpoint == "" {
+		opts.Endpoint = "https://dynamodb." + opts.Region + ".amazonaws.com"
+	}
+	if opts.Credentials == nil {
+		if opts.AccessKeyID != "" || opts.SecretAccessKey != "" {
+			opts.Credentials = StaticAWSCredentials{
+				AccessKeyID:     opts.AccessKeyID,
+				SecretAccessKey: opts.SecretAccessKey,
+				SessionToken:    opts.SessionToken,
+			}
+		} else {
+			opts.Credentials = DefaultAWSCredentials()
+		}
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: DefaultDynamoDBTimeout}
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.New()
+	}
+
+	prefixes := opts.KeyNames.prefixes(opts.KeyPrefix)
+
+	return &DynamoDBStorage{
+		client:          opts.HTTPClient,
+		endpoint:        strings.TrimSuffix(opts.Endpoint, "/") + "/",
+		region:          opts.Region,
+		table:           opts.Table,
+		creds:           opts.Credentials,
+		clock:           opts.Clock,
+		keyPrefix:       opts.KeyPrefix,
+		blockedPrefix:   prefixes.blocked,
+		bucketPrefix:    prefixes.bucket,
+		firstSeenPrefix: prefixes.firstSeen,
+		decisionPrefix:  prefixes.decision,
+	}
+}
+
+// Increment incrementa o contador para uma chave específica e retorna a contagem atual e se
+// ele foi criado por este incremento
+func (d *DynamoDBStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, bool, error) {
+	count, _, isNew, err := d.increment(ctx, d.keyPrefix+key, 1, window, d.clock.Now())
+	if err != nil {
+		return 0, false, fmt.Errorf("falha ao incrementar contador: %w", err)
+	}
+
+	return count, isNew, nil
+}
+
+// IncrementBy soma amount ao contador de uma chave e retorna a contagem atual
+func (d *DynamoDBStorage) IncrementBy(ctx context.Context, key string, amount int64, window time.Duration) (int64, error) {
+	count, _, _, err := d.increment(ctx, d.keyPrefix+key, amount, window, d.clock.Now())
+	if err != nil {
+		return 0, fmt.Errorf("falha ao incrementar contador: %w", err)
+	}
+
+	return count, nil
+}
+
+// IncrementSliding incrementa o contador de uma chave renovando a sua expiração. O contador é
+// incrementado com um UpdateItem condicional à sua validade, ou recriado quando expirou.
+func (d *DynamoDBStorage) IncrementSliding(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	counterKey := d.keyPrefix + key
+	names := map[string]string{"#count": "count", "#expires_at": "expires_at", "#ttl": "ttl"}
+
+	for attempt := 0; attempt < dynamoDBMaxAttempts; attempt++ {
+		now := d.clock.Now()
+		expiry := dynamoItem{}
+		expiry.setExpiry(now.Add(ttl))
+
+		var output struct {
+			Attributes dynamoItem
+		}
+		err := d.call(ctx, "UpdateItem", map[string]any{
+			"TableName":                d.table,
+			"Key":                      dynamoItem{"pk": dynamoString(counterKey)},
+			"UpdateExpression":         "ADD #count :one SET #expires_at = :expires_at, #ttl = :ttl",
+			"ConditionExpression":      "#expires_at > :now",
+			"ExpressionAttributeNames": names,
+			"ExpressionAttributeValues": dynamoItem{
+				":one":        dynamoNumber(1),
+				":now":        dynamoNumber(now.UnixMilli()),
+				":expires_at": expiry["expires_at"],
+				":ttl":        expiry["ttl"],
+			},
+			"ReturnValues": "ALL_NEW",
+		}, &output)
+		if err == nil {
+			return output.Attributes.int64("count"), nil
+		}
+		if !isConditionFailed(err) {
+			return 0, fmt.Errorf("falha ao incrementar contador: %w", err)
+		}
+
+		item := dynamoItem{"pk": dynamoString(counterKey), "count": dynamoNumber(1)}
+		item.setExpiry(now.Add(ttl))
+
+		err = d.call(ctx, "PutItem", map[string]any{
+			"TableName":                 d.table,
+			"Item":                      item,
+			"ConditionExpression":       "attribute_not_exists(pk) OR #expires_at <= :now",
+			"ExpressionAttributeNames":  map[string]string{"#expires_at": "expires_at"},
+			"ExpressionAttributeValues": dynamoItem{":now": dynamoNumber(now.UnixMilli())},
+		}, nil)
+		if err == nil {
+			return 1, nil
+		}
+		if !isConditionFailed(err) {
+			return 0, fmt.Errorf("falha ao incrementar contador: %w", err)
+		}
+
+		// Outra requisição recriou o contador ao mesmo tempo; tenta incrementá-lo
+	}
+
+	return 0, fmt.Errorf("falha ao incrementar contador: %w", errDynamoDBConflict)
+}
+
+// CheckAndIncrement verifica o bloqueio, incrementa o contador e bloqueia a chave ao exceder o
+// limite. O bloqueio é uma escrita condicional, de modo que apenas uma requisição o aplica.
+func (d *DynamoDBStorage) CheckAndIncrement(ctx context.Context, key string, limit Limit) (Decision, error) {
+	now := d.clock.Now()
+
+	blocked, err := d.getItem(ctx, d.blockedPrefix+key, now)
+	if err != nil {
+		return Decision{}, fmt.Errorf("falha ao verificar se a chave está bloqueada: %w", err)
+	}
+	if blocked != nil {
+		return Decision{Blocked: true, TTL: blocked.expireAt().Sub(now)}, nil
+	}
+
+	count, expireAt, _, err := d.increment(ctx, d.keyPrefix+key, 1, limit.Window, now)
+	if err != nil {
+		return Decision{}, fmt.Errorf("falha ao incrementar contador: %w", err)
+	}
+
+	if count <= limit.Requests {
+		return Decision{Allowed: true, Count: count, TTL: expireAt.Sub(now)}, nil
+	}
+
+	if limit.BlockTime <= 0 {
+		return Decision{Count: count, TTL: expireAt.Sub(now)}, nil
+	}
+
+	applied, err := d.block(ctx, key, now, now.Add(limit.BlockTime), true)
+	if err != nil {
+		return Decision{}, fmt.Errorf("falha ao bloquear chave: %w", err)
+	}
+	if applied {
+		return Decision{Count: count, Blocked: true, NewlyBlocked: true, TTL: limit.BlockTime}, nil
+	}
+
+	// Outra requisição aplicou o bloqueio ao mesmo tempo
+	blocked, err = d.getItem(ctx, d.blockedPrefix+key, now)
+	if err != nil {
+		return Decision{}, fmt.Errorf("falha ao verificar se a chave está bloqueada: %w", err)
+	}
+
+	ttl := limit.BlockTime
+	if blocked != nil {
+		ttl = blocked.expireAt().Sub(now)
+	}
+
+	return Decision{Count: count, Blocked: true, TTL: ttl}, nil
+}
+
+// Get lê o contador de uma chave e o tempo restante da sua janela
+func (d *DynamoDBStorage) Get(ctx context.Context, key string) (int64, time.Duration, error) {
+	now := d.clock.Now()
+
+	counter, err := d.getItem(ctx, d.keyPrefix+key, now)
+	if err != nil {
+		return 0, 0, fmt.Errorf("falha ao ler contador: %w", err)
+	}
+	if counter == nil {
+		return 0, 0, nil
+	}
+
+	return counter.int64("count"), counter.expireAt().Sub(now), nil
+}
+
+// IsBlocked verifica se uma chave está atualmente bloqueada e retorna o tempo restante do
+// bloqueio
+func (d *DynamoDBStorage) IsBlocked(ctx context.Context, key string) (bool, time.Duration, error) {
+	now := d.clock.Now()
+
+	blocked, err := d.getItem(ctx, d.blockedPrefix+key, now)
+	if err != nil {
+		return false, 0, fmt.Errorf("falha ao verificar se a chave está bloqueada: %w", err)
+	}
+	if blocked == nil {
+		return false, 0, nil
+	}
+
+	return true, blocked.expireAt().Sub(now), nil
+}
+
+// GetMany lê os contadores e os bloqueios das chaves com BatchGetItem, em lotes de até
+// dynamoDBBatchGetLimit itens (dois por chave)
+func (d *DynamoDBStorage) GetMany(ctx context.Context, keys []string) ([]KeyState, error) {
+	now := d.clock.Now()
+
+	// BatchGetItem rejeita chaves repetidas no mesmo lote
+	seen := make(map[string]struct{}, 2*len(keys))
+	pks := make([]string, 0, 2*len(keys))
+	for _, key := range keys {
+		for _, pk := range []string{d.keyPrefix + key, d.blockedPrefix + key} {
+			if _, ok := seen[pk]; !ok {
+				seen[pk] = struct{}{}
+				pks = append(pks, pk)
+			}
+		}
+	}
+
+	items := make(map[string]dynamoItem, len(pks))
+	for start := 0; start < len(pks); start += dynamoDBBatchGetLimit {
+		end := min(start+dynamoDBBatchGetLimit, len(pks))
+		if err := d.batchGet(ctx, pks[start:end], items); err != nil {
+			return nil, fmt.Errorf("falha ao ler chaves: %w", err)
+		}
+	}
+
+	states := make([]KeyState, len(keys))
+	for i, key := range keys {
+		if counter, ok := items[d.keyPrefix+key]; ok && now.Before(counter.expireAt()) {
+			states[i].Count, states[i].TTL = counter.int64("count"), counter.expireAt().Sub(now)
+		}
+		if blocked, ok := items[d.blockedPrefix+key]; ok && now.Before(blocked.expireAt()) {
+			states[i].Blocked, states[i].BlockTTL = true, blocked.expireAt().Sub(now)
+		}
+	}
+
+	return states, nil
+}
+
+// Block bloqueia uma chave pela duração especificada e zera o seu contador
+func (d *DynamoDBStorage) Block(ctx context.Context, key string, duration time.Duration) error {
+	now := d.clock.Now()
+
+	if _, err := d.block(ctx, key, now, now.Add(duration), false); err != nil {
+		return fmt.Errorf("falha ao bloquear chave: %w", err)
+	}
+
+	return nil
+}
+
+// Reset remove o contador, o bloqueio e o leaky bucket de uma chave, um item por vez
+func (d *DynamoDBStorage) Reset(ctx context.Context, key string) error {
+	for _, pk := range []string{d.keyPrefix + key, d.blockedPrefix + key, d.bucketPrefix + key} {
+		err := d.call(ctx, "DeleteItem", map[string]any{
+			"TableName": d.table,
+			"Key":       dynamoItem{"pk": dynamoString(pk)},
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("falha ao resetar chave: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ListBlocked lista as chaves bloqueadas que correspondem ao padrão. Usa Scan paginado com
+// filtro pelo prefixo de bloqueio; o filtro é aplicado depois da leitura, então cada página
+// consome capacidade de leitura proporcional a toda a tabela percorrida.
+func (d *DynamoDBStorage) ListBlocked(ctx context.Context, pattern string) ([]string, error) {
+	re := globPattern(pattern)
+	now := d.clock.Now()
+
+	filter := "begins_with(pk, :prefix) AND #expires_at > :now"
+	input := map[string]any{
+		"TableName":                 d.table,
+		"FilterExpression":          filter,
+		"ProjectionExpression":      "pk",
+		"ExpressionAttributeNames":  dynamoNames(filter, "expires_at"),
+		"ExpressionAttributeValues": dynamoItem{":prefix": dynamoString(d.blockedPrefix), ":now": dynamoNumber(now.UnixMilli())},
+	}
+
+	seen := make(map[string]struct{})
+	for {
+		var output struct {
+			Items            []dynamoItem
+			LastEvaluatedKey dynamoItem
+		}
+		if err := d.call(ctx, "Scan", input, &output); err != nil {
+			return nil, fmt.Errorf("falha ao listar chaves bloqueadas: %w", err)
+		}
+
+		for _, item := range output.Items {
+			if item["pk"].S == nil {
+				continue
+			}
+			if key := strings.TrimPrefix(*item["pk"].S, d.blockedPrefix); re.MatchString(key) {
+				seen[key] = struct{}{}
+			}
+		}
+
+		if len(output.LastEvaluatedKey) == 0 {
+			break
+		}
+		input["ExclusiveStartKey"] = output.LastEvaluatedKey
+	}
+
+	return sortedKeys(seen), nil
+}
+
+// LeakyBucket adiciona uma requisição ao leaky bucket da chave. O nível é regravado com uma
+// escrita condicional ao instante lido, repetida quando outra requisição o alterou.
+func (d *DynamoDBStorage) LeakyBucket(ctx context.Context, key string, capacity int64, leakInterval time.Duration, now time.Time) (bool, time.Duration, error) {
+	bucketKey := d.bucketPrefix + key
+
+	for attempt := 0; attempt < dynamoDBMaxAttempts; attempt++ {
+		bucket, err := d.getItem(ctx, bucketKey, now)
+		if err != nil {
+			return false, 0, fmt.Errorf("falha ao ler leaky bucket: %w", err)
+		}
+
+		// Escoa as requisições correspondentes ao tempo decorrido desde a última atualização
+		level := 0.0
+		condition := "attribute_not_exists(pk) OR #expires_at <= :now"
+		values := dynamoItem{":now": dynamoNumber(now.UnixMilli())}
+		if bucket != nil {
+			elapsed := math.Max(0, float64(now.UnixMicro()-bucket.int64("ts")))
+			level = math.Max(0, bucket.float64("level")-elapsed/float64(leakInterval.Microseconds()))
+			condition = "#ts = :ts"
+			values = dynamoItem{":ts": bucket["ts"]}
+		}
+
+		if level+1 > float64(capacity) {
+			wait := math.Ceil((level + 1 - float64(capacity)) * float64(leakInterval))
+			return false, time.Duration(wait), nil
+		}
+
+		level++
+		item := dynamoItem{
+			"pk":    dynamoString(bucketKey),
+			"level": dynamoFloat(level),
+			"ts":    dynamoNumber(now.UnixMicro()),
+		}
+		item.setExpiry(now.Add(time.Duration(math.Ceil(level * float64(leakInterval)))))
+
+		err = d.call(ctx, "PutItem", map[string]any{
+			"TableName":                 d.table,
+			"Item":                      item,
+			"ConditionExpression":       condition,
+			"ExpressionAttributeNames":  dynamoNames(condition, "expires_at", "ts"),
+			"ExpressionAttributeValues": values,
+		}, nil)
+		if err == nil {
+			return true, 0, nil
+		}
+		if !isConditionFailed(err) {
+			return false, 0, fmt.Errorf("falha ao gravar leaky bucket: %w", err)
+		}
+	}
+
+	return false, 0, fmt.Errorf("falha ao gravar leaky bucket: %w", errDynamoDBConflict)
+}
+
+// FirstSeen registra o primeiro contato da chave e retorna o instante registrado
+func (d *DynamoDBStorage) FirstSeen(ctx context.Context, key string, now time.Time, ttl time.Duration) (time.Time, error) {
+	firstSeenKey := d.firstSeenPrefix + key
+	expiry := dynamoItem{}
+	expiry.setExpiry(now.Add(ttl))
+
+	// Registra o instante apenas se ainda não houver registro válido e renova a expiração
+	var output struct {
+		Attributes dynamoItem
+	}
+	err := d.call(ctx, "UpdateItem", map[string]any{
+		"TableName":           d.table,
+		"Key":                 dynamoItem{"pk": dynamoString(firstSeenKey)},
+		"UpdateExpression":    "SET #at = if_not_exists(#at, :at), #expires_at = :expires_at, #ttl = :ttl",
+		"ConditionExpression": "attribute_not_exists(pk) OR #expires_at > :now",
+		"ExpressionAttributeNames": map[string]string{
+			"#at": "at", "#expires_at": "expires_at", "#ttl": "ttl",
+		},
+		"ExpressionAttributeValues": dynamoItem{
+			":at":         dynamoNumber(now.UnixNano()),
+			":now":        dynamoNumber(now.UnixMilli()),
+			":expires_at": expiry["expires_at"],
+			":ttl":        expiry["ttl"],
+		},
+		"ReturnValues": "ALL_NEW",
+	}, &output)
+	if err == nil {
+		return time.Unix(0, output.Attributes.int64("at")), nil
+	}
+	if !isConditionFailed(err) {
+		return time.Time{}, fmt.Errorf("falha ao registrar primeiro contato: %w", err)
+	}
+
+	// O registro anterior expirou e ainda não foi removido pelo TTL
+	item := dynamoItem{"pk": dynamoString(firstSeenKey), "at": dynamoNumber(now.UnixNano())}
+	item.setExpiry(now.Add(ttl))
+	if err := d.put(ctx, item); err != nil {
+		return time.Time{}, fmt.Errorf("falha ao registrar primeiro contato: %w", err)
+	}
+
+	return now, nil
+}
+
+// GetDecision obtém a decisão registrada para uma chave de idempotência, se existir
+func (d *DynamoDBStorage) GetDecision(ctx context.Context, key string) (bool, bool, error) {
+	decision, err := d.getItem(ctx, d.decisionPrefix+key, d.clock.Now())
+	if err != nil {
+		return false, false, fmt.Errorf("falha ao obter decisão: %w", err)
+	}
+	if decision == nil {
+		return false, false, nil
+	}
+
+	return decision.bool("allowed"), true, nil
+}
+
+// SetDecision registra a decisão para uma chave de idempotência pela duração especificada
+func (d *DynamoDBStorage) SetDecision(ctx context.Context, key string, allowed bool, ttl time.Duration) error {
+	item := dynamoItem{"pk": dynamoString(d.decisionPrefix + key), "allowed": dynamoBool(allowed)}
+	item.setExpiry(d.clock.Now().Add(ttl))
+
+	if err := d.put(ctx, item); err != nil {
+		return fmt.Errorf("falha ao registrar decisão: %w", err)
+	}
+
+	return nil
+}
+
+// Acquire ocupa uma vaga de concorrência da chave. O contador de vagas é incrementado com um
+// UpdateItem condicional à existência de vaga, ou recriado quando expirou.
+func (d *DynamoDBStorage) Acquire(ctx context.Context, key string, limit int64, ttl time.Duration) (bool, error) {
+	if limit <= 0 {
+		return false, nil
+	}
+
+	slotsKey := d.keyPrefix + key
+	names := map[string]string{"#count": "count", "#expires_at": "expires_at", "#ttl": "ttl"}
+
+	for attempt := 0; attempt < dynamoDBMaxAttempts; attempt++ {
+		now := d.clock.Now()
+		expiry := dynamoItem{}
+		expiry.setExpiry(now.Add(ttl))
+
+		err := d.call(ctx, "UpdateItem", map[string]any{
+			"TableName":                d.table,
+			"Key":                      dynamoItem{"pk": dynamoString(slotsKey)},
+			"UpdateExpression":         "ADD #count :one SET #expires_at = :expires_at, #ttl = :ttl",
+			"ConditionExpression":      "#count < :limit AND #expires_at > :now",
+			"ExpressionAttributeNames": names,
+			"ExpressionAttributeValues": dynamoItem{
+				":one":        dynamoNumber(1),
+				":limit":      dynamoNumber(limit),
+				":now":        dynamoNumber(now.UnixMilli()),
+				":expires_at": expiry["expires_at"],
+				":ttl":        expiry["ttl"],
+			},
+		}, nil)
+		if err == nil {
+			return true, nil
+		}
+		if !isConditionFailed(err) {
+			return false, fmt.Errorf("falha ao ocupar vaga de concorrência: %w", err)
+		}
+
+		// Sem vaga livre ou sem contador válido: cria o contador se ele não existe ou expirou
+		item := dynamoItem{"pk": dynamoString(slotsKey), "count": dynamoNumber(1)}
+		item.setExpiry(now.Add(ttl))
+
+		err = d.call(ctx, "PutItem", map[string]any{
+			"TableName":                 d.table,
+			"Item":                      item,
+			"ConditionExpression":       "attribute_not_exists(pk) OR #expires_at <= :now",
+			"ExpressionAttributeNames":  map[string]string{"#expires_at": "expires_at"},
+			"ExpressionAttributeValues": dynamoItem{":now": dynamoNumber(now.UnixMilli())},
+		}, nil)
+		if err == nil {
+			return true, nil
+		}
+		if !isConditionFailed(err) {
+			return false, fmt.Errorf("falha ao ocupar vaga de concorrência: %w", err)
+		}
+
+		// O contador existe e é válido; se está cheio, não há vaga
+		slots, err := d.getItem(ctx, slotsKey, now)
+		if err != nil {
+			return false, fmt.Errorf("falha ao ocupar vaga de concorrência: %w", err)
+		}
+		if slots != nil && slots.int64("count") >= limit {
+			return false, nil
+		}
+	}
+
+	return false, fmt.Errorf("falha ao ocupar vaga de concorrência: %w", errDynamoDBConflict)
+}
+
+// Release libera uma vaga de concorrência da chave sem deixar o contador negativo
+func (d *DynamoDBStorage) Release(ctx context.Context, key string) error {
+	err := d.call(ctx, "UpdateItem", map[string]any{
+		"TableName":                 d.table,
+		"Key":                       dynamoItem{"pk": dynamoString(d.keyPrefix + key)},
+		"UpdateExpression":          "ADD #count :minus_one",
+		"ConditionExpression":       "#count > :zero",
+		"ExpressionAttributeNames":  map[string]string{"#count": "count"},
+		"ExpressionAttributeValues": dynamoItem{":minus_one": dynamoNumber(-1), ":zero": dynamoNumber(0)},
+	}, nil)
+	if err != nil && !isConditionFailed(err) {
+		return fmt.Errorf("falha ao liberar vaga de concorrência: %w", err)
+	}
+
+	return nil
+}
+
+// Healthy verifica se a tabela está acessível
+func (d *DynamoDBStorage) Healthy(ctx context.Context) error {
+	if err := d.call(ctx, "DescribeTable", map[string]any{"TableName": d.table}, nil); err != nil {
+		return fmt.Errorf("falha ao conectar ao DynamoDB: %w", err)
+	}
+
+	return nil
+}
+
+// Close libera as conexões ociosas do cliente HTTP
+func (d *DynamoDBStorage) Close() error {
+	d.client.CloseIdleConnections()
+	return nil
+}
+
+// increment soma amount ao contador da janela ativa ou, se ela não existe ou expirou, inicia uma
+// nova janela. Retorna a contagem, o fim da janela e se ela foi iniciada pelo incremento.
+func (d *DynamoDBStorage) increment(ctx context.Context, key string, amount int64, window time.Duration, now time.Time) (int64, time.Time, bool, error) {
+	names := map[string]string{"#count": "count", "#expires_at": "expires_at"}
+
+	for attempt := 0; attempt < dynamoDBMaxAttempts; attempt++ {
+		var output struct {
+			Attributes dynamoItem
+		}
+		err := d.call(ctx, "UpdateItem", map[string]any{
+			"TableName":                 d.table,
+			"Key":                       dynamoItem{"pk": dynamoString(key)},
+			"UpdateExpression":          "ADD #count :amount",
+			"ConditionExpression":       "#expires_at > :now",
+			"ExpressionAttributeNames":  names,
+			"ExpressionAttributeValues": dynamoItem{":amount": dynamoNumber(amount), ":now": dynamoNumber(now.UnixMilli())},
+			"ReturnValues":              "ALL_NEW",
+		}, &output)
+		if err == nil {
+			return output.Attributes.int64("count"), output.Attributes.expireAt(), false, nil
+		}
+		if !isConditionFailed(err) {
+			return 0, time.Time{}, false, err
+		}
+
+		expireAt := now.Add(window)
+		item := dynamoItem{"pk": dynamoString(key), "count": dynamoNumber(amount)}
+		item.setExpiry(expireAt)
+
+		err = d.call(ctx, "PutItem", map[string]any{
+			"TableName":                 d.table,
+			"Item":                      item,
+			"ConditionExpression":       "attribute_not_exists(pk) OR #expires_at <= :now",
+			"ExpressionAttributeNames":  map[string]string{"#expires_at": "expires_at"},
+			"ExpressionAttributeValues": dynamoItem{":now": dynamoNumber(now.UnixMilli())},
+		}, nil)
+		if err == nil {
+			return amount, time.UnixMilli(item.int64("expires_at")), true, nil
+		}
+		if !isConditionFailed(err) {
+			return 0, time.Time{}, false, err
+		}
+
+		// Outra requisição iniciou a janela ao mesmo tempo; tenta incrementá-la
+	}
+
+	return 0, time.Time{}, false, errDynamoDBConflict
+}
+
+// block grava o item de bloqueio até blockedUntil e remove o contador da chave. Com onlyIfFree,
+// a gravação só ocorre se a chave não estiver bloqueada, e o retorno indica se ela ocorreu.
+func (d *DynamoDBStorage) block(ctx context.Context, key string, now, blockedUntil time.Time, onlyIfFree bool) (bool, error) {
+	item := dynamoItem{"pk": dynamoString(d.blockedPrefix + key)}
+	item.setExpiry(blockedUntil)
+
+	input := map[string]any{"TableName": d.table, "Item": item}
+	if onlyIfFree {
+		input["ConditionExpression"] = "attribute_not_exists(pk) OR #expires_at <= :now"
+		input["ExpressionAttributeNames"] = map[string]string{"#expires_at": "expires_at"}
+		input["ExpressionAttributeValues"] = dynamoItem{":now": dynamoNumber(now.UnixMilli())}
+	}
+
+	err := d.call(ctx, "PutItem", input, nil)
+	if onlyIfFree && isConditionFailed(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = d.call(ctx, "DeleteItem", map[string]any{
+		"TableName": d.table,
+		"Key":       dynamoItem{"pk": dynamoString(d.keyPrefix + key)},
+	}, nil)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// getItem lê um item com leitura consistente, retornando nil se ele não existe ou expirou
+func (d *DynamoDBStorage) getItem(ctx context.Context, key string, now time.Time) (dynamoItem, error) {
+	var output struct {
+		Item dynamoItem
+	}
+	err := d.call(ctx, "GetItem", map[string]any{
+		"TableName":      d.table,
+		"Key":            dynamoItem{"pk": dynamoString(key)},
+		"ConsistentRead": true,
+	}, &output)
+	if err != nil {
+		return nil, err
+	}
+
+	if output.Item == nil || !now.Before(output.Item.expireAt()) {
+		return nil, nil
+	}
+
+	return output.Item, nil
+}
+
+// batchGet lê os itens com BatchGetItem e leitura consistente, guardando-os em items pela
+// chave. Os itens não processados pelo DynamoDB (ex: por limite de capacidade) são lidos
+// novamente, até dynamoDBMaxAttempts requisições.
+func (d *DynamoDBStorage) batchGet(ctx context.Context, pks []string, items map[string]dynamoItem) error {
+	keys := make([]dynamoItem, len(pks))
+	for i, pk := range pks {
+		keys[i] = dynamoItem{"pk": dynamoString(pk)}
+	}
+
+	for attempt := 0; attempt < dynamoDBMaxAttempts && len(keys) > 0; attempt++ {
+		var output struct {
+			Responses       map[string][]dynamoItem
+			UnprocessedKeys map[string]struct {
+				Keys []dynamoItem
+			}
+		}
+		err := d.call(ctx, "BatchGetItem", map[string]any{
+			"RequestItems": map[string]any{
+				d.table: map[string]any{"Keys": keys, "ConsistentRead": true},
+			},
+		}, &output)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range output.Responses[d.table] {
+			if pk := item["pk"].S; pk != nil {
+				items[*pk] = item
+			}
+		}
+		keys = output.UnprocessedKeys[d.table].Keys
+	}
+
+	if len(keys) > 0 {
+		return errDynamoDBUnprocessed
+	}
+	return nil
+}
+
+// put grava um item sem condições
+func (d *DynamoDBStorage) put(ctx context.Context, item dynamoItem) error {
+	return d.call(ctx, "PutItem", map[string]any{"TableName": d.table, "Item": item}, nil)
+}
+
+// call executa uma operação da API do DynamoDB, decodificando a resposta em output (quando não
+// nil). Respostas de erro são retornadas como *DynamoDBError.
+func (d *DynamoDBStorage) call(ctx context.Context, operation string, input, output any) error {
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	req.Header.Set("X-Amz-Target", "DynamoDB_20120810."+operation)
+
+	creds, err := d.creds.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("falha ao obter credenciais da AWS: %w", err)
+	}
+
+	// A assinatura usa o relógio do sistema, independente do Clock das janelas
+	signRequest(req, payload, creds, d.region, "dynamodb", time.Now())
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(body, &apiErr)
+
+		// O tipo é qualificado pelo namespace (ex: "com.amazonaws.dynamodb.v20120810#...")
+		errType := apiErr.Type
+		if i := strings.LastIndexByte(errType, '#'); i >= 0 {
+			errType = errType[i+1:]
+		}
+
+		return &DynamoDBError{StatusCode: resp.StatusCode, Type: errType, Message: apiErr.Message}
+	}
+
+	if output == nil {
+		return nil
+	}
+
+	return json.Unmarshal(body, output)
+}
+
+// isConditionFailed indica se o erro é a falha da condição de uma escrita condicional
+func isConditionFailed(err error) bool {
+	var apiErr *DynamoDBError
+	return errors.As(err, &apiErr) && apiErr.Type == "ConditionalCheckFailedException"
+}
+
+// dynamoNames retorna os nomes de atributos (#nome) usados na expressão
+func dynamoNames(expression string, attributes ...string) map[string]string {
+	names := make(map[string]string)
+	for _, attribute := range attributes {
+		if strings.Contains(expression, "#"+attribute) {
+			names["#"+attribute] = attribute
+		}
+	}
+	return names
+}
+
+// dynamoValue é um valor de atributo no formato JSON da API do DynamoDB
+type dynamoValue struct {
+	S    *string `json:"S,omitempty"`
+	N    *string `json:"N,omitempty"`
+	BOOL *bool   `json:"BOOL,omitempty"`
+}
+
+func dynamoString(value string) dynamoValue {
+	return dynamoValue{S: &value}
+}
+
+func dynamoNumber(value int64) dynamoValue {
+	n := strconv.FormatInt(value, 10)
+	return dynamoValue{N: &n}
+}
+
+func dynamoFloat(value float64) dynamoValue {
+	n := strconv.FormatFloat(value, 'f', -1, 64)
+	return dynamoValue{N: &n}
+}
+
+func dynamoBool(value bool) dynamoValue {
+	return dynamoValue{BOOL: &value}
+}
+
+// dynamoItem é um item (ou conjunto de valores de expressão) do DynamoDB
+type dynamoItem map[string]dynamoValue
+
+// setExpiry define a expiração do item: em milissegundos, verificada nas leituras, e em
+// segundos, para o TTL da tabela
+func (i dynamoItem) setExpiry(expireAt time.Time) {
+	i["expires_at"] = dynamoNumber(expireAt.UnixMilli())
+	i["ttl"] = dynamoNumber(int64(math.Ceil(float64(expireAt.UnixMilli()) / 1000)))
+}
+
+func (i dynamoItem) expireAt() time.Time {
+	return time.UnixMilli(i.int64("expires_at"))
+}
+
+func (i dynamoItem) int64(name string) int64 {
+	if value := i[name].N; value != nil {
+		n, _ := strconv.ParseInt(*value, 10, 64)
+		return n
+	}
+	return 0
+}
+
+func (i dynamoItem) float64(name string) float64 {
+	if value := i[name].N; value != nil {
+		n, _ := strconv.ParseFloat(*value, 64)
+		return n
+	}
+	return 0
+}
+
+func (i dynamoItem) bool(name string) bool {
+	if value := i[name].BOOL; value != nil {
+		return *value
+	}
+	return false
+}
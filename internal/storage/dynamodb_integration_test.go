@@ -0,0 +1,160 @@
+//go:build integration
+
+package storage
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Testes contra o dynamodb-local, executados com:
+//
+//	docker run -p 8000:8000 amazon/dynamodb-local
+//	DYNAMODB_ENDPOINT=http://localhost:8000 go test -race -tags integration ./internal/storage/
+
+// newDynamoDBIntegrationStorage cria uma tabela exclusiva do teste no DynamoDB de
+// DYNAMODB_ENDPOINT, pulando o teste quando o servidor não está acessível
+func newDynamoDBIntegrationStorage(t *testing.T) *DynamoDBStorage {
+	t.Helper()
+
+	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "http://localhost:8000"
+	}
+
+	table := "it_" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	storage := NewDynamoDBStorage(DynamoDBOptions{
+		Table:           table,
+		Region:          "us-east-1",
+		Endpoint:        endpoint,
+		AccessKeyID:     "local",
+		SecretAccessKey: "local",
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	err := storage.call(ctx, "CreateTable", map[string]any{
+		"TableName":            table,
+		"BillingMode":          "PAY_PER_REQUEST",
+		"AttributeDefinitions": []map[string]string{{"AttributeName": "pk", "AttributeType": "S"}},
+		"KeySchema":            []map[string]string{{"AttributeName": "pk", "KeyType": "HASH"}},
+	}, nil)
+	if err != nil {
+		t.Skipf("DynamoDB indisponível em %s: %v", endpoint, err)
+	}
+
+	t.Cleanup(func() {
+		storage.call(context.Background(), "DeleteTable", map[string]any{"TableName": table}, nil)
+		storage.Close()
+	})
+
+	return storage
+}
+
+func TestDynamoDBStorage_Integration_ConcurrentIncrement(t *testing.T) {
+	storage := newDynamoDBIntegrationStorage(t)
+	ctx := context.Background()
+
+	const requests = 50
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		counts = make(map[int64]bool)
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			count, _, err := storage.Increment(ctx, "ip:concurrent", time.Minute)
+			require.NoError(t, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			counts[count] = true
+		}()
+	}
+	wg.Wait()
+
+	// Cada incremento recebe uma contagem distinta, de 1 a requests
+	assert.Len(t, counts, requests)
+	for i := int64(1); i <= requests; i++ {
+		assert.True(t, counts[i], "contagem %d ausente", i)
+	}
+}
+
+func TestDynamoDBStorage_Integration_ConcurrentCheckAndIncrement(t *testing.T) {
+	storage := newDynamoDBIntegrationStorage(t)
+	ctx := context.Background()
+
+	limit := Limit{Requests: 10, Window: time.Minute, BlockTime: time.Minute}
+
+	var (
+		wg           sync.WaitGroup
+		allowed      atomic.Int64
+		newlyBlocked atomic.Int64
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			decision, err := storage.CheckAndIncrement(ctx, "ip:limit", limit)
+			require.NoError(t, err)
+
+			if decision.Allowed {
+				allowed.Add(1)
+			}
+			if decision.NewlyBlocked {
+				newlyBlocked.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, allowed.Load(), limit.Requests+1)
+	assert.GreaterOrEqual(t, allowed.Load(), limit.Requests)
+	assert.Equal(t, int64(1), newlyBlocked.Load())
+
+	blocked, _, err := storage.IsBlocked(ctx, "ip:limit")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+}
+
+func TestDynamoDBStorage_Integration_AuxiliaryRecords(t *testing.T) {
+	storage := newDynamoDBIntegrationStorage(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	firstSeen, err := storage.FirstSeen(ctx, "ip:1", now, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, now.UnixNano(), firstSeen.UnixNano())
+
+	firstSeen, err = storage.FirstSeen(ctx, "ip:1", now.Add(time.Second), time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, now.UnixNano(), firstSeen.UnixNano())
+
+	require.NoError(t, storage.SetDecision(ctx, "req-1", true, time.Minute))
+	allowed, found, err := storage.GetDecision(ctx, "req-1")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.True(t, allowed)
+
+	for i := 0; i < 2; i++ {
+		allowed, _, err := storage.LeakyBucket(ctx, "ip:1", 2, time.Second, now)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+	allowed, wait, err := storage.LeakyBucket(ctx, "ip:1", 2, time.Second, now)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Second, wait)
+}
@@ -0,0 +1,406 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ Storage = (*DynamoDBStorage)(nil)
+
+// dynamoResponse é uma resposta roteirizada do fakeDynamoDB
+type dynamoResponse struct {
+	status int
+	body   string
+}
+
+// dynamoRequest é uma requisição recebida pelo fakeDynamoDB
+type dynamoRequest struct {
+	operation string
+	header    http.Header
+	input     map[string]any
+}
+
+// fakeDynamoDB responde às operações com as respostas roteirizadas, na ordem, e registra as
+// requisições recebidas
+type fakeDynamoDB struct {
+	mu        sync.Mutex
+	responses []dynamoResponse
+	requests  []dynamoRequest
+}
+
+func (f *fakeDynamoDB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	request := dynamoRequest{
+		operation: strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "DynamoDB_20120810."),
+		header:    r.Header.Clone(),
+	}
+	json.Unmarshal(body, &request.input)
+	f.requests = append(f.requests, request)
+
+	response := dynamoResponse{status: http.StatusOK, body: "{}"}
+	if len(f.responses) > 0 {
+		response = f.responses[0]
+		f.responses = f.responses[1:]
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.WriteHeader(response.status)
+	io.WriteString(w, response.body)
+}
+
+func (f *fakeDynamoDB) operations() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	operations := make([]string, len(f.requests))
+	for i, request := range f.requests {
+		operations[i] = request.operation
+	}
+	return operations
+}
+
+const conditionFailedResponse = `{"__type":"com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException","message":"The conditional request failed"}`
+
+func newTestDynamoDBStorage(t *testing.T, responses ...dynamoResponse) (*DynamoDBStorage, *fakeDynamoDB, *clock.FakeClock) {
+	fake := &fakeDynamoDB{responses: responses}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	fakeClock := clock.NewFakeClock(time.UnixMilli(1_700_000_000_000))
+	s := NewDynamoDBStorage(DynamoDBOptions{
+		Table:           "rate_limits",
+		Region:          "us-east-1",
+		Endpoint:        server.URL,
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+		KeyPrefix:       "app:",
+		Clock:           fakeClock,
+	})
+	t.Cleanup(func() { s.Close() })
+
+	return s, fake, fakeClock
+}
+
+func TestDynamoDBStorage_SignedRequest(t *testing.T) {
+	s, fake, _ := newTestDynamoDBStorage(t)
+
+	require.NoError(t, s.Healthy(context.Background()))
+
+	require.Len(t, fake.requests, 1)
+	request := fake.requests[0]
+	assert.Equal(t, "DescribeTable", request.operation)
+	assert.Equal(t, "application/x-amz-json-1.0", request.header.Get("Content-Type"))
+	assert.Contains(t, request.header.Get("Authorization"), "Credential=AKIDEXAMPLE/")
+	assert.Contains(t, request.header.Get("Authorization"), "/us-east-1/dynamodb/aws4_request")
+	assert.Equal(t, "rate_limits", request.input["TableName"])
+}
+
+func TestDynamoDBStorage_CredentialsRetrievedPerRequest(t *testing.T) {
+	fake := &fakeDynamoDB{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	accessKeyID := "ASIAFIRST"
+	s := NewDynamoDBStorage(DynamoDBOptions{
+		Table:    "rate_limits",
+		Region:   "us-east-1",
+		Endpoint: server.URL,
+		Credentials: AWSCredentialsProviderFunc(func(ctx context.Context) (AWSCredentials, error) {
+			return AWSCredentials{AccessKeyID: accessKeyID, SecretAccessKey: "secret", SessionToken: accessKeyID + "-token"}, nil
+		}),
+	})
+	defer s.Close()
+
+	require.NoError(t, s.Healthy(context.Background()))
+
+	// Credenciais renovadas pelo provedor valem a partir da requisição seguinte
+	accessKeyID = "ASIASECOND"
+	require.NoError(t, s.Healthy(context.Background()))
+
+	require.Len(t, fake.requests, 2)
+	assert.Contains(t, fake.requests[0].header.Get("Authorization"), "Credential=ASIAFIRST/")
+	assert.Equal(t, "ASIAFIRST-token", fake.requests[0].header.Get("X-Amz-Security-Token"))
+	assert.Contains(t, fake.requests[1].header.Get("Authorization"), "Credential=ASIASECOND/")
+	assert.Equal(t, "ASIASECOND-token", fake.requests[1].header.Get("X-Amz-Security-Token"))
+}
+
+func TestDynamoDBStorage_CredentialsError(t *testing.T) {
+	fake := &fakeDynamoDB{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	s := NewDynamoDBStorage(DynamoDBOptions{
+		Table:    "rate_limits",
+		Region:   "us-east-1",
+		Endpoint: server.URL,
+		Credentials: AWSCredentialsProviderFunc(func(ctx context.Context) (AWSCredentials, error) {
+			return AWSCredentials{}, ErrAWSCredentialsNotFound
+		}),
+	})
+	defer s.Close()
+
+	err := s.Healthy(context.Background())
+	assert.ErrorIs(t, err, ErrAWSCredentialsNotFound)
+	assert.Empty(t, fake.requests)
+}
+
+func TestDynamoDBStorage_IncrementExistingWindow(t *testing.T) {
+	s, fake, _ := newTestDynamoDBStorage(t, dynamoResponse{
+		status: http.StatusOK,
+		body:   `{"Attributes":{"pk":{"S":"app:ip:1"},"count":{"N":"3"},"expires_at":{"N":"1700000001000"}}}`,
+	})
+
+	count, isNew, err := s.Increment(context.Background(), "ip:1", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	assert.False(t, isNew)
+
+	assert.Equal(t, []string{"UpdateItem"}, fake.operations())
+	input := fake.requests[0].input
+	assert.Equal(t, "ADD #count :amount", input["UpdateExpression"])
+	assert.Equal(t, map[string]any{"pk": map[string]any{"S": "app:ip:1"}}, input["Key"])
+}
+
+func TestDynamoDBStorage_IncrementStartsWindow(t *testing.T) {
+	s, fake, _ := newTestDynamoDBStorage(t,
+		dynamoResponse{status: http.StatusBadRequest, body: conditionFailedResponse},
+		dynamoResponse{status: http.StatusOK, body: `{}`},
+	)
+
+	count, isNew, err := s.Increment(context.Background(), "ip:1", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.True(t, isNew)
+
+	assert.Equal(t, []string{"UpdateItem", "PutItem"}, fake.operations())
+	item := fake.requests[1].input["Item"].(map[string]any)
+	assert.Equal(t, map[string]any{"N": "1"}, item["count"])
+	assert.Equal(t, map[string]any{"N": "1700000001000"}, item["expires_at"])
+	assert.Equal(t, map[string]any{"N": "1700000001"}, item["ttl"])
+}
+
+func TestDynamoDBStorage_IncrementConcurrentWindowStart(t *testing.T) {
+	// A janela é iniciada por outra requisição entre o UpdateItem e o PutItem
+	s, fake, _ := newTestDynamoDBStorage(t,
+		dynamoResponse{status: http.StatusBadRequest, body: conditionFailedResponse},
+		dynamoResponse{status: http.StatusBadRequest, body: conditionFailedResponse},
+		dynamoResponse{status: http.StatusOK, body: `{"Attributes":{"count":{"N":"2"},"expires_at":{"N":"1700000001000"}}}`},
+	)
+
+	count, isNew, err := s.Increment(context.Background(), "ip:1", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	assert.False(t, isNew)
+	assert.Equal(t, []string{"UpdateItem", "PutItem", "UpdateItem"}, fake.operations())
+}
+
+func TestDynamoDBStorage_APIError(t *testing.T) {
+	s, _, _ := newTestDynamoDBStorage(t, dynamoResponse{
+		status: http.StatusBadRequest,
+		body:   `{"__type":"com.amazonaws.dynamodb.v20120810#ResourceNotFoundException","message":"Requested resource not found"}`,
+	})
+
+	_, _, err := s.Increment(context.Background(), "ip:1", time.Second)
+	require.Error(t, err)
+
+	var apiErr *DynamoDBError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "ResourceNotFoundException", apiErr.Type)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.False(t, isConditionFailed(err))
+}
+
+func TestDynamoDBStorage_CheckAndIncrementBlocks(t *testing.T) {
+	s, fake, _ := newTestDynamoDBStorage(t,
+		dynamoResponse{status: http.StatusOK, body: `{}`},
+		dynamoResponse{status: http.StatusOK, body: `{"Attributes":{"count":{"N":"3"},"expires_at":{"N":"1700000001000"}}}`},
+		dynamoResponse{status: http.StatusOK, body: `{}`},
+		dynamoResponse{status: http.StatusOK, body: `{}`},
+	)
+
+	decision, err := s.CheckAndIncrement(context.Background(), "ip:1", Limit{Requests: 2, Window: time.Second, BlockTime: time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, Decision{Count: 3, Blocked: true, NewlyBlocked: true, TTL: time.Minute}, decision)
+
+	assert.Equal(t, []string{"GetItem", "UpdateItem", "PutItem", "DeleteItem"}, fake.operations())
+	assert.Equal(t, map[string]any{"pk": map[string]any{"S": "app:blocked:ip:1"}}, fake.requests[0].input["Key"])
+	assert.Equal(t, map[string]any{"pk": map[string]any{"S": "app:ip:1"}}, fake.requests[3].input["Key"])
+}
+
+func TestDynamoDBStorage_CheckAndIncrementAlreadyBlocked(t *testing.T) {
+	s, fake, _ := newTestDynamoDBStorage(t, dynamoResponse{
+		status: http.StatusOK,
+		body:   `{"Item":{"pk":{"S":"app:blocked:ip:1"},"expires_at":{"N":"1700000030000"}}}`,
+	})
+
+	decision, err := s.CheckAndIncrement(context.Background(), "ip:1", Limit{Requests: 2, Window: time.Second, BlockTime: time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, Decision{Blocked: true, TTL: 30 * time.Second}, decision)
+	assert.Equal(t, []string{"GetItem"}, fake.operations())
+}
+
+func TestDynamoDBStorage_GetIgnoresExpiredItem(t *testing.T) {
+	// Itens expirados podem continuar na tabela até serem removidos pelo TTL
+	s, _, fakeClock := newTestDynamoDBStorage(t,
+		dynamoResponse{status: http.StatusOK, body: `{"Item":{"count":{"N":"5"},"expires_at":{"N":"1700000001000"}}}`},
+		dynamoResponse{status: http.StatusOK, body: `{"Item":{"count":{"N":"5"},"expires_at":{"N":"1700000001000"}}}`},
+	)
+
+	count, ttl, err := s.Get(context.Background(), "ip:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), count)
+	assert.Equal(t, time.Second, ttl)
+
+	fakeClock.Advance(time.Second)
+	count, ttl, err = s.Get(context.Background(), "ip:1")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	assert.Zero(t, ttl)
+}
+
+func TestDynamoDBStorage_IncrementSlidingRenewsExpiry(t *testing.T) {
+	s, fake, _ := newTestDynamoDBStorage(t, dynamoResponse{
+		status: http.StatusOK,
+		body:   `{"Attributes":{"pk":{"S":"app:ip:1:offenses"},"count":{"N":"2"},"expires_at":{"N":"1700000060000"}}}`,
+	})
+
+	count, err := s.IncrementSliding(context.Background(), "ip:1:offenses", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	assert.Equal(t, []string{"UpdateItem"}, fake.operations())
+	input := fake.requests[0].input
+	assert.Equal(t, "ADD #count :one SET #expires_at = :expires_at, #ttl = :ttl", input["UpdateExpression"])
+	values := input["ExpressionAttributeValues"].(map[string]any)
+	assert.Equal(t, map[string]any{"N": "1700000060000"}, values[":expires_at"])
+}
+
+func TestDynamoDBStorage_IncrementSlidingStartsCounter(t *testing.T) {
+	s, fake, _ := newTestDynamoDBStorage(t,
+		dynamoResponse{status: http.StatusBadRequest, body: conditionFailedResponse},
+		dynamoResponse{status: http.StatusOK, body: `{}`},
+	)
+
+	count, err := s.IncrementSliding(context.Background(), "ip:1:offenses", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	assert.Equal(t, []string{"UpdateItem", "PutItem"}, fake.operations())
+	item := fake.requests[1].input["Item"].(map[string]any)
+	assert.Equal(t, map[string]any{"N": "1700000060000"}, item["expires_at"])
+}
+
+func TestDynamoDBStorage_Reset(t *testing.T) {
+	s, fake, _ := newTestDynamoDBStorage(t,
+		dynamoResponse{status: http.StatusOK, body: `{}`},
+		dynamoResponse{status: http.StatusOK, body: `{}`},
+		dynamoResponse{status: http.StatusOK, body: `{}`},
+	)
+
+	require.NoError(t, s.Reset(context.Background(), "token:abc"))
+
+	assert.Equal(t, []string{"DeleteItem", "DeleteItem", "DeleteItem"}, fake.operations())
+	var keys []any
+	for _, request := range fake.requests {
+		keys = append(keys, request.input["Key"].(map[string]any)["pk"])
+	}
+	assert.Equal(t, []any{
+		map[string]any{"S": "app:token:abc"},
+		map[string]any{"S": "app:blocked:token:abc"},
+		map[string]any{"S": "app:bucket:token:abc"},
+	}, keys)
+}
+
+func TestDynamoDBStorage_GetMany(t *testing.T) {
+	s, fake, _ := newTestDynamoDBStorage(t,
+		dynamoResponse{status: http.StatusOK, body: `{
+			"Responses": {"rate_limits": [
+				{"pk": {"S": "app:ip:1"}, "count": {"N": "3"}, "expires_at": {"N": "1700000001000"}},
+				{"pk": {"S": "app:blocked:ip:1"}, "expires_at": {"N": "1700000060000"}},
+				{"pk": {"S": "app:ip:2"}, "count": {"N": "9"}, "expires_at": {"N": "1699999999000"}}
+			]},
+			"UnprocessedKeys": {"rate_limits": {"Keys": [{"pk": {"S": "app:token:abc"}}], "ConsistentRead": true}}
+		}`},
+		dynamoResponse{status: http.StatusOK, body: `{
+			"Responses": {"rate_limits": [
+				{"pk": {"S": "app:token:abc"}, "count": {"N": "7"}, "expires_at": {"N": "1700000000500"}}
+			]}
+		}`},
+	)
+
+	// Chaves repetidas são lidas uma vez; itens expirados são ignorados
+	states, err := s.GetMany(context.Background(), []string{"ip:1", "token:abc", "ip:2", "ip:1"})
+	require.NoError(t, err)
+	assert.Equal(t, []KeyState{
+		{Count: 3, TTL: time.Second, Blocked: true, BlockTTL: time.Minute},
+		{Count: 7, TTL: 500 * time.Millisecond},
+		{},
+		{Count: 3, TTL: time.Second, Blocked: true, BlockTTL: time.Minute},
+	}, states)
+
+	// Os itens não processados são lidos novamente
+	assert.Equal(t, []string{"BatchGetItem", "BatchGetItem"}, fake.operations())
+	keys := func(request dynamoRequest) []any {
+		return request.input["RequestItems"].(map[string]any)["rate_limits"].(map[string]any)["Keys"].([]any)
+	}
+	assert.Len(t, keys(fake.requests[0]), 6)
+	assert.Equal(t, []any{map[string]any{"pk": map[string]any{"S": "app:token:abc"}}}, keys(fake.requests[1]))
+}
+
+func TestDynamoDBStorage_GetManyBatches(t *testing.T) {
+	s, fake, _ := newTestDynamoDBStorage(t)
+
+	// Cada chave ocupa dois itens (contador e bloqueio), em lotes de até 100 itens
+	keys := make([]string, 60)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("ip:%d", i)
+	}
+
+	states, err := s.GetMany(context.Background(), keys)
+	require.NoError(t, err)
+	assert.Len(t, states, 60)
+
+	require.Len(t, fake.requests, 2)
+	for i, expected := range []int{100, 20} {
+		items := fake.requests[i].input["RequestItems"].(map[string]any)["rate_limits"].(map[string]any)
+		assert.Len(t, items["Keys"], expected)
+		assert.Equal(t, true, items["ConsistentRead"])
+	}
+}
+
+func TestDynamoDBStorage_ListBlockedPaginates(t *testing.T) {
+	s, fake, _ := newTestDynamoDBStorage(t,
+		dynamoResponse{status: http.StatusOK, body: `{"Items":[{"pk":{"S":"app:blocked:ip:2"}},{"pk":{"S":"app:blocked:token:abc"}}],"LastEvaluatedKey":{"pk":{"S":"app:blocked:token:abc"}}}`},
+		dynamoResponse{status: http.StatusOK, body: `{"Items":[{"pk":{"S":"app:blocked:ip:1"}}]}`},
+	)
+
+	keys, err := s.ListBlocked(context.Background(), "ip:*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ip:1", "ip:2"}, keys)
+
+	assert.Equal(t, []string{"Scan", "Scan"}, fake.operations())
+	first := fake.requests[0].input
+	assert.Equal(t, "begins_with(pk, :prefix) AND #expires_at > :now", first["FilterExpression"])
+	values := first["ExpressionAttributeValues"].(map[string]any)
+	assert.Equal(t, map[string]any{"S": "app:blocked:"}, values[":prefix"])
+	assert.Equal(t, map[string]any{"N": "1700000000000"}, values[":now"])
+	assert.NotContains(t, first, "ExclusiveStartKey")
+
+	// A segunda página continua da última chave avaliada
+	assert.Equal(t, map[string]any{"pk": map[string]any{"S": "app:blocked:token:abc"}}, fake.requests[1].input["ExclusiveStartKey"])
+}
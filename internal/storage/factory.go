@@ -0,0 +1,54 @@
+package storage
+
+import "fmt"
+
+// Backend identifica a implementação de Storage criada por New
+type Backend string
+
+const (
+	// BackendRedis armazena os contadores no Redis, compartilhados entre instâncias
+	BackendRedis Backend = "redis"
+
+	// BackendMemory armazena os contadores em memória, para uma única instância
+	BackendMemory Backend = "memory"
+)
+
+// Config seleciona o backend criado por New e as opções de cada implementação
+type Config struct {
+	// Backend é a implementação escolhida; vazio usa BackendRedis
+	Backend Backend
+
+	Redis  RedisOptions
+	Memory MemoryOptions
+}
+
+// ParseBackend converte o nome de um backend; vazio usa BackendRedis
+func ParseBackend(value string) (Backend, error) {
+	backend := Backend(value)
+
+	switch backend {
+	case "":
+		return BackendRedis, nil
+	case BackendRedis, BackendMemory:
+		return backend, nil
+	default:
+		return "", fmt.Errorf("backend de armazenamento desconhecido %q", value)
+	}
+}
+
+// New cria o armazenamento do backend escolhido em cfg. Funcionalidades exclusivas do Redis
+// (réplica de leitura, horário do servidor, configurações dinâmicas) exigem uma asserção de
+// tipo para *RedisStorage.
+func New(cfg Config) (Storage, error) {
+	backend, err := ParseBackend(string(cfg.Backend))
+	if err != nil {
+		return nil, err
+	}
+
+	switch backend {
+	case BackendMemory:
+		return NewMemoryStorage(cfg.Memory), nil
+	default:
+		return NewRedisStorageWithOptions(cfg.Redis), nil
+	}
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		backend  Backend
+		expected Storage
+	}{
+		{name: "padrão", backend: "", expected: &RedisStorage{}},
+		{name: "redis", backend: BackendRedis, expected: &RedisStorage{}},
+		{name: "memória", backend: BackendMemory, expected: &MemoryStorage{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := New(Config{
+				Backend: tt.backend,
+				Redis:   RedisOptions{Addr: "localhost:6379", KeyPrefix: "app"},
+				Memory:  MemoryOptions{CleanupInterval: -1},
+			})
+			require.NoError(t, err)
+			defer store.Close()
+
+			assert.IsType(t, tt.expected, store)
+		})
+	}
+}
+
+func TestNew_RedisOptions(t *testing.T) {
+	store, err := New(Config{
+		Backend: BackendRedis,
+		Redis:   RedisOptions{Addr: "redis:6379", DB: 2, KeyPrefix: "app"},
+	})
+	require.NoError(t, err)
+	defer store.Close()
+
+	redisStorage := store.(*RedisStorage)
+	assert.Equal(t, "redis:6379", redisStorage.client.Options().Addr)
+	assert.Equal(t, 2, redisStorage.client.Options().DB)
+	assert.Equal(t, "app", redisStorage.keyPrefix)
+}
+
+func TestNew_UnknownBackend(t *testing.T) {
+	store, err := New(Config{Backend: "postgres"})
+	assert.Error(t, err)
+	assert.Nil(t, store)
+}
+
+func TestParseBackend(t *testing.T) {
+	backend, err := ParseBackend("")
+	require.NoError(t, err)
+	assert.Equal(t, BackendRedis, backend)
+
+	backend, err = ParseBackend("memory")
+	require.NoError(t, err)
+	assert.Equal(t, BackendMemory, backend)
+
+	_, err = ParseBackend("Memory")
+	assert.Error(t, err)
+}
@@ -0,0 +1,318 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+)
+
+// DefaultRecoveryInterval é o intervalo padrão entre as verificações de saúde do armazenamento
+// primário enquanto o FallbackStorage usa o secundário
+const DefaultRecoveryInterval = 5 * time.Second
+
+// FallbackOptions configura o FallbackStorage
+type FallbackOptions struct {
+	// RecoveryInterval é o intervalo mínimo entre as verificações de saúde (Healthy) do
+	// primário enquanto o secundário está em uso. Zero usa DefaultRecoveryInterval.
+	RecoveryInterval time.Duration
+
+	// OnSwitch, quando definido, é chamado a cada troca de armazenamento (ex: para registrar
+	// a degradação em log), com usingFallback verdadeiro ao passar para o secundário e o erro
+	// do primário que causou a troca. É chamado de forma síncrona, então não deve bloquear.
+	OnSwitch func(usingFallback bool, err error)
+
+	// Clock é a fonte de tempo usada para medir RecoveryInterval. Nil usa o relógio do
+	// sistema.
+	Clock clock.Clock
+}
+
+// FallbackStorage encaminha as chamadas a um armazenamento primário (ex: Redis) e, quando ele
+// falha, passa a usar um secundário (ex: MemoryStorage), para que uma indisponibilidade do
+// primário degrade a limitação para local à instância em vez de desligá-la. Enquanto o
+// secundário está em uso, a saúde do primário é verificada a cada RecoveryInterval, e as
+// chamadas voltam a ele assim que responde.
+//
+// Os dois armazenamentos não compartilham estado: ao trocar, contadores e bloqueios recomeçam
+// no armazenamento de destino, e vagas de concorrência ocupadas no outro expiram pelo seu TTL.
+type FallbackStorage struct {
+	primary          Storage
+	secondary        Storage
+	recoveryInterval time.Duration
+	onSwitch         func(usingFallback bool, err error)
+	clock            clock.Clock
+
+	mu            sync.Mutex
+	usingFallback bool
+	lastCheck     time.Time
+	checking      bool
+}
+
+// NewFallbackStorage cria um FallbackStorage que usa secondary quando primary falha
+func NewFallbackStorage(primary, secondary Storage, opts FallbackOptions) *FallbackStorage {
+	if opts.RecoveryInterval <= 0 {
+		opts.RecoveryInterval = DefaultRecoveryInterval
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.New()
+	}
+
+	return &FallbackStorage{
+		primary:          primary,
+		secondary:        secondary,
+		recoveryInterval: opts.RecoveryInterval,
+		onSwitch:         opts.OnSwitch,
+		clock:            opts.Clock,
+	}
+}
+
+// UsingFallback indica se as chamadas estão sendo encaminhadas ao armazenamento secundário
+func (f *FallbackStorage) UsingFallback() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.usingFallback
+}
+
+// current escolhe o armazenamento da próxima chamada. Com o secundário em uso e o intervalo de
+// recuperação vencido, uma única chamada por vez verifica a saúde do primário e, se ele
+// responder, as chamadas voltam a ele.
+func (f *FallbackStorage) current(ctx context.Context) Storage {
+	f.mu.Lock()
+	if !f.usingFallback {
+		f.mu.Unlock()
+		return f.primary
+	}
+	if f.checking || f.clock.Now().Sub(f.lastCheck) < f.recoveryInterval {
+		f.mu.Unlock()
+		return f.secondary
+	}
+	f.checking = true
+	f.mu.Unlock()
+
+	err := f.primary.Healthy(ctx)
+
+	f.mu.Lock()
+	f.checking = false
+	f.lastCheck = f.clock.Now()
+	recovered := err == nil && f.usingFallback
+	if recovered {
+		f.usingFallback = false
+	}
+	f.mu.Unlock()
+
+	if recovered {
+		f.notify(false, nil)
+		return f.primary
+	}
+	return f.secondary
+}
+
+// failover passa a usar o secundário após uma falha do primário
+func (f *FallbackStorage) failover(err error) {
+	f.mu.Lock()
+	switched := !f.usingFallback
+	f.usingFallback = true
+	f.lastCheck = f.clock.Now()
+	f.mu.Unlock()
+
+	if switched {
+		f.notify(true, err)
+	}
+}
+
+// notify chama o hook OnSwitch, se definido
+func (f *FallbackStorage) notify(usingFallback bool, err error) {
+	if f.onSwitch != nil {
+		f.onSwitch(usingFallback, err)
+	}
+}
+
+// call executa fn no armazenamento atual e, se o primário falhar, repete a chamada no
+// secundário. Falhas causadas pelo cancelamento do contexto do chamador não provocam a troca.
+func (f *FallbackStorage) call(ctx context.Context, fn func(s Storage) error) error {
+	s := f.current(ctx)
+
+	err := fn(s)
+	if err == nil || s != f.primary || ctx.Err() != nil {
+		return err
+	}
+
+	f.failover(err)
+	return fn(f.secondary)
+}
+
+// Increment incrementa o contador no armazenamento em uso
+func (f *FallbackStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, bool, error) {
+	var count int64
+	var isNew bool
+	err := f.call(ctx, func(s Storage) (err error) {
+		count, isNew, err = s.Increment(ctx, key, window)
+		return err
+	})
+	return count, isNew, err
+}
+
+// IncrementBy soma amount ao contador no armazenamento em uso
+func (f *FallbackStorage) IncrementBy(ctx context.Context, key string, amount int64, window time.Duration) (int64, error) {
+	var count int64
+	err := f.call(ctx, func(s Storage) (err error) {
+		count, err = s.IncrementBy(ctx, key, amount, window)
+		return err
+	})
+	return count, err
+}
+
+// IncrementSliding incrementa um contador com expiração renovada no armazenamento em uso
+func (f *FallbackStorage) IncrementSliding(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	var count int64
+	err := f.call(ctx, func(s Storage) (err error) {
+		count, err = s.IncrementSliding(ctx, key, ttl)
+		return err
+	})
+	return count, err
+}
+
+// CheckAndIncrement decide a requisição no armazenamento em uso
+func (f *FallbackStorage) CheckAndIncrement(ctx context.Context, key string, limit Limit) (Decision, error) {
+	var decision Decision
+	err := f.call(ctx, func(s Storage) (err error) {
+		decision, err = s.CheckAndIncrement(ctx, key, limit)
+		return err
+	})
+	return decision, err
+}
+
+// Get lê o contador no armazenamento em uso
+func (f *FallbackStorage) Get(ctx context.Context, key string) (int64, time.Duration, error) {
+	var count int64
+	var ttl time.Duration
+	err := f.call(ctx, func(s Storage) (err error) {
+		count, ttl, err = s.Get(ctx, key)
+		return err
+	})
+	return count, ttl, err
+}
+
+// IsBlocked verifica o bloqueio no armazenamento em uso
+func (f *FallbackStorage) IsBlocked(ctx context.Context, key string) (bool, time.Duration, error) {
+	var blocked bool
+	var ttl time.Duration
+	err := f.call(ctx, func(s Storage) (err error) {
+		blocked, ttl, err = s.IsBlocked(ctx, key)
+		return err
+	})
+	return blocked, ttl, err
+}
+
+// GetMany lê os contadores e os bloqueios das chaves no armazenamento em uso
+func (f *FallbackStorage) GetMany(ctx context.Context, keys []string) ([]KeyState, error) {
+	var states []KeyState
+	err := f.call(ctx, func(s Storage) (err error) {
+		states, err = s.GetMany(ctx, keys)
+		return err
+	})
+	return states, err
+}
+
+// Block bloqueia a chave no armazenamento em uso
+func (f *FallbackStorage) Block(ctx context.Context, key string, duration time.Duration) error {
+	return f.call(ctx, func(s Storage) error {
+		return s.Block(ctx, key, duration)
+	})
+}
+
+// ListBlocked lista as chaves bloqueadas no armazenamento em uso
+func (f *FallbackStorage) ListBlocked(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+	err := f.call(ctx, func(s Storage) (err error) {
+		keys, err = s.ListBlocked(ctx, pattern)
+		return err
+	})
+	return keys, err
+}
+
+// Reset remove o contador e o bloqueio da chave no armazenamento em uso
+func (f *FallbackStorage) Reset(ctx context.Context, key string) error {
+	return f.call(ctx, func(s Storage) error {
+		return s.Reset(ctx, key)
+	})
+}
+
+// LeakyBucket atualiza o leaky bucket no armazenamento em uso
+func (f *FallbackStorage) LeakyBucket(ctx context.Context, key string, capacity int64, leakInterval time.Duration, now time.Time) (bool, time.Duration, error) {
+	var allowed bool
+	var wait time.Duration
+	err := f.call(ctx, func(s Storage) (err error) {
+		allowed, wait, err = s.LeakyBucket(ctx, key, capacity, leakInterval, now)
+		return err
+	})
+	return allowed, wait, err
+}
+
+// FirstSeen registra o primeiro contato no armazenamento em uso
+func (f *FallbackStorage) FirstSeen(ctx context.Context, key string, now time.Time, ttl time.Duration) (time.Time, error) {
+	var firstSeen time.Time
+	err := f.call(ctx, func(s Storage) (err error) {
+		firstSeen, err = s.FirstSeen(ctx, key, now, ttl)
+		return err
+	})
+	return firstSeen, err
+}
+
+// GetDecision obtém a decisão de idempotência no armazenamento em uso
+func (f *FallbackStorage) GetDecision(ctx context.Context, key string) (bool, bool, error) {
+	var allowed, found bool
+	err := f.call(ctx, func(s Storage) (err error) {
+		allowed, found, err = s.GetDecision(ctx, key)
+		return err
+	})
+	return allowed, found, err
+}
+
+// SetDecision registra a decisão de idempotência no armazenamento em uso
+func (f *FallbackStorage) SetDecision(ctx context.Context, key string, allowed bool, ttl time.Duration) error {
+	return f.call(ctx, func(s Storage) error {
+		return s.SetDecision(ctx, key, allowed, ttl)
+	})
+}
+
+// Acquire ocupa uma vaga de concorrência no armazenamento em uso
+func (f *FallbackStorage) Acquire(ctx context.Context, key string, limit int64, ttl time.Duration) (bool, error) {
+	var acquired bool
+	err := f.call(ctx, func(s Storage) (err error) {
+		acquired, err = s.Acquire(ctx, key, limit, ttl)
+		return err
+	})
+	return acquired, err
+}
+
+// Release libera uma vaga de concorrência no armazenamento em uso
+func (f *FallbackStorage) Release(ctx context.Context, key string) error {
+	return f.call(ctx, func(s Storage) error {
+		return s.Release(ctx, key)
+	})
+}
+
+// Healthy reporta o armazenamento como acessível enquanto o primário ou o secundário
+// responder, já que as chamadas continuam sendo atendidas por um deles
+func (f *FallbackStorage) Healthy(ctx context.Context) error {
+	primaryErr := f.primary.Healthy(ctx)
+	if primaryErr == nil {
+		return nil
+	}
+
+	secondaryErr := f.secondary.Healthy(ctx)
+	if secondaryErr == nil {
+		return nil
+	}
+
+	return errors.Join(primaryErr, secondaryErr)
+}
+
+// Close fecha os dois armazenamentos
+func (f *FallbackStorage) Close() error {
+	return errors.Join(f.primary.Close(), f.secondary.Close())
+}
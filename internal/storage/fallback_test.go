@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFallbackStorage_FailoverAndRecovery(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Unix(0, 0))
+	primary := &stubStorage{}
+	secondary := NewMemoryStorage(MemoryOptions{CleanupInterval: -1, Clock: fakeClock})
+
+	var switches []bool
+	s := NewFallbackStorage(primary, secondary, FallbackOptions{
+		RecoveryInterval: 5 * time.Second,
+		Clock:            fakeClock,
+		OnSwitch: func(usingFallback bool, err error) {
+			switches = append(switches, usingFallback)
+			if usingFallback {
+				assert.ErrorIs(t, err, errRedisDown)
+			}
+		},
+	})
+	defer s.Close()
+
+	ctx := context.Background()
+	limit := Limit{Requests: 2, Window: time.Minute}
+
+	// Com o primário saudável, as chamadas vão para ele
+	decision, err := s.CheckAndIncrement(ctx, "ip:1", limit)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.False(t, s.UsingFallback())
+	assert.Equal(t, 1, primary.callCount())
+
+	// A falha do primário é atendida pelo secundário, de forma transparente
+	primary.setErr(errRedisDown)
+
+	for i := int64(1); i <= 2; i++ {
+		decision, err = s.CheckAndIncrement(ctx, "ip:1", limit)
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, i, decision.Count)
+	}
+	assert.True(t, s.UsingFallback())
+
+	// O secundário continua limitando enquanto o primário está fora
+	decision, err = s.CheckAndIncrement(ctx, "ip:1", limit)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+
+	// Antes do intervalo de recuperação o primário não é consultado
+	calls := primary.callCount()
+	fakeClock.Advance(4 * time.Second)
+	_, err = s.CheckAndIncrement(ctx, "ip:1", limit)
+	require.NoError(t, err)
+	assert.Equal(t, calls, primary.callCount())
+
+	// Vencido o intervalo, a verificação de saúde falha e o secundário segue em uso
+	fakeClock.Advance(time.Second)
+	_, err = s.CheckAndIncrement(ctx, "ip:1", limit)
+	require.NoError(t, err)
+	assert.Equal(t, calls+1, primary.callCount())
+	assert.True(t, s.UsingFallback())
+
+	// Recuperado o primário, a próxima verificação devolve as chamadas a ele
+	primary.setErr(nil)
+	fakeClock.Advance(5 * time.Second)
+
+	decision, err = s.CheckAndIncrement(ctx, "ip:1", limit)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.False(t, s.UsingFallback())
+
+	// Healthy e CheckAndIncrement no primário
+	assert.Equal(t, calls+3, primary.callCount())
+	assert.Equal(t, []bool{true, false}, switches)
+}
+
+func TestFallbackStorage_CanceledContextDoesNotFailover(t *testing.T) {
+	primary := &stubStorage{err: context.Canceled}
+	secondary := NewMemoryStorage(MemoryOptions{CleanupInterval: -1})
+	s := NewFallbackStorage(primary, secondary, FallbackOptions{})
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := s.Increment(ctx, "ip:1", time.Second)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, s.UsingFallback())
+}
+
+func TestFallbackStorage_SecondaryFailure(t *testing.T) {
+	primary := &stubStorage{err: errRedisDown}
+	secondary := &stubStorage{err: errTransient}
+	s := NewFallbackStorage(primary, secondary, FallbackOptions{})
+
+	// Sem nenhum armazenamento disponível, o erro do secundário é propagado
+	_, _, err := s.Increment(context.Background(), "ip:1", time.Second)
+	assert.ErrorIs(t, err, errTransient)
+	assert.True(t, s.UsingFallback())
+}
+
+func TestFallbackStorage_Healthy(t *testing.T) {
+	primary := &stubStorage{}
+	secondary := &stubStorage{}
+	s := NewFallbackStorage(primary, secondary, FallbackOptions{})
+	ctx := context.Background()
+
+	assert.NoError(t, s.Healthy(ctx))
+
+	// Basta um dos armazenamentos responder
+	primary.setErr(errRedisDown)
+	assert.NoError(t, s.Healthy(ctx))
+
+	secondary.setErr(errTransient)
+	err := s.Healthy(ctx)
+	assert.ErrorIs(t, err, errRedisDown)
+	assert.ErrorIs(t, err, errTransient)
+}
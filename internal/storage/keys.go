@@ -0,0 +1,57 @@
+package storage
+
+const (
+	// DefaultKeySeparator separa o namespace das chaves auxiliares do restante da chave
+	DefaultKeySeparator = ":"
+
+	// DefaultBlockedNamespace é o namespace padrão das chaves de bloqueio
+	DefaultBlockedNamespace = "blocked"
+)
+
+// Namespaces das demais chaves auxiliares
+const (
+	bucketNamespace    = "bucket"
+	firstSeenNamespace = "first_seen"
+	decisionNamespace  = "decision"
+)
+
+// KeyNames define o separador e o namespace dos bloqueios nas chaves auxiliares do
+// armazenamento (bloqueios, leaky buckets, primeiro contato e decisões), para que elas sigam
+// as convenções de nomes já adotadas no Redis (ex: "blocked.ip.1.2.3.4" quando ":" é
+// reservado para outras ferramentas). Campos vazios usam DefaultKeySeparator e
+// DefaultBlockedNamespace. As chaves recebidas do rate limiter são usadas como informadas
+// (ver ratelimiter.KeyNames).
+type KeyNames struct {
+	// Separator separa o namespace do restante da chave
+	Separator string
+
+	// Blocked é o namespace das chaves de bloqueio
+	Blocked string
+}
+
+// keyPrefixes são os prefixos das chaves auxiliares, incluindo o namespace de KeyPrefix
+type keyPrefixes struct {
+	blocked   string
+	bucket    string
+	firstSeen string
+	decision  string
+}
+
+// prefixes calcula os prefixos das chaves auxiliares sob o namespace keyPrefix
+func (n KeyNames) prefixes(keyPrefix string) keyPrefixes {
+	separator := n.Separator
+	if separator == "" {
+		separator = DefaultKeySeparator
+	}
+	blocked := n.Blocked
+	if blocked == "" {
+		blocked = DefaultBlockedNamespace
+	}
+
+	return keyPrefixes{
+		blocked:   keyPrefix + blocked + separator,
+		bucket:    keyPrefix + bucketNamespace + separator,
+		firstSeen: keyPrefix + firstSeenNamespace + separator,
+		decision:  keyPrefix + decisionNamespace + separator,
+	}
+}
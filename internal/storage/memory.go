@@ -0,0 +1,476 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+)
+
+// DefaultMemoryCleanupInterval é o intervalo padrão da remoção de chaves expiradas
+const DefaultMemoryCleanupInterval = time.Minute
+
+// MemoryOptions configura o MemoryStorage
+type MemoryOptions struct {
+	// MaxKeys limita o número de chaves mantidas em memória, contando contadores, bloqueios e
+	// registros auxiliares. Ao atingir o limite, a chave usada há mais tempo é descartada
+	// (LRU). Zero não limita.
+	MaxKeys int
+
+	// CleanupInterval é o intervalo em que as chaves expiradas são removidas em segundo plano.
+	// Zero usa DefaultMemoryCleanupInterval; um valor negativo desativa a rotina de limpeza.
+	CleanupInterval time.Duration
+
+	// Clock é a fonte de tempo usada para janelas e bloqueios. Nil usa o relógio do sistema.
+	Clock clock.Clock
+
+	// KeyNames define o separador e o namespace dos bloqueios nas chaves auxiliares, como no
+	// RedisStorage
+	KeyNames KeyNames
+
+	// SlideWindowOnEachRequest renova a expiração do contador a cada incremento, como em
+	// RedisOptions
+	SlideWindowOnEachRequest bool
+}
+
+// memoryItem é uma chave mantida pelo MemoryStorage. Apenas os campos do tipo de registro
+// correspondente (contador, vagas de concorrência, bucket, primeiro contato ou decisão) são usados.
+type memoryItem struct {
+	key      string
+	expireAt time.Time
+
+	count     int64
+	level     float64
+	updatedAt time.Time
+	at        time.Time
+	allowed   bool
+}
+
+// MemoryStorage implementa Storage em memória, para uma única instância da aplicação. As
+// chaves seguem o mesmo esquema do RedisStorage; as expiradas são descartadas ao serem lidas e
+// periodicamente por uma rotina de limpeza, e MaxKeys limita a memória usada quando muitas
+// chaves distintas são criadas (ex: um ataque vindo de muitos IPs).
+type MemoryStorage struct {
+	AlwaysHealthy
+
+	mu       sync.Mutex
+	clock    clock.Clock
+	maxKeys  int
+	prefixes keyPrefixes
+
+	// slideWindow renova a expiração dos contadores a cada incremento
+	slideWindow bool
+
+	// items indexa os elementos de lru, ordenados do uso mais recente (frente) ao mais antigo
+	items map[string]*list.Element
+	lru   *list.List
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewMemoryStorage cria um armazenamento em memória e inicia a sua rotina de limpeza, encerrada
+// por Close
+func NewMemoryStorage(opts MemoryOptions) *MemoryStorage {
+	if opts.Clock == nil {
+		opts.Clock = clock.New()
+	}
+	if opts.CleanupInterval == 0 {
+		opts.CleanupInterval = DefaultMemoryCleanupInterval
+	}
+
+	s := &MemoryStorage{
+		clock:       opts.Clock,
+		maxKeys:     opts.MaxKeys,
+		prefixes:    opts.KeyNames.prefixes(""),
+		slideWindow: opts.SlideWindowOnEachRequest,
+		items:       make(map[string]*list.Element),
+		lru:         list.New(),
+		stop:        make(chan struct{}),
+	}
+
+	if opts.CleanupInterval > 0 {
+		go s.cleanup(opts.CleanupInterval)
+	}
+
+	return s
+}
+
+// Increment incrementa o contador para uma chave específica e retorna a contagem atual e se
+// ele foi criado por este incremento
+func (s *MemoryStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, isNew := s.increment(key, 1, window, s.clock.Now())
+	return counter.count, isNew, nil
+}
+
+// IncrementBy soma amount ao contador de uma chave e retorna a contagem atual
+func (s *MemoryStorage) IncrementBy(ctx context.Context, key string, amount int64, window time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, _ := s.increment(key, amount, window, s.clock.Now())
+	return counter.count, nil
+}
+
+// IncrementSliding incrementa o contador de uma chave renovando a sua expiração
+func (s *MemoryStorage) IncrementSliding(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+
+	counter, _ := s.increment(key, 1, ttl, now)
+	counter.expireAt = now.Add(ttl)
+	return counter.count, nil
+}
+
+// CheckAndIncrement verifica o bloqueio, incrementa o contador e bloqueia a chave ao exceder o
+// limite, com o lock do armazenamento mantido durante toda a operação
+func (s *MemoryStorage) CheckAndIncrement(ctx context.Context, key string, limit Limit) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+
+	if blocked := s.get(s.prefixes.blocked+key, now); blocked != nil {
+		return Decision{Blocked: true, TTL: blocked.expireAt.Sub(now)}, nil
+	}
+
+	counter, _ := s.increment(key, 1, limit.Window, now)
+	if counter.count <= limit.Requests {
+		return Decision{Allowed: true, Count: counter.count, TTL: counter.expireAt.Sub(now)}, nil
+	}
+
+	if limit.BlockTime > 0 {
+		count := counter.count
+		s.block(key, now.Add(limit.BlockTime))
+		return Decision{Count: count, Blocked: true, NewlyBlocked: true, TTL: limit.BlockTime}, nil
+	}
+
+	return Decision{Count: counter.count, TTL: counter.expireAt.Sub(now)}, nil
+}
+
+// Get lê o contador de uma chave e o tempo restante da sua janela
+func (s *MemoryStorage) Get(ctx context.Context, key string) (int64, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+
+	counter := s.get(key, now)
+	if counter == nil {
+		return 0, 0, nil
+	}
+
+	return counter.count, counter.expireAt.Sub(now), nil
+}
+
+// IsBlocked verifica se uma chave está atualmente bloqueada e retorna o tempo restante do
+// bloqueio
+func (s *MemoryStorage) IsBlocked(ctx context.Context, key string) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	blocked := s.get(s.prefixes.blocked+key, now)
+	if blocked == nil {
+		return false, 0, nil
+	}
+
+	return true, blocked.expireAt.Sub(now), nil
+}
+
+// GetMany lê os contadores e os bloqueios das chaves com uma única aquisição do lock
+func (s *MemoryStorage) GetMany(ctx context.Context, keys []string) ([]KeyState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+
+	states := make([]KeyState, len(keys))
+	for i, key := range keys {
+		if counter := s.get(key, now); counter != nil {
+			states[i].Count, states[i].TTL = counter.count, counter.expireAt.Sub(now)
+		}
+		if blocked := s.get(s.prefixes.blocked+key, now); blocked != nil {
+			states[i].Blocked, states[i].BlockTTL = true, blocked.expireAt.Sub(now)
+		}
+	}
+
+	return states, nil
+}
+
+// Block bloqueia uma chave pela duração especificada e zera o seu contador
+func (s *MemoryStorage) Block(ctx context.Context, key string, duration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.block(key, s.clock.Now().Add(duration))
+	return nil
+}
+
+// Reset remove o contador, o bloqueio e o leaky bucket de uma chave
+func (s *MemoryStorage) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(key)
+	s.remove(s.prefixes.blocked + key)
+	s.remove(s.prefixes.bucket + key)
+	return nil
+}
+
+// ListBlocked lista as chaves bloqueadas que correspondem ao padrão, percorrendo todas as
+// chaves mantidas com o lock
+func (s *MemoryStorage) ListBlocked(ctx context.Context, pattern string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	re := globPattern(pattern)
+
+	var keys []string
+	for key, element := range s.items {
+		if !strings.HasPrefix(key, s.prefixes.blocked) || !now.Before(element.Value.(*memoryItem).expireAt) {
+			continue
+		}
+		if key = strings.TrimPrefix(key, s.prefixes.blocked); re.MatchString(key) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// LeakyBucket adiciona uma requisição ao leaky bucket da chave
+func (s *MemoryStorage) LeakyBucket(ctx context.Context, key string, capacity int64, leakInterval time.Duration, now time.Time) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucketKey := s.prefixes.bucket + key
+
+	// Escoa as requisições correspondentes ao tempo decorrido desde a última atualização
+	level := 0.0
+	if bucket := s.get(bucketKey, now); bucket != nil {
+		elapsed := math.Max(0, float64(now.Sub(bucket.updatedAt)))
+		level = math.Max(0, bucket.level-elapsed/float64(leakInterval))
+	}
+
+	if level+1 > float64(capacity) {
+		wait := math.Ceil((level + 1 - float64(capacity)) * float64(leakInterval))
+		return false, time.Duration(wait), nil
+	}
+
+	bucket := s.set(bucketKey)
+	bucket.level = level + 1
+	bucket.updatedAt = now
+	bucket.expireAt = now.Add(time.Duration(math.Ceil(bucket.level * float64(leakInterval))))
+	return true, 0, nil
+}
+
+// FirstSeen registra o primeiro contato da chave e retorna o instante registrado
+func (s *MemoryStorage) FirstSeen(ctx context.Context, key string, now time.Time, ttl time.Duration) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	firstSeenKey := s.prefixes.firstSeen + key
+
+	item := s.get(firstSeenKey, now)
+	if item == nil {
+		item = s.set(firstSeenKey)
+		item.at = now
+	}
+
+	item.expireAt = now.Add(ttl)
+	return item.at, nil
+}
+
+// GetDecision obtém a decisão registrada para uma chave de idempotência, se existir
+func (s *MemoryStorage) GetDecision(ctx context.Context, key string) (bool, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item := s.get(s.prefixes.decision+key, s.clock.Now())
+	if item == nil {
+		return false, false, nil
+	}
+
+	return item.allowed, true, nil
+}
+
+// SetDecision registra a decisão para uma chave de idempotência pela duração especificada
+func (s *MemoryStorage) SetDecision(ctx context.Context, key string, allowed bool, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item := s.set(s.prefixes.decision + key)
+	item.allowed = allowed
+	item.expireAt = s.clock.Now().Add(ttl)
+	return nil
+}
+
+// Acquire ocupa uma vaga de concorrência da chave
+func (s *MemoryStorage) Acquire(ctx context.Context, key string, limit int64, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+
+	slots := s.get(key, now)
+	if limit <= 0 || (slots != nil && slots.count >= limit) {
+		return false, nil
+	}
+	if slots == nil {
+		slots = s.set(key)
+		slots.count = 0
+	}
+
+	slots.count++
+	slots.expireAt = now.Add(ttl)
+	return true, nil
+}
+
+// Release libera uma vaga de concorrência da chave
+func (s *MemoryStorage) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slots := s.get(key, s.clock.Now())
+	if slots == nil {
+		return nil
+	}
+
+	slots.count--
+	if slots.count <= 0 {
+		s.remove(key)
+	}
+	return nil
+}
+
+// Len retorna o número de chaves mantidas, incluindo as expiradas ainda não removidas
+func (s *MemoryStorage) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lru.Len()
+}
+
+// Close encerra a rotina de limpeza. Pode ser chamado mais de uma vez.
+func (s *MemoryStorage) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+	})
+	return nil
+}
+
+// increment soma amount ao contador da chave, iniciando uma nova janela quando não há contador
+// válido, e indica se a janela foi iniciada. Com SlideWindowOnEachRequest, a expiração é
+// renovada a cada incremento. Deve ser chamado com o lock.
+func (s *MemoryStorage) increment(key string, amount int64, window time.Duration, now time.Time) (*memoryItem, bool) {
+	counter := s.get(key, now)
+	isNew := counter == nil
+	if isNew {
+		counter = s.set(key)
+		counter.count = 0
+	}
+	if isNew || s.slideWindow {
+		counter.expireAt = now.Add(window)
+	}
+
+	counter.count += amount
+	return counter, isNew
+}
+
+// block registra o bloqueio da chave até blockedUntil e remove o seu contador. Deve ser chamado
+// com o lock.
+func (s *MemoryStorage) block(key string, blockedUntil time.Time) {
+	s.set(s.prefixes.blocked + key).expireAt = blockedUntil
+	s.remove(key)
+}
+
+// get retorna a chave marcando-a como a usada mais recentemente, ou nil se ela não existe ou
+// expirou. Deve ser chamado com o lock.
+func (s *MemoryStorage) get(key string, now time.Time) *memoryItem {
+	element, exists := s.items[key]
+	if !exists {
+		return nil
+	}
+
+	item := element.Value.(*memoryItem)
+	if !now.Before(item.expireAt) {
+		s.removeElement(element)
+		return nil
+	}
+
+	s.lru.MoveToFront(element)
+	return item
+}
+
+// set retorna a chave existente ou cria uma nova, descartando as chaves usadas há mais tempo
+// quando MaxKeys é atingido. Deve ser chamado com o lock.
+func (s *MemoryStorage) set(key string) *memoryItem {
+	if element, exists := s.items[key]; exists {
+		s.lru.MoveToFront(element)
+		return element.Value.(*memoryItem)
+	}
+
+	for s.maxKeys > 0 && s.lru.Len() >= s.maxKeys {
+		s.removeElement(s.lru.Back())
+	}
+
+	item := &memoryItem{key: key}
+	s.items[key] = s.lru.PushFront(item)
+	return item
+}
+
+// remove descarta a chave, se existir. Deve ser chamado com o lock.
+func (s *MemoryStorage) remove(key string) {
+	if element, exists := s.items[key]; exists {
+		s.removeElement(element)
+	}
+}
+
+// removeElement descarta o elemento da lista e do índice. Deve ser chamado com o lock.
+func (s *MemoryStorage) removeElement(element *list.Element) {
+	s.lru.Remove(element)
+	delete(s.items, element.Value.(*memoryItem).key)
+}
+
+// deleteExpired remove todas as chaves expiradas
+func (s *MemoryStorage) deleteExpired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	for element := s.lru.Back(); element != nil; {
+		previous := element.Prev()
+		if !now.Before(element.Value.(*memoryItem).expireAt) {
+			s.removeElement(element)
+		}
+		element = previous
+	}
+}
+
+// cleanup remove periodicamente as chaves expiradas até que Close seja chamado
+func (s *MemoryStorage) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.deleteExpired()
+		case <-s.stop:
+			return
+		}
+	}
+}
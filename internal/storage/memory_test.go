@@ -0,0 +1,506 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ Storage = (*MemoryStorage)(nil)
+
+func newTestMemoryStorage(t *testing.T, maxKeys int) (*MemoryStorage, *clock.FakeClock) {
+	fakeClock := clock.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := NewMemoryStorage(MemoryOptions{MaxKeys: maxKeys, CleanupInterval: -1, Clock: fakeClock})
+	t.Cleanup(func() { s.Close() })
+	return s, fakeClock
+}
+
+func TestMemoryStorage_Increment(t *testing.T) {
+	s, fakeClock := newTestMemoryStorage(t, 0)
+	ctx := context.Background()
+
+	// Apenas o incremento que cria o contador inicia a janela
+	for i := int64(1); i <= 3; i++ {
+		count, isNew, err := s.Increment(ctx, "ip:1", time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, i, count)
+		assert.Equal(t, i == 1, isNew)
+	}
+
+	// A janela não desliza com novos incrementos
+	fakeClock.Advance(500 * time.Millisecond)
+	count, ttl, err := s.Get(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	assert.Equal(t, 500*time.Millisecond, ttl)
+
+	fakeClock.Advance(500 * time.Millisecond)
+	count, isNew, err := s.Increment(ctx, "ip:1", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.True(t, isNew)
+}
+
+func TestMemoryStorage_IncrementBy(t *testing.T) {
+	s, fakeClock := newTestMemoryStorage(t, 0)
+	ctx := context.Background()
+
+	count, err := s.IncrementBy(ctx, "ip:1", 512, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(512), count)
+
+	// O contador criado por IncrementBy já existe para Increment
+	count, isNew, err := s.Increment(ctx, "ip:1", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(513), count)
+	assert.False(t, isNew)
+
+	fakeClock.Advance(time.Second)
+	count, err = s.IncrementBy(ctx, "ip:1", 100, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), count)
+}
+
+func TestMemoryStorage_IncrementSliding(t *testing.T) {
+	s, fakeClock := newTestMemoryStorage(t, 0)
+	ctx := context.Background()
+
+	count, err := s.IncrementSliding(ctx, "ip:1:offenses", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// Cada incremento renova a expiração, ao contrário de Increment
+	fakeClock.Advance(50 * time.Second)
+	count, err = s.IncrementSliding(ctx, "ip:1:offenses", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	fakeClock.Advance(50 * time.Second)
+	count, ttl, err := s.Get(ctx, "ip:1:offenses")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	assert.Equal(t, 10*time.Second, ttl)
+
+	fakeClock.Advance(10 * time.Second)
+	count, err = s.IncrementSliding(ctx, "ip:1:offenses", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestMemoryStorage_CheckAndIncrement(t *testing.T) {
+	s, fakeClock := newTestMemoryStorage(t, 0)
+	ctx := context.Background()
+	limit := Limit{Requests: 2, Window: time.Second, BlockTime: time.Minute}
+
+	for i := int64(1); i <= 2; i++ {
+		decision, err := s.CheckAndIncrement(ctx, "ip:1", limit)
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, i, decision.Count)
+	}
+
+	decision, err := s.CheckAndIncrement(ctx, "ip:1", limit)
+	require.NoError(t, err)
+	assert.Equal(t, Decision{Count: 3, Blocked: true, NewlyBlocked: true, TTL: time.Minute}, decision)
+
+	// O contador é zerado pelo bloqueio
+	count, _, err := s.Get(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	fakeClock.Advance(30 * time.Second)
+	decision, err = s.CheckAndIncrement(ctx, "ip:1", limit)
+	require.NoError(t, err)
+	assert.Equal(t, Decision{Blocked: true, TTL: 30 * time.Second}, decision)
+
+	fakeClock.Advance(30 * time.Second)
+	decision, err = s.CheckAndIncrement(ctx, "ip:1", limit)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, int64(1), decision.Count)
+}
+
+func TestMemoryStorage_CheckAndIncrementConcurrent(t *testing.T) {
+	s := NewMemoryStorage(MemoryOptions{})
+	defer s.Close()
+
+	limit := Limit{Requests: 10, Window: time.Minute, BlockTime: time.Minute}
+
+	var (
+		wg           sync.WaitGroup
+		mu           sync.Mutex
+		allowed      int
+		newlyBlocked int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			decision, err := s.CheckAndIncrement(context.Background(), "ip:1", limit)
+			assert.NoError(t, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if decision.Allowed {
+				allowed++
+			}
+			if decision.NewlyBlocked {
+				newlyBlocked++
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 10, allowed)
+	assert.Equal(t, 1, newlyBlocked)
+}
+
+func TestMemoryStorage_Block(t *testing.T) {
+	s, fakeClock := newTestMemoryStorage(t, 0)
+	ctx := context.Background()
+
+	_, _, err := s.Increment(ctx, "ip:1", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, s.Block(ctx, "ip:1", time.Second))
+
+	blocked, ttl, err := s.IsBlocked(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+	assert.Equal(t, time.Second, ttl)
+
+	count, _, err := s.Get(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// O tempo restante diminui conforme o bloqueio envelhece
+	fakeClock.Advance(400 * time.Millisecond)
+	blocked, ttl, err = s.IsBlocked(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+	assert.Equal(t, 600*time.Millisecond, ttl)
+
+	fakeClock.Advance(600 * time.Millisecond)
+	blocked, ttl, err = s.IsBlocked(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.False(t, blocked)
+	assert.Zero(t, ttl)
+}
+
+func TestMemoryStorage_SlideWindowOnEachRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		slide bool
+
+		// Contagem e tempo restante da janela após cada incremento, feitos a cada 600ms
+		counts []int64
+		ttls   []time.Duration
+	}{
+		{name: "janela fixa", slide: false,
+			counts: []int64{1, 2, 1, 2}, ttls: []time.Duration{time.Second, 400 * time.Millisecond, time.Second, 400 * time.Millisecond}},
+		{name: "janela deslizante", slide: true,
+			counts: []int64{1, 2, 3, 4}, ttls: []time.Duration{time.Second, time.Second, time.Second, time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClock := clock.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+			s := NewMemoryStorage(MemoryOptions{CleanupInterval: -1, Clock: fakeClock, SlideWindowOnEachRequest: tt.slide})
+			defer s.Close()
+
+			ctx := context.Background()
+			for i := range tt.counts {
+				count, _, err := s.Increment(ctx, "ip:1", time.Second)
+				require.NoError(t, err)
+				assert.Equal(t, tt.counts[i], count, i)
+
+				_, ttl, err := s.Get(ctx, "ip:1")
+				require.NoError(t, err)
+				assert.Equal(t, tt.ttls[i], ttl, i)
+
+				fakeClock.Advance(600 * time.Millisecond)
+			}
+
+			// CheckAndIncrement segue o mesmo comportamento
+			limit := Limit{Requests: 100, Window: time.Second}
+			for i := range tt.counts {
+				decision, err := s.CheckAndIncrement(ctx, "ip:2", limit)
+				require.NoError(t, err)
+				assert.Equal(t, tt.counts[i], decision.Count, i)
+				assert.Equal(t, tt.ttls[i], decision.TTL, i)
+
+				fakeClock.Advance(600 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestMemoryStorage_GetMany(t *testing.T) {
+	s, fakeClock := newTestMemoryStorage(t, 0)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, _, err := s.Increment(ctx, "ip:1", time.Second)
+		require.NoError(t, err)
+	}
+	require.NoError(t, s.Block(ctx, "token:abc", time.Minute))
+	fakeClock.Advance(400 * time.Millisecond)
+
+	states, err := s.GetMany(ctx, []string{"ip:1", "token:abc", "ip:2"})
+	require.NoError(t, err)
+	assert.Equal(t, []KeyState{
+		{Count: 3, TTL: 600 * time.Millisecond},
+		{Blocked: true, BlockTTL: time.Minute - 400*time.Millisecond},
+		{},
+	}, states)
+
+	states, err = s.GetMany(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, states)
+}
+
+func TestMemoryStorage_ListBlocked(t *testing.T) {
+	s, fakeClock := newTestMemoryStorage(t, 0)
+	ctx := context.Background()
+
+	require.NoError(t, s.Block(ctx, "ip:2", time.Minute))
+	require.NoError(t, s.Block(ctx, "ip:1", time.Second))
+	require.NoError(t, s.Block(ctx, "token:abc", time.Minute))
+	_, _, err := s.Increment(ctx, "ip:3", time.Minute)
+	require.NoError(t, err)
+
+	keys, err := s.ListBlocked(ctx, "ip:*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ip:1", "ip:2"}, keys)
+
+	keys, err = s.ListBlocked(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ip:1", "ip:2", "token:abc"}, keys)
+
+	keys, err = s.ListBlocked(ctx, "ip:?")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ip:1", "ip:2"}, keys)
+
+	// Bloqueios expirados não são listados
+	fakeClock.Advance(time.Second)
+	keys, err = s.ListBlocked(ctx, "ip:*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ip:2"}, keys)
+}
+
+func TestMemoryStorage_KeyNames(t *testing.T) {
+	s := NewMemoryStorage(MemoryOptions{CleanupInterval: -1, KeyNames: KeyNames{Separator: ".", Blocked: "ban"}})
+	defer s.Close()
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, s.Block(ctx, "ip.1", time.Minute))
+	_, _, err := s.LeakyBucket(ctx, "ip.2", 1, time.Minute, now)
+	require.NoError(t, err)
+	_, err = s.FirstSeen(ctx, "ip.3", now, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, s.SetDecision(ctx, "ip.4.retry", true, time.Minute))
+
+	assert.Contains(t, s.items, "ban.ip.1")
+	assert.Contains(t, s.items, "bucket.ip.2")
+	assert.Contains(t, s.items, "first_seen.ip.3")
+	assert.Contains(t, s.items, "decision.ip.4.retry")
+
+	keys, err := s.ListBlocked(ctx, "ip.*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ip.1"}, keys)
+
+	require.NoError(t, s.Reset(ctx, "ip.1"))
+	blocked, _, err := s.IsBlocked(ctx, "ip.1")
+	require.NoError(t, err)
+	assert.False(t, blocked)
+}
+
+func TestMemoryStorage_Reset(t *testing.T) {
+	s, fakeClock := newTestMemoryStorage(t, 0)
+	ctx := context.Background()
+
+	_, _, err := s.Increment(ctx, "ip:1", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, s.Block(ctx, "ip:2", time.Minute))
+	_, _, err = s.LeakyBucket(ctx, "ip:3", 1, time.Minute, fakeClock.Now())
+	require.NoError(t, err)
+
+	for _, key := range []string{"ip:1", "ip:2", "ip:3", "ip:inexistente"} {
+		require.NoError(t, s.Reset(ctx, key))
+	}
+
+	count, _, err := s.Get(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	blocked, _, err := s.IsBlocked(ctx, "ip:2")
+	require.NoError(t, err)
+	assert.False(t, blocked)
+
+	// O bucket vazio volta a aceitar a requisição imediatamente
+	allowed, _, err := s.LeakyBucket(ctx, "ip:3", 1, time.Minute, fakeClock.Now())
+	require.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+func TestMemoryStorage_LeakyBucket(t *testing.T) {
+	s, fakeClock := newTestMemoryStorage(t, 0)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		allowed, _, err := s.LeakyBucket(ctx, "ip:1", 2, time.Second, fakeClock.Now())
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+
+	allowed, wait, err := s.LeakyBucket(ctx, "ip:1", 2, time.Second, fakeClock.Now())
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Second, wait)
+
+	fakeClock.Advance(time.Second)
+	allowed, _, err = s.LeakyBucket(ctx, "ip:1", 2, time.Second, fakeClock.Now())
+	require.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+func TestMemoryStorage_FirstSeenAndDecision(t *testing.T) {
+	s, fakeClock := newTestMemoryStorage(t, 0)
+	ctx := context.Background()
+	start := fakeClock.Now()
+
+	firstSeen, err := s.FirstSeen(ctx, "ip:1", start, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, start, firstSeen)
+
+	// Contatos dentro do ttl renovam o registro sem alterar o instante
+	firstSeen, err = s.FirstSeen(ctx, "ip:1", start.Add(50*time.Second), time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, start, firstSeen)
+
+	require.NoError(t, s.SetDecision(ctx, "req-1", true, time.Second))
+	allowed, found, err := s.GetDecision(ctx, "req-1")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.True(t, allowed)
+
+	fakeClock.Advance(time.Second)
+	_, found, err = s.GetDecision(ctx, "req-1")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestMemoryStorage_MaxKeysEvictsOldest(t *testing.T) {
+	s, _ := newTestMemoryStorage(t, 3)
+	ctx := context.Background()
+
+	for i := 1; i <= 5; i++ {
+		_, _, err := s.Increment(ctx, fmt.Sprintf("ip:%d", i), time.Minute)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 3, s.Len())
+
+	// As duas chaves mais antigas foram descartadas
+	for i, expected := range []int64{0, 0, 1, 1, 1} {
+		count, _, err := s.Get(ctx, fmt.Sprintf("ip:%d", i+1))
+		require.NoError(t, err)
+		assert.Equal(t, expected, count, "ip:%d", i+1)
+	}
+}
+
+func TestMemoryStorage_MaxKeysKeepsRecentlyUsed(t *testing.T) {
+	s, _ := newTestMemoryStorage(t, 3)
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		_, _, err := s.Increment(ctx, fmt.Sprintf("ip:%d", i), time.Minute)
+		require.NoError(t, err)
+	}
+
+	// Usar ip:1 o torna o mais recente, de modo que ip:2 passa a ser o descartado
+	_, _, err := s.Increment(ctx, "ip:1", time.Minute)
+	require.NoError(t, err)
+	_, _, err = s.Increment(ctx, "ip:4", time.Minute)
+	require.NoError(t, err)
+
+	count, _, err := s.Get(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	count, _, err = s.Get(ctx, "ip:2")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestMemoryStorage_DeleteExpired(t *testing.T) {
+	s, fakeClock := newTestMemoryStorage(t, 0)
+	ctx := context.Background()
+
+	_, _, err := s.Increment(ctx, "ip:1", time.Second)
+	require.NoError(t, err)
+	_, _, err = s.Increment(ctx, "ip:2", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, s.Block(ctx, "ip:3", time.Second))
+
+	fakeClock.Advance(time.Second)
+	s.deleteExpired()
+
+	assert.Equal(t, 1, s.Len())
+	count, _, err := s.Get(ctx, "ip:2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestMemoryStorage_CleanupRoutine(t *testing.T) {
+	s := NewMemoryStorage(MemoryOptions{CleanupInterval: 10 * time.Millisecond})
+
+	_, _, err := s.Increment(context.Background(), "ip:1", time.Millisecond)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return s.Len() == 0
+	}, time.Second, 10*time.Millisecond)
+
+	// Close pode ser chamado mais de uma vez
+	assert.NoError(t, s.Close())
+	assert.NoError(t, s.Close())
+}
+
+func TestMemoryStorage_AcquireRelease(t *testing.T) {
+	s, fakeClock := newTestMemoryStorage(t, 0)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		acquired, err := s.Acquire(ctx, "ip:1:concurrency", 2, time.Minute)
+		require.NoError(t, err)
+		assert.True(t, acquired)
+	}
+
+	acquired, err := s.Acquire(ctx, "ip:1:concurrency", 2, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	require.NoError(t, s.Release(ctx, "ip:1:concurrency"))
+	acquired, err = s.Acquire(ctx, "ip:1:concurrency", 2, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// Vagas não liberadas expiram após o ttl
+	fakeClock.Advance(time.Minute)
+	acquired, err = s.Acquire(ctx, "ip:1:concurrency", 2, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// Liberar mais vagas do que as ocupadas não deixa o contador negativo
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.Release(ctx, "ip:1:concurrency"))
+	}
+	assert.Zero(t, s.Len())
+}
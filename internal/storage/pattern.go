@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// globPattern compila um padrão no estilo glob, em que "*" corresponde a qualquer sequência e
+// "?" a um único caractere; os demais caracteres são literais. Vazio equivale a "*".
+func globPattern(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+
+	return regexp.MustCompile(expr.String())
+}
+
+// redisGlob converte o padrão para a sintaxe do MATCH do Redis, escapando os caracteres
+// especiais que não sejam "*" e "?" para que o resultado coincida com globPattern. Com literal,
+// todos os caracteres especiais são escapados (ex: prefixos de chave).
+func redisGlob(pattern string, literal bool) string {
+	var escaped strings.Builder
+	for _, r := range pattern {
+		switch {
+		case r == '[' || r == ']' || r == '\\':
+			escaped.WriteRune('\\')
+		case literal && (r == '*' || r == '?'):
+			escaped.WriteRune('\\')
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
+
+// sortedKeys retorna as chaves do conjunto em ordem
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
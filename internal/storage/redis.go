@@ -2,65 +2,461 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
+// scanCount é a quantidade de chaves sugerida ao Redis por página do SCAN em ListBlocked
+const scanCount = 1000
+
+// leakyBucketScript atualiza atomicamente o nível do leaky bucket de uma chave.
+// O estado é um hash com o nível atual e o instante da última atualização (em microssegundos).
+var leakyBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local leak_interval = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call('HMGET', KEYS[1], 'level', 'ts')
+local level = tonumber(state[1]) or 0
+local ts = tonumber(state[2]) or now
+
+-- Escoa as requisições correspondentes ao tempo decorrido desde a última atualização
+local elapsed = math.max(0, now - ts)
+level = math.max(0, level - elapsed / leak_interval)
+
+if level + 1 > capacity then
+	return {0, math.ceil((level + 1 - capacity) * leak_interval)}
+end
+
+level = level + 1
+redis.call('HSET', KEYS[1], 'level', tostring(level), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(level * leak_interval / 1000))
+
+return {1, 0}
+`)
+
+// acquireScript ocupa uma vaga de concorrência se houver vaga livre, renovando a expiração do
+// contador de vagas. Retorna 1 quando a vaga foi ocupada.
+var acquireScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count > tonumber(ARGV[1]) then
+	redis.call('DECR', KEYS[1])
+	return 0
+end
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return 1
+`)
+
+// releaseScript libera uma vaga de concorrência sem deixar o contador negativo (ex: quando ele
+// já expirou)
+var releaseScript = redis.NewScript(`
+local count = tonumber(redis.call('GET', KEYS[1]) or '0')
+if count > 0 then
+	return redis.call('DECR', KEYS[1])
+end
+return 0
+`)
+
+// incrementScript soma ARGV[2] atomicamente ao contador de uma chave, definindo a expiração
+// apenas no início da janela para que ela não deslize, ou a cada incremento com ARGV[3] igual a
+// 1. Um contador existente sem expiração recebe a janela, mas não é informado como novo.
+// Retorna {count, is_new}.
+var incrementScript = redis.NewScript(`
+local count = redis.call('INCRBY', KEYS[1], ARGV[2])
+local is_new = 0
+if count == tonumber(ARGV[2]) then
+	is_new = 1
+end
+if is_new == 1 or ARGV[3] == '1' or redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return {count, is_new}
+`)
+
+// incrementSlidingScript incrementa atomicamente o contador de uma chave renovando a sua
+// expiração. Retorna a contagem atual.
+var incrementSlidingScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+return count
+`)
+
+// checkAndIncrementScript decide atomicamente uma requisição de janela fixa: verifica o
+// bloqueio, incrementa o contador e bloqueia a chave ao exceder o limite. Com ARGV[4] igual a 1,
+// cada requisição renova a expiração da janela. Retorna
+// {allowed, count, blocked, newly_blocked, ttl_ms}.
+var checkAndIncrementScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local block_time = tonumber(ARGV[3])
+local slide = ARGV[4] == '1'
+
+-- Um bloqueio sem expiração é inconsistente (ex: restaurado de um snapshot sem TTL) e
+-- bloquearia a chave para sempre: recebe o tempo de bloqueio atual ou é descartado
+local block_ttl = redis.call('PTTL', KEYS[2])
+if block_ttl == -1 then
+	if block_time > 0 then
+		redis.call('PEXPIRE', KEYS[2], block_time)
+		block_ttl = block_time
+	else
+		redis.call('DEL', KEYS[2])
+		block_ttl = -2
+	end
+end
+
+-- Chave bloqueada: rejeita sem contabilizar a requisição
+if block_ttl ~= -2 then
+	return {0, 0, 1, 0, block_ttl}
+end
+
+-- A expiração é definida apenas no início da janela para que ela não deslize, a menos que
+-- o deslizamento tenha sido configurado
+local count = redis.call('INCR', KEYS[1])
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 or slide then
+	redis.call('PEXPIRE', KEYS[1], window)
+	ttl = window
+end
+
+if count <= limit then
+	return {1, count, 0, 0, ttl}
+end
+
+if block_time > 0 then
+	redis.call('SET', KEYS[2], '1', 'PX', block_time)
+	redis.call('DEL', KEYS[1])
+	return {0, count, 1, 1, block_time}
+end
+
+return {0, count, 0, 0, ttl}
+`)
+
 // RedisStorage implementa a interface Storage usando Redis
 type RedisStorage struct {
+	// client executa as escritas e as leituras que precisam de consistência; reader executa as
+	// leituras simples (Get, IsBlocked, GetDecision e ReadHash) e é o próprio client quando não
+	// há réplica configurada
 	client *redis.Client
+	reader *redis.Client
+
+	// Prefixos pré-calculados com o namespace configurado em KeyPrefix
+	keyPrefix       string
+	blockedPrefix   string
+	bucketPrefix    string
+	firstSeenPrefix string
+	decisionPrefix  string
+
+	// slideWindow renova a expiração dos contadores a cada incremento
+	slideWindow bool
+
+	// Tempos máximos das leituras e das escritas; zero mantém apenas o prazo do contexto
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+}
+
+// RedisOptions configura a conexão e as chaves do RedisStorage
+type RedisOptions struct {
+	Addr     string
+	Password string
+	DB       int
+
+	// KeyPrefix é o namespace (ex: "myapp:") aplicado a todas as chaves, inclusive às de
+	// bloqueio, para que serviços que compartilham o mesmo Redis não colidam
+	KeyPrefix string
+
+	// KeyNames define o separador e o namespace dos bloqueios nas chaves auxiliares; vazio
+	// usa "blocked:" e os demais padrões
+	KeyNames KeyNames
+
+	// ReadAddr é o endereço de uma réplica para as leituras simples (Get, IsBlocked,
+	// GetDecision, ReadHash e ListBlocked), aliviando o primário. As escritas e os scripts continuam no
+	// primário. Por causa do atraso da replicação, uma leitura pode não refletir uma escrita
+	// recente (ex: um bloqueio aplicado há instantes). Vazio lê do primário.
+	ReadAddr string
+
+	// StrongConsistency ignora ReadAddr e faz todas as leituras no primário
+	StrongConsistency bool
+
+	// SlideWindowOnEachRequest renova a expiração do contador a cada incremento, de modo que a
+	// janela só termina após um intervalo sem requisições. Falso (o padrão) define a expiração
+	// apenas no início da janela, que termina no mesmo instante independentemente do tráfego.
+	SlideWindowOnEachRequest bool
+
+	// ReadTimeout limita cada leitura (Get, IsBlocked, GetMany, ListBlocked, GetDecision e
+	// ReadHash), que costuma estar no caminho de cada requisição, e WriteTimeout limita as
+	// escritas e os scripts (ex: CheckAndIncrement, Block, Reset), que podem tolerar mais
+	// latência. São aplicados ao contexto de cada operação, sem estender um prazo menor já
+	// definido nele. Zero não aplica limite.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
 }
 
 // NewRedisStorage cria uma nova instância de armazenamento Redis
 func NewRedisStorage(addr, password string, db int) *RedisStorage {
-	rdb := redis.NewClient(&redis.Options{
+	return NewRedisStorageWithOptions(RedisOptions{
 		Addr:     addr,
 		Password: password,
 		DB:       db,
 	})
+}
+
+// NewRedisStorageWithOptions cria uma nova instância de armazenamento Redis com as opções informadas
+func NewRedisStorageWithOptions(opts RedisOptions) *RedisStorage {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     opts.Addr,
+		Password: opts.Password,
+		DB:       opts.DB,
+	})
+
+	// A réplica usa as mesmas credenciais e o mesmo banco do primário
+	reader := rdb
+	if opts.ReadAddr != "" && !opts.StrongConsistency {
+		reader = redis.NewClient(&redis.Options{
+			Addr:     opts.ReadAddr,
+			Password: opts.Password,
+			DB:       opts.DB,
+		})
+	}
+
+	prefixes := opts.KeyNames.prefixes(opts.KeyPrefix)
 
 	return &RedisStorage{
-		client: rdb,
+		client:          rdb,
+		reader:          reader,
+		keyPrefix:       opts.KeyPrefix,
+		blockedPrefix:   prefixes.blocked,
+		bucketPrefix:    prefixes.bucket,
+		firstSeenPrefix: prefixes.firstSeen,
+		decisionPrefix:  prefixes.decision,
+		slideWindow:     opts.SlideWindowOnEachRequest,
+		readTimeout:     opts.ReadTimeout,
+		writeTimeout:    opts.WriteTimeout,
 	}
 }
 
-// Increment incrementa o contador para uma chave específica e retorna a contagem atual
-func (r *RedisStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
-	pipe := r.client.Pipeline()
+// readContext limita o contexto de uma leitura a ReadTimeout
+func (r *RedisStorage) readContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, r.readTimeout)
+}
 
-	// Incrementa o contador
-	incrCmd := pipe.Incr(ctx, key)
+// writeContext limita o contexto de uma escrita a WriteTimeout
+func (r *RedisStorage) writeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, r.writeTimeout)
+}
 
-	// Define expiração se esta for a primeira incrementação
-	pipe.Expire(ctx, key, window)
+// withTimeout aplica o tempo máximo ao contexto; zero o mantém inalterado
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
 
-	_, err := pipe.Exec(ctx)
+// Increment incrementa o contador para uma chave específica e retorna a contagem atual e se
+// ele foi criado por este incremento
+func (r *RedisStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, bool, error) {
+	return r.incrementBy(ctx, key, 1, window)
+}
+
+// IncrementBy soma amount ao contador de uma chave e retorna a contagem atual
+func (r *RedisStorage) IncrementBy(ctx context.Context, key string, amount int64, window time.Duration) (int64, error) {
+	count, _, err := r.incrementBy(ctx, key, amount, window)
+	return count, err
+}
+
+// incrementBy soma amount ao contador de uma chave e retorna a contagem atual e se o contador
+// foi criado pelo incremento
+func (r *RedisStorage) incrementBy(ctx context.Context, key string, amount int64, window time.Duration) (int64, bool, error) {
+	ctx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	key = r.keyPrefix + key
+
+	// Contagem e expiração são atualizadas em uma única operação, de modo que requisições
+	// concorrentes nunca observem um contador sem expiração
+	result, err := incrementScript.Run(ctx, r.client, []string{key}, window.Milliseconds(), amount, r.slideWindow).Int64Slice()
+	if err != nil {
+		return 0, false, fmt.Errorf("falha ao incrementar contador: %w", err)
+	}
+
+	return result[0], result[1] == 1, nil
+}
+
+// IncrementSliding incrementa o contador de uma chave renovando a sua expiração
+func (r *RedisStorage) IncrementSliding(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	ctx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	count, err := incrementSlidingScript.Run(ctx, r.client, []string{r.keyPrefix + key}, ttl.Milliseconds()).Int64()
 	if err != nil {
 		return 0, fmt.Errorf("falha ao incrementar contador: %w", err)
 	}
 
-	return incrCmd.Val(), nil
+	return count, nil
+}
+
+// CheckAndIncrement decide a requisição em uma única ida ao Redis via script Lua
+func (r *RedisStorage) CheckAndIncrement(ctx context.Context, key string, limit Limit) (Decision, error) {
+	ctx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	keys := []string{r.keyPrefix + key, r.blockedPrefix + key}
+
+	result, err := checkAndIncrementScript.Run(ctx, r.client, keys,
+		limit.Requests, limit.Window.Milliseconds(), limit.BlockTime.Milliseconds(), r.slideWindow).Int64Slice()
+	if err != nil {
+		return Decision{}, fmt.Errorf("falha ao executar script de verificação: %w", err)
+	}
+
+	return Decision{
+		Allowed:      result[0] == 1,
+		Count:        result[1],
+		Blocked:      result[2] == 1,
+		NewlyBlocked: result[3] == 1,
+		TTL:          time.Duration(result[4]) * time.Millisecond,
+	}, nil
+}
+
+// Get lê o contador e o tempo restante da janela em uma única ida ao Redis
+func (r *RedisStorage) Get(ctx context.Context, key string) (int64, time.Duration, error) {
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
+
+	key = r.keyPrefix + key
+
+	pipe := r.reader.Pipeline()
+
+	getCmd := pipe.Get(ctx, key)
+	ttlCmd := pipe.PTTL(ctx, key)
+
+	_, err := pipe.Exec(ctx)
+	if err != nil && err != redis.Nil {
+		return 0, 0, fmt.Errorf("falha ao ler contador: %w", err)
+	}
+
+	count, err := getCmd.Int64()
+	if err == redis.Nil {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("falha ao ler contador: %w", err)
+	}
+
+	// PTTL retorna valores negativos para chaves sem expiração
+	ttl := ttlCmd.Val()
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	return count, ttl, nil
 }
 
-// IsBlocked verifica se uma chave está atualmente bloqueada
-func (r *RedisStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
-	blockedKey := fmt.Sprintf("blocked:%s", key)
+// IsBlocked verifica se uma chave está atualmente bloqueada e retorna o tempo restante do
+// bloqueio, lidos em um único PTTL
+func (r *RedisStorage) IsBlocked(ctx context.Context, key string) (bool, time.Duration, error) {
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
+
+	blockedKey := r.blockedPrefix + key
 
-	result, err := r.client.Exists(ctx, blockedKey).Result()
+	ttl, err := r.reader.PTTL(ctx, blockedKey).Result()
 	if err != nil {
-		return false, fmt.Errorf("falha ao verificar se a chave está bloqueada: %w", err)
+		return false, 0, fmt.Errorf("falha ao verificar se a chave está bloqueada: %w", err)
+	}
+
+	// PTTL retorna -2 para chaves inexistentes e -1 para chaves sem expiração
+	switch {
+	case ttl == -2:
+		return false, 0, nil
+	case ttl < 0:
+		return true, 0, nil
+	}
+	return true, ttl, nil
+}
+
+// GetMany lê os contadores e os bloqueios das chaves em um único pipeline, com GET e PTTL do
+// contador e PTTL do bloqueio de cada chave
+func (r *RedisStorage) GetMany(ctx context.Context, keys []string) ([]KeyState, error) {
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
+
+	type keyCmds struct {
+		get      *redis.StringCmd
+		ttl      *redis.DurationCmd
+		blockTTL *redis.DurationCmd
+	}
+
+	pipe := r.reader.Pipeline()
+
+	cmds := make([]keyCmds, len(keys))
+	for i, key := range keys {
+		cmds[i] = keyCmds{
+			get:      pipe.Get(ctx, r.keyPrefix+key),
+			ttl:      pipe.PTTL(ctx, r.keyPrefix+key),
+			blockTTL: pipe.PTTL(ctx, r.blockedPrefix+key),
+		}
+	}
+
+	if len(keys) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return nil, fmt.Errorf("falha ao ler chaves: %w", err)
+		}
+	}
+
+	// Exec retorna apenas o primeiro erro, que é redis.Nil quando uma chave não existe, então
+	// o erro de cada comando é verificado para que uma falha parcial não seja lida como um
+	// valor zero (ex: um PTTL com falha reportando a chave como bloqueada)
+	states := make([]KeyState, len(keys))
+	for i, cmd := range cmds {
+		count, err := cmd.get.Int64()
+		switch {
+		case err == redis.Nil:
+		case err != nil:
+			return nil, fmt.Errorf("falha ao ler contador: %w", err)
+		default:
+			ttl, err := cmd.ttl.Result()
+			if err != nil {
+				return nil, fmt.Errorf("falha ao ler expiração do contador: %w", err)
+			}
+
+			// PTTL retorna valores negativos para chaves sem expiração
+			states[i].Count, states[i].TTL = count, max(ttl, 0)
+		}
+
+		blockTTL, err := cmd.blockTTL.Result()
+		if err != nil {
+			return nil, fmt.Errorf("falha ao verificar se a chave está bloqueada: %w", err)
+		}
+
+		// PTTL retorna -2 para chaves inexistentes e -1 para chaves sem expiração
+		if blockTTL != -2 {
+			states[i].Blocked, states[i].BlockTTL = true, max(blockTTL, 0)
+		}
 	}
 
-	return result > 0, nil
+	return states, nil
 }
 
-// Block bloqueia uma chave pela duração especificada
+// Block bloqueia uma chave pela duração especificada e zera o seu contador
 func (r *RedisStorage) Block(ctx context.Context, key string, duration time.Duration) error {
-	blockedKey := fmt.Sprintf("blocked:%s", key)
+	ctx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	blockedKey := r.blockedPrefix + key
+
+	pipe := r.client.TxPipeline()
+
+	// Aplica o bloqueio
+	pipe.Set(ctx, blockedKey, "1", duration)
 
-	err := r.client.Set(ctx, blockedKey, "1", duration).Err()
+	// Remove o contador que disparou o bloqueio para que a chave recomece do zero
+	pipe.Del(ctx, r.keyPrefix+key)
+
+	_, err := pipe.Exec(ctx)
 	if err != nil {
 		return fmt.Errorf("falha ao bloquear chave: %w", err)
 	}
@@ -68,7 +464,225 @@ func (r *RedisStorage) Block(ctx context.Context, key string, duration time.Dura
 	return nil
 }
 
-// Close fecha a conexão Redis
+// Reset remove o contador, o bloqueio e o leaky bucket de uma chave em um único comando
+func (r *RedisStorage) Reset(ctx context.Context, key string) error {
+	ctx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	err := r.client.Del(ctx, r.keyPrefix+key, r.blockedPrefix+key, r.bucketPrefix+key).Err()
+	if err != nil {
+		return fmt.Errorf("falha ao resetar chave: %w", err)
+	}
+
+	return nil
+}
+
+// ListBlocked lista as chaves bloqueadas que correspondem ao padrão. Usa SCAN em páginas de
+// scanCount chaves, em vez de KEYS, para não travar o Redis; ainda assim a varredura percorre
+// todo o keyspace, então evite chamá-la com frequência em bases grandes. Com ReadAddr, a
+// varredura é feita na réplica.
+func (r *RedisStorage) ListBlocked(ctx context.Context, pattern string) ([]string, error) {
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
+
+	if pattern == "" {
+		pattern = "*"
+	}
+	match := redisGlob(r.blockedPrefix, true) + redisGlob(pattern, false)
+
+	// O SCAN pode retornar a mesma chave em mais de uma página
+	seen := make(map[string]struct{})
+	var cursor uint64
+	for {
+		keys, next, err := r.reader.Scan(ctx, cursor, match, scanCount).Result()
+		if err != nil {
+			return nil, fmt.Errorf("falha ao listar chaves bloqueadas: %w", err)
+		}
+		for _, key := range keys {
+			seen[strings.TrimPrefix(key, r.blockedPrefix)] = struct{}{}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+
+	return sortedKeys(seen), nil
+}
+
+// LeakyBucket adiciona uma requisição ao leaky bucket da chave de forma atômica via script Lua
+func (r *RedisStorage) LeakyBucket(ctx context.Context, key string, capacity int64, leakInterval time.Duration, now time.Time) (bool, time.Duration, error) {
+	ctx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	bucketKey := r.bucketPrefix + key
+
+	result, err := leakyBucketScript.Run(ctx, r.client, []string{bucketKey},
+		capacity, leakInterval.Microseconds(), now.UnixMicro()).Int64Slice()
+	if err != nil {
+		return false, 0, fmt.Errorf("falha ao executar script do leaky bucket: %w", err)
+	}
+
+	return result[0] == 1, time.Duration(result[1]) * time.Microsecond, nil
+}
+
+// FirstSeen registra o primeiro contato da chave e retorna o instante registrado
+func (r *RedisStorage) FirstSeen(ctx context.Context, key string, now time.Time, ttl time.Duration) (time.Time, error) {
+	ctx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	firstSeenKey := r.firstSeenPrefix + key
+
+	pipe := r.client.TxPipeline()
+
+	// Registra o instante apenas se ainda não houver registro
+	pipe.SetNX(ctx, firstSeenKey, now.UnixNano(), ttl)
+
+	// Lê o instante registrado e renova a expiração
+	getCmd := pipe.Get(ctx, firstSeenKey)
+	pipe.PExpire(ctx, firstSeenKey, ttl)
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("falha ao registrar primeiro contato: %w", err)
+	}
+
+	firstSeen, err := getCmd.Int64()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("falha ao ler primeiro contato: %w", err)
+	}
+
+	return time.Unix(0, firstSeen), nil
+}
+
+// GetDecision obtém a decisão registrada para uma chave de idempotência, se existir
+func (r *RedisStorage) GetDecision(ctx context.Context, key string) (bool, bool, error) {
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
+
+	decisionKey := r.decisionPrefix + key
+
+	value, err := r.reader.Get(ctx, decisionKey).Result()
+	if err == redis.Nil {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("falha ao obter decisão: %w", err)
+	}
+
+	return value == "1", true, nil
+}
+
+// SetDecision registra a decisão para uma chave de idempotência pela duração especificada
+func (r *RedisStorage) SetDecision(ctx context.Context, key string, allowed bool, ttl time.Duration) error {
+	ctx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	decisionKey := r.decisionPrefix + key
+
+	value := "0"
+	if allowed {
+		value = "1"
+	}
+
+	err := r.client.Set(ctx, decisionKey, value, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("falha ao registrar decisão: %w", err)
+	}
+
+	return nil
+}
+
+// Acquire ocupa uma vaga de concorrência da chave
+func (r *RedisStorage) Acquire(ctx context.Context, key string, limit int64, ttl time.Duration) (bool, error) {
+	ctx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	acquired, err := acquireScript.Run(ctx, r.client, []string{r.keyPrefix + key}, limit, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return false, fmt.Errorf("falha ao ocupar vaga de concorrência: %w", err)
+	}
+
+	return acquired == 1, nil
+}
+
+// Release libera uma vaga de concorrência da chave
+func (r *RedisStorage) Release(ctx context.Context, key string) error {
+	ctx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	err := releaseScript.Run(ctx, r.client, []string{r.keyPrefix + key}).Err()
+	if err != nil {
+		return fmt.Errorf("falha ao liberar vaga de concorrência: %w", err)
+	}
+
+	return nil
+}
+
+// ReadHash lê todos os campos de um hash (ex: a configuração de um token compartilhada entre
+// instâncias). Um hash inexistente retorna um mapa vazio.
+func (r *RedisStorage) ReadHash(ctx context.Context, key string) (map[string]string, error) {
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
+
+	fields, err := r.reader.HGetAll(ctx, r.keyPrefix+key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("falha ao ler hash: %w", err)
+	}
+
+	return fields, nil
+}
+
+// Ping verifica a conexão com o Redis e, se configurada, com a réplica de leitura
+func (r *RedisStorage) Ping(ctx context.Context) error {
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
+
+	err := r.client.Ping(ctx).Err()
+	if err != nil {
+		return fmt.Errorf("falha ao conectar ao Redis: %w", err)
+	}
+
+	if r.reader != r.client {
+		err = r.reader.Ping(ctx).Err()
+		if err != nil {
+			return fmt.Errorf("falha ao conectar à réplica de leitura do Redis: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Time retorna o horário do servidor Redis (comando TIME), lido do primário
+func (r *RedisStorage) Time(ctx context.Context) (time.Time, error) {
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
+
+	serverTime, err := r.client.Time(ctx).Result()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("falha ao obter horário do Redis: %w", err)
+	}
+
+	return serverTime, nil
+}
+
+// ServerClock retorna um relógio que segue o horário do Redis, para uso com
+// ratelimiter.WithClock quando as instâncias podem ter relógios dessincronizados
+func (r *RedisStorage) ServerClock(opts ServerClockOptions) *ServerClock {
+	return NewServerClock(r.Time, opts)
+}
+
+// Healthy verifica se o Redis está acessível
+func (r *RedisStorage) Healthy(ctx context.Context) error {
+	return r.Ping(ctx)
+}
+
+// Close fecha as conexões Redis
 func (r *RedisStorage) Close() error {
-	return r.client.Close()
+	err := r.client.Close()
+	if r.reader != r.client {
+		err = errors.Join(err, r.reader.Close())
+	}
+	return err
 }
@@ -0,0 +1,235 @@
+//go:build integration
+
+package storage
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Testes contra um Redis real, executados com:
+//
+//	REDIS_ADDR=localhost:6379 go test -race -tags integration ./internal/storage/
+
+// newIntegrationStorage conecta ao Redis de REDIS_ADDR com um prefixo exclusivo do teste,
+// pulando o teste quando o servidor não está acessível
+func newIntegrationStorage(t *testing.T) *RedisStorage {
+	t.Helper()
+
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+
+	storage := NewRedisStorageWithOptions(RedisOptions{
+		Addr:      addr,
+		KeyPrefix: "it:" + strconv.FormatInt(time.Now().UnixNano(), 36) + ":",
+	})
+	t.Cleanup(func() { storage.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := storage.Ping(ctx); err != nil {
+		t.Skipf("Redis indisponível em %s: %v", addr, err)
+	}
+
+	return storage
+}
+
+func TestRedisStorage_Integration_ConcurrentIncrement(t *testing.T) {
+	storage := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	const goroutines = 100
+	const window = 10 * time.Second
+
+	counts := make([]int64, goroutines)
+	var created atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			count, isNew, err := storage.Increment(ctx, "fresh", window)
+			assert.NoError(t, err)
+			counts[i] = count
+			if isNew {
+				created.Add(1)
+				assert.Equal(t, int64(1), count)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Apenas um incremento cria o contador
+	assert.Equal(t, int64(1), created.Load())
+
+	// Cada requisição observa uma contagem distinta entre 1 e N
+	seen := make(map[int64]bool, goroutines)
+	for _, count := range counts {
+		assert.False(t, seen[count], "contagem %d repetida", count)
+		seen[count] = true
+	}
+	for count := int64(1); count <= goroutines; count++ {
+		assert.True(t, seen[count], "contagem %d ausente", count)
+	}
+
+	// A expiração foi definida no início da janela e não é renovada pelos incrementos
+	count, ttl, err := storage.Get(ctx, "fresh")
+	require.NoError(t, err)
+	assert.Equal(t, int64(goroutines), count)
+	assert.Greater(t, ttl, time.Duration(0))
+	assert.LessOrEqual(t, ttl, window)
+}
+
+func TestRedisStorage_Integration_ConcurrentCheckAndIncrementAllowsExactLimit(t *testing.T) {
+	storage := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	const goroutines = 200
+	limit := Limit{Requests: 50, Window: 10 * time.Second, BlockTime: time.Minute}
+
+	var allowed atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			decision, err := storage.CheckAndIncrement(ctx, "fresh", limit)
+			assert.NoError(t, err)
+			if decision.Allowed {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, limit.Requests, allowed.Load())
+
+	blocked, ttl, err := storage.IsBlocked(ctx, "fresh")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+	assert.Greater(t, ttl, time.Duration(0))
+	assert.LessOrEqual(t, ttl, limit.BlockTime)
+}
+
+func TestRedisStorage_Integration_ConcurrentAcquire(t *testing.T) {
+	storage := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	const goroutines = 100
+	const limit = 10
+
+	var acquired atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ok, err := storage.Acquire(ctx, "slots", limit, time.Minute)
+			assert.NoError(t, err)
+			if ok {
+				acquired.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(limit), acquired.Load())
+
+	// Liberar uma vaga permite uma nova aquisição
+	require.NoError(t, storage.Release(ctx, "slots"))
+	ok, err := storage.Acquire(ctx, "slots", limit, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestRedisStorage_Integration_ServerClock(t *testing.T) {
+	storage := newIntegrationStorage(t)
+
+	serverTime, err := storage.Time(context.Background())
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), serverTime, time.Minute)
+
+	// Um relógio local adiantado em uma hora é corrigido pelo horário do Redis
+	local := clock.NewFakeClock(time.Now().Add(time.Hour))
+	serverClock := storage.ServerClock(ServerClockOptions{Clock: local})
+	assert.WithinDuration(t, time.Now(), serverClock.Now(), time.Minute)
+}
+
+func TestRedisStorage_Integration_ListBlocked(t *testing.T) {
+	storage := newIntegrationStorage(t)
+	ctx := context.Background()
+
+	// Chaves suficientes para exigir mais de uma página do SCAN
+	for i := 0; i < 2500; i++ {
+		require.NoError(t, storage.Block(ctx, "ip:10.0."+strconv.Itoa(i/256)+"."+strconv.Itoa(i%256), time.Minute))
+	}
+	require.NoError(t, storage.Block(ctx, "token:abc", time.Minute))
+	require.NoError(t, storage.Block(ctx, "token:short", 50*time.Millisecond))
+	_, _, err := storage.Increment(ctx, "token:counter", time.Minute)
+	require.NoError(t, err)
+
+	keys, err := storage.ListBlocked(ctx, "ip:*")
+	require.NoError(t, err)
+	assert.Len(t, keys, 2500)
+
+	keys, err = storage.ListBlocked(ctx, "token:*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"token:abc", "token:short"}, keys)
+
+	// Bloqueios expirados não são listados
+	time.Sleep(100 * time.Millisecond)
+	keys, err = storage.ListBlocked(ctx, "token:*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"token:abc"}, keys)
+}
+
+func TestRedisStorage_Integration_EvictedKeys(t *testing.T) {
+	storage := newIntegrationStorage(t)
+	ctx := context.Background()
+	limit := Limit{Requests: 2, Window: time.Minute, BlockTime: time.Minute}
+
+	// Contador descartado no meio da janela: a contagem recomeça
+	for i := 0; i < 2; i++ {
+		_, err := storage.CheckAndIncrement(ctx, "ip:1", limit)
+		require.NoError(t, err)
+	}
+	require.NoError(t, storage.client.Del(ctx, storage.keyPrefix+"ip:1").Err())
+
+	decision, err := storage.CheckAndIncrement(ctx, "ip:1", limit)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, int64(1), decision.Count)
+
+	// Bloqueio sem expiração: recebe o tempo de bloqueio em vez de durar para sempre
+	require.NoError(t, storage.client.Set(ctx, storage.blockedPrefix+"ip:2", "1", 0).Err())
+
+	decision, err = storage.CheckAndIncrement(ctx, "ip:2", limit)
+	require.NoError(t, err)
+	assert.True(t, decision.Blocked)
+	assert.Equal(t, time.Minute, decision.TTL)
+
+	ttl, err := storage.client.PTTL(ctx, storage.blockedPrefix+"ip:2").Result()
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+
+	// Sem tempo de bloqueio configurado, o bloqueio inconsistente é descartado
+	require.NoError(t, storage.client.Set(ctx, storage.blockedPrefix+"ip:3", "1", 0).Err())
+
+	decision, err = storage.CheckAndIncrement(ctx, "ip:3", Limit{Requests: 2, Window: time.Minute})
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+}
@@ -0,0 +1,281 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Testes dos scripts Lua e pipelines do RedisStorage contra o miniredis, um Redis em processo
+// que dispensa um servidor externo. O tempo do miniredis só avança com FastForward, então as
+// expirações são determinísticas.
+
+func newMiniredisStorage(t *testing.T) (*RedisStorage, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	s := NewRedisStorageWithOptions(RedisOptions{Addr: server.Addr(), KeyPrefix: "app:"})
+	t.Cleanup(func() { s.Close() })
+	return s, server
+}
+
+func TestRedisStorage_Miniredis_IncrementTTL(t *testing.T) {
+	s, server := newMiniredisStorage(t)
+	ctx := context.Background()
+
+	count, isNew, err := s.Increment(ctx, "ip:1.1.1.1", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.True(t, isNew)
+	assert.Equal(t, time.Second, server.TTL("app:ip:1.1.1.1"))
+
+	// Incrementos seguintes não renovam a expiração: a janela não desliza
+	server.FastForward(600 * time.Millisecond)
+
+	count, isNew, err = s.Increment(ctx, "ip:1.1.1.1", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	assert.False(t, isNew)
+	assert.Equal(t, 400*time.Millisecond, server.TTL("app:ip:1.1.1.1"))
+
+	count, ttl, err := s.Get(ctx, "ip:1.1.1.1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	assert.Equal(t, 400*time.Millisecond, ttl)
+
+	// Ao fim da janela iniciada pelo primeiro incremento, o contador recomeça
+	server.FastForward(400 * time.Millisecond)
+
+	count, isNew, err = s.Increment(ctx, "ip:1.1.1.1", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.True(t, isNew)
+}
+
+func TestRedisStorage_Miniredis_SlideWindowOnEachRequest(t *testing.T) {
+	server := miniredis.RunT(t)
+	fixed := NewRedisStorageWithOptions(RedisOptions{Addr: server.Addr(), KeyPrefix: "fixed:"})
+	sliding := NewRedisStorageWithOptions(RedisOptions{Addr: server.Addr(), KeyPrefix: "sliding:", SlideWindowOnEachRequest: true})
+	t.Cleanup(func() {
+		fixed.Close()
+		sliding.Close()
+	})
+
+	ctx := context.Background()
+	limit := Limit{Requests: 100, Window: time.Second}
+
+	// A cada 600ms, a janela fixa recomeça a cada dois incrementos; a deslizante é renovada e
+	// continua acumulando
+	for i := int64(1); i <= 4; i++ {
+		count, _, err := fixed.Increment(ctx, "ip:1.1.1.1", time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, (i-1)%2+1, count)
+
+		count, _, err = sliding.Increment(ctx, "ip:1.1.1.1", time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, i, count)
+		assert.Equal(t, time.Second, server.TTL("sliding:ip:1.1.1.1"))
+
+		decision, err := sliding.CheckAndIncrement(ctx, "token:abc", limit)
+		require.NoError(t, err)
+		assert.Equal(t, Decision{Allowed: true, Count: i, TTL: time.Second}, decision)
+
+		server.FastForward(600 * time.Millisecond)
+	}
+
+	// IncrementBy também renova a expiração, e a janela deslizante termina após um intervalo
+	// sem incrementos
+	count, err := sliding.IncrementBy(ctx, "ip:1.1.1.1", 10, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(14), count)
+	assert.Equal(t, time.Second, server.TTL("sliding:ip:1.1.1.1"))
+
+	server.FastForward(time.Second)
+	assert.False(t, server.Exists("sliding:ip:1.1.1.1"))
+	assert.False(t, server.Exists("sliding:token:abc"))
+}
+
+func TestRedisStorage_Miniredis_IncrementWithoutExpiration(t *testing.T) {
+	s, server := newMiniredisStorage(t)
+	ctx := context.Background()
+
+	// Um contador sem expiração (ex: restaurado de um snapshot) recebe a janela no próximo
+	// incremento em vez de nunca recomeçar, mas não é informado como criado, pois já tinha
+	// contagem
+	require.NoError(t, server.Set("app:ip:1.1.1.1", "5"))
+
+	count, isNew, err := s.Increment(ctx, "ip:1.1.1.1", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), count)
+	assert.False(t, isNew)
+	assert.Equal(t, time.Second, server.TTL("app:ip:1.1.1.1"))
+
+	// O mesmo vale para IncrementBy
+	require.NoError(t, server.Set("app:ip:2.2.2.2", "5"))
+
+	total, err := s.IncrementBy(ctx, "ip:2.2.2.2", 3, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), total)
+	assert.Equal(t, time.Second, server.TTL("app:ip:2.2.2.2"))
+
+	// Um contador novo continua sendo informado como criado
+	count, isNew, err = s.Increment(ctx, "ip:3.3.3.3", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.True(t, isNew)
+}
+
+func TestRedisStorage_Miniredis_Block(t *testing.T) {
+	s, server := newMiniredisStorage(t)
+	ctx := context.Background()
+
+	_, _, err := s.Increment(ctx, "ip:1.1.1.1", time.Second)
+	require.NoError(t, err)
+
+	blocked, ttl, err := s.IsBlocked(ctx, "ip:1.1.1.1")
+	require.NoError(t, err)
+	assert.False(t, blocked)
+	assert.Zero(t, ttl)
+
+	// O bloqueio zera o contador que o disparou
+	require.NoError(t, s.Block(ctx, "ip:1.1.1.1", time.Minute))
+	assert.False(t, server.Exists("app:ip:1.1.1.1"))
+
+	blocked, ttl, err = s.IsBlocked(ctx, "ip:1.1.1.1")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+	assert.Equal(t, time.Minute, ttl)
+
+	keys, err := s.ListBlocked(ctx, "ip:*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ip:1.1.1.1"}, keys)
+
+	// O tempo restante diminui até o bloqueio expirar
+	server.FastForward(40 * time.Second)
+
+	blocked, ttl, err = s.IsBlocked(ctx, "ip:1.1.1.1")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+	assert.Equal(t, 20*time.Second, ttl)
+
+	server.FastForward(20 * time.Second)
+
+	blocked, _, err = s.IsBlocked(ctx, "ip:1.1.1.1")
+	require.NoError(t, err)
+	assert.False(t, blocked)
+
+	// Reset remove um bloqueio antes do fim
+	require.NoError(t, s.Block(ctx, "ip:1.1.1.1", time.Minute))
+	require.NoError(t, s.Reset(ctx, "ip:1.1.1.1"))
+
+	blocked, _, err = s.IsBlocked(ctx, "ip:1.1.1.1")
+	require.NoError(t, err)
+	assert.False(t, blocked)
+}
+
+func TestRedisStorage_Miniredis_CheckAndIncrement(t *testing.T) {
+	s, server := newMiniredisStorage(t)
+	ctx := context.Background()
+	limit := Limit{Requests: 2, Window: time.Second, BlockTime: time.Minute}
+
+	for i := int64(1); i <= 2; i++ {
+		decision, err := s.CheckAndIncrement(ctx, "ip:1.1.1.1", limit)
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, i, decision.Count)
+	}
+
+	// A requisição excedente bloqueia a chave e descarta o contador
+	decision, err := s.CheckAndIncrement(ctx, "ip:1.1.1.1", limit)
+	require.NoError(t, err)
+	assert.Equal(t, Decision{Count: 3, Blocked: true, NewlyBlocked: true, TTL: time.Minute}, decision)
+	assert.False(t, server.Exists("app:ip:1.1.1.1"))
+
+	// Enquanto bloqueada, a chave é rejeitada sem contabilizar a requisição
+	server.FastForward(15 * time.Second)
+
+	decision, err = s.CheckAndIncrement(ctx, "ip:1.1.1.1", limit)
+	require.NoError(t, err)
+	assert.Equal(t, Decision{Blocked: true, TTL: 45 * time.Second}, decision)
+	assert.False(t, server.Exists("app:ip:1.1.1.1"))
+
+	// Após o bloqueio a chave recomeça uma nova janela
+	server.FastForward(45 * time.Second)
+
+	decision, err = s.CheckAndIncrement(ctx, "ip:1.1.1.1", limit)
+	require.NoError(t, err)
+	assert.Equal(t, Decision{Allowed: true, Count: 1, TTL: time.Second}, decision)
+}
+
+func TestRedisStorage_Miniredis_CheckAndIncrementWindowReset(t *testing.T) {
+	s, server := newMiniredisStorage(t)
+	ctx := context.Background()
+
+	// Sem bloqueio, as requisições excedentes são rejeitadas apenas até o fim da janela, que
+	// não é estendida por elas
+	limit := Limit{Requests: 1, Window: time.Second}
+
+	decision, err := s.CheckAndIncrement(ctx, "token:abc", limit)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+
+	server.FastForward(500 * time.Millisecond)
+
+	decision, err = s.CheckAndIncrement(ctx, "token:abc", limit)
+	require.NoError(t, err)
+	assert.Equal(t, Decision{Count: 2, TTL: 500 * time.Millisecond}, decision)
+
+	server.FastForward(500 * time.Millisecond)
+
+	decision, err = s.CheckAndIncrement(ctx, "token:abc", limit)
+	require.NoError(t, err)
+	assert.Equal(t, Decision{Allowed: true, Count: 1, TTL: time.Second}, decision)
+}
+
+func TestRedisStorage_Miniredis_BlockWithoutExpiration(t *testing.T) {
+	s, server := newMiniredisStorage(t)
+	ctx := context.Background()
+
+	// Um bloqueio sem expiração recebe o tempo de bloqueio atual em vez de durar para sempre
+	require.NoError(t, server.Set("app:blocked:ip:1.1.1.1", "1"))
+
+	decision, err := s.CheckAndIncrement(ctx, "ip:1.1.1.1", Limit{Requests: 1, Window: time.Second, BlockTime: time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, Decision{Blocked: true, TTL: time.Minute}, decision)
+	assert.Equal(t, time.Minute, server.TTL("app:blocked:ip:1.1.1.1"))
+
+	// Sem tempo de bloqueio configurado, o bloqueio inconsistente é descartado
+	require.NoError(t, server.Set("app:blocked:ip:2.2.2.2", "1"))
+
+	decision, err = s.CheckAndIncrement(ctx, "ip:2.2.2.2", Limit{Requests: 1, Window: time.Second})
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.False(t, server.Exists("app:blocked:ip:2.2.2.2"))
+}
+
+func TestRedisStorage_Miniredis_Acquire(t *testing.T) {
+	s, server := newMiniredisStorage(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		acquired, err := s.Acquire(ctx, "token:abc:concurrency", 2, time.Minute)
+		require.NoError(t, err)
+		assert.True(t, acquired)
+	}
+
+	acquired, err := s.Acquire(ctx, "token:abc:concurrency", 2, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	// A liberação nunca deixa o contador negativo
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.Release(ctx, "token:abc:concurrency"))
+	}
+	value, err := server.Get("app:token:abc:concurrency")
+	require.NoError(t, err)
+	assert.Equal(t, "0", value)
+}
@@ -0,0 +1,472 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+var errCommandRecorded = errors.New("comando registrado sem execução")
+
+// keyRecorder é um hook do go-redis que registra as chaves dos comandos e interrompe a
+// execução, permitindo inspecionar as chaves sem um servidor Redis
+type keyRecorder struct {
+	keys []string
+}
+
+func (h *keyRecorder) record(cmd redis.Cmder) {
+	args := cmd.Args()
+	switch cmd.Name() {
+	case "evalsha", "eval":
+		// EVALSHA <sha> <numkeys> <key>...
+		h.keys = append(h.keys, args[3].(string))
+	case "scan":
+		// SCAN <cursor> MATCH <padrão> COUNT <n>
+		h.keys = append(h.keys, args[3].(string))
+	case "ping":
+		// Comando sem chave
+	default:
+		h.keys = append(h.keys, args[1].(string))
+	}
+}
+
+func (h *keyRecorder) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	h.record(cmd)
+	return ctx, errCommandRecorded
+}
+
+func (h *keyRecorder) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h *keyRecorder) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	for _, cmd := range cmds {
+		if name := cmd.Name(); name != "multi" && name != "exec" {
+			h.record(cmd)
+		}
+	}
+	return ctx, errCommandRecorded
+}
+
+func (h *keyRecorder) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+func TestRedisStorage_KeyPrefix(t *testing.T) {
+	redisStorage := NewRedisStorageWithOptions(RedisOptions{Addr: "localhost:0", KeyPrefix: "myapp:"})
+	defer redisStorage.Close()
+
+	recorder := &keyRecorder{}
+	redisStorage.client.AddHook(recorder)
+
+	ctx := context.Background()
+	now := time.Now()
+
+	_, _, _ = redisStorage.Increment(ctx, "ip:1", time.Second)
+	_, _, _ = redisStorage.Get(ctx, "ip:1")
+	_, _, _ = redisStorage.IsBlocked(ctx, "ip:1")
+	_ = redisStorage.Block(ctx, "ip:1", time.Minute)
+	_, _, _ = redisStorage.LeakyBucket(ctx, "ip:1", 10, time.Millisecond, now)
+	_, _ = redisStorage.FirstSeen(ctx, "ip:1", now, time.Minute)
+	_, _, _ = redisStorage.GetDecision(ctx, "ip:1:retry")
+	_ = redisStorage.SetDecision(ctx, "ip:1:retry", true, time.Second)
+	_, _ = redisStorage.ListBlocked(ctx, "ip:*")
+
+	assert.NotEmpty(t, recorder.keys)
+	for _, key := range recorder.keys {
+		assert.Regexp(t, `^myapp:`, key)
+	}
+	assert.Contains(t, recorder.keys, "myapp:ip:1")
+	assert.Contains(t, recorder.keys, "myapp:blocked:ip:1")
+	assert.Contains(t, recorder.keys, "myapp:bucket:ip:1")
+	assert.Contains(t, recorder.keys, "myapp:first_seen:ip:1")
+	assert.Contains(t, recorder.keys, "myapp:decision:ip:1:retry")
+	assert.Contains(t, recorder.keys, "myapp:blocked:ip:*")
+}
+
+func TestRedisStorage_KeyNames(t *testing.T) {
+	redisStorage := NewRedisStorageWithOptions(RedisOptions{
+		Addr:      "localhost:0",
+		KeyPrefix: "myapp.",
+		KeyNames:  KeyNames{Separator: ".", Blocked: "ban"},
+	})
+	defer redisStorage.Close()
+
+	recorder := &keyRecorder{}
+	redisStorage.client.AddHook(recorder)
+
+	ctx := context.Background()
+	now := time.Now()
+
+	_, _, _ = redisStorage.Increment(ctx, "ip.1", time.Second)
+	_, _, _ = redisStorage.IsBlocked(ctx, "ip.1")
+	_ = redisStorage.Block(ctx, "ip.1", time.Minute)
+	_ = redisStorage.Reset(ctx, "ip.1")
+	_, _, _ = redisStorage.LeakyBucket(ctx, "ip.1", 10, time.Millisecond, now)
+	_, _ = redisStorage.FirstSeen(ctx, "ip.1", now, time.Minute)
+	_, _, _ = redisStorage.GetDecision(ctx, "ip.1.retry")
+	_ = redisStorage.SetDecision(ctx, "ip.1.retry", true, time.Second)
+	_, _ = redisStorage.ListBlocked(ctx, "ip.*")
+
+	assert.NotEmpty(t, recorder.keys)
+	for _, key := range recorder.keys {
+		assert.Regexp(t, `^myapp\.`, key)
+		assert.NotContains(t, key, ":")
+	}
+	assert.Contains(t, recorder.keys, "myapp.ip.1")
+	assert.Contains(t, recorder.keys, "myapp.ban.ip.1")
+	assert.Contains(t, recorder.keys, "myapp.bucket.ip.1")
+	assert.Contains(t, recorder.keys, "myapp.first_seen.ip.1")
+	assert.Contains(t, recorder.keys, "myapp.decision.ip.1.retry")
+	assert.Contains(t, recorder.keys, "myapp.ban.ip.*")
+
+	// CheckAndIncrement recebe as chaves do contador e do bloqueio
+	scriptStorage := NewRedisStorageWithOptions(RedisOptions{
+		Addr:      "localhost:0",
+		KeyPrefix: "myapp.",
+		KeyNames:  KeyNames{Separator: ".", Blocked: "ban"},
+	})
+	defer scriptStorage.Close()
+
+	scriptRecorder := &scriptKeysRecorder{}
+	scriptStorage.client.AddHook(scriptRecorder)
+	_, _ = scriptStorage.CheckAndIncrement(ctx, "ip.1", Limit{Requests: 1, Window: time.Second})
+	assert.Equal(t, []string{"myapp.ip.1", "myapp.ban.ip.1"}, scriptRecorder.keys)
+}
+
+// scriptKeysRecorder registra todas as chaves informadas a scripts Lua, e não apenas a primeira
+type scriptKeysRecorder struct {
+	keyRecorder
+}
+
+func (h *scriptKeysRecorder) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	args := cmd.Args()
+	if name := cmd.Name(); name == "evalsha" || name == "eval" {
+		// EVALSHA <sha> <numkeys> <key>...
+		numKeys := args[2].(int)
+		for _, key := range args[3 : 3+numKeys] {
+			h.keys = append(h.keys, key.(string))
+		}
+	}
+	return ctx, errCommandRecorded
+}
+
+// scriptArgsRecorder registra os argumentos (ARGV) informados a scripts Lua
+type scriptArgsRecorder struct {
+	keyRecorder
+	args [][]interface{}
+}
+
+func (h *scriptArgsRecorder) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	args := cmd.Args()
+	if name := cmd.Name(); name == "evalsha" || name == "eval" {
+		// EVALSHA <sha> <numkeys> <key>... <arg>...
+		h.args = append(h.args, args[3+args[2].(int):])
+	}
+	return ctx, errCommandRecorded
+}
+
+func TestRedisStorage_SlideWindowOnEachRequest(t *testing.T) {
+	for _, slide := range []bool{false, true} {
+		redisStorage := NewRedisStorageWithOptions(RedisOptions{Addr: "localhost:0", SlideWindowOnEachRequest: slide})
+		defer redisStorage.Close()
+
+		recorder := &scriptArgsRecorder{}
+		redisStorage.client.AddHook(recorder)
+
+		ctx := context.Background()
+		_, _, _ = redisStorage.Increment(ctx, "ip:1", time.Second)
+		_, _ = redisStorage.IncrementBy(ctx, "ip:1", 5, time.Second)
+		_, _ = redisStorage.CheckAndIncrement(ctx, "ip:1", Limit{Requests: 10, Window: time.Second, BlockTime: time.Minute})
+
+		// Os scripts recebem a opção como último argumento
+		assert.Equal(t, [][]interface{}{
+			{int64(1000), int64(1), slide},
+			{int64(1000), int64(5), slide},
+			{int64(10), int64(1000), int64(60000), slide},
+		}, recorder.args, slide)
+	}
+}
+
+func TestRedisStorage_ListBlockedEscapesPattern(t *testing.T) {
+	redisStorage := NewRedisStorageWithOptions(RedisOptions{Addr: "localhost:0", KeyPrefix: "app[*]:"})
+	defer redisStorage.Close()
+
+	recorder := &keyRecorder{}
+	redisStorage.client.AddHook(recorder)
+
+	ctx := context.Background()
+
+	// O prefixo é literal; no padrão, apenas "*" e "?" são curingas
+	_, err := redisStorage.ListBlocked(ctx, "ip:10.0.0.?[1]")
+	assert.ErrorIs(t, err, errCommandRecorded)
+	_, _ = redisStorage.ListBlocked(ctx, "")
+
+	assert.Equal(t, []string{`app\[\*\]:blocked:ip:10.0.0.?\[1\]`, `app\[\*\]:blocked:*`}, recorder.keys)
+}
+
+func TestRedisStorage_HealthyReportsUnreachableRedis(t *testing.T) {
+	redisStorage := NewRedisStorageWithOptions(RedisOptions{Addr: "localhost:0"})
+	defer redisStorage.Close()
+
+	recorder := &keyRecorder{}
+	redisStorage.client.AddHook(recorder)
+
+	err := redisStorage.Healthy(context.Background())
+	assert.ErrorIs(t, err, errCommandRecorded)
+	assert.ErrorContains(t, err, "falha ao conectar ao Redis")
+}
+
+// commandRecorder é um hook do go-redis que registra os nomes dos comandos e interrompe a
+// execução, permitindo verificar para qual cliente cada operação é roteada
+type commandRecorder struct {
+	commands []string
+}
+
+func (h *commandRecorder) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	h.commands = append(h.commands, cmd.Name())
+	return ctx, errCommandRecorded
+}
+
+func (h *commandRecorder) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h *commandRecorder) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	for _, cmd := range cmds {
+		if name := cmd.Name(); name != "multi" && name != "exec" {
+			h.commands = append(h.commands, name)
+		}
+	}
+	return ctx, errCommandRecorded
+}
+
+func (h *commandRecorder) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+// exerciseRedisStorage executa uma operação de cada tipo no armazenamento
+func exerciseRedisStorage(redisStorage *RedisStorage) {
+	ctx := context.Background()
+
+	_, _, _ = redisStorage.Increment(ctx, "ip:1", time.Second)
+	_, _ = redisStorage.CheckAndIncrement(ctx, "ip:1", Limit{Requests: 1, Window: time.Second})
+	_ = redisStorage.Block(ctx, "ip:1", time.Minute)
+	_ = redisStorage.SetDecision(ctx, "ip:1:retry", true, time.Second)
+	_, _, _ = redisStorage.Get(ctx, "ip:1")
+	_, _, _ = redisStorage.IsBlocked(ctx, "ip:1")
+	_, _, _ = redisStorage.GetDecision(ctx, "ip:1:retry")
+	_, _ = redisStorage.ReadHash(ctx, "token_config:abc")
+}
+
+func TestRedisStorage_ReadReplicaRouting(t *testing.T) {
+	redisStorage := NewRedisStorageWithOptions(RedisOptions{Addr: "localhost:0", ReadAddr: "localhost:1"})
+	defer redisStorage.Close()
+
+	writer, reader := &commandRecorder{}, &commandRecorder{}
+	redisStorage.client.AddHook(writer)
+	redisStorage.reader.AddHook(reader)
+
+	exerciseRedisStorage(redisStorage)
+
+	// Escritas e scripts vão para o primário; leituras simples, para a réplica
+	assert.NotContains(t, writer.commands, "get")
+	assert.NotContains(t, writer.commands, "pttl")
+	assert.NotContains(t, writer.commands, "hgetall")
+	assert.Contains(t, writer.commands, "set")
+	assert.Contains(t, writer.commands, "del")
+	assert.Equal(t, []string{"get", "pttl", "pttl", "get", "hgetall"}, reader.commands)
+}
+
+func TestRedisStorage_GetManyPipeline(t *testing.T) {
+	redisStorage := NewRedisStorageWithOptions(RedisOptions{Addr: "localhost:0", ReadAddr: "localhost:1", KeyPrefix: "app:"})
+	defer redisStorage.Close()
+
+	writer, reader := &commandRecorder{}, &keyRecorder{}
+	redisStorage.client.AddHook(writer)
+	redisStorage.reader.AddHook(reader)
+
+	ctx := context.Background()
+
+	// Um único pipeline na réplica lê o contador, a sua expiração e o bloqueio de cada chave
+	_, err := redisStorage.GetMany(ctx, []string{"ip:1", "token:abc"})
+	assert.ErrorIs(t, err, errCommandRecorded)
+	assert.Empty(t, writer.commands)
+	assert.Equal(t, []string{
+		"app:ip:1", "app:ip:1", "app:blocked:ip:1",
+		"app:token:abc", "app:token:abc", "app:blocked:token:abc",
+	}, reader.keys)
+
+	// Sem chaves, nenhum comando é enviado
+	states, err := redisStorage.GetMany(ctx, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, states)
+	assert.Len(t, reader.keys, 6)
+}
+
+func TestRedisStorage_StrongConsistencyReadsFromPrimary(t *testing.T) {
+	redisStorage := NewRedisStorageWithOptions(RedisOptions{Addr: "localhost:0", ReadAddr: "localhost:1", StrongConsistency: true})
+	defer redisStorage.Close()
+
+	assert.Same(t, redisStorage.client, redisStorage.reader)
+
+	writer := &commandRecorder{}
+	redisStorage.client.AddHook(writer)
+
+	exerciseRedisStorage(redisStorage)
+
+	assert.Contains(t, writer.commands, "pttl")
+	assert.Contains(t, writer.commands, "hgetall")
+}
+
+// deadlineRecorder é um hook do go-redis que registra o tempo restante até o prazo do contexto
+// de cada comando (zero sem prazo) e interrompe a execução
+type deadlineRecorder struct {
+	remaining map[string]time.Duration
+}
+
+func (h *deadlineRecorder) record(ctx context.Context, name string) {
+	if h.remaining == nil {
+		h.remaining = make(map[string]time.Duration)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		h.remaining[name] = time.Until(deadline)
+	} else {
+		h.remaining[name] = 0
+	}
+}
+
+func (h *deadlineRecorder) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	h.record(ctx, cmd.Name())
+	return ctx, errCommandRecorded
+}
+
+func (h *deadlineRecorder) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h *deadlineRecorder) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	for _, cmd := range cmds {
+		if name := cmd.Name(); name != "multi" && name != "exec" {
+			h.record(ctx, name)
+		}
+	}
+	return ctx, errCommandRecorded
+}
+
+func (h *deadlineRecorder) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+func TestRedisStorage_OperationTimeouts(t *testing.T) {
+	const readTimeout, writeTimeout = 100 * time.Millisecond, 5 * time.Second
+
+	redisStorage := NewRedisStorageWithOptions(RedisOptions{Addr: "localhost:0", ReadTimeout: readTimeout, WriteTimeout: writeTimeout})
+	defer redisStorage.Close()
+
+	recorder := &deadlineRecorder{}
+	redisStorage.client.AddHook(recorder)
+
+	exerciseRedisStorage(redisStorage)
+
+	// As leituras recebem o prazo de leitura; as escritas e os scripts, o de escrita
+	for _, name := range []string{"get", "pttl", "hgetall"} {
+		assert.Greater(t, recorder.remaining[name], time.Duration(0), name)
+		assert.LessOrEqual(t, recorder.remaining[name], readTimeout, name)
+	}
+	for _, name := range []string{"evalsha", "set", "del"} {
+		assert.Greater(t, recorder.remaining[name], readTimeout, name)
+		assert.LessOrEqual(t, recorder.remaining[name], writeTimeout, name)
+	}
+
+	// As verificações de saúde e o horário do servidor também recebem o prazo de leitura, para
+	// que um Redis travado não prenda a requisição que as dispara (ex: FallbackStorage)
+	_ = redisStorage.Healthy(context.Background())
+	_, _ = redisStorage.Time(context.Background())
+	for _, name := range []string{"ping", "time"} {
+		assert.Greater(t, recorder.remaining[name], time.Duration(0), name)
+		assert.LessOrEqual(t, recorder.remaining[name], readTimeout, name)
+	}
+
+	// Um prazo menor já definido no contexto não é estendido
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_ = redisStorage.Block(ctx, "ip:1", time.Minute)
+	assert.LessOrEqual(t, recorder.remaining["set"], 50*time.Millisecond)
+}
+
+func TestRedisStorage_WithoutOperationTimeouts(t *testing.T) {
+	redisStorage := NewRedisStorageWithOptions(RedisOptions{Addr: "localhost:0"})
+	defer redisStorage.Close()
+
+	recorder := &deadlineRecorder{}
+	redisStorage.client.AddHook(recorder)
+
+	exerciseRedisStorage(redisStorage)
+
+	// Sem tempos configurados, os comandos seguem apenas o prazo do contexto
+	assert.NotEmpty(t, recorder.remaining)
+	for name, remaining := range recorder.remaining {
+		assert.Zero(t, remaining, name)
+	}
+}
+
+// pipelineReplies é um hook do go-redis que substitui as respostas dos pipelines sem um
+// servidor Redis: reply define o resultado de cada comando e, como no Redis, Exec retorna o
+// primeiro erro entre eles
+type pipelineReplies struct {
+	reply func(i int, cmd redis.Cmder)
+}
+
+func (h *pipelineReplies) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return ctx, errCommandRecorded
+}
+
+func (h *pipelineReplies) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h *pipelineReplies) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ctx, errCommandRecorded
+}
+
+func (h *pipelineReplies) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	for i, cmd := range cmds {
+		cmd.SetErr(nil)
+		h.reply(i, cmd)
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRedisStorage_GetManyPartialFailure(t *testing.T) {
+	redisStorage := NewRedisStorageWithOptions(RedisOptions{Addr: "localhost:0"})
+	defer redisStorage.Close()
+
+	// O contador não existe, então Exec retorna redis.Nil, e o PTTL do bloqueio falha. Como no
+	// go-redis o erro retornado pelo hook é atribuído aos comandos sem erro, o cenário usa uma
+	// única chave, cujo PTTL do contador não é lido quando ele não existe.
+	errLoading := errors.New("LOADING Redis is loading the dataset in memory")
+	redisStorage.client.AddHook(&pipelineReplies{reply: func(i int, cmd redis.Cmder) {
+		switch i {
+		case 0:
+			cmd.SetErr(redis.Nil)
+		case 2:
+			cmd.SetErr(errLoading)
+		}
+	}})
+
+	// A falha parcial é retornada em vez de a chave ser reportada como bloqueada
+	states, err := redisStorage.GetMany(context.Background(), []string{"ip:1"})
+	assert.ErrorIs(t, err, errLoading)
+	assert.Nil(t, states)
+}
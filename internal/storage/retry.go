@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Valores padrão do decorator de retentativas
+const (
+	DefaultRetryAttempts   = 3
+	DefaultRetryBackoff    = 10 * time.Millisecond
+	DefaultRetryMaxBackoff = 200 * time.Millisecond
+)
+
+// RetryOptions configura as retentativas do RetryStorage
+type RetryOptions struct {
+	// Attempts é o número máximo de tentativas por chamada, incluindo a primeira.
+	// Zero usa DefaultRetryAttempts.
+	Attempts int
+
+	// Backoff é a espera antes da segunda tentativa, dobrada a cada nova tentativa.
+	// Zero usa DefaultRetryBackoff.
+	Backoff time.Duration
+
+	// MaxBackoff limita a espera entre tentativas. Zero usa DefaultRetryMaxBackoff.
+	MaxBackoff time.Duration
+}
+
+// RetryStorage encapsula um Storage e repete CheckAndIncrement, Increment, IsBlocked e Block
+// com backoff exponencial em caso de erro, para que falhas transitórias (ex: uma conexão
+// reiniciada) não rejeitem a requisição. As demais operações são apenas delegadas.
+//
+// Uma retentativa de CheckAndIncrement ou Increment pode contar a requisição duas vezes se o
+// comando chegou a ser aplicado antes do erro; o excesso é limitado à janela atual.
+type RetryStorage struct {
+	Storage
+
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+// NewRetryStorage cria um decorator de retentativas em torno do armazenamento informado
+func NewRetryStorage(inner Storage, opts RetryOptions) *RetryStorage {
+	if opts.Attempts <= 0 {
+		opts.Attempts = DefaultRetryAttempts
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = DefaultRetryBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultRetryMaxBackoff
+	}
+
+	return &RetryStorage{
+		Storage:    inner,
+		attempts:   opts.Attempts,
+		backoff:    opts.Backoff,
+		maxBackoff: opts.MaxBackoff,
+	}
+}
+
+// retry executa fn até obter sucesso, esgotar as tentativas ou o contexto terminar.
+// Erros de contexto e de circuito aberto não são repetidos.
+func (r *RetryStorage) retry(ctx context.Context, fn func() error) error {
+	backoff := r.backoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= r.attempts || !retryable(err) {
+			return err
+		}
+
+		// Não inicia uma espera que terminaria depois do prazo do contexto
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < backoff {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		backoff = min(backoff*2, r.maxBackoff)
+	}
+}
+
+// retryable indica se vale a pena repetir a chamada que retornou err
+func retryable(err error) bool {
+	return !errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded) &&
+		!errors.Is(err, ErrCircuitOpen)
+}
+
+// CheckAndIncrement decide a requisição repetindo em caso de erro
+func (r *RetryStorage) CheckAndIncrement(ctx context.Context, key string, limit Limit) (Decision, error) {
+	var decision Decision
+	err := r.retry(ctx, func() (err error) {
+		decision, err = r.Storage.CheckAndIncrement(ctx, key, limit)
+		return err
+	})
+	return decision, err
+}
+
+// Increment incrementa o contador repetindo em caso de erro
+func (r *RetryStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, bool, error) {
+	var count int64
+	var isNew bool
+	err := r.retry(ctx, func() (err error) {
+		count, isNew, err = r.Storage.Increment(ctx, key, window)
+		return err
+	})
+	return count, isNew, err
+}
+
+// IncrementBy soma amount ao contador repetindo em caso de erro. Como em Increment, uma
+// tentativa que falhou depois de aplicada no armazenamento é contada novamente.
+func (r *RetryStorage) IncrementBy(ctx context.Context, key string, amount int64, window time.Duration) (int64, error) {
+	var count int64
+	err := r.retry(ctx, func() (err error) {
+		count, err = r.Storage.IncrementBy(ctx, key, amount, window)
+		return err
+	})
+	return count, err
+}
+
+// IsBlocked verifica o bloqueio repetindo em caso de erro
+func (r *RetryStorage) IsBlocked(ctx context.Context, key string) (bool, time.Duration, error) {
+	var blocked bool
+	var ttl time.Duration
+	err := r.retry(ctx, func() (err error) {
+		blocked, ttl, err = r.Storage.IsBlocked(ctx, key)
+		return err
+	})
+	return blocked, ttl, err
+}
+
+// Block bloqueia a chave repetindo em caso de erro
+func (r *RetryStorage) Block(ctx context.Context, key string, duration time.Duration) error {
+	return r.retry(ctx, func() error {
+		return r.Storage.Block(ctx, key, duration)
+	})
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryStorage_SucceedsAfterTransientFailures(t *testing.T) {
+	inner := &stubStorage{failFirst: 2}
+	retryStorage := NewRetryStorage(inner, RetryOptions{Attempts: 3, Backoff: time.Millisecond})
+
+	count, _, err := retryStorage.Increment(context.Background(), "ip:1", time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.Equal(t, 3, inner.callCount())
+}
+
+func TestRetryStorage_GivesUpAfterAttempts(t *testing.T) {
+	inner := &stubStorage{failFirst: 5}
+	retryStorage := NewRetryStorage(inner, RetryOptions{Attempts: 3, Backoff: time.Millisecond})
+
+	err := retryStorage.Block(context.Background(), "ip:1", time.Minute)
+	assert.ErrorIs(t, err, errTransient)
+	assert.Equal(t, 3, inner.callCount())
+}
+
+func TestRetryStorage_RespectsContextDeadline(t *testing.T) {
+	inner := &stubStorage{failFirst: 5}
+	retryStorage := NewRetryStorage(inner, RetryOptions{Attempts: 5, Backoff: time.Second})
+
+	// O backoff ultrapassaria o prazo do contexto, então não há nova tentativa
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, _, err := retryStorage.IsBlocked(ctx, "ip:1")
+	assert.ErrorIs(t, err, errTransient)
+	assert.Equal(t, 1, inner.callCount())
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestRetryStorage_DoesNotRetryOpenCircuit(t *testing.T) {
+	inner := &stubStorage{err: ErrCircuitOpen}
+	retryStorage := NewRetryStorage(inner, RetryOptions{Attempts: 3, Backoff: time.Millisecond})
+
+	_, _, err := retryStorage.IsBlocked(context.Background(), "ip:1")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 1, inner.callCount())
+}
+
+func TestRetryStorage_DelegatesOtherOperations(t *testing.T) {
+	inner := &stubStorage{failFirst: 1}
+	retryStorage := NewRetryStorage(inner, RetryOptions{Attempts: 3, Backoff: time.Millisecond})
+
+	// Operações fora de Increment, IsBlocked e Block não são repetidas
+	err := retryStorage.SetDecision(context.Background(), "ip:1:key", true, time.Second)
+	assert.ErrorIs(t, err, errTransient)
+	assert.Equal(t, 1, inner.callCount())
+}
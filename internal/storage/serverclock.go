@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+)
+
+// DefaultServerClockRefresh é o intervalo padrão entre as consultas ao horário do servidor
+const DefaultServerClockRefresh = time.Minute
+
+// serverClockTimeout limita a consulta ao horário do servidor feita durante uma requisição
+const serverClockTimeout = time.Second
+
+// ServerClockOptions configura o ServerClock
+type ServerClockOptions struct {
+	// Refresh é o intervalo entre as consultas ao horário do servidor. Zero usa
+	// DefaultServerClockRefresh.
+	Refresh time.Duration
+
+	// Clock é o relógio local corrigido pela diferença para o servidor. Nil usa o relógio do
+	// sistema.
+	Clock clock.Clock
+}
+
+// ServerClock implementa clock.Clock com o horário de um servidor compartilhado (ex: o comando
+// TIME do Redis), para que instâncias com relógios dessincronizados tomem decisões
+// consistentes. A diferença entre o relógio local e o do servidor é medida a cada Refresh,
+// descontando metade do tempo de ida e volta, e aplicada ao relógio local nas demais leituras,
+// sem uma consulta por requisição. Se a consulta falhar, a última diferença conhecida (ou
+// nenhuma, antes da primeira consulta bem-sucedida) continua em uso até a próxima tentativa.
+type ServerClock struct {
+	local   clock.Clock
+	fetch   func(ctx context.Context) (time.Time, error)
+	refresh time.Duration
+
+	mu       sync.Mutex
+	offset   time.Duration
+	syncedAt time.Time
+	synced   bool
+}
+
+// NewServerClock cria um relógio que segue o horário retornado por fetch
+func NewServerClock(fetch func(ctx context.Context) (time.Time, error), opts ServerClockOptions) *ServerClock {
+	if opts.Clock == nil {
+		opts.Clock = clock.New()
+	}
+	if opts.Refresh <= 0 {
+		opts.Refresh = DefaultServerClockRefresh
+	}
+
+	return &ServerClock{
+		local:   opts.Clock,
+		fetch:   fetch,
+		refresh: opts.Refresh,
+	}
+}
+
+// Now retorna o instante atual no relógio do servidor, consultando-o quando a última medição
+// tem mais de Refresh
+func (c *ServerClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.local.Now()
+	if !c.synced || now.Sub(c.syncedAt) >= c.refresh {
+		now = c.sync(now)
+	}
+
+	return now.Add(c.offset)
+}
+
+// Offset retorna a diferença medida entre o relógio do servidor e o local
+func (c *ServerClock) Offset() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.offset
+}
+
+// sync mede a diferença para o servidor e retorna o instante local após a consulta. Deve ser
+// chamado com o lock.
+func (c *ServerClock) sync(before time.Time) time.Time {
+	ctx, cancel := context.WithTimeout(context.Background(), serverClockTimeout)
+	defer cancel()
+
+	serverTime, err := c.fetch(ctx)
+	after := c.local.Now()
+
+	// Falhas também aguardam Refresh, para que um servidor indisponível não seja consultado a
+	// cada requisição
+	c.syncedAt = after
+	c.synced = true
+
+	if err == nil {
+		c.offset = serverTime.Sub(before.Add(after.Sub(before) / 2))
+	}
+
+	return after
+}
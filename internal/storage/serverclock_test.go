@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cleibson/goexpert-rate-limiter/internal/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ clock.Clock = (*ServerClock)(nil)
+
+// fakeServerTime simula o horário de um servidor compartilhado, contando as consultas
+type fakeServerTime struct {
+	clock *clock.FakeClock
+	err   error
+	calls int
+}
+
+func (f *fakeServerTime) fetch(ctx context.Context) (time.Time, error) {
+	f.calls++
+	if f.err != nil {
+		return time.Time{}, f.err
+	}
+	return f.clock.Now(), nil
+}
+
+func TestServerClock_ConsistentAcrossSkewedInstances(t *testing.T) {
+	serverNow := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	server := &fakeServerTime{clock: clock.NewFakeClock(serverNow)}
+
+	// Duas instâncias, uma adiantada e outra atrasada em relação ao servidor
+	ahead := clock.NewFakeClock(serverNow.Add(7 * time.Second))
+	behind := clock.NewFakeClock(serverNow.Add(-3 * time.Second))
+
+	aheadClock := NewServerClock(server.fetch, ServerClockOptions{Clock: ahead})
+	behindClock := NewServerClock(server.fetch, ServerClockOptions{Clock: behind})
+
+	assert.Equal(t, serverNow, aheadClock.Now())
+	assert.Equal(t, serverNow, behindClock.Now())
+	assert.Equal(t, -7*time.Second, aheadClock.Offset())
+	assert.Equal(t, 3*time.Second, behindClock.Offset())
+
+	// Entre as consultas, a diferença medida é aplicada ao relógio local
+	for _, c := range []*clock.FakeClock{server.clock, ahead, behind} {
+		c.Advance(30 * time.Second)
+	}
+	assert.Equal(t, serverNow.Add(30*time.Second), aheadClock.Now())
+	assert.Equal(t, serverNow.Add(30*time.Second), behindClock.Now())
+	assert.Equal(t, 2, server.calls)
+}
+
+func TestServerClock_Refresh(t *testing.T) {
+	serverNow := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	server := &fakeServerTime{clock: clock.NewFakeClock(serverNow)}
+	local := clock.NewFakeClock(serverNow)
+
+	c := NewServerClock(server.fetch, ServerClockOptions{Clock: local, Refresh: time.Minute})
+	assert.Equal(t, serverNow, c.Now())
+
+	// O relógio local passa a divergir; a correção ocorre na próxima consulta
+	local.Advance(30 * time.Second)
+	server.clock.Advance(32 * time.Second)
+	assert.Equal(t, serverNow.Add(30*time.Second), c.Now())
+	assert.Equal(t, 1, server.calls)
+
+	local.Advance(30 * time.Second)
+	server.clock.Advance(30 * time.Second)
+	assert.Equal(t, serverNow.Add(62*time.Second), c.Now())
+	assert.Equal(t, 2*time.Second, c.Offset())
+	assert.Equal(t, 2, server.calls)
+}
+
+func TestServerClock_FetchErrorKeepsLastOffset(t *testing.T) {
+	serverNow := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	server := &fakeServerTime{clock: clock.NewFakeClock(serverNow)}
+	local := clock.NewFakeClock(serverNow.Add(5 * time.Second))
+
+	c := NewServerClock(server.fetch, ServerClockOptions{Clock: local, Refresh: time.Minute})
+	assert.Equal(t, serverNow, c.Now())
+
+	server.err = errors.New("redis indisponível")
+	local.Advance(time.Minute)
+	assert.Equal(t, serverNow.Add(time.Minute), c.Now())
+	assert.Equal(t, -5*time.Second, c.Offset())
+
+	// A falha não é repetida a cada leitura
+	c.Now()
+	assert.Equal(t, 2, server.calls)
+}
+
+func TestServerClock_CompensatesRoundTrip(t *testing.T) {
+	serverNow := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	local := clock.NewFakeClock(serverNow)
+
+	// A consulta leva 100ms e o servidor responde no meio do caminho
+	fetch := func(ctx context.Context) (time.Time, error) {
+		local.Advance(100 * time.Millisecond)
+		return serverNow.Add(50 * time.Millisecond), nil
+	}
+
+	c := NewServerClock(fetch, ServerClockOptions{Clock: local})
+	assert.Equal(t, serverNow.Add(100*time.Millisecond), c.Now())
+	assert.Zero(t, c.Offset())
+}
@@ -5,17 +5,133 @@ import (
 	"time"
 )
 
+// Limit descreve o limite de janela fixa avaliado por CheckAndIncrement
+type Limit struct {
+	// Requests é o número máximo de requisições permitidas na janela
+	Requests int64
+
+	// Window é a duração da janela do contador
+	Window time.Duration
+
+	// BlockTime é a duração do bloqueio aplicado ao exceder o limite; zero apenas rejeita
+	// até a janela terminar
+	BlockTime time.Duration
+}
+
+// Decision é o resultado de CheckAndIncrement
+type Decision struct {
+	// Allowed indica se a requisição está dentro do limite
+	Allowed bool
+
+	// Count é a contagem da janela após o incremento; zero se a chave já estava bloqueada
+	Count int64
+
+	// Blocked indica que a chave está bloqueada, seja por um bloqueio anterior ou por esta
+	// requisição
+	Blocked bool
+
+	// NewlyBlocked indica que esta requisição excedeu o limite e aplicou o bloqueio
+	NewlyBlocked bool
+
+	// TTL é o tempo restante do bloqueio, para chaves bloqueadas, ou da janela atual
+	TTL time.Duration
+}
+
+// KeyState é o estado de uma chave lido por GetMany: o contador e o tempo restante da janela,
+// como em Get, e o bloqueio e o seu tempo restante, como em IsBlocked
+type KeyState struct {
+	Count    int64
+	TTL      time.Duration
+	Blocked  bool
+	BlockTTL time.Duration
+}
+
 // Storage define a interface para estratégias de armazenamento do rate limiter
 type Storage interface {
-	// Increment incrementa o contador para uma chave específica e retorna a contagem atual
-	Increment(ctx context.Context, key string, window time.Duration) (int64, error)
+	// Increment incrementa o contador para uma chave específica e retorna a contagem atual. A
+	// expiração de window é definida apenas no início da janela, atomicamente com o incremento,
+	// e isNew indica que este incremento criou o contador, iniciando a janela.
+	Increment(ctx context.Context, key string, window time.Duration) (count int64, isNew bool, err error)
+
+	// IncrementBy soma amount ao contador de uma chave, com a mesma janela de Increment, e
+	// retorna a contagem atual. Usado por requisições com peso (ex: bytes do corpo).
+	IncrementBy(ctx context.Context, key string, amount int64, window time.Duration) (int64, error)
+
+	// IncrementSliding incrementa o contador de uma chave e renova a sua expiração para ttl a
+	// cada incremento, de modo que ele só é zerado após ttl sem incrementos (ex: reincidências
+	// de bloqueio). Retorna a contagem atual.
+	IncrementSliding(ctx context.Context, key string, ttl time.Duration) (int64, error)
+
+	// CheckAndIncrement verifica o bloqueio, incrementa o contador e bloqueia a chave ao
+	// exceder o limite em uma única operação atômica, de modo que requisições concorrentes não
+	// ultrapassem o limite nem disparem bloqueios duplicados. Uma chave bloqueada não tem o
+	// contador incrementado.
+	CheckAndIncrement(ctx context.Context, key string, limit Limit) (Decision, error)
 
-	// IsBlocked verifica se uma chave está atualmente bloqueada
-	IsBlocked(ctx context.Context, key string) (bool, error)
+	// Get lê o contador de uma chave e o tempo restante da sua janela sem alterá-los.
+	// Uma chave inexistente retorna contagem e tempo restante zero.
+	Get(ctx context.Context, key string) (count int64, ttl time.Duration, err error)
 
-	// Block bloqueia uma chave pela duração especificada
+	// IsBlocked verifica se uma chave está atualmente bloqueada e retorna o tempo restante do
+	// bloqueio, para que o Retry-After informe quando a chave será liberada. Uma chave não
+	// bloqueada retorna tempo restante zero, assim como um bloqueio sem expiração.
+	IsBlocked(ctx context.Context, key string) (blocked bool, ttl time.Duration, err error)
+
+	// GetMany lê o contador e o bloqueio de várias chaves de uma vez (ex: em um único pipeline
+	// no Redis), sem alterá-los. O resultado segue a ordem das chaves, e chaves inexistentes
+	// retornam o estado zero.
+	GetMany(ctx context.Context, keys []string) ([]KeyState, error)
+
+	// Block bloqueia uma chave pela duração especificada e zera o seu contador, para que a
+	// chave volte a ter o limite completo disponível quando o bloqueio expirar
 	Block(ctx context.Context, key string, duration time.Duration) error
 
+	// ListBlocked lista, em ordem, as chaves atualmente bloqueadas que correspondem ao padrão,
+	// em que "*" corresponde a qualquer sequência e "?" a um único caractere (vazio lista
+	// todas). Percorre todas as chaves do armazenamento, então é destinada a consultas
+	// operacionais, não ao caminho de cada requisição.
+	ListBlocked(ctx context.Context, pattern string) ([]string, error)
+
+	// Reset remove o contador, o bloqueio e o leaky bucket de uma chave, devolvendo o limite
+	// completo. Resetar uma chave inexistente não tem efeito.
+	Reset(ctx context.Context, key string) error
+
+	// LeakyBucket adiciona uma requisição ao leaky bucket da chave, que escoa uma requisição a
+	// cada leakInterval e comporta no máximo capacity requisições. Quando o bucket está cheio a
+	// requisição é rejeitada e o tempo estimado até haver espaço é retornado.
+	LeakyBucket(ctx context.Context, key string, capacity int64, leakInterval time.Duration, now time.Time) (allowed bool, wait time.Duration, err error)
+
+	// FirstSeen registra now como o primeiro contato da chave, caso ainda não exista registro,
+	// renova a expiração do registro para ttl e retorna o instante do primeiro contato
+	FirstSeen(ctx context.Context, key string, now time.Time, ttl time.Duration) (time.Time, error)
+
+	// GetDecision obtém a decisão registrada para uma chave de idempotência, se existir
+	GetDecision(ctx context.Context, key string) (allowed bool, found bool, err error)
+
+	// SetDecision registra a decisão para uma chave de idempotência pela duração especificada
+	SetDecision(ctx context.Context, key string, allowed bool, ttl time.Duration) error
+
+	// Acquire ocupa uma das limit vagas simultâneas da chave, retornando false sem ocupá-la
+	// quando todas estão em uso. Cada aquisição renova a expiração das vagas para ttl, de modo
+	// que vagas não liberadas (ex: queda da instância) sejam descartadas.
+	Acquire(ctx context.Context, key string, limit int64, ttl time.Duration) (bool, error)
+
+	// Release libera uma vaga ocupada por Acquire. Liberar uma chave sem vagas ocupadas não tem
+	// efeito.
+	Release(ctx context.Context, key string) error
+
+	// Healthy verifica se o armazenamento está acessível, retornando o erro encontrado
+	Healthy(ctx context.Context) error
+
 	// Close fecha a conexão de armazenamento
 	Close() error
 }
+
+// AlwaysHealthy fornece a implementação padrão de Healthy para armazenamentos sem
+// dependências externas, como os mantidos em memória. Basta incorporá-lo à implementação.
+type AlwaysHealthy struct{}
+
+// Healthy sempre reporta o armazenamento como acessível
+func (AlwaysHealthy) Healthy(ctx context.Context) error {
+	return nil
+}